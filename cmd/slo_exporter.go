@@ -13,22 +13,12 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/spf13/viper"
 
 	"github.com/seznam/slo-exporter/pkg/config"
-	"github.com/seznam/slo-exporter/pkg/dynamic_classifier"
-	"github.com/seznam/slo-exporter/pkg/envoy_access_log_server"
-	"github.com/seznam/slo-exporter/pkg/event_key_generator"
-	"github.com/seznam/slo-exporter/pkg/event_metadata_renamer"
-	"github.com/seznam/slo-exporter/pkg/kafka_ingester"
-	"github.com/seznam/slo-exporter/pkg/metadata_classifier"
+	"github.com/seznam/slo-exporter/pkg/dead_letter"
+	"github.com/seznam/slo-exporter/pkg/modules"
 	"github.com/seznam/slo-exporter/pkg/pipeline"
-	"github.com/seznam/slo-exporter/pkg/prometheus_exporter"
-	"github.com/seznam/slo-exporter/pkg/prometheus_ingester"
-	"github.com/seznam/slo-exporter/pkg/relabel"
-	"github.com/seznam/slo-exporter/pkg/slo_event_producer"
-	"github.com/seznam/slo-exporter/pkg/statistical_classifier"
-	"github.com/seznam/slo-exporter/pkg/tailer"
+	"github.com/seznam/slo-exporter/pkg/replay"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
@@ -54,43 +44,29 @@ var (
 		Help:        "Metadata metric with information about application build and version",
 		ConstLabels: prometheus.Labels{"app": "slo-exporter", "version": version, "revision": commit, "build_date": date, "built_by": builtBy},
 	})
+	configReloadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "config_reloads_total",
+		Help: "Total number of configuration reloads by result.",
+	}, []string{"result"})
+	configLastReloadSuccessful = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "config_last_reload_successful",
+		Help: "Whether the last configuration reload attempt was successful.",
+	})
+	configLastReloadSuccessTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "config_last_reload_success_timestamp_seconds",
+		Help: "Timestamp of the last successful configuration reload.",
+	})
 )
 
 func init() {
+	modules.RegisterBuiltinTypes(version)
 	appBuildInfo.Set(1)
 	prometheusRegistry.MustRegister(appBuildInfo)
-}
-
-// Factory to instantiate pipeline modules.
-func moduleFactory(moduleName string, logger logrus.FieldLogger, conf *viper.Viper) (pipeline.Module, error) {
-	switch moduleName {
-	case "tailer":
-		return tailer.NewFromViper(conf, logger)
-	case "prometheusIngester":
-		return prometheus_ingester.NewFromViper(conf, logger, version)
-	case "kafkaIngester":
-		return kafka_ingester.NewFromViper(conf, logger)
-	case "envoyAccessLogServer":
-		return envoy_access_log_server.NewFromViper(conf, logger)
-	case "eventMetadataRenamer":
-		return event_metadata_renamer.NewFromViper(conf, logger)
-	case "relabel":
-		return relabel.NewFromViper(conf, logger)
-	case "eventKeyGenerator":
-		return event_key_generator.NewFromViper(conf, logger)
-	case "metadataClassifier":
-		return metadata_classifier.NewFromViper(conf, logger)
-	case "dynamicClassifier":
-		return dynamic_classifier.NewFromViper(conf, logger)
-	case "statisticalClassifier":
-		return statistical_classifier.NewFromViper(conf, logger)
-	case "sloEventProducer":
-		return slo_event_producer.NewFromViper(conf, logger)
-	case "prometheusExporter":
-		return prometheus_exporter.NewFromViper(conf, logger)
-	default:
-		return nil, fmt.Errorf("unknown module %s", moduleName)
-	}
+	configReloadsTotal.WithLabelValues("success").Add(0)
+	configReloadsTotal.WithLabelValues("failure").Add(0)
+	configLastReloadSuccessful.Set(1)
+	configLastReloadSuccessTimestamp.SetToCurrentTime()
+	wrappedPrometheusRegistry.MustRegister(configReloadsTotal, configLastReloadSuccessful, configLastReloadSuccessTimestamp)
 }
 
 func setupLogger(logLevel, logFormat string) (*logrus.Logger, error) {
@@ -120,6 +96,38 @@ func setupLogger(logLevel, logFormat string) (*logrus.Logger, error) {
 	return newLogger, nil
 }
 
+// reloadConfig loads the configuration file again and passes it to the pipeline modules.
+func reloadConfig(configFilePath string, pipelineManager *pipeline.Manager, logger *logrus.Logger) error {
+	conf := config.New(logger.WithField("component", "config"))
+	err := conf.LoadFromFile(configFilePath)
+	if err == nil {
+		err = pipelineManager.ReloadConfig(conf)
+	}
+	if err != nil {
+		configReloadsTotal.WithLabelValues("failure").Inc()
+		configLastReloadSuccessful.Set(0)
+		return err
+	}
+	configReloadsTotal.WithLabelValues("success").Inc()
+	configLastReloadSuccessful.Set(1)
+	configLastReloadSuccessTimestamp.SetToCurrentTime()
+	return nil
+}
+
+func setupReloadHandler(router *mux.Router, reloadRequestChan chan<- chan error) {
+	router.HandleFunc("/-/reload", func(w http.ResponseWriter, req *http.Request) {
+		resultChan := make(chan error, 1)
+		select {
+		case reloadRequestChan <- resultChan:
+		case <-req.Context().Done():
+			return
+		}
+		if err := <-resultChan; err != nil {
+			http.Error(w, "failed to reload configuration: "+err.Error(), http.StatusInternalServerError)
+		}
+	}).Methods(http.MethodPost)
+}
+
 func setupDefaultServer(listenAddr string, liveness, readiness *prober.Prober, logger *logrus.Logger) (*http.Server, *mux.Router) {
 	dynamicLoggingHandler := func(w http.ResponseWriter, req *http.Request) {
 		if req.Method == http.MethodPost {
@@ -153,6 +161,64 @@ func setupDefaultServer(listenAddr string, liveness, readiness *prober.Prober, l
 	return &http.Server{Addr: listenAddr, Handler: router}, router
 }
 
+// replayPipeline runs the pipeline until all the producers finish and writes the recorded metrics to the output file.
+// Metrics of the pipeline itself are not recorded.
+func replayPipeline(pipelineManager *pipeline.Manager, deadLetterSink *dead_letter.Sink, outputPath string, resolution time.Duration, maxSamples int, logger *logrus.Logger) error {
+	recorder, err := replay.New(resolution, maxSamples)
+	if err != nil {
+		return err
+	}
+	if err := pipelineManager.SetReplayRecorder(recorder); err != nil {
+		return err
+	}
+	registry := prometheus.NewRegistry()
+	if err := pipelineManager.RegisterPrometheusMetrics(registry, prometheus.WrapRegistererWithPrefix(appName+"_", registry)); err != nil {
+		return fmt.Errorf("failed to register pipeline metrics: %w", err)
+	}
+	if deadLetterSink != nil {
+		pipelineManager.SetDeadLetterSink(deadLetterSink)
+		deadLetterSink.Run()
+		defer func() {
+			if err := deadLetterSink.Close(); err != nil {
+				logger.Errorf("failed to close the dead-letter sink: %v", err)
+			}
+		}()
+	}
+
+	logger.Infof("replaying events with resolution %s", resolution)
+	if err := pipelineManager.StartPipeline(); err != nil {
+		return fmt.Errorf("failed to start the pipeline: %w", err)
+	}
+	sigChan := make(chan os.Signal, 3)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer signal.Stop(sigChan)
+	select {
+	case <-pipelineManager.Done():
+		logger.Info("finished processing all events")
+	case sig := <-sigChan:
+		logger.Infof("received signal %+v, stopping the replay", sig)
+	case err := <-pipelineManager.Errors():
+		logger.Errorf("pipeline module failed, stopping the replay: %v", err)
+	}
+	if err := pipelineManager.StopPipeline(context.Background()); err != nil {
+		return fmt.Errorf("failed to stop the pipeline: %w", err)
+	}
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create the replay output: %w", err)
+	}
+	_, err = recorder.WriteTo(output)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the replay output: %w", err)
+	}
+	logger.Infof("replayed metrics written to %s", outputPath)
+	return nil
+}
+
 func main() {
 	// Enable mutex and block profiling
 	runtime.SetBlockProfileRate(1)
@@ -163,6 +229,10 @@ func main() {
 	logFormat := kingpin.Flag("log-format", "Log format (text, json).").Envar("SLO_EXPORTER_LOGFORMAT").Default("text").Enum("json", "text")
 	checkConfig := kingpin.Flag("check-config", "Only check config file and exit with 0 if ok and other status code if not.").Default("false").Bool()
 	versionFlag := kingpin.Flag("version", "Display version.").Default("false").Bool()
+	replayFlag := kingpin.Flag("replay", "Process the events until all the producers finish and write the resulting metrics with timestamps of the events to the replay output instead of serving them.").Default("false").Bool()
+	replayOutput := kingpin.Flag("replay-output", "Path to the file where to write the replayed metrics in the OpenMetrics format.").Default("replay.om").String()
+	replayResolution := kingpin.Flag("replay-resolution", "Interval between the samples of the replayed metrics.").Default("1m").Duration()
+	replayMaxSamples := kingpin.Flag("replay-max-samples", "Maximum number of the replayed samples, all of them are kept in memory until the replay output is written.").Default("10000000").Int()
 	kingpin.Parse()
 
 	// If version is requested, end here.
@@ -188,17 +258,32 @@ func main() {
 	}
 
 	// Initialize the pipeline
-	pipelineManager, err := pipeline.NewManager(moduleFactory, conf, logger.WithField("component", "pipeline_manager"))
+	pipelineManager, err := pipeline.NewManager(pipeline.NewRegisteredModule, conf, logger.WithField("component", "pipeline_manager"))
 	if err != nil {
 		logger.Fatalf("failed to initialize the pipeline: %v", err)
 	}
 
+	var deadLetterSink *dead_letter.Sink
+	if deadLetterSinkConfig := conf.DeadLetterSinkConfig(); deadLetterSinkConfig != nil {
+		deadLetterSink, err = dead_letter.NewFromViper(deadLetterSinkConfig, logger.WithField("component", "dead_letter_sink"))
+		if err != nil {
+			logger.Fatalf("failed to initialize the dead-letter sink: %v", err)
+		}
+	}
+
 	// If configuration check is required, end here.
 	if *checkConfig {
 		logger.Info("Configuration is valid!")
 		return
 	}
 
+	if *replayFlag {
+		if err := replayPipeline(pipelineManager, deadLetterSink, *replayOutput, *replayResolution, *replayMaxSamples, logger); err != nil {
+			logger.Fatalf("failed to replay the events: %v", err)
+		}
+		return
+	}
+
 	liveness, err := prober.NewLiveness(prometheusRegistry, logger.WithField("component", "prober"))
 	if err != nil {
 		logger.Fatalf("failed to initialize liveness prober: %v", err)
@@ -211,6 +296,7 @@ func main() {
 	// shared error channel
 	errChan := make(chan error, 10)
 	gracefulShutdownRequestChan := make(chan struct{}, 10)
+	reloadRequestChan := make(chan chan error)
 
 	// Start default server
 	defaultServer, router := setupDefaultServer(conf.WebServerListenAddress, liveness, readiness, logger)
@@ -224,33 +310,52 @@ func main() {
 	if err := pipelineManager.RegisterPrometheusMetrics(prometheusRegistry, wrappedPrometheusRegistry); err != nil {
 		logger.Fatalf("failed to register pipeline metrics: %v", err)
 	}
+	if deadLetterSink != nil {
+		if err := deadLetterSink.RegisterMetrics(wrappedPrometheusRegistry); err != nil {
+			logger.Fatalf("failed to register dead-letter sink metrics: %v", err)
+		}
+		pipelineManager.SetDeadLetterSink(deadLetterSink)
+		deadLetterSink.Run()
+	}
 	pipelineManager.RegisterWebInterface(router)
+	pipelineManager.RegisterHealthChecks(readiness, liveness)
+	setupReloadHandler(router, reloadRequestChan)
 
 	// Start the pipeline processing
 	if err := pipelineManager.StartPipeline(); err != nil {
 		logger.Fatalf("failed to start the pipeline: %v", err)
 	}
+	healthChecksCtx, stopHealthChecks := context.WithCancel(context.Background())
+	go readiness.RunChecks(healthChecksCtx, conf.HealthCheckInterval)
+	go liveness.RunChecks(healthChecksCtx, conf.HealthCheckInterval)
 
 	// listen for OS signals
 	sigChan := make(chan os.Signal, 3)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	reloadSigChan := make(chan os.Signal, 1)
+	signal.Notify(reloadSigChan, syscall.SIGHUP)
 
 	readiness.Ok()
-	pipelineDoneCheck := time.NewTicker(time.Second)
-
-	defer func() {
-		pipelineDoneCheck.Stop()
-		logger.Info("see you next time!")
-	}()
+	pipelineDone := pipelineManager.Done()
+	pipelineFailed := false
 
 	for {
 		select {
 		case <-gracefulShutdownRequestChan:
 			logger.Info("gracefully shutting down")
-			readiness.NotOk(fmt.Errorf("shutting down"))
+			readiness.NotOk(prober.ErrShuttingDown)
+			stopHealthChecks()
 			shutdownCtx, cancel := context.WithTimeout(context.Background(), conf.MaximumGracefulShutdownDuration)
-			<-pipelineManager.StopPipeline(shutdownCtx)
+			if err := pipelineManager.StopPipeline(shutdownCtx); err != nil {
+				logger.Errorf("failed to stop the pipeline: %v", err)
+				pipelineFailed = true
+			}
 			cancel()
+			if deadLetterSink != nil {
+				if err := deadLetterSink.Close(); err != nil {
+					logger.Errorf("failed to close the dead-letter sink: %v", err)
+				}
+			}
 			// Add the delay after pipeline shutdown.
 			delayedShutdownContext, cancel := context.WithTimeout(shutdownCtx, conf.AfterPipelineShutdownDelay)
 			// Wait until any of the context expires
@@ -261,18 +366,38 @@ func main() {
 			if err := defaultServer.Shutdown(shutdownCtx); err != nil {
 				logger.Errorf("failed to gracefully shutdown HTTP server %+v. ", err)
 			}
+			logger.Info("see you next time!")
+			if pipelineFailed {
+				os.Exit(1)
+			}
 			return
 		case sig := <-sigChan:
 			logger.Infof("received signal %+v", sig)
 			gracefulShutdownRequestChan <- struct{}{}
+		case <-reloadSigChan:
+			logger.Info("received SIGHUP, reloading configuration")
+			if err := reloadConfig(*configFilePath, pipelineManager, logger); err != nil {
+				logger.Errorf("failed to reload configuration: %v", err)
+			}
+		case resultChan := <-reloadRequestChan:
+			logger.Info("reloading configuration requested using the web interface")
+			err := reloadConfig(*configFilePath, pipelineManager, logger)
+			if err != nil {
+				logger.Errorf("failed to reload configuration: %v", err)
+			}
+			resultChan <- err
 		case err := <-errChan:
 			logger.Errorf("encountered error: %+v", err)
 			gracefulShutdownRequestChan <- struct{}{}
-		case <-pipelineDoneCheck.C:
-			if pipelineManager.Done() {
-				logger.Info("finished processing all logs")
-				gracefulShutdownRequestChan <- struct{}{}
-			}
+		case err := <-pipelineManager.Errors():
+			logger.Errorf("pipeline module failed, shutting down: %v", err)
+			pipelineFailed = true
+			gracefulShutdownRequestChan <- struct{}{}
+		case <-pipelineDone:
+			logger.Info("finished processing all logs")
+			// Closed channel would be selected repeatedly.
+			pipelineDone = nil
+			gracefulShutdownRequestChan <- struct{}{}
 		}
 	}
 }
@@ -3,8 +3,10 @@ package config
 import (
 	"fmt"
 	"os"
+	"reflect"
 	"time"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
@@ -13,14 +15,43 @@ func New(logger logrus.FieldLogger) *Config {
 	return &Config{logger: logger}
 }
 
+// PipelineEntry defines single module in the pipeline and modules it should receive events from.
+type PipelineEntry struct {
+	// Name of the module instance, its configuration is loaded from the `modules.<Name>` key.
+	Name string
+	// Type of the module, if empty the Name is used as the module type.
+	Type string
+	// Inputs lists names of the modules events of which should be passed to this module.
+	// If empty, ingester module is linked to the module defined right before it in the pipeline.
+	Inputs []string
+	// Concurrency is number of workers processing the events, supported only by some modules.
+	Concurrency int
+	// BufferSize is number of events which can wait in the queue of every input of the module.
+	BufferSize int
+	// StopTimeout limits how long to wait for the module to finish once it should stop, zero means no limit.
+	StopTimeout time.Duration
+}
+
 type Config struct {
-	Pipeline                        []string
+	Pipeline                        []PipelineEntry
 	LogLevel                        string
 	WebServerListenAddress          string
 	MaximumGracefulShutdownDuration time.Duration
 	AfterPipelineShutdownDelay      time.Duration
+	HealthCheckInterval             time.Duration
+	PipelineStallTimeout            time.Duration
 	Modules                         map[string]interface{}
-	logger                          logrus.FieldLogger
+	// DeadLetterSink configures output for events rejected by the modules, it is optional.
+	DeadLetterSink map[string]interface{}
+	logger         logrus.FieldLogger
+}
+
+// ModuleType returns type of the module instance.
+func (e PipelineEntry) ModuleType() string {
+	if e.Type == "" {
+		return e.Name
+	}
+	return e.Type
 }
 
 func (c *Config) setupViper() {
@@ -29,12 +60,32 @@ func (c *Config) setupViper() {
 	viper.AutomaticEnv()
 }
 
+// pipelineEntryFromStringHook allows to define the pipeline entry only using the module name.
+func pipelineEntryFromStringHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(PipelineEntry{}) {
+		return data, nil
+	}
+	return map[string]interface{}{"name": data}, nil
+}
+
+func decoderConfigOption() viper.DecoderConfigOption {
+	return viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		// Keep the default viper hooks.
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		pipelineEntryFromStringHook,
+	))
+}
+
 func (c *Config) LoadFromFile(path string) error {
 	c.setupViper()
 	viper.SetDefault("LogLevel", "info")
 	viper.SetDefault("WebServerListenAddress", "0.0.0.0:8080")
 	viper.SetDefault("MaximumGracefulShutdownDuration", 20*time.Second)
 	viper.SetDefault("AfterPipelineShutdownDelay", 0*time.Second)
+	viper.SetDefault("HealthCheckInterval", 5*time.Second)
+	viper.SetDefault("PipelineStallTimeout", 0*time.Second)
+
 	yamlFile, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open configuration file: %w", err)
@@ -42,7 +93,7 @@ func (c *Config) LoadFromFile(path string) error {
 	if err := viper.ReadConfig(yamlFile); err != nil {
 		return fmt.Errorf("failed to load configuration file: %w", err)
 	}
-	if err := viper.UnmarshalExact(c); err != nil {
+	if err := viper.UnmarshalExact(c, decoderConfigOption()); err != nil {
 		return fmt.Errorf("failed to unmarshall configuration file: %w", err)
 	}
 	return nil
@@ -57,3 +108,14 @@ func (c *Config) ModuleConfig(moduleName string) (*viper.Viper, error) {
 	subConfig.AutomaticEnv()
 	return subConfig, nil
 }
+
+// DeadLetterSinkConfig returns configuration of the dead-letter sink or nil if it is not configured.
+func (c *Config) DeadLetterSinkConfig() *viper.Viper {
+	subConfig := viper.Sub("deadLetterSink")
+	if subConfig == nil {
+		return nil
+	}
+	subConfig.SetEnvPrefix("slo_exporter_deadLetterSink")
+	subConfig.AutomaticEnv()
+	return subConfig
+}
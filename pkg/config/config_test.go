@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_LoadFromFile(t *testing.T) {
+	tests := []struct {
+		name             string
+		content          string
+		expectedPipeline []PipelineEntry
+		expectErr        bool
+	}{
+		{
+			name:             "pipeline defined only by module names",
+			content:          `pipeline: ["tailer", "relabel"]`,
+			expectedPipeline: []PipelineEntry{{Name: "tailer"}, {Name: "relabel"}},
+		},
+		{
+			name: "pipeline with explicit inputs",
+			content: `
+pipeline:
+  - tailer
+  - kafkaIngester
+  - name: relabel
+    inputs: ["tailer", "kafkaIngester"]
+`,
+			expectedPipeline: []PipelineEntry{{Name: "tailer"}, {Name: "kafkaIngester"}, {Name: "relabel", Inputs: []string{"tailer", "kafkaIngester"}}},
+		},
+		{
+			name: "pipeline with named module instances",
+			content: `
+pipeline:
+  - tailer
+  - name: relabelBeforeClassification
+    type: relabel
+  - dynamicClassifier
+  - name: relabelAfterClassification
+    type: relabel
+`,
+			expectedPipeline: []PipelineEntry{{Name: "tailer"}, {Name: "relabelBeforeClassification", Type: "relabel"}, {Name: "dynamicClassifier"}, {Name: "relabelAfterClassification", Type: "relabel"}},
+		},
+		{
+			name: "pipeline with concurrency and buffer size",
+			content: `
+pipeline:
+  - tailer
+  - name: dynamicClassifier
+    concurrency: 4
+    bufferSize: 100
+`,
+			expectedPipeline: []PipelineEntry{{Name: "tailer"}, {Name: "dynamicClassifier", Concurrency: 4, BufferSize: 100}},
+		},
+		{
+			name: "pipeline with stop timeout",
+			content: `
+pipeline:
+  - name: tailer
+    stopTimeout: 5s
+`,
+			expectedPipeline: []PipelineEntry{{Name: "tailer", StopTimeout: 5 * time.Second}},
+		},
+		{
+			name: "unknown pipeline entry field",
+			content: `
+pipeline:
+  - name: relabel
+    foo: bar
+`,
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(viper.Reset)
+			path := filepath.Join(t.TempDir(), "slo_exporter.yaml")
+			assert.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+			conf := New(logrus.New())
+			err := conf.LoadFromFile(path)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedPipeline, conf.Pipeline)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package dead_letter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	fileSinkType  = "file"
+	kafkaSinkType = "kafka"
+)
+
+// Record is a single event rejected by the pipeline.
+type Record struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Stage is name of the module which rejected the event.
+	Stage  string `json:"stage"`
+	Reason string `json:"reason"`
+	// Payload is the original input of the module, either raw data it failed to parse or the event itself.
+	Payload interface{} `json:"payload"`
+}
+
+// SinkConfig is configuration of the dead-letter sink.
+type SinkConfig struct {
+	// Type of the sink, one of `file` or `kafka`.
+	Type string
+	// BufferSize is number of records waiting to be written, new records are dropped when the buffer is full.
+	BufferSize int
+	File       FileWriterConfig
+	Kafka      KafkaWriterConfig
+}
+
+// recordWriter writes the serialized records to the output.
+type recordWriter interface {
+	write(record marshaledRecord) error
+	close() error
+}
+
+type marshaledRecord struct {
+	stage string
+	data  []byte
+}
+
+// Sink asynchronously writes records of the rejected events.
+type Sink struct {
+	writer    recordWriter
+	records   chan marshaledRecord
+	started   bool
+	closed    bool
+	closedMtx sync.RWMutex
+	done      chan struct{}
+	logger    logrus.FieldLogger
+
+	recordsTotal        *prometheus.CounterVec
+	droppedRecordsTotal prometheus.Counter
+	errorsTotal         prometheus.Counter
+}
+
+func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*Sink, error) {
+	var config SinkConfig
+	viperConfig.SetDefault("bufferSize", 1000)
+	viperConfig.SetDefault("file.maxSizeMegabytes", 100)
+	viperConfig.SetDefault("file.maxBackups", 3)
+	if err := viperConfig.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return New(config, logger)
+}
+
+// New returns new Sink writing to the output of configured type.
+func New(config SinkConfig, logger logrus.FieldLogger) (*Sink, error) {
+	sink := Sink{
+		records: make(chan marshaledRecord, config.BufferSize),
+		done:    make(chan struct{}),
+		logger:  logger,
+		recordsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "dead_letter_records_total",
+			Help: "Total number of events rejected by the pipeline modules.",
+		}, []string{"stage"}),
+		droppedRecordsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "dead_letter_dropped_records_total",
+			Help: "Total number of dead-letter records dropped because the buffer was full.",
+		}),
+		errorsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "dead_letter_errors_total",
+			Help: "Total number of errors while writing the dead-letter records.",
+		}),
+	}
+	var err error
+	switch config.Type {
+	case fileSinkType:
+		sink.writer, err = newFileWriter(config.File)
+	case kafkaSinkType:
+		sink.writer, err = newKafkaWriter(config.Kafka, sink.errorsTotal, logger)
+	default:
+		err = fmt.Errorf("unknown dead-letter sink type %q, must be one of %q or %q", config.Type, fileSinkType, kafkaSinkType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sink, nil
+}
+
+func (s *Sink) RegisterMetrics(wrappedRegistry prometheus.Registerer) error {
+	toRegister := []prometheus.Collector{s.recordsTotal, s.droppedRecordsTotal, s.errorsTotal}
+	for _, collector := range toRegister {
+		if err := wrappedRegistry.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write passes the record to be written, it never blocks and drops the record if the buffer is full.
+// The record is serialized immediately so the payload can be modified once Write returns.
+func (s *Sink) Write(record Record) {
+	s.recordsTotal.WithLabelValues(record.Stage).Inc()
+	data, err := json.Marshal(record)
+	if err != nil {
+		s.errorsTotal.Inc()
+		s.logger.Errorf("failed to serialize dead-letter record: %v", err)
+		return
+	}
+	s.closedMtx.RLock()
+	defer s.closedMtx.RUnlock()
+	if s.closed {
+		s.droppedRecordsTotal.Inc()
+		return
+	}
+	select {
+	case s.records <- marshaledRecord{stage: record.Stage, data: data}:
+	default:
+		s.droppedRecordsTotal.Inc()
+	}
+}
+
+// ForStage returns sink recording events rejected by the given pipeline stage.
+func (s *Sink) ForStage(stage string) *StageSink {
+	return &StageSink{sink: s, stage: stage}
+}
+
+// Run starts writing the records until the Sink is closed, it does nothing if the Sink is already running or closed.
+func (s *Sink) Run() {
+	s.closedMtx.Lock()
+	defer s.closedMtx.Unlock()
+	if s.started || s.closed {
+		return
+	}
+	s.started = true
+	go s.writeRecords()
+}
+
+func (s *Sink) writeRecords() {
+	defer close(s.done)
+	for record := range s.records {
+		if err := s.writer.write(record); err != nil {
+			s.errorsTotal.Inc()
+			s.logger.Errorf("failed to write dead-letter record: %v", err)
+		}
+	}
+}
+
+// Close writes all the buffered records and closes the output.
+// The records are written by Close itself if the Sink has never been run.
+func (s *Sink) Close() error {
+	s.closedMtx.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.records)
+	}
+	started := s.started
+	s.started = true
+	s.closedMtx.Unlock()
+	if started {
+		<-s.done
+	} else {
+		s.writeRecords()
+	}
+	return s.writer.close()
+}
+
+// StageSink records events rejected by single stage of the pipeline.
+type StageSink struct {
+	sink  *Sink
+	stage string
+}
+
+// Reject records the payload rejected by the stage for the given reason.
+func (s *StageSink) Reject(payload interface{}, reason string) {
+	s.sink.Write(Record{
+		Timestamp: time.Now(),
+		Stage:     s.stage,
+		Reason:    reason,
+		Payload:   payload,
+	})
+}
@@ -0,0 +1,100 @@
+package dead_letter
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFromViper(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      map[string]interface{}
+		expectError bool
+	}{
+		{name: "file sink", config: map[string]interface{}{"type": "file", "file": map[string]interface{}{"path": "/tmp/dead_letter.jsonl"}}},
+		{name: "file sink without path", config: map[string]interface{}{"type": "file"}, expectError: true},
+		{name: "kafka sink", config: map[string]interface{}{"type": "kafka", "kafka": map[string]interface{}{"brokers": []string{"localhost:9092"}, "topic": "dead-letter"}}},
+		{name: "kafka sink without topic", config: map[string]interface{}{"type": "kafka", "kafka": map[string]interface{}{"brokers": []string{"localhost:9092"}}}, expectError: true},
+		{name: "unknown type", config: map[string]interface{}{"type": "foo"}, expectError: true},
+		{name: "unknown key", config: map[string]interface{}{"type": "file", "foo": "bar"}, expectError: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := viper.New()
+			assert.NoError(t, conf.MergeConfigMap(tc.config))
+			_, err := NewFromViper(conf, logrus.New())
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSink_FileWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead_letter.jsonl")
+	sink, err := New(SinkConfig{Type: fileSinkType, BufferSize: 10, File: FileWriterConfig{Path: path, MaxSizeMegabytes: 1}}, logrus.New())
+	assert.NoError(t, err)
+	sink.Run()
+	sink.ForStage("tailer").Reject("malformed line", "failed to parse line")
+	sink.ForStage("relabel").Reject(map[string]string{"foo": "bar"}, "dropped by relabel config")
+	assert.NoError(t, sink.Close())
+	// Records written after the sink is closed are dropped.
+	sink.ForStage("relabel").Reject("late", "dropped by relabel config")
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		assert.NotEmpty(t, record["timestamp"])
+		delete(record, "timestamp")
+		records = append(records, record)
+	}
+	assert.Equal(t, []map[string]interface{}{
+		{"stage": "tailer", "reason": "failed to parse line", "payload": "malformed line"},
+		{"stage": "relabel", "reason": "dropped by relabel config", "payload": map[string]interface{}{"foo": "bar"}},
+	}, records)
+	assert.Equal(t, 1.0, testutil.ToFloat64(sink.recordsTotal.WithLabelValues("tailer")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(sink.droppedRecordsTotal))
+}
+
+func TestSink_FullBuffer(t *testing.T) {
+	sink, err := New(SinkConfig{Type: fileSinkType, BufferSize: 1, File: FileWriterConfig{Path: filepath.Join(t.TempDir(), "dead_letter.jsonl"), MaxSizeMegabytes: 1}}, logrus.New())
+	assert.NoError(t, err)
+	// The sink is not running, so only the first record fits into the buffer.
+	sink.ForStage("tailer").Reject("first", "failed to parse line")
+	sink.ForStage("tailer").Reject("second", "failed to parse line")
+	assert.Equal(t, 2.0, testutil.ToFloat64(sink.recordsTotal.WithLabelValues("tailer")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(sink.droppedRecordsTotal))
+	sink.Run()
+	assert.NoError(t, sink.Close())
+}
+
+func TestSink_CloseWithoutRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead_letter.jsonl")
+	sink, err := New(SinkConfig{Type: fileSinkType, BufferSize: 10, File: FileWriterConfig{Path: path, MaxSizeMegabytes: 1}}, logrus.New())
+	assert.NoError(t, err)
+	sink.ForStage("tailer").Reject("malformed line", "failed to parse line")
+	// The buffered records are written by Close since the sink has never been run.
+	assert.NoError(t, sink.Close())
+	assert.NoError(t, sink.Close())
+	// Run of the closed sink does nothing.
+	sink.Run()
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"payload":"malformed line"`)
+}
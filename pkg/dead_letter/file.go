@@ -0,0 +1,48 @@
+package dead_letter
+
+import (
+	"fmt"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// FileWriterConfig is configuration of the dead-letter sink writing to a file.
+type FileWriterConfig struct {
+	// Path of the file the records are appended to as JSON lines.
+	Path string
+	// MaxSizeMegabytes is size of the file after which it gets rotated.
+	MaxSizeMegabytes int
+	// MaxBackups is number of the rotated files to keep.
+	MaxBackups int
+	// Compress the rotated files using gzip.
+	Compress bool
+}
+
+// fileWriter writes the records to a file which is rotated once it reaches the configured size.
+type fileWriter struct {
+	logger *lumberjack.Logger
+}
+
+func newFileWriter(config FileWriterConfig) (*fileWriter, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("path of the dead-letter file must be set")
+	}
+	if config.MaxSizeMegabytes <= 0 {
+		return nil, fmt.Errorf("maxSizeMegabytes of the dead-letter file must be positive, got %d", config.MaxSizeMegabytes)
+	}
+	return &fileWriter{logger: &lumberjack.Logger{
+		Filename:   config.Path,
+		MaxSize:    config.MaxSizeMegabytes,
+		MaxBackups: config.MaxBackups,
+		Compress:   config.Compress,
+	}}, nil
+}
+
+func (f *fileWriter) write(record marshaledRecord) error {
+	_, err := f.logger.Write(append(record.data, '\n'))
+	return err
+}
+
+func (f *fileWriter) close() error {
+	return f.logger.Close()
+}
@@ -0,0 +1,50 @@
+package dead_letter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// KafkaWriterConfig is configuration of the dead-letter sink writing to a Kafka topic.
+type KafkaWriterConfig struct {
+	Brokers []string
+	Topic   string
+}
+
+// kafkaWriter produces the records as messages to a Kafka topic, using stage of the record as the message key.
+type kafkaWriter struct {
+	writer *kafka.Writer
+}
+
+func newKafkaWriter(config KafkaWriterConfig, errorsTotal prometheus.Counter, logger logrus.FieldLogger) (*kafkaWriter, error) {
+	if len(config.Brokers) == 0 {
+		return nil, fmt.Errorf("at least one Kafka broker of the dead-letter sink must be set")
+	}
+	if config.Topic == "" {
+		return nil, fmt.Errorf("topic of the dead-letter Kafka sink must be set")
+	}
+	return &kafkaWriter{writer: &kafka.Writer{
+		Addr:  kafka.TCP(config.Brokers...),
+		Topic: config.Topic,
+		// Messages are batched in background, write errors are reported only in the completion callback.
+		Async: true,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				errorsTotal.Add(float64(len(messages)))
+				logger.Errorf("failed to write %d dead-letter records to Kafka: %v", len(messages), err)
+			}
+		},
+	}}, nil
+}
+
+func (k *kafkaWriter) write(record marshaledRecord) error {
+	return k.writer.WriteMessages(context.Background(), kafka.Message{Key: []byte(record.stage), Value: record.data})
+}
+
+func (k *kafkaWriter) close() error {
+	return k.writer.Close()
+}
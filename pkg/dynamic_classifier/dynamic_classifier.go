@@ -1,13 +1,16 @@
 package dynamic_classifier
 
 import (
+	"context"
 	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"slices"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -26,25 +29,18 @@ const (
 	unclassifiedEventLabel = "unclassified"
 )
 
-var (
-	// TODO matcher cache size, matcher or something related has to implement the prometheus.Collector interface and count the items.
-	matcherOperationDurationSeconds = prometheus.NewHistogramVec(
+// TODO matcher cache size, matcher or something related has to implement the prometheus.Collector interface and count the items.
+func newMatcherOperationDurationSeconds() *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "matcher_operation_duration_seconds",
 			Help:    "Histogram of duration matcher operations in dynamic classifier.",
 			Buckets: prometheus.ExponentialBuckets(0.0001, 5, 7),
 		}, []string{"operation", "matcher_type"})
+}
 
-	errorsTotal = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "errors_total",
-			Help: "Total number of processed events by result.",
-		},
-		[]string{"type"},
-	)
-)
-
-type classifierConfig struct {
+// ClassifierConfig is configuration of the dynamic classifier module.
+type ClassifierConfig struct {
 	UnclassifiedEventMetadataKeys []string
 	ExactMatchesCsvFiles          []string
 	RegexpMatchesCsvFiles         []string
@@ -54,13 +50,16 @@ type classifierConfig struct {
 type DynamicClassifier struct {
 	exactMatches                  matcher
 	regexpMatches                 matcher
+	matchersMtx                   sync.RWMutex
 	unclassifiedEventMetadataKeys []string
 	eventsMetric                  *prometheus.CounterVec
+	errorsTotal                   *prometheus.CounterVec
+	matcherOperationDuration      *prometheus.HistogramVec
 	observer                      pipeline.EventProcessingDurationObserver
 	inputChannel                  chan *event.Raw
 	outputChannel                 chan *event.Raw
 	logger                        logrus.FieldLogger
-	done                          bool
+	concurrency                   int
 }
 
 func (dc *DynamicClassifier) RegisterInMux(router *mux.Router) {
@@ -82,7 +81,7 @@ func (dc *DynamicClassifier) String() string {
 }
 
 func (dc *DynamicClassifier) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
-	toRegister := []prometheus.Collector{dc.eventsMetric, errorsTotal, matcherOperationDurationSeconds}
+	toRegister := []prometheus.Collector{dc.eventsMetric, dc.errorsTotal, dc.matcherOperationDuration}
 	for _, collector := range toRegister {
 		if err := wrappedRegistry.Register(collector); err != nil {
 			return err
@@ -91,11 +90,8 @@ func (dc *DynamicClassifier) RegisterMetrics(_, wrappedRegistry prometheus.Regis
 	return nil
 }
 
-func (dc *DynamicClassifier) Done() bool {
-	return dc.done
-}
-
-func (dc *DynamicClassifier) Stop() {
+func (dc *DynamicClassifier) SetConcurrency(workers int) {
+	dc.concurrency = workers
 }
 
 func (dc *DynamicClassifier) SetInputChannel(channel chan *event.Raw) {
@@ -107,39 +103,90 @@ func (dc *DynamicClassifier) OutputChannel() chan *event.Raw {
 }
 
 func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*DynamicClassifier, error) {
-	var config classifierConfig
+	var config ClassifierConfig
 	if err := viperConfig.UnmarshalExact(&config); err != nil {
 		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
 	return New(config, logger)
 }
 
+// PrepareReload loads the classification CSV files again and replaces the current matchers once applied.
+// Classifications cached from the already processed events are dropped.
+// The UnclassifiedEventMetadataKeys cannot be reloaded since they define labels of the already registered metric.
+func (dc *DynamicClassifier) PrepareReload(viperConfig *viper.Viper) (func() error, error) {
+	var config ClassifierConfig
+	if err := viperConfig.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	sort.Strings(config.UnclassifiedEventMetadataKeys)
+	if !slices.Equal(config.UnclassifiedEventMetadataKeys, dc.unclassifiedEventMetadataKeys) {
+		return nil, fmt.Errorf("unclassifiedEventMetadataKeys cannot be changed by reload, restart slo-exporter to apply the change")
+	}
+	exactMatches, regexpMatches, err := dc.newMatchersFromCSV(config)
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		dc.matchersMtx.Lock()
+		defer dc.matchersMtx.Unlock()
+		dc.exactMatches = exactMatches
+		dc.regexpMatches = regexpMatches
+		return nil
+	}, nil
+}
+
 func metadataKeyToLabel(metadataKey string) string {
 	return "metadata_" + strcase.ToSnake(metadataKey)
 }
 
 // New returns new instance of DynamicClassifier.
-func New(conf classifierConfig, logger logrus.FieldLogger) (*DynamicClassifier, error) {
+func New(conf ClassifierConfig, logger logrus.FieldLogger) (*DynamicClassifier, error) {
 	sort.Strings(conf.UnclassifiedEventMetadataKeys)
+	matcherOperationDuration := newMatcherOperationDurationSeconds()
 	classifier := DynamicClassifier{
-		exactMatches:                  newMemoryExactMatcher(logger),
-		regexpMatches:                 newRegexpMatcher(logger),
 		unclassifiedEventMetadataKeys: conf.UnclassifiedEventMetadataKeys,
-		inputChannel:                  make(chan *event.Raw),
-		outputChannel:                 make(chan *event.Raw),
-		done:                          false,
-		logger:                        logger,
+		errorsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "errors_total",
+				Help: "Total number of processed events by result.",
+			},
+			[]string{"type"},
+		),
+		matcherOperationDuration: matcherOperationDuration,
+		inputChannel:             make(chan *event.Raw),
+		outputChannel:            make(chan *event.Raw),
+		logger:                   logger,
 	}
 	classifier.initializeEventsMetric()
-	if err := classifier.LoadExactMatchesFromMultipleCSV(conf.ExactMatchesCsvFiles); err != nil {
-		return nil, fmt.Errorf("failed to load exact matches from CSV: %w", err)
-	}
-	if err := classifier.LoadRegexpMatchesFromMultipleCSV(conf.RegexpMatchesCsvFiles); err != nil {
-		return nil, fmt.Errorf("failed to load regexp matches from CSV: %w", err)
+	exactMatches, regexpMatches, err := classifier.newMatchersFromCSV(conf)
+	if err != nil {
+		return nil, err
 	}
+	classifier.exactMatches = exactMatches
+	classifier.regexpMatches = regexpMatches
 	return &classifier, nil
 }
 
+// newMatchersFromCSV creates new exact and regexp matchers with matches loaded from the configured CSV files.
+func (dc *DynamicClassifier) newMatchersFromCSV(conf ClassifierConfig) (matcher, matcher, error) {
+	exactMatches := newMemoryExactMatcher(dc.matcherOperationDuration, dc.logger)
+	if err := dc.loadMatchesFromMultipleCSV(exactMatches, conf.ExactMatchesCsvFiles); err != nil {
+		return nil, nil, fmt.Errorf("failed to load exact matches from CSV: %w", err)
+	}
+	regexpMatches := newRegexpMatcher(dc.matcherOperationDuration, dc.logger)
+	if err := dc.loadMatchesFromMultipleCSV(regexpMatches, conf.RegexpMatchesCsvFiles); err != nil {
+		return nil, nil, fmt.Errorf("failed to load regexp matches from CSV: %w", err)
+	}
+	return exactMatches, regexpMatches, nil
+}
+
+// matchers returns the currently used exact and regexp matchers.
+func (dc *DynamicClassifier) matchers() (matcher, matcher) {
+	dc.matchersMtx.RLock()
+	defer dc.matchersMtx.RUnlock()
+	return dc.exactMatches, dc.regexpMatches
+}
+
 func (dc *DynamicClassifier) initializeEventsMetric() {
 	labels := []string{"result", "classified_by"}
 	for _, key := range dc.unclassifiedEventMetadataKeys {
@@ -178,12 +225,14 @@ func (dc *DynamicClassifier) observeDuration(start time.Time) {
 
 // LoadExactMatchesFromMultipleCSV loads exact matches from csv.
 func (dc *DynamicClassifier) LoadExactMatchesFromMultipleCSV(paths []string) error {
-	return dc.loadMatchesFromMultipleCSV(dc.exactMatches, paths)
+	exactMatches, _ := dc.matchers()
+	return dc.loadMatchesFromMultipleCSV(exactMatches, paths)
 }
 
 // LoadRegexpMatchesFromMultipleCSV loads regexp matches from csv.
 func (dc *DynamicClassifier) LoadRegexpMatchesFromMultipleCSV(paths []string) error {
-	return dc.loadMatchesFromMultipleCSV(dc.regexpMatches, paths)
+	_, regexpMatches := dc.matchers()
+	return dc.loadMatchesFromMultipleCSV(regexpMatches, paths)
 }
 
 func (dc *DynamicClassifier) loadMatchesFromMultipleCSV(matcher matcher, paths []string) error {
@@ -252,14 +301,15 @@ func (dc *DynamicClassifier) Classify(newEvent *event.Raw) (bool, error) {
 		classification       *event.SloClassification
 		classifiedBy         matcherType
 	)
+	exactMatches, regexpMatches := dc.matchers()
 	if newEvent.IsClassified() {
-		if err := dc.exactMatches.set(newEvent.EventKey(), newEvent.SloClassification); err != nil {
+		if err := exactMatches.set(newEvent.EventKey(), newEvent.SloClassification); err != nil {
 			return true, fmt.Errorf("failed to set the exact matcher: %w", err)
 		}
 		return true, nil
 	}
 
-	classifiers := []matcher{dc.exactMatches, dc.regexpMatches}
+	classifiers := []matcher{exactMatches, regexpMatches}
 	for _, classifier := range classifiers {
 		var err error
 		classification, err = dc.classifyByMatch(classifier, newEvent)
@@ -284,7 +334,7 @@ func (dc *DynamicClassifier) Classify(newEvent *event.Raw) (bool, error) {
 
 	// Those matched by regex we want to write to the exact matcher so it is cached
 	if classifiedBy == regexpMatcherType {
-		if err := dc.exactMatches.set(newEvent.EventKey(), classification); err != nil {
+		if err := exactMatches.set(newEvent.EventKey(), classification); err != nil {
 			return true, fmt.Errorf("failed to set the exact matcher: %w", err)
 		}
 	}
@@ -293,9 +343,10 @@ func (dc *DynamicClassifier) Classify(newEvent *event.Raw) (bool, error) {
 
 // DumpCSV dump matches in CSV format to io.Writer.
 func (dc *DynamicClassifier) DumpCSV(w io.Writer, matcherType string) error {
+	exactMatches, regexpMatches := dc.matchers()
 	matchers := map[string]matcher{
-		string(dc.exactMatches.getType()):  dc.exactMatches,
-		string(dc.regexpMatches.getType()): dc.regexpMatches,
+		string(exactMatches.getType()):  exactMatches,
+		string(regexpMatches.getType()): regexpMatches,
 	}
 
 	matcher, ok := matchers[matcherType]
@@ -303,36 +354,39 @@ func (dc *DynamicClassifier) DumpCSV(w io.Writer, matcherType string) error {
 		return errors.New("MetadataMatcher '" + matcherType + "' does not exists")
 	}
 
-	return matcher.dumpCSV(w)
+	if err := matcher.dumpCSV(w); err != nil {
+		dc.errorsTotal.WithLabelValues("dump" + strcase.ToCamel(matcherType) + "MatchersToCSV").Inc()
+		return err
+	}
+	return nil
 }
 
 func (dc *DynamicClassifier) classifyByMatch(matcher matcher, e *event.Raw) (*event.SloClassification, error) {
 	return matcher.get(e.EventKey())
 }
 
-// Run event normalizer receiving events and filling their Key if not already filled.
-func (dc *DynamicClassifier) Run() {
-	go func() {
-		defer func() {
-			close(dc.outputChannel)
-			dc.done = true
-		}()
-
-		for newEvent := range dc.inputChannel {
-			start := time.Now()
-			classified, err := dc.Classify(newEvent)
-			if err != nil {
-				dc.logger.Error(err)
-				errorsTotal.WithLabelValues("failedToClassify").Inc()
-			}
-			if !classified {
-				dc.logger.Warnf("unable to classify %s", newEvent)
-			} else {
-				dc.logger.Debugf("processed newEvent with Key: %s", newEvent.EventKey())
-			}
-			dc.outputChannel <- newEvent
-			dc.observeDuration(start)
+func (dc *DynamicClassifier) processEvents() {
+	for newEvent := range dc.inputChannel {
+		start := time.Now()
+		classified, err := dc.Classify(newEvent)
+		if err != nil {
+			dc.logger.Error(err)
+			dc.errorsTotal.WithLabelValues("failedToClassify").Inc()
 		}
-		dc.logger.Info("input channel closed, finishing")
-	}()
+		if !classified {
+			dc.logger.Warnf("unable to classify %s", newEvent)
+		} else {
+			dc.logger.Debugf("processed newEvent with Key: %s", newEvent.EventKey())
+		}
+		dc.outputChannel <- newEvent
+		dc.observeDuration(start)
+	}
+}
+
+// Run event normalizer receiving events and filling their Key if not already filled.
+func (dc *DynamicClassifier) Run(_ context.Context) error {
+	defer close(dc.outputChannel)
+	pipeline.RunConcurrently(dc.concurrency, dc.processEvents)
+	dc.logger.Info("input channel closed, finishing")
+	return nil
 }
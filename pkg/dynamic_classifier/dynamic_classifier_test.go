@@ -8,10 +8,11 @@ import (
 
 	"github.com/seznam/slo-exporter/pkg/event"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
-func newClassifier(t *testing.T, config classifierConfig) *DynamicClassifier {
+func newClassifier(t *testing.T, config ClassifierConfig) *DynamicClassifier {
 	classifier, err := New(config, logrus.New())
 	if err != nil {
 		t.Error(err)
@@ -24,13 +25,13 @@ func goldenFile(t *testing.T) []string {
 }
 
 func TestLoadExactMatchesFromMultipleCSV(t *testing.T) {
-	config := classifierConfig{
+	config := ClassifierConfig{
 		ExactMatchesCsvFiles: goldenFile(t),
 	}
 	classifier := newClassifier(t, config)
 
 	expectedClassification := newTestSloClassification()
-	expectedExactMatches := newMemoryExactMatcher(logrus.New())
+	expectedExactMatches := newMemoryExactMatcher(newMatcherOperationDurationSeconds(), logrus.New())
 	expectedExactMatches.exactMatches["GET:/testing-endpoint"] = expectedClassification
 
 	classification, err := classifier.exactMatches.get("GET:/testing-endpoint")
@@ -39,7 +40,7 @@ func TestLoadExactMatchesFromMultipleCSV(t *testing.T) {
 }
 
 func TestLoadRegexpMatchesFromMultipleCSV(t *testing.T) {
-	config := classifierConfig{
+	config := ClassifierConfig{
 		RegexpMatchesCsvFiles: goldenFile(t),
 	}
 	classifier := newClassifier(t, config)
@@ -49,7 +50,7 @@ func TestLoadRegexpMatchesFromMultipleCSV(t *testing.T) {
 		regexpCompiled: regexp.MustCompile(".*"),
 		classification: expectedClassification,
 	}
-	expectedExactMatches := newRegexpMatcher(logrus.New())
+	expectedExactMatches := newRegexpMatcher(newMatcherOperationDurationSeconds(), logrus.New())
 	expectedExactMatches.matchers = append(expectedExactMatches.matchers, expectedRegexpSloClassification)
 
 	classification, err := classifier.regexpMatches.get("foo")
@@ -58,7 +59,7 @@ func TestLoadRegexpMatchesFromMultipleCSV(t *testing.T) {
 }
 
 func TestClassificationByExactMatches(t *testing.T) {
-	config := classifierConfig{
+	config := ClassifierConfig{
 		ExactMatchesCsvFiles: goldenFile(t),
 	}
 	classifier := newClassifier(t, config)
@@ -91,7 +92,7 @@ func TestClassificationByExactMatches(t *testing.T) {
 }
 
 func TestClassificationByRegexpMatches(t *testing.T) {
-	config := classifierConfig{
+	config := ClassifierConfig{
 		RegexpMatchesCsvFiles: goldenFile(t),
 	}
 	classifier := newClassifier(t, config)
@@ -136,7 +137,7 @@ func Test_DynamicClassifier_Classify_UpdatesEmptyCache(t *testing.T) {
 	classifiedEvent.SetEventKey(eventKey)
 
 	// test that classified event updates an empty exact matches cache
-	classifier := newClassifier(t, classifierConfig{})
+	classifier := newClassifier(t, ClassifierConfig{})
 	ok, err := classifier.Classify(classifiedEvent)
 	if !ok || err != nil {
 		t.Fatalf("unable to classify tested event %+v: %v", classifiedEvent, err)
@@ -162,7 +163,7 @@ func Test_DynamicClassifier_Classify_OverridesCacheFromConfig(t *testing.T) {
 	}
 	classifiedEvent.SetEventKey(eventKey)
 
-	classifier := newClassifier(t, classifierConfig{RegexpMatchesCsvFiles: goldenFile(t)})
+	classifier := newClassifier(t, ClassifierConfig{RegexpMatchesCsvFiles: goldenFile(t)})
 
 	ok, err := classifier.Classify(classifiedEvent)
 	if !ok || err != nil {
@@ -182,7 +183,7 @@ func Test_DynamicClassifier_Classify_OverridesCacheFromPreviousClassifiedEvent(t
 	eventKey := "GET:/testing-endpoint"
 	eventClasses := []string{"class1", "class2"}
 
-	classifier := newClassifier(t, classifierConfig{})
+	classifier := newClassifier(t, ClassifierConfig{})
 	for _, eventClass := range eventClasses {
 		classifiedEvent := &event.Raw{
 			SloClassification: &event.SloClassification{
@@ -219,3 +220,33 @@ func Test_DynamicClassifier_Classify_metadataKeyToLabel(t *testing.T) {
 		assert.Equal(t, output, metadataKeyToLabel(input))
 	}
 }
+
+func TestDynamicClassifier_PrepareReload(t *testing.T) {
+	classifier := newClassifier(t, ClassifierConfig{})
+	eventKey := "GET:/testing-endpoint"
+
+	invalidConfig := viper.New()
+	invalidConfig.Set("exactMatchesCsvFiles", []string{"testdata/nonexistent.csv"})
+	_, err := classifier.PrepareReload(invalidConfig)
+	assert.Error(t, err)
+
+	changedKeysConfig := viper.New()
+	changedKeysConfig.Set("unclassifiedEventMetadataKeys", []string{"userAgent"})
+	_, err = classifier.PrepareReload(changedKeysConfig)
+	assert.Error(t, err, "unclassifiedEventMetadataKeys cannot be reloaded")
+
+	validConfig := viper.New()
+	validConfig.Set("exactMatchesCsvFiles", []string{filepath.Join("testdata", "TestClassificationByExactMatches.golden")})
+	apply, err := classifier.PrepareReload(validConfig)
+	assert.NoError(t, err)
+	exactMatches, _ := classifier.matchers()
+	classification, err := exactMatches.get(eventKey)
+	assert.NoError(t, err)
+	assert.Nil(t, classification, "matchers must not change before the reload is applied")
+
+	assert.NoError(t, apply())
+	exactMatches, _ = classifier.matchers()
+	classification, err = exactMatches.get(eventKey)
+	assert.NoError(t, err)
+	assert.Equal(t, newTestSloClassification(), classification)
+}
@@ -33,11 +33,11 @@ func TestMatcher(t *testing.T) {
 		setErr      string
 		getErr      string
 	}{
-		{newMemoryExactMatcher(logger), "test", newTestSloClassification(), "test", newTestSloClassification(), "", ""},
-		{newMemoryExactMatcher(logger), "", newTestSloClassification(), "", newTestSloClassification(), "", ""},
-		{newMemoryExactMatcher(logger), "test", newTestSloClassification(), "aaa", nil, "", ""},
-		{newRegexpMatcher(logger), ".*", newTestSloClassification(), "aaa", newTestSloClassification(), "", ""},
-		{newRegexpMatcher(logger), ".*****", newTestSloClassification(), "aaa", newTestSloClassification(), "failed to create new regexp endpoint classification: error parsing regexp: invalid nested repetition operator: `**`", ""},
+		{newMemoryExactMatcher(newMatcherOperationDurationSeconds(), logger), "test", newTestSloClassification(), "test", newTestSloClassification(), "", ""},
+		{newMemoryExactMatcher(newMatcherOperationDurationSeconds(), logger), "", newTestSloClassification(), "", newTestSloClassification(), "", ""},
+		{newMemoryExactMatcher(newMatcherOperationDurationSeconds(), logger), "test", newTestSloClassification(), "aaa", nil, "", ""},
+		{newRegexpMatcher(newMatcherOperationDurationSeconds(), logger), ".*", newTestSloClassification(), "aaa", newTestSloClassification(), "", ""},
+		{newRegexpMatcher(newMatcherOperationDurationSeconds(), logger), ".*****", newTestSloClassification(), "aaa", newTestSloClassification(), "failed to create new regexp endpoint classification: error parsing regexp: invalid nested repetition operator: `**`", ""},
 	}
 
 	for _, v := range cases {
@@ -75,13 +75,13 @@ func testDumpCSV(t *testing.T, matcher matcher) {
 }
 
 func TestMatcherExactDumpCSV(t *testing.T) {
-	matcher := newMemoryExactMatcher(logrus.New())
+	matcher := newMemoryExactMatcher(newMatcherOperationDurationSeconds(), logrus.New())
 	matcher.exactMatches["test-endpoint"] = newTestSloClassification()
 	testDumpCSV(t, matcher)
 }
 
 func TestMatcherRegexpDumpCSV(t *testing.T) {
-	matcher := newRegexpMatcher(logrus.New())
+	matcher := newRegexpMatcher(newMatcherOperationDurationSeconds(), logrus.New())
 	matcher.matchers = append(matcher.matchers,
 		&regexpSloClassification{
 			regexpCompiled: regexp.MustCompile(".*"),
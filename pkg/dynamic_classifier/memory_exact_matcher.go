@@ -14,24 +14,26 @@ import (
 const exactMatcherType = "exact"
 
 type memoryExactMatcher struct {
-	exactMatches map[string]*event.SloClassification
-	mtx          sync.RWMutex
-	logger       logrus.FieldLogger
+	exactMatches             map[string]*event.SloClassification
+	mtx                      sync.RWMutex
+	operationDurationSeconds prometheus.ObserverVec
+	logger                   logrus.FieldLogger
 }
 
 // newMemoryExactMatcher returns instance of memoryCache.
-func newMemoryExactMatcher(logger logrus.FieldLogger) *memoryExactMatcher {
+func newMemoryExactMatcher(operationDurationSeconds prometheus.ObserverVec, logger logrus.FieldLogger) *memoryExactMatcher {
 	exactMatches := map[string]*event.SloClassification{}
 	return &memoryExactMatcher{
-		exactMatches: exactMatches,
-		mtx:          sync.RWMutex{},
-		logger:       logger,
+		exactMatches:             exactMatches,
+		mtx:                      sync.RWMutex{},
+		operationDurationSeconds: operationDurationSeconds,
+		logger:                   logger,
 	}
 }
 
 // set sets endpoint classification in cache.
 func (c *memoryExactMatcher) set(key string, classification *event.SloClassification) error {
-	timer := prometheus.NewTimer(matcherOperationDurationSeconds.WithLabelValues("set", exactMatcherType))
+	timer := prometheus.NewTimer(c.operationDurationSeconds.WithLabelValues("set", exactMatcherType))
 	defer timer.ObserveDuration()
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
@@ -42,7 +44,7 @@ func (c *memoryExactMatcher) set(key string, classification *event.SloClassifica
 
 // get gets endpoint classification from cache.
 func (c *memoryExactMatcher) get(key string) (*event.SloClassification, error) {
-	timer := prometheus.NewTimer(matcherOperationDurationSeconds.WithLabelValues("get", exactMatcherType))
+	timer := prometheus.NewTimer(c.operationDurationSeconds.WithLabelValues("get", exactMatcherType))
 	defer timer.ObserveDuration()
 	c.mtx.RLock()
 	defer c.mtx.RUnlock()
@@ -64,7 +66,6 @@ func (c *memoryExactMatcher) dumpCSV(w io.Writer) error {
 	for k, v := range c.exactMatches {
 		err := buffer.Write([]string{v.Domain, v.App, v.Class, k})
 		if err != nil {
-			errorsTotal.WithLabelValues("dumpExactMatchersToCSV").Inc()
 			return fmt.Errorf("failed to dump csv: %w", err)
 		}
 		buffer.Flush()
@@ -22,16 +22,18 @@ type regexpSloClassification struct {
 
 // regexpMatcher is list of endpoint classifications.
 type regexpMatcher struct {
-	matchers []*regexpSloClassification
-	mtx      sync.RWMutex
-	logger   logrus.FieldLogger
+	matchers                 []*regexpSloClassification
+	mtx                      sync.RWMutex
+	operationDurationSeconds prometheus.ObserverVec
+	logger                   logrus.FieldLogger
 }
 
 // newRegexpMatcher returns new instance of regexpMatcher.
-func newRegexpMatcher(logger logrus.FieldLogger) *regexpMatcher {
+func newRegexpMatcher(operationDurationSeconds prometheus.ObserverVec, logger logrus.FieldLogger) *regexpMatcher {
 	return &regexpMatcher{
-		mtx:    sync.RWMutex{},
-		logger: logger,
+		mtx:                      sync.RWMutex{},
+		operationDurationSeconds: operationDurationSeconds,
+		logger:                   logger,
 	}
 }
 
@@ -50,7 +52,7 @@ func newRegexSloClassification(regexpString string, classification *event.SloCla
 
 // set adds new endpoint classification regexp to list.
 func (rm *regexpMatcher) set(regexpString string, classification *event.SloClassification) error {
-	timer := prometheus.NewTimer(matcherOperationDurationSeconds.WithLabelValues("set", regexpMatcherType))
+	timer := prometheus.NewTimer(rm.operationDurationSeconds.WithLabelValues("set", regexpMatcherType))
 	defer timer.ObserveDuration()
 	rm.mtx.Lock()
 	defer rm.mtx.Unlock()
@@ -65,7 +67,7 @@ func (rm *regexpMatcher) set(regexpString string, classification *event.SloClass
 
 // get gets through all regexes and returns first endpoint classification which matches it.
 func (rm *regexpMatcher) get(key string) (*event.SloClassification, error) {
-	timer := prometheus.NewTimer(matcherOperationDurationSeconds.WithLabelValues("get", regexpMatcherType))
+	timer := prometheus.NewTimer(rm.operationDurationSeconds.WithLabelValues("get", regexpMatcherType))
 	defer timer.ObserveDuration()
 	rm.mtx.RLock()
 	defer rm.mtx.RUnlock()
@@ -100,7 +102,6 @@ func (rm *regexpMatcher) dumpCSV(w io.Writer) error {
 	for _, v := range rm.matchers {
 		err := buffer.Write([]string{v.classification.Domain, v.classification.App, v.classification.Class, v.regexpCompiled.String()})
 		if err != nil {
-			errorsTotal.WithLabelValues("dumpRegexpMatchersToCSV").Inc()
 			return fmt.Errorf("failed to dump csv: %w", err)
 		}
 		buffer.Flush()
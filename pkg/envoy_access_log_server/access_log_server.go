@@ -1,48 +1,43 @@
 package envoy_access_log_server
 
 import (
+	"context"
 	"fmt"
-	"net"
 	"time"
 
-	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/grpc_server"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
-	"google.golang.org/grpc"
 )
 
-var (
-	logEntriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+func newLogEntriesTotal() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "processed_logentries_total",
 		Help: "Total number of processed log entries.",
 	}, []string{"protocol", "api_version"})
+}
 
-	errorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+func newErrorsTotal() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "errors_total",
 		Help: "Errors while processing the received logs.",
 	}, []string{"type"})
-	serverMetrics *grpc_prometheus.ServerMetrics
-)
+}
 
-type accessLogServerConfig struct {
+// AccessLogServerConfig is configuration of the Envoy access log server module.
+type AccessLogServerConfig struct {
 	Address                 string
 	GracefulShutdownTimeout time.Duration
 }
 
 type AccessLogServer struct {
-	outputChannel           chan *event.Raw
-	logger                  logrus.FieldLogger
-	done                    bool
-	server                  *grpc.Server
-	serviceV3               *AccessLogServiceV3
-	address                 string
-	gracefulShutdownTimeout time.Duration
-}
-
-func init() {
-	serverMetrics = grpc_prometheus.NewServerMetrics()
+	outputChannel   chan *event.Raw
+	logger          logrus.FieldLogger
+	server          *grpc_server.Server
+	logEntriesTotal *prometheus.CounterVec
+	errorsTotal     *prometheus.CounterVec
 }
 
 func (als *AccessLogServer) String() string {
@@ -52,7 +47,7 @@ func (als *AccessLogServer) String() string {
 func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*AccessLogServer, error) {
 	viperConfig.SetDefault("address", ":18090")
 	viperConfig.SetDefault("gracefulShutdownTimeout", 5*time.Second)
-	var config accessLogServerConfig
+	var config AccessLogServerConfig
 	if err := viperConfig.UnmarshalExact(&config); err != nil {
 		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
@@ -60,75 +55,42 @@ func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*AccessL
 }
 
 // New returns an instance of AccessLogServer.
-func New(config accessLogServerConfig, logger logrus.FieldLogger) (*AccessLogServer, error) {
+func New(config AccessLogServerConfig, logger logrus.FieldLogger) (*AccessLogServer, error) {
 	als := AccessLogServer{
-		outputChannel:           make(chan *event.Raw),
-		logger:                  logger,
-		address:                 config.Address,
-		gracefulShutdownTimeout: config.GracefulShutdownTimeout,
+		outputChannel:   make(chan *event.Raw),
+		logger:          logger,
+		server:          grpc_server.New(grpc_server.Config{Address: config.Address, GracefulShutdownTimeout: config.GracefulShutdownTimeout}, logger),
+		logEntriesTotal: newLogEntriesTotal(),
+		errorsTotal:     newErrorsTotal(),
 	}
 	return &als, nil
 }
 
-func (als *AccessLogServer) Run() {
-	listen, err := net.Listen("tcp", als.address)
-	if err != nil {
-		als.logger.Fatalf("Error while starting the %s: %v", als, err)
+// Run serves the access logs feeding events to output channel until the context is cancelled.
+func (als *AccessLogServer) Run(ctx context.Context) error {
+	defer close(als.outputChannel)
+	serviceV3 := &AccessLogServiceV3{
+		outChan:         als.outputChannel,
+		logEntriesTotal: als.logEntriesTotal,
+		errorsTotal:     als.errorsTotal,
+		logger:          als.logger.WithField("EnvoyApiVersion", "3"),
 	}
-	als.server = grpc.NewServer(
-		grpc.StreamInterceptor(serverMetrics.StreamServerInterceptor()),
-	)
-
-	als.serviceV3 = &AccessLogServiceV3{
-		outChan: als.outputChannel,
-		logger:  als.logger.WithField("EnvoyApiVersion", "3"),
+	serviceV3.Register(als.server.GRPCServer())
+	if err := als.server.Run(ctx); err != nil {
+		return fmt.Errorf("%s failed: %w", als, err)
 	}
-	als.serviceV3.Register(als.server)
-
-	serverMetrics.InitializeMetrics(als.server)
-
-	// Start the server
-	go func() {
-		err = als.server.Serve(listen)
-		if err != nil {
-			als.logger.Errorf("%s GRPC server fatal error, initiating graceful shutdown: %v", als, err)
-			als.Stop()
-		}
-	}()
-}
-
-func (als *AccessLogServer) Stop() {
-	go func() {
-		// Initiate the server shutdown
-		stopped := make(chan struct{})
-		go func() {
-			als.server.GracefulStop()
-			close(stopped)
-		}()
-		t := time.NewTimer(als.gracefulShutdownTimeout)
-		select {
-		case <-t.C:
-		case <-stopped:
-		}
-		als.server.Stop()
-		close(als.outputChannel)
-		als.done = true
-	}()
-}
-
-func (als *AccessLogServer) Done() bool {
-	return als.done
+	return nil
 }
 
 func (als *AccessLogServer) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
-	toRegister := []prometheus.Collector{logEntriesTotal, errorsTotal}
+	toRegister := []prometheus.Collector{als.logEntriesTotal, als.errorsTotal}
 	for _, collector := range toRegister {
 		if err := wrappedRegistry.Register(collector); err != nil {
 			return fmt.Errorf("error registering metric %s: %w", collector, err)
 		}
 	}
-	if err := wrappedRegistry.Register(serverMetrics); err != nil {
-		return fmt.Errorf("error registering metric %+v: %w", serverMetrics, err)
+	if err := wrappedRegistry.Register(als.server.Metrics()); err != nil {
+		return fmt.Errorf("error registering metric %+v: %w", als.server.Metrics(), err)
 	}
 	return nil
 }
@@ -9,6 +9,7 @@ import (
 
 	envoy_data_accesslog_v3 "github.com/envoyproxy/go-control-plane/envoy/data/accesslog/v3"
 	envoy_service_accesslog_v3 "github.com/envoyproxy/go-control-plane/envoy/service/accesslog/v3"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 
@@ -17,20 +18,22 @@ import (
 )
 
 type AccessLogServiceV3 struct {
-	outChan chan *event.Raw
-	logger  logrus.FieldLogger
+	outChan         chan *event.Raw
+	logEntriesTotal *prometheus.CounterVec
+	errorsTotal     *prometheus.CounterVec
+	logger          logrus.FieldLogger
 	envoy_service_accesslog_v3.UnimplementedAccessLogServiceServer
 }
 
-func envoyV3AccessLogEntryCommonPropertiesToStringMap(logger logrus.FieldLogger, p *envoy_data_accesslog_v3.AccessLogCommon) stringmap.StringMap {
+func (service_v3 *AccessLogServiceV3) envoyV3AccessLogEntryCommonPropertiesToStringMap(p *envoy_data_accesslog_v3.AccessLogCommon) stringmap.StringMap {
 	m := stringmap.StringMap{}
 	if p.DownstreamDirectRemoteAddress != nil {
 		if sa := p.DownstreamDirectRemoteAddress.GetSocketAddress(); sa != nil {
 			m["downstreamDirectRemoteAddress"] = sa.GetAddress()
 			m["downstreamDirectRemotePort"] = fmt.Sprint(sa.GetPortValue())
 		} else {
-			logger.Warnf("%s address is in unsupported format", "DownstreamDirectRemoteAddress")
-			errorsTotal.WithLabelValues("AddressFormatUnsupported").Inc()
+			service_v3.logger.Warnf("%s address is in unsupported format", "DownstreamDirectRemoteAddress")
+			service_v3.errorsTotal.WithLabelValues("AddressFormatUnsupported").Inc()
 		}
 	}
 	if p.DownstreamRemoteAddress != nil {
@@ -38,8 +41,8 @@ func envoyV3AccessLogEntryCommonPropertiesToStringMap(logger logrus.FieldLogger,
 			m["downstreamRemoteAddress"] = sa.GetAddress()
 			m["downstreamRemotePort"] = fmt.Sprint(sa.GetPortValue())
 		} else {
-			logger.Warnf("%s address is in unsupported format", "DownstreamRemoteAddress")
-			errorsTotal.WithLabelValues("AddressFormatUnsupported").Inc()
+			service_v3.logger.Warnf("%s address is in unsupported format", "DownstreamRemoteAddress")
+			service_v3.errorsTotal.WithLabelValues("AddressFormatUnsupported").Inc()
 		}
 	}
 	if p.DownstreamLocalAddress != nil {
@@ -47,15 +50,15 @@ func envoyV3AccessLogEntryCommonPropertiesToStringMap(logger logrus.FieldLogger,
 			m["downstreamLocalAddress"] = sa.GetAddress()
 			m["downstreamLocalPort"] = fmt.Sprint(sa.GetPortValue())
 		} else {
-			logger.Warnf("%s address is in unsupported format", "DownstreamLocalAddress")
-			errorsTotal.WithLabelValues("AddressFormatUnsupported").Inc()
+			service_v3.logger.Warnf("%s address is in unsupported format", "DownstreamLocalAddress")
+			service_v3.errorsTotal.WithLabelValues("AddressFormatUnsupported").Inc()
 		}
 	}
 	m["routeName"] = p.RouteName
 	if ts := p.StartTime; ts != nil {
 		if !ts.IsValid() {
-			logger.Warnf("Unable to parse %s timestamp", "StartTime")
-			errorsTotal.WithLabelValues("InvalidTimestamp").Inc()
+			service_v3.logger.Warnf("Unable to parse %s timestamp", "StartTime")
+			service_v3.errorsTotal.WithLabelValues("InvalidTimestamp").Inc()
 		} else {
 			m["startTime"] = ts.AsTime().Format(time.RFC3339)
 		}
@@ -64,56 +67,56 @@ func envoyV3AccessLogEntryCommonPropertiesToStringMap(logger logrus.FieldLogger,
 		if duration, err := pbDurationDeterministicString(p.TimeToFirstDownstreamTxByte); err == nil {
 			m["timeToFirstDownstreamTxByte"] = duration
 		} else {
-			logger.Warnf("Unable to parse %s duration", "TimeToFirstDownstreamTxByte")
-			errorsTotal.WithLabelValues("InvalidDuration").Inc()
+			service_v3.logger.Warnf("Unable to parse %s duration", "TimeToFirstDownstreamTxByte")
+			service_v3.errorsTotal.WithLabelValues("InvalidDuration").Inc()
 		}
 	}
 	if p.TimeToFirstUpstreamRxByte != nil {
 		if duration, err := pbDurationDeterministicString(p.TimeToFirstUpstreamRxByte); err == nil {
 			m["timeToFirstUpstreamRxByte"] = duration
 		} else {
-			logger.Warnf("Unable to parse %s duration", "TimeToFirstUpstreamRxByte")
-			errorsTotal.WithLabelValues("InvalidDuration").Inc()
+			service_v3.logger.Warnf("Unable to parse %s duration", "TimeToFirstUpstreamRxByte")
+			service_v3.errorsTotal.WithLabelValues("InvalidDuration").Inc()
 		}
 	}
 	if p.TimeToFirstUpstreamTxByte != nil {
 		if duration, err := pbDurationDeterministicString(p.TimeToFirstUpstreamTxByte); err == nil {
 			m["timeToFirstUpstreamTxByte"] = duration
 		} else {
-			logger.Warnf("Unable to parse %s duration", "TimeToFirstUpstreamTxByte")
-			errorsTotal.WithLabelValues("InvalidDuration").Inc()
+			service_v3.logger.Warnf("Unable to parse %s duration", "TimeToFirstUpstreamTxByte")
+			service_v3.errorsTotal.WithLabelValues("InvalidDuration").Inc()
 		}
 	}
 	if p.TimeToLastDownstreamTxByte != nil {
 		if duration, err := pbDurationDeterministicString(p.TimeToLastDownstreamTxByte); err == nil {
 			m["timeToLastDownstreamTxByte"] = duration
 		} else {
-			logger.Warnf("Unable to parse %s duration", "TimeToLastDownstreamTxByte")
-			errorsTotal.WithLabelValues("InvalidDuration").Inc()
+			service_v3.logger.Warnf("Unable to parse %s duration", "TimeToLastDownstreamTxByte")
+			service_v3.errorsTotal.WithLabelValues("InvalidDuration").Inc()
 		}
 	}
 	if p.TimeToLastRxByte != nil {
 		if duration, err := pbDurationDeterministicString(p.TimeToLastRxByte); err == nil {
 			m["timeToLastRxByte"] = duration
 		} else {
-			logger.Warnf("Unable to parse %s duration", "TimeToLastRxByte")
-			errorsTotal.WithLabelValues("InvalidDuration").Inc()
+			service_v3.logger.Warnf("Unable to parse %s duration", "TimeToLastRxByte")
+			service_v3.errorsTotal.WithLabelValues("InvalidDuration").Inc()
 		}
 	}
 	if p.TimeToLastUpstreamRxByte != nil {
 		if duration, err := pbDurationDeterministicString(p.TimeToLastUpstreamRxByte); err == nil {
 			m["timeToLastUpstreamRxByte"] = duration
 		} else {
-			logger.Warnf("Unable to parse %s duration", "TimeToLastUpstreamRxByte")
-			errorsTotal.WithLabelValues("InvalidDuration").Inc()
+			service_v3.logger.Warnf("Unable to parse %s duration", "TimeToLastUpstreamRxByte")
+			service_v3.errorsTotal.WithLabelValues("InvalidDuration").Inc()
 		}
 	}
 	if p.TimeToLastUpstreamTxByte != nil {
 		if duration, err := pbDurationDeterministicString(p.TimeToLastUpstreamTxByte); err == nil {
 			m["timeToLastUpstreamTxByte"] = duration
 		} else {
-			logger.Warnf("Unable to parse %s duration", "TimeToLastUpstreamTxByte")
-			errorsTotal.WithLabelValues("InvalidDuration").Inc()
+			service_v3.logger.Warnf("Unable to parse %s duration", "TimeToLastUpstreamTxByte")
+			service_v3.errorsTotal.WithLabelValues("InvalidDuration").Inc()
 		}
 	}
 	m["upstreamCluster"] = p.UpstreamCluster
@@ -122,8 +125,8 @@ func envoyV3AccessLogEntryCommonPropertiesToStringMap(logger logrus.FieldLogger,
 			m["upstreamLocalAddress"] = sa.GetAddress()
 			m["upstreamLocalPort"] = fmt.Sprint(sa.GetPortValue())
 		} else {
-			logger.Warnf("%s address is in unsupported format", "UpstreamLocalAddress")
-			errorsTotal.WithLabelValues("AddressFormatUnsupported").Inc()
+			service_v3.logger.Warnf("%s address is in unsupported format", "UpstreamLocalAddress")
+			service_v3.errorsTotal.WithLabelValues("AddressFormatUnsupported").Inc()
 		}
 	}
 	if p.UpstreamRemoteAddress != nil {
@@ -131,8 +134,8 @@ func envoyV3AccessLogEntryCommonPropertiesToStringMap(logger logrus.FieldLogger,
 			m["upstreamRemoteAddress"] = sa.GetAddress()
 			m["upstreamRemotePort"] = fmt.Sprint(sa.GetPortValue())
 		} else {
-			logger.Warnf("%s address is in unsupported format", "UpstreamRemoteAddress")
-			errorsTotal.WithLabelValues("AddressFormatUnsupported").Inc()
+			service_v3.logger.Warnf("%s address is in unsupported format", "UpstreamRemoteAddress")
+			service_v3.errorsTotal.WithLabelValues("AddressFormatUnsupported").Inc()
 		}
 	}
 	m["upstreamTransportFailureReason"] = p.UpstreamTransportFailureReason
@@ -141,7 +144,7 @@ func envoyV3AccessLogEntryCommonPropertiesToStringMap(logger logrus.FieldLogger,
 	return m
 }
 
-func envoyV3AccessLogEntryHTTPRequestPropertiesToStringMap(_ logrus.FieldLogger, request *envoy_data_accesslog_v3.HTTPRequestProperties) stringmap.StringMap {
+func envoyV3AccessLogEntryHTTPRequestPropertiesToStringMap(request *envoy_data_accesslog_v3.HTTPRequestProperties) stringmap.StringMap {
 	result := stringmap.StringMap{}
 
 	result["authority"] = request.Authority
@@ -167,7 +170,7 @@ func envoyV3AccessLogEntryHTTPRequestPropertiesToStringMap(_ logrus.FieldLogger,
 	return result
 }
 
-func envoyV3AccessLogEntryHTTPResponsePropertiesToStringMap(_ logrus.FieldLogger, response *envoy_data_accesslog_v3.HTTPResponseProperties) stringmap.StringMap {
+func envoyV3AccessLogEntryHTTPResponsePropertiesToStringMap(response *envoy_data_accesslog_v3.HTTPResponseProperties) stringmap.StringMap {
 	result := stringmap.StringMap{}
 
 	result["responseBodyBytes"] = strconv.FormatUint(response.ResponseBodyBytes, 10)
@@ -188,45 +191,55 @@ func envoyV3AccessLogEntryHTTPResponsePropertiesToStringMap(_ logrus.FieldLogger
 	return result
 }
 
-func envoyV3HttpAccessLogEntryToStringMap(logger logrus.FieldLogger, l *envoy_data_accesslog_v3.HTTPAccessLogEntry) stringmap.StringMap {
-	m := envoyV3AccessLogEntryCommonPropertiesToStringMap(logger, l.CommonProperties)
+func (service_v3 *AccessLogServiceV3) envoyV3HttpAccessLogEntryToStringMap(l *envoy_data_accesslog_v3.HTTPAccessLogEntry) stringmap.StringMap {
+	m := service_v3.envoyV3AccessLogEntryCommonPropertiesToStringMap(l.CommonProperties)
 	m["protocolVersion"] = l.ProtocolVersion.String()
-	m = m.Merge(envoyV3AccessLogEntryHTTPRequestPropertiesToStringMap(logger, l.Request))
-	m = m.Merge(envoyV3AccessLogEntryHTTPResponsePropertiesToStringMap(logger, l.Response))
+	m = m.Merge(envoyV3AccessLogEntryHTTPRequestPropertiesToStringMap(l.Request))
+	m = m.Merge(envoyV3AccessLogEntryHTTPResponsePropertiesToStringMap(l.Response))
 	return m
 }
 
-func envoyV3TcpAccessLogEntryToStringMap(logger logrus.FieldLogger, l *envoy_data_accesslog_v3.TCPAccessLogEntry) stringmap.StringMap {
-	m := envoyV3AccessLogEntryCommonPropertiesToStringMap(logger, l.CommonProperties)
+func (service_v3 *AccessLogServiceV3) envoyV3TcpAccessLogEntryToStringMap(l *envoy_data_accesslog_v3.TCPAccessLogEntry) stringmap.StringMap {
+	m := service_v3.envoyV3AccessLogEntryCommonPropertiesToStringMap(l.CommonProperties)
 	m["receivedBytes"] = strconv.FormatUint(l.ConnectionProperties.ReceivedBytes, 10)
 	m["sentBytes"] = strconv.FormatUint(l.ConnectionProperties.SentBytes, 10)
 	return m
 }
 
+// envoyV3AccessLogEntryStartTime returns start time of the request or zero time if it is not valid.
+func envoyV3AccessLogEntryStartTime(p *envoy_data_accesslog_v3.AccessLogCommon) time.Time {
+	if ts := p.GetStartTime(); ts != nil && ts.IsValid() {
+		return ts.AsTime()
+	}
+	return time.Time{}
+}
+
 func (service_v3 *AccessLogServiceV3) emitEvents(msg *envoy_service_accesslog_v3.StreamAccessLogsMessage) {
 	if logs := msg.GetHttpLogs(); logs != nil {
 		for _, l := range logs.LogEntry {
-			logEntriesTotal.WithLabelValues("HTTP", "v3").Inc()
+			service_v3.logEntriesTotal.WithLabelValues("HTTP", "v3").Inc()
 			e := &event.Raw{
-				Metadata: envoyV3HttpAccessLogEntryToStringMap(service_v3.logger, l),
-				Quantity: 1,
+				Metadata:  service_v3.envoyV3HttpAccessLogEntryToStringMap(l),
+				Quantity:  1,
+				Timestamp: envoyV3AccessLogEntryStartTime(l.CommonProperties),
 			}
 			service_v3.logger.Debug(e)
 			service_v3.outChan <- e
 		}
 	} else if logs := msg.GetTcpLogs(); logs != nil {
 		for _, l := range logs.LogEntry {
-			logEntriesTotal.WithLabelValues("TCP", "v3").Inc()
+			service_v3.logEntriesTotal.WithLabelValues("TCP", "v3").Inc()
 			e := &event.Raw{
-				Metadata: envoyV3TcpAccessLogEntryToStringMap(service_v3.logger, l),
-				Quantity: 1,
+				Metadata:  service_v3.envoyV3TcpAccessLogEntryToStringMap(l),
+				Quantity:  1,
+				Timestamp: envoyV3AccessLogEntryStartTime(l.CommonProperties),
 			}
 			service_v3.logger.Debug(e)
 			service_v3.outChan <- e
 		}
 	} else {
 		// Unknown access log type
-		errorsTotal.WithLabelValues("UnknownLogType").Inc()
+		service_v3.errorsTotal.WithLabelValues("UnknownLogType").Inc()
 		service_v3.logger.Warnf("Unknown access log message type: %v", msg)
 	}
 }
@@ -238,7 +251,7 @@ func (service_v3 *AccessLogServiceV3) StreamAccessLogs(stream envoy_service_acce
 			return nil
 		}
 		if err != nil {
-			errorsTotal.WithLabelValues("ProcessingStream").Inc()
+			service_v3.errorsTotal.WithLabelValues("ProcessingStream").Inc()
 			return err
 		}
 		service_v3.emitEvents(msg)
@@ -2,6 +2,7 @@ package envoy_access_log_server
 
 import (
 	"testing"
+	"time"
 
 	v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_data_accesslog_v3 "github.com/envoyproxy/go-control-plane/envoy/data/accesslog/v3"
@@ -10,12 +11,22 @@ import (
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/seznam/slo-exporter/pkg/lineparser"
 	"github.com/seznam/slo-exporter/pkg/stringmap"
 )
 
 var logger = &logrus.Logger{}
 
+func newTestAccessLogServiceV3(logger logrus.FieldLogger) *AccessLogServiceV3 {
+	return &AccessLogServiceV3{
+		logEntriesTotal: newLogEntriesTotal(),
+		errorsTotal:     newErrorsTotal(),
+		logger:          logger,
+	}
+}
+
 func Test_exportCommonPropertiesV3(t *testing.T) {
 	tests := []struct {
 		description    string
@@ -120,7 +131,7 @@ func Test_exportCommonPropertiesV3(t *testing.T) {
 	}
 	for _, tt := range tests {
 		f := func(*testing.T) {
-			output := envoyV3AccessLogEntryCommonPropertiesToStringMap(logger, tt.input)
+			output := newTestAccessLogServiceV3(logger).envoyV3AccessLogEntryCommonPropertiesToStringMap(tt.input)
 			for k, v := range tt.expectedResult {
 				assert.Equal(t, v, output[k], "expected %s=%s", k, v)
 			}
@@ -129,6 +140,38 @@ func Test_exportCommonPropertiesV3(t *testing.T) {
 	}
 }
 
+// The tailer presets have to produce the same startTime as the access log server, so the same SLO rules can be used for both.
+func Test_exportCommonPropertiesV3_startTimeSameAsPreset(t *testing.T) {
+	tests := []struct {
+		preset    string
+		line      string
+		startTime time.Time
+	}{
+		{
+			preset:    "envoy",
+			line:      `[2016-04-15T20:17:00.310Z] "POST /api/v1/locations HTTP/2" 204 - 154 0 226 100 "10.0.35.28" "nsq2http" "cc21d9b0-cf5c-432b-8c7e-98aeb7988cd2" "locations" "tcp://10.0.2.1:80"`,
+			startTime: time.Date(2016, 4, 15, 20, 17, 0, 310000000, time.UTC),
+		},
+		{
+			preset:    "nginxCombined",
+			line:      `127.0.0.1 - - [24/Apr/2020:13:49:54 +0200] "GET / HTTP/1.1" 200 10 "-" "curl/7.74.0"`,
+			startTime: time.Date(2020, 4, 24, 11, 49, 54, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			parser, err := lineparser.New(lineparser.Config{Preset: tt.preset, EmptyGroupRE: "^-$"})
+			assert.NoError(t, err)
+			presetMetadata, _, err := parser.Parse(tt.line)
+			assert.NoError(t, err)
+			output := newTestAccessLogServiceV3(logger).envoyV3AccessLogEntryCommonPropertiesToStringMap(&envoy_data_accesslog_v3.AccessLogCommon{
+				StartTime: timestamppb.New(tt.startTime),
+			})
+			assert.Equal(t, output["startTime"], presetMetadata["startTime"])
+		})
+	}
+}
+
 func Test_exportHTTPRequestPropertiesV3(t *testing.T) {
 	tests := []struct {
 		description    string
@@ -179,7 +222,7 @@ func Test_exportHTTPRequestPropertiesV3(t *testing.T) {
 	for _, test := range tests {
 		f := func(*testing.T) {
 			for k, v := range test.expectedResult {
-				assert.Equal(t, v, envoyV3AccessLogEntryHTTPRequestPropertiesToStringMap(logger, test.input)[k], "expected %s=%s", k, v)
+				assert.Equal(t, v, envoyV3AccessLogEntryHTTPRequestPropertiesToStringMap(test.input)[k], "expected %s=%s", k, v)
 			}
 		}
 		t.Run(test.description, f)
@@ -226,7 +269,7 @@ func Test_exportHTTPResponsePropertiesV3(t *testing.T) {
 
 	for _, test := range tests {
 		f := func(*testing.T) {
-			assert.Equal(t, test.result, envoyV3AccessLogEntryHTTPResponsePropertiesToStringMap(logger, test.input))
+			assert.Equal(t, test.result, envoyV3AccessLogEntryHTTPResponsePropertiesToStringMap(test.input))
 		}
 		t.Run(test.description, f)
 	}
@@ -278,7 +321,7 @@ func Test_exportHttpLogEntryV3(t *testing.T) {
 
 	for _, test := range tests {
 		f := func(*testing.T) {
-			output := envoyV3HttpAccessLogEntryToStringMap(logger, test.logEntry)
+			output := newTestAccessLogServiceV3(logger).envoyV3HttpAccessLogEntryToStringMap(test.logEntry)
 			for k, v := range test.expectedOutput {
 				assert.Equal(t, v, output[k], "expected %s=%s", k, v)
 			}
@@ -322,9 +365,27 @@ func Test_exportTcpLogEntryV3(t *testing.T) {
 	for _, test := range tests {
 		f := func(*testing.T) {
 			for k, v := range test.expectedOutput {
-				assert.Equal(t, v, envoyV3TcpAccessLogEntryToStringMap(logger, test.logEntry)[k], "expected %s=%s", k, v)
+				assert.Equal(t, v, newTestAccessLogServiceV3(logger).envoyV3TcpAccessLogEntryToStringMap(test.logEntry)[k], "expected %s=%s", k, v)
 			}
 		}
 		t.Run(test.description, f)
 	}
 }
+
+func Test_envoyV3AccessLogEntryStartTime(t *testing.T) {
+	tests := []struct {
+		description string
+		input       *envoy_data_accesslog_v3.AccessLogCommon
+		expected    time.Time
+	}{
+		{description: "missing common properties", input: nil},
+		{description: "missing start time", input: &envoy_data_accesslog_v3.AccessLogCommon{}},
+		{description: "invalid start time", input: &envoy_data_accesslog_v3.AccessLogCommon{StartTime: &timestamp.Timestamp{Nanos: -1}}},
+		{description: "valid start time", input: &envoy_data_accesslog_v3.AccessLogCommon{StartTime: &timestamp.Timestamp{Seconds: 1600000000}}, expected: time.Unix(1600000000, 0)},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			assert.True(t, test.expected.Equal(envoyV3AccessLogEntryStartTime(test.input)))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package event
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/seznam/slo-exporter/pkg/stringmap"
 )
@@ -11,6 +12,8 @@ type Raw struct {
 	Metadata          stringmap.StringMap
 	SloClassification *SloClassification
 	Quantity          float64
+	// Timestamp is time when the event occurred, zero if the source of the event does not provide it.
+	Timestamp time.Time
 }
 
 const (
@@ -57,6 +60,29 @@ func (r Raw) GetSloClassification() *SloClassification {
 	return r.SloClassification
 }
 
+// Copy returns a deep copy of the event.
+func (r *Raw) Copy() *Raw {
+	var classification *SloClassification
+	if r.SloClassification != nil {
+		classificationCopy := r.SloClassification.Copy()
+		classification = &classificationCopy
+	}
+	return &Raw{
+		Metadata:          r.Metadata.Copy(),
+		SloClassification: classification,
+		Quantity:          r.Quantity,
+		Timestamp:         r.Timestamp,
+	}
+}
+
 func (r Raw) String() string {
 	return fmt.Sprintf("key: %s, quantity: %f, metadata: %s, classification: %s", r.EventKey(), r.Quantity, r.Metadata, r.GetSloMetadata())
 }
+
+// OccurredAt returns time when the event occurred or the given time if the event has no timestamp.
+func (r *Raw) OccurredAt(fallback time.Time) time.Time {
+	if r.Timestamp.IsZero() {
+		return fallback
+	}
+	return r.Timestamp
+}
@@ -2,6 +2,7 @@ package event
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/seznam/slo-exporter/pkg/stringmap"
 )
@@ -30,6 +31,8 @@ type Slo struct {
 
 	Metadata stringmap.StringMap
 	Quantity float64
+	// Timestamp is time when the original event occurred, zero if unknown.
+	Timestamp time.Time
 
 	OriginalEvent Raw
 }
@@ -50,6 +53,16 @@ func (s Slo) Copy() Slo {
 		Class:         s.Class,
 		App:           s.App,
 		Metadata:      s.Metadata.Copy(),
-		OriginalEvent: s.OriginalEvent,
+		Quantity:      s.Quantity,
+		Timestamp:     s.Timestamp,
+		OriginalEvent: *s.OriginalEvent.Copy(),
 	}
 }
+
+// OccurredAt returns time when the event occurred or the given time if the event has no timestamp.
+func (s *Slo) OccurredAt(fallback time.Time) time.Time {
+	if s.Timestamp.IsZero() {
+		return fallback
+	}
+	return s.Timestamp
+}
@@ -0,0 +1,41 @@
+package event
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	// UnixTimestampFormat is number of seconds since the Unix epoch, fractional part is allowed.
+	UnixTimestampFormat = "unix"
+	// UnixMilliTimestampFormat is number of milliseconds since the Unix epoch.
+	UnixMilliTimestampFormat = "unixMilli"
+)
+
+// ParseTimestamp parses time of the event in the given format.
+// The format is either one of the Unix timestamp formats or layout accepted by the time.Parse.
+func ParseTimestamp(value, format string) (time.Time, error) {
+	switch format {
+	case UnixTimestampFormat:
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid unix timestamp %q: %w", value, err)
+		}
+		integer, fraction := math.Modf(seconds)
+		return time.Unix(int64(integer), int64(fraction*float64(time.Second))), nil
+	case UnixMilliTimestampFormat:
+		milliseconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid unix timestamp in milliseconds %q: %w", value, err)
+		}
+		return time.UnixMilli(milliseconds), nil
+	default:
+		timestamp, err := time.Parse(format, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp: %w", err)
+		}
+		return timestamp, nil
+	}
+}
@@ -0,0 +1,35 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		value  string
+		format string
+		exp    time.Time
+		expErr bool
+	}{
+		{value: "1600000000", format: UnixTimestampFormat, exp: time.Unix(1600000000, 0)},
+		{value: "1600000000.5", format: UnixTimestampFormat, exp: time.Unix(1600000000, int64(500*time.Millisecond))},
+		{value: "1600000000123", format: UnixMilliTimestampFormat, exp: time.UnixMilli(1600000000123)},
+		{value: "2020-09-13T12:26:40Z", format: time.RFC3339, exp: time.Unix(1600000000, 0)},
+		{value: "13/Sep/2020:14:26:40 +0200", format: "02/Jan/2006:15:04:05 -0700", exp: time.Unix(1600000000, 0)},
+		{value: "foo", format: UnixTimestampFormat, expErr: true},
+		{value: "1600000000.5", format: UnixMilliTimestampFormat, expErr: true},
+		{value: "2020-09-13", format: time.RFC3339, expErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			timestamp, err := ParseTimestamp(tt.value, tt.format)
+			assert.Equal(t, tt.expErr, err != nil, err)
+			if err == nil {
+				assert.True(t, tt.exp.Equal(timestamp), "expected %s, got %s", tt.exp, timestamp)
+			}
+		})
+	}
+}
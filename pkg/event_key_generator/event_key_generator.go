@@ -1,6 +1,7 @@
 package event_key_generator
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -12,42 +13,32 @@ import (
 	"github.com/spf13/viper"
 )
 
-var processedEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
-	Name: "processed_events_total",
-	Help: "Total number of processed events by operation.",
-}, []string{"operation"})
-
-type eventKeyGeneratorConfig struct {
+// EventKeyGeneratorConfig is configuration of the event key generator module.
+type EventKeyGeneratorConfig struct {
 	FiledSeparator           string
 	OverrideExistingEventKey bool
 	MetadataKeys             []string
 }
 
 type EventKeyGenerator struct {
-	separator           string
-	overrideExistingKey bool
-	metadataKeys        []string
-	observer            pipeline.EventProcessingDurationObserver
-	logger              logrus.FieldLogger
-	inputChannel        chan *event.Raw
-	outputChannel       chan *event.Raw
-	done                bool
+	separator            string
+	overrideExistingKey  bool
+	metadataKeys         []string
+	observer             pipeline.EventProcessingDurationObserver
+	logger               logrus.FieldLogger
+	inputChannel         chan *event.Raw
+	outputChannel        chan *event.Raw
+	processedEventsTotal *prometheus.CounterVec
 }
 
 func (e *EventKeyGenerator) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
-	return wrappedRegistry.Register(processedEventsTotal)
+	return wrappedRegistry.Register(e.processedEventsTotal)
 }
 
 func (e *EventKeyGenerator) String() string {
 	return "eventKeyGenerator"
 }
 
-func (e *EventKeyGenerator) Done() bool {
-	return e.done
-}
-
-func (e *EventKeyGenerator) Stop() {}
-
 func (e *EventKeyGenerator) SetInputChannel(channel chan *event.Raw) {
 	e.inputChannel = channel
 }
@@ -57,7 +48,7 @@ func (e *EventKeyGenerator) OutputChannel() chan *event.Raw {
 }
 
 func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*EventKeyGenerator, error) {
-	var config eventKeyGeneratorConfig
+	var config EventKeyGeneratorConfig
 	viperConfig.SetDefault("OverrideExistingEventKey", true)
 	viperConfig.SetDefault("FiledSeparator", ":")
 	if err := viperConfig.UnmarshalExact(&config); err != nil {
@@ -66,15 +57,18 @@ func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*EventKe
 	return NewFromConfig(config, logger)
 }
 
-func NewFromConfig(config eventKeyGeneratorConfig, logger logrus.FieldLogger) (*EventKeyGenerator, error) {
+func NewFromConfig(config EventKeyGeneratorConfig, logger logrus.FieldLogger) (*EventKeyGenerator, error) {
 	filter := EventKeyGenerator{
 		separator:           config.FiledSeparator,
 		overrideExistingKey: config.OverrideExistingEventKey,
 		metadataKeys:        config.MetadataKeys,
 		outputChannel:       make(chan *event.Raw),
 		inputChannel:        make(chan *event.Raw),
-		done:                false,
-		logger:              logger,
+		processedEventsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "processed_events_total",
+			Help: "Total number of processed events by operation.",
+		}, []string{"operation"}),
+		logger: logger,
 	}
 	return &filter, nil
 }
@@ -107,26 +101,22 @@ func (e *EventKeyGenerator) generateEventKey(metadata stringmap.StringMap) strin
 	return eventKey
 }
 
-func (e *EventKeyGenerator) Run() {
-	go func() {
-		defer func() {
-			close(e.outputChannel)
-			e.done = true
-		}()
-		for newEvent := range e.inputChannel {
-			start := time.Now()
-			if newEvent.EventKey() == "" || e.overrideExistingKey {
-				newKey := e.generateEventKey(newEvent.Metadata)
-				newEvent.SetEventKey(newKey)
-				processedEventsTotal.WithLabelValues("generated-event-key").Inc()
-				e.logger.WithField("event", newEvent).WithField("event-key", newKey).Debug("generated new event key for event")
-			} else {
-				e.logger.WithField("event", newEvent).Debug("skipped generating of eventKey because it is already set")
-				processedEventsTotal.WithLabelValues("skipped").Inc()
-			}
-			e.outputChannel <- newEvent
-			e.observeDuration(start)
+func (e *EventKeyGenerator) Run(_ context.Context) error {
+	defer close(e.outputChannel)
+	for newEvent := range e.inputChannel {
+		start := time.Now()
+		if newEvent.EventKey() == "" || e.overrideExistingKey {
+			newKey := e.generateEventKey(newEvent.Metadata)
+			newEvent.SetEventKey(newKey)
+			e.processedEventsTotal.WithLabelValues("generated-event-key").Inc()
+			e.logger.WithField("event", newEvent).WithField("event-key", newKey).Debug("generated new event key for event")
+		} else {
+			e.logger.WithField("event", newEvent).Debug("skipped generating of eventKey because it is already set")
+			e.processedEventsTotal.WithLabelValues("skipped").Inc()
 		}
-		e.logger.Info("input channel closed, finishing")
-	}()
+		e.outputChannel <- newEvent
+		e.observeDuration(start)
+	}
+	e.logger.Info("input channel closed, finishing")
+	return nil
 }
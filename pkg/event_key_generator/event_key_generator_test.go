@@ -11,17 +11,17 @@ import (
 func TestEventKeyGenerator_generateEventKey(t *testing.T) {
 	testCases := []struct {
 		metadata stringmap.StringMap
-		config   eventKeyGeneratorConfig
+		config   EventKeyGeneratorConfig
 		result   string
 	}{
-		{metadata: stringmap.StringMap{"foo": "foo"}, config: eventKeyGeneratorConfig{FiledSeparator: ":", MetadataKeys: []string{}}, result: ""},
-		{metadata: stringmap.StringMap{"foo": "foo"}, config: eventKeyGeneratorConfig{FiledSeparator: ":", MetadataKeys: []string{"bar"}}, result: ""},
-		{metadata: stringmap.StringMap{"foo": "foo"}, config: eventKeyGeneratorConfig{FiledSeparator: ":", MetadataKeys: []string{"foo"}}, result: "foo"},
-		{metadata: stringmap.StringMap{"foo": "foo", "bar": "bar"}, config: eventKeyGeneratorConfig{FiledSeparator: ":", MetadataKeys: []string{"foo"}}, result: "foo"},
-		{metadata: stringmap.StringMap{"foo": "foo", "bar": "bar"}, config: eventKeyGeneratorConfig{FiledSeparator: ":", MetadataKeys: []string{"foo", "bar"}}, result: "foo:bar"},
-		{metadata: stringmap.StringMap{"foo": "foo", "bar": ""}, config: eventKeyGeneratorConfig{FiledSeparator: ":", MetadataKeys: []string{"foo", "bar"}}, result: "foo:"},
-		{metadata: stringmap.StringMap{"foo": "foo", "bar": "bar"}, config: eventKeyGeneratorConfig{FiledSeparator: "|", MetadataKeys: []string{"foo", "bar"}}, result: "foo|bar"},
-		{metadata: stringmap.StringMap{"foo": "foo", "bar": "bar"}, config: eventKeyGeneratorConfig{FiledSeparator: ":", MetadataKeys: []string{"xxx", "bar"}}, result: "bar"},
+		{metadata: stringmap.StringMap{"foo": "foo"}, config: EventKeyGeneratorConfig{FiledSeparator: ":", MetadataKeys: []string{}}, result: ""},
+		{metadata: stringmap.StringMap{"foo": "foo"}, config: EventKeyGeneratorConfig{FiledSeparator: ":", MetadataKeys: []string{"bar"}}, result: ""},
+		{metadata: stringmap.StringMap{"foo": "foo"}, config: EventKeyGeneratorConfig{FiledSeparator: ":", MetadataKeys: []string{"foo"}}, result: "foo"},
+		{metadata: stringmap.StringMap{"foo": "foo", "bar": "bar"}, config: EventKeyGeneratorConfig{FiledSeparator: ":", MetadataKeys: []string{"foo"}}, result: "foo"},
+		{metadata: stringmap.StringMap{"foo": "foo", "bar": "bar"}, config: EventKeyGeneratorConfig{FiledSeparator: ":", MetadataKeys: []string{"foo", "bar"}}, result: "foo:bar"},
+		{metadata: stringmap.StringMap{"foo": "foo", "bar": ""}, config: EventKeyGeneratorConfig{FiledSeparator: ":", MetadataKeys: []string{"foo", "bar"}}, result: "foo:"},
+		{metadata: stringmap.StringMap{"foo": "foo", "bar": "bar"}, config: EventKeyGeneratorConfig{FiledSeparator: "|", MetadataKeys: []string{"foo", "bar"}}, result: "foo|bar"},
+		{metadata: stringmap.StringMap{"foo": "foo", "bar": "bar"}, config: EventKeyGeneratorConfig{FiledSeparator: ":", MetadataKeys: []string{"xxx", "bar"}}, result: "bar"},
 	}
 	for _, tc := range testCases {
 		generator, err := NewFromConfig(tc.config, logrus.New())
@@ -1,6 +1,7 @@
 package event_metadata_renamer
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -14,13 +15,8 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var renamingCollisionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
-	Name: "renaming_collisions_total",
-	Help: "Total number of collision occurred while attempting to rename a metadata key.",
-}, []string{"Source", "Destination"})
-
 func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*EventMetadataRenamerManager, error) {
-	var config []renamerConfig
+	var config []RenamerConfig
 	marshalledConfig, err := yaml.Marshal(viperConfig.Get("eventMetadataRenamerConfigs"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load configuration: %w", err)
@@ -32,38 +28,39 @@ func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*EventMe
 }
 
 // New returns requestNormalizer which allows to add Key to RequestEvent.
-func NewFromConfig(config []renamerConfig, logger logrus.FieldLogger) (*EventMetadataRenamerManager, error) {
+func NewFromConfig(config []RenamerConfig, logger logrus.FieldLogger) (*EventMetadataRenamerManager, error) {
 	relabelManager := EventMetadataRenamerManager{
 		renamerConfig: config,
 		outputChannel: make(chan *event.Raw),
-		logger:        logger,
+		renamingCollisionsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "renaming_collisions_total",
+			Help: "Total number of collision occurred while attempting to rename a metadata key.",
+		}, []string{"Source", "Destination"}),
+		logger: logger,
 	}
 	return &relabelManager, nil
 }
 
-type renamerConfig struct {
+// RenamerConfig is configuration of single renaming rule of the event metadata renamer module.
+type RenamerConfig struct {
 	Source, Destination string
 }
 
 type EventMetadataRenamerManager struct {
-	renamerConfig []renamerConfig
-	observer      pipeline.EventProcessingDurationObserver
-	inputChannel  chan *event.Raw
-	outputChannel chan *event.Raw
-	done          bool
-	logger        logrus.FieldLogger
+	renamerConfig           []RenamerConfig
+	observer                pipeline.EventProcessingDurationObserver
+	inputChannel            chan *event.Raw
+	outputChannel           chan *event.Raw
+	renamingCollisionsTotal *prometheus.CounterVec
+	logger                  logrus.FieldLogger
 }
 
 func (r *EventMetadataRenamerManager) String() string {
 	return "eventMetadataRenamer"
 }
 
-func (r *EventMetadataRenamerManager) Done() bool {
-	return r.done
-}
-
 func (r *EventMetadataRenamerManager) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
-	return wrappedRegistry.Register(renamingCollisionsTotal)
+	return wrappedRegistry.Register(r.renamingCollisionsTotal)
 }
 
 func (r *EventMetadataRenamerManager) SetInputChannel(channel chan *event.Raw) {
@@ -74,8 +71,6 @@ func (r *EventMetadataRenamerManager) OutputChannel() chan *event.Raw {
 	return r.outputChannel
 }
 
-func (r *EventMetadataRenamerManager) Stop() {}
-
 func (r *EventMetadataRenamerManager) RegisterEventProcessingDurationObserver(observer pipeline.EventProcessingDurationObserver) {
 	r.observer = observer
 }
@@ -94,7 +89,7 @@ func (r *EventMetadataRenamerManager) renameEventMetadata(e *event.Raw) *event.R
 		}
 		if _, ok := e.Metadata[renameConfig.Destination]; ok {
 			r.logger.Warnf("refusing to override metadata's %s:%s with %s:%s", renameConfig.Destination, e.Metadata[renameConfig.Destination], renameConfig.Source, e.Metadata[renameConfig.Source])
-			renamingCollisionsTotal.WithLabelValues(renameConfig.Source, renameConfig.Destination).Inc()
+			r.renamingCollisionsTotal.WithLabelValues(renameConfig.Source, renameConfig.Destination).Inc()
 			continue
 		}
 		e.Metadata[renameConfig.Destination] = e.Metadata[renameConfig.Source]
@@ -104,19 +99,15 @@ func (r *EventMetadataRenamerManager) renameEventMetadata(e *event.Raw) *event.R
 }
 
 // Run event replacer receiving events and filling their Key if not already filled.
-func (r *EventMetadataRenamerManager) Run() {
-	go func() {
-		defer func() {
-			close(r.outputChannel)
-			r.done = true
-		}()
-		for newEvent := range r.inputChannel {
-			start := time.Now()
-			processedEvent := r.renameEventMetadata(newEvent)
-			r.logger.WithField("event", newEvent).Debug("processed event")
-			r.outputChannel <- processedEvent
-			r.observeDuration(start)
-		}
-		r.logger.Info("input channel closed, finishing")
-	}()
+func (r *EventMetadataRenamerManager) Run(_ context.Context) error {
+	defer close(r.outputChannel)
+	for newEvent := range r.inputChannel {
+		start := time.Now()
+		processedEvent := r.renameEventMetadata(newEvent)
+		r.logger.WithField("event", newEvent).Debug("processed event")
+		r.outputChannel <- processedEvent
+		r.observeDuration(start)
+	}
+	r.logger.Info("input channel closed, finishing")
+	return nil
 }
@@ -44,7 +44,7 @@ func TestRelabel_Run(t *testing.T) {
 - source: source
   destination: destination
 `
-	var config []renamerConfig
+	var config []RenamerConfig
 	err := yaml.UnmarshalStrict([]byte(configYaml), &config)
 	if err != nil {
 		t.Fatal(err)
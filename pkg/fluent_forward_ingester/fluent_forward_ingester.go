@@ -0,0 +1,295 @@
+package fluent_forward_ingester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/lineparser"
+	"github.com/seznam/slo-exporter/pkg/pipeline"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// FluentForwardIngesterConfig is configuration of the Fluent Forward ingester module.
+type FluentForwardIngesterConfig struct {
+	// Address to listen on for the TCP connections of the forwarders.
+	Address string
+	// Tags are patterns of the tags of records to be processed, records of other tags are dropped. All the records are processed if empty.
+	Tags []string
+	// TagMetadataKey is the event metadata key of the tag of the record, if empty the tag is not added.
+	TagMetadataKey string
+	// KeySeparator joins keys of the nested maps and indexes of arrays of the record when flattening them to metadata keys.
+	KeySeparator string
+}
+
+// FluentForwardIngester produces events from the records received using the Fluent Forward protocol.
+type FluentForwardIngester struct {
+	address        string
+	tagPatterns    [][]string
+	tagMetadataKey string
+	keySeparator   string
+	outputChannel  chan *event.Raw
+	observer       pipeline.EventProcessingDurationObserver
+	logger         logrus.FieldLogger
+	// connections are the accepted connections which are closed once the ingester is stopped.
+	connections   map[net.Conn]struct{}
+	connectionsMu sync.Mutex
+
+	recordsTotal         *prometheus.CounterVec
+	filteredRecordsTotal prometheus.Counter
+	errorsTotal          *prometheus.CounterVec
+}
+
+func (f *FluentForwardIngester) String() string {
+	return "fluentForwardIngester"
+}
+
+func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*FluentForwardIngester, error) {
+	viperConfig.SetDefault("Address", ":24224")
+	viperConfig.SetDefault("TagMetadataKey", "tag")
+	viperConfig.SetDefault("KeySeparator", ".")
+	var config FluentForwardIngesterConfig
+	if err := viperConfig.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return New(config, logger)
+}
+
+// New returns an instance of FluentForwardIngester.
+func New(config FluentForwardIngesterConfig, logger logrus.FieldLogger) (*FluentForwardIngester, error) {
+	var tagPatterns [][]string
+	for _, pattern := range config.Tags {
+		parts := strings.Split(pattern, ".")
+		for _, part := range parts {
+			if _, err := path.Match(part, ""); err != nil {
+				return nil, fmt.Errorf("invalid tag pattern '%s': %w", pattern, err)
+			}
+		}
+		tagPatterns = append(tagPatterns, parts)
+	}
+	return &FluentForwardIngester{
+		address:        config.Address,
+		tagPatterns:    tagPatterns,
+		tagMetadataKey: config.TagMetadataKey,
+		keySeparator:   config.KeySeparator,
+		outputChannel:  make(chan *event.Raw),
+		logger:         logger,
+		connections:    map[net.Conn]struct{}{},
+		recordsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "processed_records_total",
+			Help: "Total number of processed records by the mode of the Forward protocol.",
+		}, []string{"mode"}),
+		filteredRecordsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "filtered_records_total",
+			Help: "Total number of records dropped since their tag matches none of the tag patterns.",
+		}),
+		errorsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "errors_total",
+			Help: "Errors while processing the received messages.",
+		}, []string{"type"}),
+	}, nil
+}
+
+func (f *FluentForwardIngester) RegisterEventProcessingDurationObserver(observer pipeline.EventProcessingDurationObserver) {
+	f.observer = observer
+}
+
+func (f *FluentForwardIngester) observeDuration(start time.Time) {
+	if f.observer != nil {
+		f.observer.Observe(time.Since(start).Seconds())
+	}
+}
+
+func (f *FluentForwardIngester) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
+	toRegister := []prometheus.Collector{f.recordsTotal, f.filteredRecordsTotal, f.errorsTotal}
+	for _, collector := range toRegister {
+		if err := wrappedRegistry.Register(collector); err != nil {
+			return fmt.Errorf("error registering metric %s: %w", collector, err)
+		}
+	}
+	return nil
+}
+
+func (f *FluentForwardIngester) OutputChannel() chan *event.Raw {
+	return f.outputChannel
+}
+
+// Run receives the forwarded records feeding events to output channel until the context is cancelled.
+func (f *FluentForwardIngester) Run(ctx context.Context) error {
+	defer close(f.outputChannel)
+	listener, err := net.Listen("tcp", f.address)
+	if err != nil {
+		return fmt.Errorf("error while starting the %s: %w", f, err)
+	}
+	f.logger.Infof("listening for Fluent Forward connections on %s", listener.Addr())
+	var wg sync.WaitGroup
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- f.serve(ctx, listener, &wg)
+	}()
+	var acceptErr error
+	select {
+	case acceptErr = <-serveErr:
+	case <-ctx.Done():
+		listener.Close()
+		<-serveErr
+	}
+	f.connectionsMu.Lock()
+	for conn := range f.connections {
+		conn.Close()
+	}
+	f.connectionsMu.Unlock()
+	wg.Wait()
+	if acceptErr != nil {
+		return fmt.Errorf("%s failed to accept connections: %w", f, acceptErr)
+	}
+	return nil
+}
+
+// serve accepts the connections until the listener is closed, which is not considered an error if the context is cancelled.
+func (f *FluentForwardIngester) serve(ctx context.Context, listener net.Listener, wg *sync.WaitGroup) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		f.connectionsMu.Lock()
+		if ctx.Err() != nil {
+			f.connectionsMu.Unlock()
+			conn.Close()
+			return nil
+		}
+		f.connections[conn] = struct{}{}
+		wg.Add(1)
+		f.connectionsMu.Unlock()
+		go func() {
+			defer wg.Done()
+			f.serveConnection(ctx, conn)
+		}()
+	}
+}
+
+// serveConnection processes the messages of the connection until it is closed or a malformed message is received.
+func (f *FluentForwardIngester) serveConnection(ctx context.Context, conn net.Conn) {
+	defer func() {
+		f.connectionsMu.Lock()
+		delete(f.connections, conn)
+		f.connectionsMu.Unlock()
+		conn.Close()
+	}()
+	logger := f.logger.WithField("remoteAddress", conn.RemoteAddr())
+	decoder := newDecoder(conn)
+	for {
+		msg, err := decodeMessage(decoder)
+		if err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return
+			}
+			// The stream cannot be synchronized again once the message cannot be decoded.
+			logger.Errorf("closing the connection after failing to decode message: %v", err)
+			f.errorsTotal.WithLabelValues("InvalidMessage").Inc()
+			return
+		}
+		if !f.processMessage(ctx, msg) {
+			return
+		}
+		if msg.options.Chunk == "" {
+			continue
+		}
+		ack, err := msgpack.Marshal(map[string]string{"ack": msg.options.Chunk})
+		if err != nil {
+			logger.Errorf("failed to encode the ack: %v", err)
+			return
+		}
+		if _, err := conn.Write(ack); err != nil {
+			if ctx.Err() == nil {
+				logger.Errorf("closing the connection after failing to send the ack: %v", err)
+				f.errorsTotal.WithLabelValues("SendingAck").Inc()
+			}
+			return
+		}
+	}
+}
+
+// processMessage emits events of the records of the message, returns false if the context was cancelled before all of them were emitted.
+func (f *FluentForwardIngester) processMessage(ctx context.Context, msg forwardMessage) bool {
+	// Fluent Bit can forward also metrics and traces.
+	if msg.options.FluentSignal != 0 {
+		f.errorsTotal.WithLabelValues("UnsupportedSignal").Inc()
+		return true
+	}
+	if !f.matchesTag(msg.tag) {
+		f.filteredRecordsTotal.Add(float64(len(msg.entries)))
+		return true
+	}
+	for _, e := range msg.entries {
+		start := time.Now()
+		f.recordsTotal.WithLabelValues(msg.mode).Inc()
+		metadata := stringmap.StringMap{}
+		lineparser.Flatten(metadata, "", f.keySeparator, e.record, isEmpty)
+		if f.tagMetadataKey != "" {
+			metadata[f.tagMetadataKey] = msg.tag
+		}
+		f.observeDuration(start)
+		select {
+		case f.outputChannel <- &event.Raw{Metadata: metadata, Quantity: 1, Timestamp: e.timestamp}:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// matchesTag reports whether the tag matches any of the tag patterns.
+func (f *FluentForwardIngester) matchesTag(tag string) bool {
+	if len(f.tagPatterns) == 0 {
+		return true
+	}
+	tagParts := strings.Split(tag, ".")
+	for _, pattern := range f.tagPatterns {
+		if matchTagParts(pattern, tagParts) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmpty reports whether the value of the record is omitted from the metadata.
+func isEmpty(value string) bool {
+	return value == ""
+}
+
+// matchTagParts matches the tag split by dots using the Fluentd semantics of the patterns,
+// `*` matches single part of the tag and `**` matches zero or more parts.
+func matchTagParts(pattern, tag []string) bool {
+	if len(pattern) == 0 {
+		return len(tag) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(tag); i++ {
+			if matchTagParts(pattern[1:], tag[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(tag) == 0 {
+		return false
+	}
+	matched, _ := path.Match(pattern[0], tag[0])
+	return matched && matchTagParts(pattern[1:], tag[1:])
+}
@@ -0,0 +1,85 @@
+package fluent_forward_ingester
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/internal/moduletest"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestFluentForwardIngester_matchesTag(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		tag      string
+		expMatch bool
+	}{
+		{patterns: nil, tag: "anything", expMatch: true},
+		{patterns: []string{"nginx.access"}, tag: "nginx.access", expMatch: true},
+		{patterns: []string{"nginx.access"}, tag: "nginx.error", expMatch: false},
+		{patterns: []string{"nginx.*"}, tag: "nginx.access", expMatch: true},
+		{patterns: []string{"nginx.*"}, tag: "nginx", expMatch: false},
+		{patterns: []string{"nginx.*"}, tag: "nginx.access.vhost", expMatch: false},
+		{patterns: []string{"nginx.**"}, tag: "nginx", expMatch: true},
+		{patterns: []string{"nginx.**"}, tag: "nginx.access.vhost", expMatch: true},
+		{patterns: []string{"kube.**.nginx-*"}, tag: "kube.var.log.containers.nginx-abc", expMatch: true},
+		{patterns: []string{"haproxy", "nginx.*"}, tag: "haproxy", expMatch: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			ingester, err := New(FluentForwardIngesterConfig{Tags: tt.patterns}, logrus.New())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expMatch, ingester.matchesTag(tt.tag), tt.patterns)
+		})
+	}
+	_, err := New(FluentForwardIngesterConfig{Tags: []string{"nginx.[a"}}, logrus.New())
+	assert.Error(t, err)
+}
+
+func TestFluentForwardIngester(t *testing.T) {
+	address := moduletest.FreeAddress(t)
+	ingester, err := New(FluentForwardIngesterConfig{Address: address, Tags: []string{"nginx.*"}, TagMetadataKey: "tag", KeySeparator: "."}, logrus.New())
+	assert.NoError(t, err)
+
+	stop := moduletest.Run(t, ingester)
+	var conn net.Conn
+	assert.Eventually(t, func() bool {
+		conn, err = net.Dial("tcp", address)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	eventTime := time.Unix(1704103200, 123456789)
+	record := map[string]interface{}{"method": "GET"}
+	// Records of the filtered tag are acknowledged without emitting events.
+	_, err = conn.Write(marshal(t,
+		[]interface{}{"haproxy", eventTime.Unix(), record},
+		[]interface{}{"nginx.access", []interface{}{[]interface{}{testEventTime(eventTime), record}}, map[string]interface{}{"chunk": "abc"}},
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, &event.Raw{Metadata: stringmap.StringMap{"method": "GET", "tag": "nginx.access"}, Quantity: 1, Timestamp: eventTime}, <-ingester.OutputChannel())
+	var ack map[string]string
+	assert.NoError(t, msgpack.NewDecoder(conn).Decode(&ack))
+	assert.Equal(t, map[string]string{"ack": "abc"}, ack)
+	assert.Equal(t, 1.0, testutil.ToFloat64(ingester.recordsTotal.WithLabelValues(forwardMode)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(ingester.filteredRecordsTotal))
+
+	// Connection is closed after invalid message.
+	_, err = conn.Write(marshal(t, "invalid"))
+	assert.NoError(t, err)
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(ingester.errorsTotal.WithLabelValues("InvalidMessage")))
+	conn.Close()
+
+	// Open connection must not block the shutdown.
+	conn, err = net.Dial("tcp", address)
+	assert.NoError(t, err)
+	defer conn.Close()
+	stop()
+}
@@ -0,0 +1,250 @@
+package fluent_forward_ingester
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// Modes of the Fluent Forward protocol, see https://github.com/fluent/fluentd/wiki/Forward-Protocol-Specification-v1.
+const (
+	messageMode                 = "Message"
+	forwardMode                 = "Forward"
+	packedForwardMode           = "PackedForward"
+	compressedPackedForwardMode = "CompressedPackedForward"
+)
+
+// eventTimeExtID is the msgpack extension type of the EventTime with nanosecond precision.
+const eventTimeExtID = 0
+
+// entry is a single record of the forwarded message.
+type entry struct {
+	timestamp time.Time
+	record    map[string]interface{}
+}
+
+// options of the forwarded message.
+type options struct {
+	// Size is number of the entries in the message.
+	Size int `msgpack:"size"`
+	// Chunk is ID of the message which the server acknowledges once it is processed.
+	Chunk string `msgpack:"chunk"`
+	// Compressed is compression of the entries of the CompressedPackedForward mode, only gzip is defined.
+	Compressed string `msgpack:"compressed"`
+	// FluentSignal is type of the data sent by Fluent Bit, 0 are logs.
+	FluentSignal int `msgpack:"fluent_signal"`
+}
+
+// forwardMessage is a decoded message of any mode of the protocol.
+type forwardMessage struct {
+	tag     string
+	mode    string
+	entries []entry
+	options options
+}
+
+func newDecoder(r io.Reader) *msgpack.Decoder {
+	decoder := msgpack.NewDecoder(r)
+	// Binary values of the records are converted to strings.
+	decoder.UseLooseInterfaceDecoding(true)
+	return decoder
+}
+
+func isArray(code byte) bool {
+	return msgpcode.IsFixedArray(code) || code == msgpcode.Array16 || code == msgpcode.Array32
+}
+
+// decodeMessage decodes the next message `[tag, time, record, option?]`, `[tag, [entry...], option?]` or `[tag, packed entries, option?]`.
+func decodeMessage(decoder *msgpack.Decoder) (forwardMessage, error) {
+	length, err := decoder.DecodeArrayLen()
+	if err != nil {
+		return forwardMessage{}, err
+	}
+	if length < 2 || length > 4 {
+		return forwardMessage{}, fmt.Errorf("message is array of %d items, expected 2 to 4", length)
+	}
+	var msg forwardMessage
+	if msg.tag, err = decoder.DecodeString(); err != nil {
+		return forwardMessage{}, fmt.Errorf("invalid tag: %w", err)
+	}
+	code, err := decoder.PeekCode()
+	if err != nil {
+		return forwardMessage{}, err
+	}
+	decodedItems := 2
+	var packedEntries []byte
+	switch {
+	case isArray(code):
+		msg.mode = forwardMode
+		entriesCount, err := decoder.DecodeArrayLen()
+		if err != nil {
+			return forwardMessage{}, err
+		}
+		for i := 0; i < entriesCount; i++ {
+			e, err := decodeEntry(decoder)
+			if err != nil {
+				return forwardMessage{}, err
+			}
+			msg.entries = append(msg.entries, e)
+		}
+	case msgpcode.IsString(code) || msgpcode.IsBin(code):
+		msg.mode = packedForwardMode
+		if packedEntries, err = decoder.DecodeBytes(); err != nil {
+			return forwardMessage{}, err
+		}
+	default:
+		msg.mode = messageMode
+		if length < 3 {
+			return forwardMessage{}, errors.New("missing record of the message")
+		}
+		var e entry
+		if e.timestamp, err = decodeEventTime(decoder); err != nil {
+			return forwardMessage{}, err
+		}
+		if e.record, err = decodeRecord(decoder); err != nil {
+			return forwardMessage{}, err
+		}
+		msg.entries = []entry{e}
+		decodedItems = 3
+	}
+	if length > decodedItems+1 {
+		return forwardMessage{}, fmt.Errorf("unexpected %d items of the %s mode message", length, msg.mode)
+	}
+	if length == decodedItems+1 {
+		if err := decoder.Decode(&msg.options); err != nil {
+			return forwardMessage{}, fmt.Errorf("invalid options of the message: %w", err)
+		}
+	}
+	if msg.mode == packedForwardMode {
+		if msg.entries, err = decodePackedEntries(packedEntries, msg.options.Compressed); err != nil {
+			return forwardMessage{}, err
+		}
+		if msg.options.Compressed != "" {
+			msg.mode = compressedPackedForwardMode
+		}
+	}
+	return msg, nil
+}
+
+// decodePackedEntries decodes the entries of the PackedForward mode which are concatenated msgpack encoded entries, optionally gzip compressed.
+func decodePackedEntries(packedEntries []byte, compression string) ([]entry, error) {
+	var reader io.Reader = bytes.NewReader(packedEntries)
+	switch compression {
+	case "":
+	case "gzip":
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip compressed entries: %w", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	default:
+		return nil, fmt.Errorf("unsupported compression of the entries '%s'", compression)
+	}
+	decoder := newDecoder(reader)
+	var entries []entry
+	for {
+		if _, err := decoder.PeekCode(); errors.Is(err, io.EOF) {
+			return entries, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid packed entries: %w", err)
+		}
+		e, err := decodeEntry(decoder)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+}
+
+// decodeEntry decodes the entry `[time, record]`, time can be also `[time, metadata]` as sent by Fluent Bit since v2.1.
+func decodeEntry(decoder *msgpack.Decoder) (entry, error) {
+	length, err := decoder.DecodeArrayLen()
+	if err != nil {
+		return entry{}, fmt.Errorf("invalid entry: %w", err)
+	}
+	if length != 2 {
+		return entry{}, fmt.Errorf("entry is array of %d items, expected 2", length)
+	}
+	code, err := decoder.PeekCode()
+	if err != nil {
+		return entry{}, err
+	}
+	var e entry
+	if isArray(code) {
+		headerLength, err := decoder.DecodeArrayLen()
+		if err != nil {
+			return entry{}, err
+		}
+		if headerLength < 1 {
+			return entry{}, errors.New("missing time of the entry")
+		}
+		if e.timestamp, err = decodeEventTime(decoder); err != nil {
+			return entry{}, err
+		}
+		// Metadata of the Fluent Bit entry is not used.
+		for i := 1; i < headerLength; i++ {
+			if err := decoder.Skip(); err != nil {
+				return entry{}, err
+			}
+		}
+	} else if e.timestamp, err = decodeEventTime(decoder); err != nil {
+		return entry{}, err
+	}
+	if e.record, err = decodeRecord(decoder); err != nil {
+		return entry{}, err
+	}
+	return e, nil
+}
+
+func decodeRecord(decoder *msgpack.Decoder) (map[string]interface{}, error) {
+	record, err := decoder.DecodeMap()
+	if err != nil {
+		return nil, fmt.Errorf("invalid record: %w", err)
+	}
+	return record, nil
+}
+
+// decodeEventTime decodes time of the entry which is either integer with seconds since epoch or the EventTime extension.
+func decodeEventTime(decoder *msgpack.Decoder) (time.Time, error) {
+	code, err := decoder.PeekCode()
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch {
+	case msgpcode.IsExt(code):
+		extID, extLength, err := decoder.DecodeExtHeader()
+		if err != nil {
+			return time.Time{}, err
+		}
+		if extID != eventTimeExtID || extLength != 8 {
+			return time.Time{}, fmt.Errorf("unexpected extension type %d of length %d, expected EventTime", extID, extLength)
+		}
+		eventTime := make([]byte, extLength)
+		if err := decoder.ReadFull(eventTime); err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(int64(binary.BigEndian.Uint32(eventTime[:4])), int64(binary.BigEndian.Uint32(eventTime[4:]))), nil
+	case code == msgpcode.Float || code == msgpcode.Double:
+		seconds, err := decoder.DecodeFloat64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		integer, fraction := math.Modf(seconds)
+		return time.Unix(int64(integer), int64(math.Round(fraction*1e9))), nil
+	default:
+		seconds, err := decoder.DecodeInt64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time of the entry: %w", err)
+		}
+		return time.Unix(seconds, 0), nil
+	}
+}
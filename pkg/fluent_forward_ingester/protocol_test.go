@@ -0,0 +1,113 @@
+package fluent_forward_ingester
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// testEventTime is encoded as the EventTime extension.
+type testEventTime time.Time
+
+func (t testEventTime) EncodeMsgpack(encoder *msgpack.Encoder) error {
+	if err := encoder.EncodeExtHeader(eventTimeExtID, 8); err != nil {
+		return err
+	}
+	eventTime := make([]byte, 8)
+	binary.BigEndian.PutUint32(eventTime[:4], uint32(time.Time(t).Unix()))
+	binary.BigEndian.PutUint32(eventTime[4:], uint32(time.Time(t).Nanosecond()))
+	_, err := encoder.Writer().Write(eventTime)
+	return err
+}
+
+func marshal(t *testing.T, values ...interface{}) []byte {
+	var buffer bytes.Buffer
+	encoder := msgpack.NewEncoder(&buffer)
+	for _, value := range values {
+		assert.NoError(t, encoder.Encode(value))
+	}
+	return buffer.Bytes()
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	_, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func Test_decodeMessage(t *testing.T) {
+	eventTime := time.Unix(1704103200, 123456789)
+	record := map[string]interface{}{"method": "GET"}
+	expEntries := []entry{
+		{timestamp: time.Unix(eventTime.Unix(), 0), record: record},
+		{timestamp: eventTime, record: record},
+	}
+	packedEntries := marshal(t, []interface{}{eventTime.Unix(), record}, []interface{}{testEventTime(eventTime), record})
+	tests := []struct {
+		name   string
+		data   []byte
+		expMsg forwardMessage
+		expErr bool
+	}{
+		{
+			name:   "Message mode",
+			data:   marshal(t, []interface{}{"app", testEventTime(eventTime), record}),
+			expMsg: forwardMessage{tag: "app", mode: messageMode, entries: []entry{{timestamp: eventTime, record: record}}},
+		},
+		{
+			name:   "Message mode with options",
+			data:   marshal(t, []interface{}{"app", eventTime.Unix(), record, map[string]interface{}{"chunk": "p8n9gmxTQVC8/nh2wlKKeQ=="}}),
+			expMsg: forwardMessage{tag: "app", mode: messageMode, entries: []entry{{timestamp: time.Unix(eventTime.Unix(), 0), record: record}}, options: options{Chunk: "p8n9gmxTQVC8/nh2wlKKeQ=="}},
+		},
+		{
+			name:   "Forward mode",
+			data:   marshal(t, []interface{}{"app", []interface{}{[]interface{}{eventTime.Unix(), record}, []interface{}{testEventTime(eventTime), record}}}),
+			expMsg: forwardMessage{tag: "app", mode: forwardMode, entries: expEntries},
+		},
+		{
+			name:   "Forward mode with Fluent Bit metadata",
+			data:   marshal(t, []interface{}{"app", []interface{}{[]interface{}{[]interface{}{testEventTime(eventTime), map[string]interface{}{}}, record}}}),
+			expMsg: forwardMessage{tag: "app", mode: forwardMode, entries: []entry{{timestamp: eventTime, record: record}}},
+		},
+		{
+			name:   "PackedForward mode",
+			data:   marshal(t, []interface{}{"app", packedEntries, map[string]interface{}{"size": 2}}),
+			expMsg: forwardMessage{tag: "app", mode: packedForwardMode, entries: expEntries, options: options{Size: 2}},
+		},
+		{
+			name:   "PackedForward mode as string",
+			data:   marshal(t, []interface{}{"app", string(packedEntries)}),
+			expMsg: forwardMessage{tag: "app", mode: packedForwardMode, entries: expEntries},
+		},
+		{
+			name:   "CompressedPackedForward mode",
+			data:   marshal(t, []interface{}{"app", gzipped(t, packedEntries), map[string]interface{}{"compressed": "gzip", "chunk": "abc"}}),
+			expMsg: forwardMessage{tag: "app", mode: compressedPackedForwardMode, entries: expEntries, options: options{Compressed: "gzip", Chunk: "abc"}},
+		},
+		{name: "unsupported compression", data: marshal(t, []interface{}{"app", packedEntries, map[string]interface{}{"compressed": "zstd"}}), expErr: true},
+		{name: "invalid compressed entries", data: marshal(t, []interface{}{"app", packedEntries, map[string]interface{}{"compressed": "gzip"}}), expErr: true},
+		{name: "not an array", data: marshal(t, record), expErr: true},
+		{name: "missing record", data: marshal(t, []interface{}{"app", eventTime.Unix()}), expErr: true},
+		{name: "invalid record", data: marshal(t, []interface{}{"app", eventTime.Unix(), "GET"}), expErr: true},
+		{name: "invalid time", data: marshal(t, []interface{}{"app", true, record}), expErr: true},
+		{name: "invalid entry", data: marshal(t, []interface{}{"app", []interface{}{[]interface{}{eventTime.Unix()}}}), expErr: true},
+		{name: "too many items", data: marshal(t, []interface{}{"app", eventTime.Unix(), record, map[string]interface{}{}, 1}), expErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := decodeMessage(newDecoder(bytes.NewReader(tt.data)))
+			assert.Equal(t, tt.expErr, err != nil, err)
+			if !tt.expErr {
+				assert.Equal(t, tt.expMsg, msg)
+			}
+		})
+	}
+}
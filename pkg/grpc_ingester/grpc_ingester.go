@@ -0,0 +1,201 @@
+package grpc_ingester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/grpc_server"
+	ingestionv1 "github.com/seznam/slo-exporter/pkg/ingestion/v1"
+	"github.com/seznam/slo-exporter/pkg/internal/shutdown"
+	"github.com/seznam/slo-exporter/pkg/pipeline"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Reasons of the rejected events used as a label of the rejected_events_total metric.
+const (
+	reasonInvalidEvent = "invalidEvent"
+	reasonShuttingDown = "shuttingDown"
+)
+
+// GRPCIngesterConfig is configuration of the gRPC ingester module.
+type GRPCIngesterConfig struct {
+	// Config of the gRPC server.
+	grpc_server.Config `mapstructure:",squash"`
+}
+
+// GRPCIngester produces events pushed using the slo-exporter event ingestion API.
+type GRPCIngester struct {
+	server         *grpc_server.Server
+	outputChannel  chan *event.Raw
+	observer       pipeline.EventProcessingDurationObserver
+	deadLetterSink pipeline.DeadLetterSink
+	logger         logrus.FieldLogger
+
+	acceptedEventsTotal prometheus.Counter
+	rejectedEventsTotal *prometheus.CounterVec
+}
+
+func (g *GRPCIngester) String() string {
+	return "grpcIngester"
+}
+
+func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*GRPCIngester, error) {
+	viperConfig.SetDefault("Address", ":18091")
+	viperConfig.SetDefault("GracefulShutdownTimeout", 5*time.Second)
+	var config GRPCIngesterConfig
+	if err := viperConfig.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return New(config, logger)
+}
+
+// New returns an instance of GRPCIngester.
+func New(config GRPCIngesterConfig, logger logrus.FieldLogger) (*GRPCIngester, error) {
+	return &GRPCIngester{
+		server:        grpc_server.New(config.Config, logger),
+		outputChannel: make(chan *event.Raw),
+		logger:        logger,
+		acceptedEventsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "accepted_events_total",
+			Help: "Total number of events passed to the pipeline.",
+		}),
+		rejectedEventsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rejected_events_total",
+			Help: "Total number of events rejected in the response by the reason.",
+		}, []string{"reason"}),
+	}, nil
+}
+
+func (g *GRPCIngester) RegisterEventProcessingDurationObserver(observer pipeline.EventProcessingDurationObserver) {
+	g.observer = observer
+}
+
+func (g *GRPCIngester) observeDuration(start time.Time) {
+	if g.observer != nil {
+		g.observer.Observe(time.Since(start).Seconds())
+	}
+}
+
+func (g *GRPCIngester) SetDeadLetterSink(sink pipeline.DeadLetterSink) {
+	g.deadLetterSink = sink
+}
+
+func (g *GRPCIngester) reject(ingestedEvent *ingestionv1.Event, err error) {
+	g.rejectedEventsTotal.WithLabelValues(reasonInvalidEvent).Inc()
+	if g.deadLetterSink != nil {
+		payload, _ := protojson.Marshal(ingestedEvent)
+		g.deadLetterSink.Reject(string(payload), err.Error())
+	}
+}
+
+func (g *GRPCIngester) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
+	toRegister := []prometheus.Collector{g.acceptedEventsTotal, g.rejectedEventsTotal, g.server.Metrics()}
+	for _, collector := range toRegister {
+		if err := wrappedRegistry.Register(collector); err != nil {
+			return fmt.Errorf("error registering metric %s: %w", collector, err)
+		}
+	}
+	return nil
+}
+
+func (g *GRPCIngester) OutputChannel() chan *event.Raw {
+	return g.outputChannel
+}
+
+// Run serves the event ingestion API feeding events to output channel until the context is cancelled.
+func (g *GRPCIngester) Run(ctx context.Context) error {
+	defer close(g.outputChannel)
+	ingestionv1.RegisterEventIngestionServiceServer(g.server.GRPCServer(), &ingestionService{ingester: g, ctx: ctx})
+	if err := g.server.Run(ctx); err != nil {
+		return fmt.Errorf("%s failed: %w", g, err)
+	}
+	return nil
+}
+
+// ingestionService implements the event ingestion service, the events are emitted until the ctx is cancelled.
+type ingestionService struct {
+	ingestionv1.UnimplementedEventIngestionServiceServer
+	ingester *GRPCIngester
+	ctx      context.Context
+}
+
+func (s *ingestionService) Push(ctx context.Context, request *ingestionv1.PushRequest) (*ingestionv1.PushResponse, error) {
+	return s.push(ctx, request)
+}
+
+func (s *ingestionService) PushStream(stream ingestionv1.EventIngestionService_PushStreamServer) error {
+	for {
+		request, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		response, err := s.push(stream.Context(), request)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+}
+
+// push emits events of the request, events which cannot be processed are reported as rejected in the response.
+func (s *ingestionService) push(ctx context.Context, request *ingestionv1.PushRequest) (*ingestionv1.PushResponse, error) {
+	g := s.ingester
+	receivedAt := time.Now()
+	response := &ingestionv1.PushResponse{}
+	events := request.GetEvents()
+	for i, ingestedEvent := range events {
+		newEvent, err := g.processEvent(ingestedEvent, receivedAt)
+		if err != nil {
+			err = fmt.Errorf("invalid event %d: %w", i, err)
+			g.reject(ingestedEvent, err)
+			response.RejectedEvents++
+			response.ErrorMessage = err.Error()
+			continue
+		}
+		select {
+		case g.outputChannel <- newEvent:
+			response.AcceptedEvents++
+			g.acceptedEventsTotal.Inc()
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case <-s.ctx.Done():
+			return s.shuttingDown(response, len(events)-int(response.RejectedEvents))
+		}
+	}
+	return response, nil
+}
+
+// shuttingDown returns response of the request interrupted by the shutdown, total is number of its events which were not rejected as invalid.
+func (s *ingestionService) shuttingDown(response *ingestionv1.PushResponse, total int) (*ingestionv1.PushResponse, error) {
+	rejected, err := shutdown.RejectRemaining(s.ingester, int(response.AcceptedEvents), total)
+	if err != nil {
+		return nil, err
+	}
+	s.ingester.rejectedEventsTotal.WithLabelValues(reasonShuttingDown).Add(float64(rejected))
+	response.RejectedEvents += int64(rejected)
+	response.ErrorMessage = (&shutdown.Error{Module: s.ingester}).Error()
+	return response, nil
+}
+
+// processEvent returns the raw event, the receivedAt is used as its time if the event does not specify it.
+func (g *GRPCIngester) processEvent(ingestedEvent *ingestionv1.Event, receivedAt time.Time) (*event.Raw, error) {
+	start := time.Now()
+	defer g.observeDuration(start)
+	if err := ingestedEvent.Validate(); err != nil {
+		return nil, err
+	}
+	return ingestedEvent.ToRaw(receivedAt), nil
+}
@@ -0,0 +1,120 @@
+package grpc_ingester
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/grpc_server"
+	"github.com/seznam/slo-exporter/pkg/ingestion/client"
+	ingestionv1 "github.com/seznam/slo-exporter/pkg/ingestion/v1"
+	"github.com/seznam/slo-exporter/pkg/internal/moduletest"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestIngester(t *testing.T, address string) *GRPCIngester {
+	ingester, err := New(GRPCIngesterConfig{Config: grpc_server.Config{Address: address, GracefulShutdownTimeout: time.Second}}, logrus.New())
+	assert.NoError(t, err)
+	return ingester
+}
+
+func float64Pointer(value float64) *float64 {
+	return &value
+}
+
+func TestIngestionService_Push(t *testing.T) {
+	ingester := newTestIngester(t, "")
+	deadLetterSink := &moduletest.DeadLetterSink{}
+	ingester.SetDeadLetterSink(deadLetterSink)
+	service := &ingestionService{ingester: ingester, ctx: context.Background()}
+
+	collected := moduletest.CollectEvents(ingester.OutputChannel(), 2)
+	response, err := service.Push(context.Background(), &ingestionv1.PushRequest{Events: []*ingestionv1.Event{
+		{Metadata: map[string]string{"name": "checkout"}},
+		{Metadata: map[string]string{"name": "cart"}, Quantity: float64Pointer(-1)},
+		{Metadata: map[string]string{"name": "order"}, Quantity: float64Pointer(2)},
+	}})
+	assert.NoError(t, err)
+	events := <-collected
+	assert.Equal(t, stringmap.StringMap{"name": "checkout"}, events[0].Metadata)
+	assert.Equal(t, 1.0, events[0].Quantity)
+	assert.False(t, events[0].Timestamp.IsZero(), "time of the request is used if the event has no timestamp")
+	assert.Equal(t, 2.0, events[1].Quantity)
+	assert.Equal(t, int64(2), response.GetAcceptedEvents())
+	assert.Equal(t, int64(1), response.GetRejectedEvents())
+	assert.Contains(t, response.GetErrorMessage(), "invalid event 1")
+	if assert.Len(t, deadLetterSink.Payloads, 1) {
+		assert.JSONEq(t, `{"metadata": {"name": "cart"}, "quantity": -1}`, deadLetterSink.Payloads[0].(string))
+	}
+	assert.Equal(t, 2.0, testutil.ToFloat64(ingester.acceptedEventsTotal))
+	assert.Equal(t, 1.0, testutil.ToFloat64(ingester.rejectedEventsTotal.WithLabelValues(reasonInvalidEvent)))
+}
+
+func TestIngestionService_PushShuttingDown(t *testing.T) {
+	ingester := newTestIngester(t, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	service := &ingestionService{ingester: ingester, ctx: ctx}
+	request := &ingestionv1.PushRequest{Events: []*ingestionv1.Event{
+		{Metadata: map[string]string{"name": "checkout"}},
+		{Metadata: map[string]string{"name": "cart"}},
+		{Metadata: map[string]string{"name": "order"}},
+	}}
+
+	// Rest of the events is rejected if some of them were already accepted.
+	go func() {
+		<-ingester.OutputChannel()
+		cancel()
+	}()
+	response, err := service.Push(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), response.GetAcceptedEvents())
+	assert.Equal(t, int64(2), response.GetRejectedEvents())
+	assert.Equal(t, 2.0, testutil.ToFloat64(ingester.rejectedEventsTotal.WithLabelValues(reasonShuttingDown)))
+
+	// Whole request can be retried if none of the events was accepted.
+	_, err = service.Push(context.Background(), request)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestGRPCIngester_Run(t *testing.T) {
+	address := moduletest.FreeAddress(t)
+	ingester := newTestIngester(t, address)
+	stop := moduletest.Run(t, ingester)
+
+	ingestionClient, err := client.New(address)
+	assert.NoError(t, err)
+	defer ingestionClient.Close()
+	timestamp := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	checkout := &event.Raw{
+		Metadata:          stringmap.StringMap{"name": "checkout"},
+		SloClassification: &event.SloClassification{Domain: "shop", App: "frontend", Class: "critical"},
+		Quantity:          1,
+		Timestamp:         timestamp,
+	}
+	collected := moduletest.CollectEvents(ingester.OutputChannel(), 1)
+	assert.Eventually(t, func() bool {
+		return ingestionClient.Push(context.Background(), checkout) == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []*event.Raw{checkout}, <-collected)
+
+	stream, err := ingestionClient.NewStream(context.Background())
+	assert.NoError(t, err)
+	collected = moduletest.CollectEvents(ingester.OutputChannel(), 3)
+	assert.NoError(t, stream.Push(checkout, checkout))
+	err = stream.Push(checkout, &event.Raw{Quantity: -1})
+	var rejectedErr *client.RejectedEventsError
+	assert.True(t, errors.As(err, &rejectedErr))
+	assert.Equal(t, int64(1), rejectedErr.Accepted)
+	assert.Equal(t, int64(1), rejectedErr.Rejected)
+	assert.NoError(t, stream.Close())
+	assert.Len(t, <-collected, 3)
+
+	stop()
+}
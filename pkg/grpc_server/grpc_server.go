@@ -0,0 +1,95 @@
+// Package grpc_server implements the gRPC server with the grpc_prometheus metrics shared by the modules receiving events over gRPC.
+package grpc_server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// Config is configuration of the gRPC server, modules embed it to their configuration.
+type Config struct {
+	// Address to listen on for the gRPC requests.
+	Address string
+	// GracefulShutdownTimeout limits how long to wait for the pending requests during shutdown.
+	GracefulShutdownTimeout time.Duration
+}
+
+// Server is the gRPC server instrumented with the grpc_prometheus metrics.
+type Server struct {
+	address                 string
+	gracefulShutdownTimeout time.Duration
+	server                  *grpc.Server
+	serverMetrics           *grpc_prometheus.ServerMetrics
+	logger                  logrus.FieldLogger
+}
+
+// New returns Server, the services have to be registered before it is run.
+func New(config Config, logger logrus.FieldLogger) *Server {
+	serverMetrics := grpc_prometheus.NewServerMetrics()
+	return &Server{
+		address:                 config.Address,
+		gracefulShutdownTimeout: config.GracefulShutdownTimeout,
+		server: grpc.NewServer(
+			grpc.StreamInterceptor(serverMetrics.StreamServerInterceptor()),
+			grpc.UnaryInterceptor(serverMetrics.UnaryServerInterceptor()),
+		),
+		serverMetrics: serverMetrics,
+		logger:        logger,
+	}
+}
+
+// GRPCServer returns the gRPC server to register the services to.
+func (s *Server) GRPCServer() *grpc.Server {
+	return s.server
+}
+
+// Metrics returns the grpc_prometheus metrics of the server to be registered by the module.
+func (s *Server) Metrics() prometheus.Collector {
+	return s.serverMetrics
+}
+
+// Run serves the requests until the context is cancelled.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen for the gRPC requests: %w", err)
+	}
+	s.logger.Infof("listening for the gRPC requests on %s", listener.Addr())
+	s.serverMetrics.InitializeMetrics(s.server)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.server.Serve(listener)
+	}()
+	select {
+	case err := <-serveErr:
+		s.server.Stop()
+		return fmt.Errorf("gRPC server fatal error: %w", err)
+	case <-ctx.Done():
+	}
+	s.stop()
+	return nil
+}
+
+// stop gracefully stops the server, pending requests are terminated once the graceful shutdown timeout expires.
+func (s *Server) stop() {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	t := time.NewTimer(s.gracefulShutdownTimeout)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-stopped:
+	}
+	s.server.Stop()
+}
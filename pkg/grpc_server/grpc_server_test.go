@@ -0,0 +1,42 @@
+package grpc_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/internal/moduletest"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestServer_Run(t *testing.T) {
+	address := moduletest.FreeAddress(t)
+	server := New(Config{Address: address, GracefulShutdownTimeout: time.Second}, logrus.New())
+	healthpb.RegisterHealthServer(server.GRPCServer(), health.NewServer())
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error)
+	go func() {
+		runErr <- server.Run(ctx)
+	}()
+
+	connection, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer connection.Close()
+	assert.Eventually(t, func() bool {
+		_, err := healthpb.NewHealthClient(connection).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-runErr)
+}
+
+func TestServer_RunInvalidAddress(t *testing.T) {
+	server := New(Config{Address: "invalid address", GracefulShutdownTimeout: time.Second}, logrus.New())
+	assert.Error(t, server.Run(context.Background()))
+}
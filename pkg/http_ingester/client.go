@@ -0,0 +1,86 @@
+package http_ingester
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Values of the client label of the metrics if the client is not identified.
+const (
+	anonymousClient       = "anonymous"
+	unauthenticatedClient = "unauthenticated"
+)
+
+// ClientConfig is configuration of the client authenticated by the bearer token.
+type ClientConfig struct {
+	// Name of the client used as the client label of the metrics.
+	Name string
+	// Token of the client, exactly one of Token and TokenFromEnv must be set.
+	Token *string
+	// TokenFromEnv is name of the environment variable containing the token.
+	TokenFromEnv *string
+}
+
+func (c ClientConfig) token() (string, error) {
+	if c.Name == "" {
+		return "", fmt.Errorf("client name must be set")
+	}
+	if (c.Token == nil) == (c.TokenFromEnv == nil) {
+		return "", fmt.Errorf("exactly one of 'Token' or 'TokenFromEnv' must be set for client '%s'", c.Name)
+	}
+	token := ""
+	if c.TokenFromEnv != nil {
+		value, ok := os.LookupEnv(*c.TokenFromEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable '%s' with token of client '%s' is not set", *c.TokenFromEnv, c.Name)
+		}
+		token = value
+	} else {
+		token = *c.Token
+	}
+	if token == "" {
+		return "", fmt.Errorf("token of client '%s' must not be empty", c.Name)
+	}
+	return token, nil
+}
+
+// authenticator identifies the clients by the bearer token of the request.
+type authenticator struct {
+	// clientTokens maps the names of the clients to their tokens, authentication is disabled if empty.
+	clientTokens map[string]string
+}
+
+func newAuthenticator(clients []ClientConfig) (*authenticator, error) {
+	clientTokens := make(map[string]string, len(clients))
+	for _, client := range clients {
+		token, err := client.token()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := clientTokens[client.Name]; ok {
+			return nil, fmt.Errorf("duplicate client name '%s'", client.Name)
+		}
+		clientTokens[client.Name] = token
+	}
+	return &authenticator{clientTokens: clientTokens}, nil
+}
+
+// authenticate returns name of the client of the request and whether it is allowed to push the events.
+func (a *authenticator) authenticate(r *http.Request) (string, bool) {
+	if len(a.clientTokens) == 0 {
+		return anonymousClient, true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return unauthenticatedClient, false
+	}
+	for name, clientToken := range a.clientTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(clientToken)) == 1 {
+			return name, true
+		}
+	}
+	return unauthenticatedClient, false
+}
@@ -0,0 +1,72 @@
+package http_ingester
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	t.Setenv("CHECKOUT_TOKEN", "secret")
+	tests := []struct {
+		name    string
+		clients []ClientConfig
+		expErr  bool
+	}{
+		{name: "no clients", clients: nil},
+		{name: "token and token from env", clients: []ClientConfig{{Name: "web", Token: stringPointer("token")}, {Name: "checkout", TokenFromEnv: stringPointer("CHECKOUT_TOKEN")}}},
+		{name: "missing name", clients: []ClientConfig{{Token: stringPointer("token")}}, expErr: true},
+		{name: "missing token", clients: []ClientConfig{{Name: "web"}}, expErr: true},
+		{name: "both tokens", clients: []ClientConfig{{Name: "web", Token: stringPointer("token"), TokenFromEnv: stringPointer("CHECKOUT_TOKEN")}}, expErr: true},
+		{name: "empty token", clients: []ClientConfig{{Name: "web", Token: stringPointer("")}}, expErr: true},
+		{name: "missing environment variable", clients: []ClientConfig{{Name: "web", TokenFromEnv: stringPointer("MISSING_TOKEN")}}, expErr: true},
+		{name: "duplicate name", clients: []ClientConfig{{Name: "web", Token: stringPointer("a")}, {Name: "web", Token: stringPointer("b")}}, expErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newAuthenticator(tt.clients)
+			if tt.expErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_authenticate(t *testing.T) {
+	disabled, err := newAuthenticator(nil)
+	assert.NoError(t, err)
+	enabled, err := newAuthenticator([]ClientConfig{{Name: "web", Token: stringPointer("web-token")}, {Name: "checkout", Token: stringPointer("checkout-token")}})
+	assert.NoError(t, err)
+	tests := []struct {
+		name          string
+		authenticator *authenticator
+		authorization string
+		expClient     string
+		expOk         bool
+	}{
+		{name: "disabled", authenticator: disabled, expClient: anonymousClient, expOk: true},
+		{name: "valid token", authenticator: enabled, authorization: "Bearer checkout-token", expClient: "checkout", expOk: true},
+		{name: "invalid token", authenticator: enabled, authorization: "Bearer foo", expClient: unauthenticatedClient},
+		{name: "missing token", authenticator: enabled, expClient: unauthenticatedClient},
+		{name: "basic authentication", authenticator: enabled, authorization: "Basic d2ViLXRva2Vu", expClient: unauthenticatedClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, eventsPath, nil)
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			client, ok := tt.authenticator.authenticate(request)
+			assert.Equal(t, tt.expClient, client)
+			assert.Equal(t, tt.expOk, ok)
+		})
+	}
+}
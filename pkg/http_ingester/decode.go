@@ -0,0 +1,40 @@
+package http_ingester
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Content types of the requests.
+const (
+	jsonContentType   = "application/json"
+	ndjsonContentType = "application/x-ndjson"
+)
+
+// splitEvents splits the request body to the JSON encoded events.
+// The JSON body contains a single event or an array of events, the NDJSON body contains an event per line.
+func splitEvents(contentType string, body []byte) ([]json.RawMessage, error) {
+	if contentType == ndjsonContentType {
+		var events []json.RawMessage
+		for _, line := range bytes.Split(body, []byte("\n")) {
+			line = bytes.TrimSpace(line)
+			if len(line) > 0 {
+				events = append(events, line)
+			}
+		}
+		return events, nil
+	}
+	body = bytes.TrimSpace(body)
+	if bytes.HasPrefix(body, []byte("[")) {
+		var events []json.RawMessage
+		if err := json.Unmarshal(body, &events); err != nil {
+			return nil, fmt.Errorf("invalid JSON array of events: %w", err)
+		}
+		return events, nil
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("invalid JSON event")
+	}
+	return []json.RawMessage{body}, nil
+}
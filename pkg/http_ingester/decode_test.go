@@ -0,0 +1,36 @@
+package http_ingester
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitEvents(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		expEvents   []json.RawMessage
+		expErr      bool
+	}{
+		{name: "single event", contentType: jsonContentType, body: ` {"quantity": 1} `, expEvents: []json.RawMessage{json.RawMessage(`{"quantity": 1}`)}},
+		{name: "array of events", contentType: jsonContentType, body: `[{"quantity": 1}, {"quantity": 2}]`, expEvents: []json.RawMessage{json.RawMessage(`{"quantity": 1}`), json.RawMessage(`{"quantity": 2}`)}},
+		{name: "empty array", contentType: jsonContentType, body: `[]`, expEvents: []json.RawMessage{}},
+		{name: "invalid array", contentType: jsonContentType, body: `[{"quantity": 1}`, expErr: true},
+		{name: "invalid event", contentType: jsonContentType, body: `{"quantity": `, expErr: true},
+		{name: "NDJSON", contentType: ndjsonContentType, body: "{\"quantity\": 1}\r\n\n{\"quantity\": \n", expEvents: []json.RawMessage{json.RawMessage(`{"quantity": 1}`), json.RawMessage(`{"quantity":`)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := splitEvents(tt.contentType, []byte(tt.body))
+			if tt.expErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expEvents, events)
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package http_ingester
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/internal/httpingest"
+	"github.com/seznam/slo-exporter/pkg/internal/shutdown"
+	"github.com/seznam/slo-exporter/pkg/kafka_ingester"
+	"github.com/seznam/slo-exporter/pkg/pipeline"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// eventsPath is the path of the events push API.
+const eventsPath = "/events"
+
+// Reasons of the rejected events used as a label of the rejected_events_total metric.
+const (
+	reasonInvalidEvent = "invalidEvent"
+	reasonShuttingDown = "shuttingDown"
+)
+
+// HTTPIngesterConfig is configuration of the HTTP ingester module.
+type HTTPIngesterConfig struct {
+	// Address to listen on for the HTTP requests.
+	Address string
+	// GracefulShutdownTimeout limits how long to wait for the pending requests during shutdown.
+	GracefulShutdownTimeout time.Duration
+	// MaxRequestBytes limits size of the request body, decompressed size for the gzip encoded requests.
+	MaxRequestBytes int64
+	// SaturationTimeout limits how long to wait for the pipeline to accept the first event of the request before it is refused.
+	SaturationTimeout time.Duration
+	// Clients allowed to push the events, authentication is disabled if empty.
+	Clients []ClientConfig
+}
+
+// HTTPIngester produces events pushed over HTTP in the kafka_ingester.IngestedEventV1 schema.
+type HTTPIngester struct {
+	address                 string
+	gracefulShutdownTimeout time.Duration
+	maxRequestBytes         int64
+	saturationTimeout       time.Duration
+	authenticator           *authenticator
+	outputChannel           chan *event.Raw
+	observer                pipeline.EventProcessingDurationObserver
+	deadLetterSink          pipeline.DeadLetterSink
+	logger                  logrus.FieldLogger
+
+	requestsTotal       *prometheus.CounterVec
+	acceptedEventsTotal *prometheus.CounterVec
+	rejectedEventsTotal *prometheus.CounterVec
+}
+
+func (h *HTTPIngester) String() string {
+	return "httpIngester"
+}
+
+func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*HTTPIngester, error) {
+	viperConfig.SetDefault("Address", ":8090")
+	viperConfig.SetDefault("GracefulShutdownTimeout", 5*time.Second)
+	viperConfig.SetDefault("MaxRequestBytes", 1024*1024)
+	viperConfig.SetDefault("SaturationTimeout", time.Second)
+	var config HTTPIngesterConfig
+	if err := viperConfig.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return New(config, logger)
+}
+
+// New returns an instance of HTTPIngester.
+func New(config HTTPIngesterConfig, logger logrus.FieldLogger) (*HTTPIngester, error) {
+	if config.MaxRequestBytes <= 0 {
+		return nil, fmt.Errorf("maximum request size must be positive, got %d", config.MaxRequestBytes)
+	}
+	authenticator, err := newAuthenticator(config.Clients)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clients configuration: %w", err)
+	}
+	return &HTTPIngester{
+		address:                 config.Address,
+		gracefulShutdownTimeout: config.GracefulShutdownTimeout,
+		maxRequestBytes:         config.MaxRequestBytes,
+		saturationTimeout:       config.SaturationTimeout,
+		authenticator:           authenticator,
+		outputChannel:           make(chan *event.Raw),
+		logger:                  logger,
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "requests_total",
+			Help: "Total number of requests by the client and HTTP status code of the response.",
+		}, []string{"client", "code"}),
+		acceptedEventsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "accepted_events_total",
+			Help: "Total number of events passed to the pipeline by the client.",
+		}, []string{"client"}),
+		rejectedEventsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rejected_events_total",
+			Help: "Total number of events of the processed requests rejected by the client and reason.",
+		}, []string{"client", "reason"}),
+	}, nil
+}
+
+func (h *HTTPIngester) RegisterEventProcessingDurationObserver(observer pipeline.EventProcessingDurationObserver) {
+	h.observer = observer
+}
+
+func (h *HTTPIngester) observeDuration(start time.Time) {
+	if h.observer != nil {
+		h.observer.Observe(time.Since(start).Seconds())
+	}
+}
+
+func (h *HTTPIngester) SetDeadLetterSink(sink pipeline.DeadLetterSink) {
+	h.deadLetterSink = sink
+}
+
+func (h *HTTPIngester) reject(payload json.RawMessage, err error) {
+	if h.deadLetterSink != nil {
+		h.deadLetterSink.Reject(string(payload), err.Error())
+	}
+}
+
+func (h *HTTPIngester) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
+	toRegister := []prometheus.Collector{h.requestsTotal, h.acceptedEventsTotal, h.rejectedEventsTotal}
+	for _, collector := range toRegister {
+		if err := wrappedRegistry.Register(collector); err != nil {
+			return fmt.Errorf("error registering metric %s: %w", collector, err)
+		}
+	}
+	return nil
+}
+
+func (h *HTTPIngester) OutputChannel() chan *event.Raw {
+	return h.outputChannel
+}
+
+// Run serves the HTTP requests feeding events to output channel until the context is cancelled.
+func (h *HTTPIngester) Run(ctx context.Context) error {
+	defer close(h.outputChannel)
+	mux := http.NewServeMux()
+	mux.Handle(eventsPath, h.eventsHandler(ctx))
+	server, err := httpingest.Listen(h.address, mux, h.gracefulShutdownTimeout)
+	if err != nil {
+		return fmt.Errorf("error while starting the %s: %w", h, err)
+	}
+	h.logger.Infof("listening for events on %s", server.Addr())
+	if err := server.Run(ctx); err != nil {
+		return fmt.Errorf("%s HTTP server fatal error: %w", h, err)
+	}
+	return nil
+}
+
+// ingestResponse is the JSON body of the response.
+type ingestResponse struct {
+	// Accepted is number of events passed to the pipeline.
+	Accepted int `json:"accepted"`
+	// Rejected is number of events which were not passed to the pipeline.
+	Rejected int `json:"rejected"`
+	// Error describes why the request or some of its events were rejected.
+	Error string `json:"error,omitempty"`
+}
+
+// invalidEvent is the payload of the event which could not be decoded.
+type invalidEvent struct {
+	payload json.RawMessage
+	err     error
+}
+
+// eventsHandler handles the requests pushing the events, the events are emitted until the ctx is cancelled.
+func (h *HTTPIngester) eventsHandler(ctx context.Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, ok := h.authenticator.authenticate(r)
+		var code int
+		var response ingestResponse
+		if ok {
+			code, response = h.ingest(ctx, r, client)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="slo-exporter"`)
+			code, response = http.StatusUnauthorized, ingestResponse{Error: "missing or invalid bearer token"}
+		}
+		h.requestsTotal.WithLabelValues(client, fmt.Sprint(code)).Inc()
+		logger := h.logger.WithFields(logrus.Fields{"client": client, "remoteAddress": r.RemoteAddr})
+		switch {
+		case code >= http.StatusInternalServerError:
+			logger.Errorf("failed to process the request: %s", response.Error)
+		case code >= http.StatusBadRequest:
+			logger.Warnf("invalid request: %s", response.Error)
+		case response.Rejected > 0:
+			logger.Warnf("rejected %d events of the request: %s", response.Rejected, response.Error)
+		}
+		if code == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		body, err := json.Marshal(response)
+		if err != nil {
+			h.logger.Errorf("failed to encode the response: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		w.WriteHeader(code)
+		if _, err := w.Write(body); err != nil {
+			logger.Debugf("failed to write the response: %v", err)
+		}
+	})
+}
+
+// ingest decodes the request and emits its events, returns HTTP status code and body of the response.
+func (h *HTTPIngester) ingest(ctx context.Context, r *http.Request, client string) (int, ingestResponse) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed, ingestResponse{Error: fmt.Sprintf("method %s is not allowed", r.Method)}
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != jsonContentType && contentType != ndjsonContentType {
+		return http.StatusUnsupportedMediaType, ingestResponse{Error: fmt.Sprintf("unsupported content type '%s', expected %s or %s", contentType, jsonContentType, ndjsonContentType)}
+	}
+	body, err := httpingest.ReadBody(r, h.maxRequestBytes)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return http.StatusRequestEntityTooLarge, ingestResponse{Error: err.Error()}
+		}
+		return http.StatusBadRequest, ingestResponse{Error: err.Error()}
+	}
+	payloads, err := splitEvents(contentType, body)
+	if err != nil {
+		return http.StatusBadRequest, ingestResponse{Error: err.Error()}
+	}
+
+	receivedAt := time.Now()
+	var response ingestResponse
+	var events []*event.Raw
+	var invalidEvents []invalidEvent
+	for i, payload := range payloads {
+		newEvent, err := h.processEvent(payload, receivedAt)
+		if err != nil {
+			err = fmt.Errorf("invalid event %d: %w", i, err)
+			invalidEvents = append(invalidEvents, invalidEvent{payload: payload, err: err})
+			if response.Error == "" {
+				response.Error = err.Error()
+			}
+			continue
+		}
+		events = append(events, newEvent)
+	}
+
+	accepted, err := h.emit(ctx, r.Context(), events)
+	response.Accepted = accepted
+	h.acceptedEventsTotal.WithLabelValues(client).Add(float64(accepted))
+	switch {
+	case errors.Is(err, errSaturated):
+		return http.StatusTooManyRequests, ingestResponse{Error: err.Error()}
+	case errors.As(err, new(*shutdown.Error)):
+		rejected, err := shutdown.RejectRemaining(h, accepted, len(events))
+		if err != nil {
+			return http.StatusServiceUnavailable, ingestResponse{Error: err.Error()}
+		}
+		h.rejectedEventsTotal.WithLabelValues(client, reasonShuttingDown).Add(float64(rejected))
+		response.Rejected = rejected
+		response.Error = (&shutdown.Error{Module: h}).Error()
+	case err != nil:
+		return http.StatusServiceUnavailable, ingestResponse{Accepted: accepted, Error: err.Error()}
+	}
+
+	// Invalid events are recorded only if the request is not refused, so they are not recorded again on its retry.
+	for _, invalid := range invalidEvents {
+		h.rejectedEventsTotal.WithLabelValues(client, reasonInvalidEvent).Inc()
+		h.reject(invalid.payload, invalid.err)
+	}
+	response.Rejected += len(invalidEvents)
+	return http.StatusOK, response
+}
+
+var errSaturated = errors.New("pipeline is saturated, retry later")
+
+// emit passes the events to the output channel and returns number of the accepted ones. Fails with errSaturated
+// if the first event is not accepted within the saturation timeout, the rest of the events is waited for so the request is not refused half-way.
+func (h *HTTPIngester) emit(ctx, requestCtx context.Context, events []*event.Raw) (int, error) {
+	if len(events) == 0 {
+		return 0, nil
+	}
+	saturationTimer := time.NewTimer(h.saturationTimeout)
+	defer saturationTimer.Stop()
+	saturated := saturationTimer.C
+	for i, newEvent := range events {
+		select {
+		case h.outputChannel <- newEvent:
+			saturated = nil
+		case <-saturated:
+			return i, errSaturated
+		case <-requestCtx.Done():
+			return i, requestCtx.Err()
+		case <-ctx.Done():
+			return i, &shutdown.Error{Module: h}
+		}
+	}
+	return len(events), nil
+}
+
+// processEvent returns event of the payload, the receivedAt is used as its time if the payload does not specify it.
+func (h *HTTPIngester) processEvent(payload json.RawMessage, receivedAt time.Time) (*event.Raw, error) {
+	start := time.Now()
+	defer h.observeDuration(start)
+	var ingestedEvent kafka_ingester.IngestedEventV1
+	if err := json.Unmarshal(payload, &ingestedEvent); err != nil {
+		return nil, err
+	}
+	return ingestedEvent.ToRaw(receivedAt), nil
+}
@@ -0,0 +1,181 @@
+package http_ingester
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/internal/moduletest"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestIngester(t *testing.T, address string, clients ...ClientConfig) *HTTPIngester {
+	ingester, err := New(HTTPIngesterConfig{
+		Address:                 address,
+		GracefulShutdownTimeout: time.Second,
+		MaxRequestBytes:         1024,
+		SaturationTimeout:       50 * time.Millisecond,
+		Clients:                 clients,
+	}, logrus.New())
+	assert.NoError(t, err)
+	return ingester
+}
+
+func decodeResponse(t *testing.T, recorder *httptest.ResponseRecorder) ingestResponse {
+	var response ingestResponse
+	assert.Equal(t, jsonContentType, recorder.Header().Get("Content-Type"))
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	return response
+}
+
+func TestHTTPIngester_eventsHandler(t *testing.T) {
+	timestamp := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	ingester := newTestIngester(t, "", ClientConfig{Name: "web", Token: stringPointer("web-token")})
+	deadLetterSink := &moduletest.DeadLetterSink{}
+	ingester.SetDeadLetterSink(deadLetterSink)
+	handler := ingester.eventsHandler(context.Background())
+
+	collected := moduletest.CollectEvents(ingester.OutputChannel(), 2)
+	body := `[{"metadata": {"name": "checkout"}, "slo_classification": {"domain": "shop", "app": "frontend", "class": "critical"}, "quantity": 2, "timestamp": "2024-01-01T10:00:00Z"}, {"metadata": {"name": "cart"}}]`
+	request := httptest.NewRequest(http.MethodPost, eventsPath, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	request.Header.Set("Authorization", "Bearer web-token")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, ingestResponse{Accepted: 2}, decodeResponse(t, recorder))
+	events := <-collected
+	assert.Equal(t, &event.Raw{
+		Metadata:          stringmap.StringMap{"name": "checkout"},
+		SloClassification: &event.SloClassification{Domain: "shop", App: "frontend", Class: "critical"},
+		Quantity:          2,
+		Timestamp:         timestamp,
+	}, events[0])
+	assert.Equal(t, stringmap.StringMap{"name": "cart"}, events[1].Metadata)
+	assert.Equal(t, 1.0, events[1].Quantity)
+	assert.False(t, events[1].Timestamp.IsZero(), "time of the request is used if the event has no timestamp")
+
+	// Invalid events of the NDJSON request are rejected.
+	collected = moduletest.CollectEvents(ingester.OutputChannel(), 1)
+	var gzipBody bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipBody)
+	_, err := gzipWriter.Write([]byte("{\"metadata\": {\"name\": \"checkout\"}}\n{\"quantity\": \"many\"}\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, gzipWriter.Close())
+	request = httptest.NewRequest(http.MethodPost, eventsPath, &gzipBody)
+	request.Header.Set("Content-Type", ndjsonContentType)
+	request.Header.Set("Content-Encoding", "gzip")
+	request.Header.Set("Authorization", "Bearer web-token")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	response := decodeResponse(t, recorder)
+	assert.Equal(t, 1, response.Accepted)
+	assert.Equal(t, 1, response.Rejected)
+	assert.Contains(t, response.Error, "invalid event 1")
+	assert.Len(t, <-collected, 1)
+	assert.Equal(t, []interface{}{`{"quantity": "many"}`}, deadLetterSink.Payloads)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(ingester.requestsTotal.WithLabelValues("web", "200")))
+	assert.Equal(t, 3.0, testutil.ToFloat64(ingester.acceptedEventsTotal.WithLabelValues("web")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(ingester.rejectedEventsTotal.WithLabelValues("web", reasonInvalidEvent)))
+}
+
+func TestHTTPIngester_eventsHandlerRefusedRequests(t *testing.T) {
+	ingester := newTestIngester(t, "")
+	handler := ingester.eventsHandler(context.Background())
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		expCode     int
+	}{
+		{name: "invalid method", method: http.MethodGet, contentType: jsonContentType, expCode: http.StatusMethodNotAllowed},
+		{name: "unsupported content type", method: http.MethodPost, contentType: "application/x-www-form-urlencoded", body: `{}`, expCode: http.StatusUnsupportedMediaType},
+		{name: "invalid JSON", method: http.MethodPost, contentType: jsonContentType, body: `{`, expCode: http.StatusBadRequest},
+		{name: "too large body", method: http.MethodPost, contentType: jsonContentType, body: strings.Repeat(" ", 2048), expCode: http.StatusRequestEntityTooLarge},
+		{name: "saturated pipeline", method: http.MethodPost, contentType: jsonContentType, body: `{"metadata": {"name": "checkout"}}`, expCode: http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, eventsPath, strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", tt.contentType)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, tt.expCode, recorder.Code)
+			assert.NotEmpty(t, decodeResponse(t, recorder).Error)
+		})
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(ingester.requestsTotal.WithLabelValues(anonymousClient, "429")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(ingester.acceptedEventsTotal.WithLabelValues(anonymousClient)))
+}
+
+func TestHTTPIngester_eventsHandlerUnauthenticated(t *testing.T) {
+	ingester := newTestIngester(t, "", ClientConfig{Name: "web", Token: stringPointer("web-token")})
+	request := httptest.NewRequest(http.MethodPost, eventsPath, strings.NewReader(`{}`))
+	request.Header.Set("Content-Type", jsonContentType)
+	request.Header.Set("Authorization", "Bearer foo")
+	recorder := httptest.NewRecorder()
+	ingester.eventsHandler(context.Background()).ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, 1.0, testutil.ToFloat64(ingester.requestsTotal.WithLabelValues(unauthenticatedClient, "401")))
+}
+
+func TestHTTPIngester_eventsHandlerShuttingDown(t *testing.T) {
+	ingester := newTestIngester(t, "")
+	ingester.saturationTimeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := ingester.eventsHandler(ctx)
+	body := `[{"metadata": {"name": "checkout"}}, {"metadata": {"name": "cart"}}, {"metadata": {"name": "order"}}]`
+
+	// Rest of the events is rejected if some of them were already accepted.
+	go func() {
+		<-ingester.OutputChannel()
+		cancel()
+	}()
+	request := httptest.NewRequest(http.MethodPost, eventsPath, strings.NewReader(body))
+	request.Header.Set("Content-Type", jsonContentType)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, ingestResponse{Accepted: 1, Rejected: 2, Error: "httpIngester is shutting down"}, decodeResponse(t, recorder))
+	assert.Equal(t, 2.0, testutil.ToFloat64(ingester.rejectedEventsTotal.WithLabelValues(anonymousClient, reasonShuttingDown)))
+
+	// Whole request can be retried if none of the events was accepted.
+	request = httptest.NewRequest(http.MethodPost, eventsPath, strings.NewReader(body))
+	request.Header.Set("Content-Type", jsonContentType)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
+
+func TestHTTPIngester_Run(t *testing.T) {
+	address := moduletest.FreeAddress(t)
+	ingester := newTestIngester(t, address)
+	stop := moduletest.Run(t, ingester)
+
+	collected := moduletest.CollectEvents(ingester.OutputChannel(), 1)
+	assert.Eventually(t, func() bool {
+		response, err := http.Post("http://"+address+eventsPath, jsonContentType, strings.NewReader(`{"metadata": {"name": "checkout"}}`))
+		if err != nil {
+			return false
+		}
+		response.Body.Close()
+		return response.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, stringmap.StringMap{"name": "checkout"}, (<-collected)[0].Metadata)
+
+	stop()
+}
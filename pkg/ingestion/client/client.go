@@ -0,0 +1,115 @@
+// Package client implements the Go client of the slo-exporter event ingestion API served by the grpcIngester module.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/seznam/slo-exporter/pkg/event"
+	ingestionv1 "github.com/seznam/slo-exporter/pkg/ingestion/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// RejectedEventsError is returned if some of the pushed events were rejected by the server.
+type RejectedEventsError struct {
+	// Accepted is number of the events passed to the pipeline.
+	Accepted int64
+	// Rejected is number of the events which were not passed to the pipeline.
+	Rejected int64
+	// Message describes why the events were rejected.
+	Message string
+}
+
+func (e *RejectedEventsError) Error() string {
+	return fmt.Sprintf("%d of %d events rejected: %s", e.Rejected, e.Accepted+e.Rejected, e.Message)
+}
+
+func checkResponse(response *ingestionv1.PushResponse) error {
+	if response.GetRejectedEvents() > 0 {
+		return &RejectedEventsError{Accepted: response.GetAcceptedEvents(), Rejected: response.GetRejectedEvents(), Message: response.GetErrorMessage()}
+	}
+	return nil
+}
+
+func newPushRequest(events []*event.Raw) *ingestionv1.PushRequest {
+	request := &ingestionv1.PushRequest{Events: make([]*ingestionv1.Event, 0, len(events))}
+	for _, e := range events {
+		request.Events = append(request.Events, ingestionv1.NewEvent(e))
+	}
+	return request
+}
+
+// Client pushes the events to the grpcIngester module, it is safe for concurrent use.
+type Client struct {
+	connection *grpc.ClientConn
+	service    ingestionv1.EventIngestionServiceClient
+}
+
+// New returns Client of the target, e.g. `slo-exporter:18091`.
+// The connection is not encrypted unless the transport credentials are passed in the options.
+func New(target string, options ...grpc.DialOption) (*Client, error) {
+	options = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, options...)
+	connection, err := grpc.NewClient(target, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client of %s: %w", target, err)
+	}
+	return &Client{connection: connection, service: ingestionv1.NewEventIngestionServiceClient(connection)}, nil
+}
+
+// Push pushes the events in a single request, returns RejectedEventsError if some of them were rejected.
+func (c *Client) Push(ctx context.Context, events ...*event.Raw) error {
+	response, err := c.service.Push(ctx, newPushRequest(events))
+	if err != nil {
+		return err
+	}
+	return checkResponse(response)
+}
+
+// NewStream opens stream to push the events, it is closed once the ctx is cancelled or the stream is closed.
+func (c *Client) NewStream(ctx context.Context) (*Stream, error) {
+	stream, err := c.service.PushStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream{stream: stream}, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.connection.Close()
+}
+
+// Stream pushes the events over a single stream, it is not safe for concurrent use.
+type Stream struct {
+	stream ingestionv1.EventIngestionService_PushStreamClient
+}
+
+// Push pushes the events and waits for the response, returns RejectedEventsError if some of them were rejected.
+func (s *Stream) Push(events ...*event.Raw) error {
+	if err := s.stream.Send(newPushRequest(events)); err != nil {
+		if errors.Is(err, io.EOF) {
+			// The actual error is returned by the Recv once the stream is closed by the server.
+			_, err = s.stream.Recv()
+		}
+		return err
+	}
+	response, err := s.stream.Recv()
+	if err != nil {
+		return err
+	}
+	return checkResponse(response)
+}
+
+// Close closes the stream and waits for the server to finish it.
+func (s *Stream) Close() error {
+	if err := s.stream.CloseSend(); err != nil {
+		return err
+	}
+	if _, err := s.stream.Recv(); !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/event"
+	ingestionv1 "github.com/seznam/slo-exporter/pkg/ingestion/v1"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testService accepts the events with metadata and rejects the rest.
+type testService struct {
+	ingestionv1.UnimplementedEventIngestionServiceServer
+	events chan *ingestionv1.Event
+}
+
+func (s *testService) Push(_ context.Context, request *ingestionv1.PushRequest) (*ingestionv1.PushResponse, error) {
+	response := &ingestionv1.PushResponse{}
+	for _, e := range request.GetEvents() {
+		if len(e.GetMetadata()) == 0 {
+			response.RejectedEvents++
+			response.ErrorMessage = "missing metadata"
+			continue
+		}
+		response.AcceptedEvents++
+		s.events <- e
+	}
+	return response, nil
+}
+
+func (s *testService) PushStream(stream ingestionv1.EventIngestionService_PushStreamServer) error {
+	for {
+		request, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(request.GetEvents()) == 0 {
+			return status.Error(codes.InvalidArgument, "empty request")
+		}
+		response, _ := s.Push(stream.Context(), request)
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+}
+
+func newTestClient(t *testing.T) (*Client, *testService) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	service := &testService{events: make(chan *ingestionv1.Event, 10)}
+	server := grpc.NewServer()
+	ingestionv1.RegisterEventIngestionServiceServer(server, service)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+	ingestionClient, err := New(listener.Addr().String())
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, ingestionClient.Close())
+	})
+	return ingestionClient, service
+}
+
+func TestClient_Push(t *testing.T) {
+	ingestionClient, service := newTestClient(t)
+	checkout := &event.Raw{Metadata: stringmap.StringMap{"name": "checkout"}, Quantity: 2, Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+
+	assert.NoError(t, ingestionClient.Push(context.Background(), checkout))
+	assert.Equal(t, checkout, (<-service.events).ToRaw(time.Time{}))
+
+	err := ingestionClient.Push(context.Background(), checkout, &event.Raw{Quantity: 1})
+	assert.Equal(t, &RejectedEventsError{Accepted: 1, Rejected: 1, Message: "missing metadata"}, err)
+	assert.EqualError(t, err, "1 of 2 events rejected: missing metadata")
+}
+
+func TestStream_Push(t *testing.T) {
+	ingestionClient, service := newTestClient(t)
+	checkout := &event.Raw{Metadata: stringmap.StringMap{"name": "checkout"}, Quantity: 1}
+
+	stream, err := ingestionClient.NewStream(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Push(checkout))
+	assert.NoError(t, stream.Push(checkout, checkout))
+	assert.Len(t, service.events, 3)
+	assert.NoError(t, stream.Close())
+
+	// Error of the stream closed by the server is returned.
+	stream, err = ingestionClient.NewStream(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(stream.Push()))
+}
@@ -0,0 +1,68 @@
+// Package ingestionv1 contains the protobuf schema of the slo-exporter event ingestion API served by the grpcIngester module.
+// The Go code is generated from ingestion.proto using `make generate-proto`.
+package ingestionv1
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/event"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// NewEvent returns Event of the raw event, zero timestamp of the raw event is not set.
+func NewEvent(raw *event.Raw) *Event {
+	quantity := raw.Quantity
+	newEvent := &Event{Metadata: raw.Metadata, Quantity: &quantity}
+	if raw.SloClassification != nil {
+		newEvent.SloClassification = &SloClassification{
+			Domain: raw.SloClassification.Domain,
+			App:    raw.SloClassification.App,
+			Class:  raw.SloClassification.Class,
+		}
+	}
+	if !raw.Timestamp.IsZero() {
+		newEvent.Timestamp = timestamppb.New(raw.Timestamp)
+	}
+	return newEvent
+}
+
+// Validate checks that the quantity and timestamp of the event are valid.
+func (x *Event) Validate() error {
+	if x.Quantity != nil && (*x.Quantity < 0 || math.IsNaN(*x.Quantity) || math.IsInf(*x.Quantity, 0)) {
+		return fmt.Errorf("quantity must be a finite non-negative number, got %v", *x.Quantity)
+	}
+	if x.Timestamp != nil {
+		if err := x.Timestamp.CheckValid(); err != nil {
+			return fmt.Errorf("invalid timestamp: %w", err)
+		}
+	}
+	return nil
+}
+
+// ToRaw returns the raw event, the defaultTimestamp is used if the timestamp of the event is not set.
+func (x *Event) ToRaw(defaultTimestamp time.Time) *event.Raw {
+	quantity := 1.0
+	if x.Quantity != nil {
+		quantity = *x.Quantity
+	}
+	var classification *event.SloClassification
+	if x.SloClassification != nil {
+		classification = &event.SloClassification{
+			Domain: x.SloClassification.Domain,
+			App:    x.SloClassification.App,
+			Class:  x.SloClassification.Class,
+		}
+	}
+	timestamp := defaultTimestamp
+	if x.Timestamp != nil {
+		timestamp = x.Timestamp.AsTime()
+	}
+	return &event.Raw{
+		Metadata:          x.Metadata,
+		Quantity:          quantity,
+		SloClassification: classification,
+		Timestamp:         timestamp,
+	}
+}
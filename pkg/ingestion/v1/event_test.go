@@ -0,0 +1,72 @@
+package ingestionv1
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func float64Pointer(value float64) *float64 {
+	return &value
+}
+
+func TestEvent_ToRaw(t *testing.T) {
+	receivedAt := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	timestamp := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		event    *Event
+		expEvent *event.Raw
+	}{
+		{
+			name:     "defaults",
+			event:    &Event{Metadata: map[string]string{"name": "checkout"}},
+			expEvent: &event.Raw{Metadata: stringmap.StringMap{"name": "checkout"}, Quantity: 1, Timestamp: receivedAt},
+		},
+		{
+			name: "all fields",
+			event: &Event{
+				Metadata:          map[string]string{"name": "checkout"},
+				SloClassification: &SloClassification{Domain: "shop", App: "frontend", Class: "critical"},
+				Quantity:          float64Pointer(0),
+				Timestamp:         timestamppb.New(timestamp),
+			},
+			expEvent: &event.Raw{
+				Metadata:          stringmap.StringMap{"name": "checkout"},
+				SloClassification: &event.SloClassification{Domain: "shop", App: "frontend", Class: "critical"},
+				Quantity:          0,
+				Timestamp:         timestamp,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expEvent, tt.event.ToRaw(receivedAt))
+		})
+	}
+}
+
+func TestNewEvent(t *testing.T) {
+	raw := &event.Raw{
+		Metadata:          stringmap.StringMap{"name": "checkout"},
+		SloClassification: &event.SloClassification{Domain: "shop", App: "frontend", Class: "critical"},
+		Quantity:          2,
+		Timestamp:         time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+	assert.Equal(t, raw, NewEvent(raw).ToRaw(time.Now()))
+	assert.Nil(t, NewEvent(&event.Raw{Quantity: 1}).GetTimestamp(), "zero timestamp must not be set")
+}
+
+func TestEvent_Validate(t *testing.T) {
+	assert.NoError(t, (&Event{}).Validate())
+	assert.NoError(t, (&Event{Quantity: float64Pointer(0), Timestamp: timestamppb.Now()}).Validate())
+	assert.Error(t, (&Event{Quantity: float64Pointer(-1)}).Validate())
+	assert.Error(t, (&Event{Quantity: float64Pointer(math.NaN())}).Validate())
+	assert.Error(t, (&Event{Quantity: float64Pointer(math.Inf(1))}).Validate())
+	assert.Error(t, (&Event{Timestamp: &timestamppb.Timestamp{Nanos: -1}}).Validate())
+}
@@ -0,0 +1,162 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: pkg/ingestion/v1/ingestion.proto
+
+package ingestionv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	EventIngestionService_Push_FullMethodName       = "/seznam.slo_exporter.ingestion.v1.EventIngestionService/Push"
+	EventIngestionService_PushStream_FullMethodName = "/seznam.slo_exporter.ingestion.v1.EventIngestionService/PushStream"
+)
+
+// EventIngestionServiceClient is the client API for EventIngestionService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// EventIngestionService receives the events pushed by the applications to the grpcIngester module.
+type EventIngestionServiceClient interface {
+	// Push passes the events of the request to the pipeline.
+	Push(ctx context.Context, in *PushRequest, opts ...grpc.CallOption) (*PushResponse, error)
+	// PushStream passes the events of every request of the stream to the pipeline and answers it with a response.
+	PushStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[PushRequest, PushResponse], error)
+}
+
+type eventIngestionServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEventIngestionServiceClient(cc grpc.ClientConnInterface) EventIngestionServiceClient {
+	return &eventIngestionServiceClient{cc}
+}
+
+func (c *eventIngestionServiceClient) Push(ctx context.Context, in *PushRequest, opts ...grpc.CallOption) (*PushResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PushResponse)
+	err := c.cc.Invoke(ctx, EventIngestionService_Push_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventIngestionServiceClient) PushStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[PushRequest, PushResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &EventIngestionService_ServiceDesc.Streams[0], EventIngestionService_PushStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PushRequest, PushResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EventIngestionService_PushStreamClient = grpc.BidiStreamingClient[PushRequest, PushResponse]
+
+// EventIngestionServiceServer is the server API for EventIngestionService service.
+// All implementations must embed UnimplementedEventIngestionServiceServer
+// for forward compatibility.
+//
+// EventIngestionService receives the events pushed by the applications to the grpcIngester module.
+type EventIngestionServiceServer interface {
+	// Push passes the events of the request to the pipeline.
+	Push(context.Context, *PushRequest) (*PushResponse, error)
+	// PushStream passes the events of every request of the stream to the pipeline and answers it with a response.
+	PushStream(grpc.BidiStreamingServer[PushRequest, PushResponse]) error
+	mustEmbedUnimplementedEventIngestionServiceServer()
+}
+
+// UnimplementedEventIngestionServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedEventIngestionServiceServer struct{}
+
+func (UnimplementedEventIngestionServiceServer) Push(context.Context, *PushRequest) (*PushResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Push not implemented")
+}
+func (UnimplementedEventIngestionServiceServer) PushStream(grpc.BidiStreamingServer[PushRequest, PushResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method PushStream not implemented")
+}
+func (UnimplementedEventIngestionServiceServer) mustEmbedUnimplementedEventIngestionServiceServer() {}
+func (UnimplementedEventIngestionServiceServer) testEmbeddedByValue()                               {}
+
+// UnsafeEventIngestionServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EventIngestionServiceServer will
+// result in compilation errors.
+type UnsafeEventIngestionServiceServer interface {
+	mustEmbedUnimplementedEventIngestionServiceServer()
+}
+
+func RegisterEventIngestionServiceServer(s grpc.ServiceRegistrar, srv EventIngestionServiceServer) {
+	// If the following call pancis, it indicates UnimplementedEventIngestionServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&EventIngestionService_ServiceDesc, srv)
+}
+
+func _EventIngestionService_Push_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PushRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventIngestionServiceServer).Push(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventIngestionService_Push_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventIngestionServiceServer).Push(ctx, req.(*PushRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventIngestionService_PushStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(EventIngestionServiceServer).PushStream(&grpc.GenericServerStream[PushRequest, PushResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EventIngestionService_PushStreamServer = grpc.BidiStreamingServer[PushRequest, PushResponse]
+
+// EventIngestionService_ServiceDesc is the grpc.ServiceDesc for EventIngestionService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var EventIngestionService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "seznam.slo_exporter.ingestion.v1.EventIngestionService",
+	HandlerType: (*EventIngestionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Push",
+			Handler:    _EventIngestionService_Push_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "PushStream",
+			Handler:       _EventIngestionService_PushStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "pkg/ingestion/v1/ingestion.proto",
+}
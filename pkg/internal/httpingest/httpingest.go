@@ -0,0 +1,80 @@
+// Package httpingest provides the HTTP server and request body reading shared by the modules ingesting data over HTTP.
+package httpingest
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ReadBody reads the request body which can be gzip encoded, maxBytes limits its size, decompressed size for the gzip encoded body.
+// Returns *http.MaxBytesError if the body is larger.
+func ReadBody(r *http.Request, maxBytes int64) ([]byte, error) {
+	var reader io.Reader = http.MaxBytesReader(nil, r.Body, maxBytes)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip encoded body: %w", err)
+		}
+		defer gzipReader.Close()
+		reader = &io.LimitedReader{R: gzipReader, N: maxBytes + 1}
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, &http.MaxBytesError{Limit: maxBytes}
+	}
+	return body, nil
+}
+
+// Server serves the HTTP requests of the ingesting module.
+type Server struct {
+	listener                net.Listener
+	server                  *http.Server
+	gracefulShutdownTimeout time.Duration
+}
+
+// Listen starts listening on the address, the requests are handled by the handler once the Server is run.
+func Listen(address string, handler http.Handler, gracefulShutdownTimeout time.Duration) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		listener:                listener,
+		server:                  &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second},
+		gracefulShutdownTimeout: gracefulShutdownTimeout,
+	}, nil
+}
+
+// Addr returns the address the Server listens on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Run serves the requests until the context is cancelled, then the Server is shut down gracefully.
+// Returns error only if the serving fails before the context is cancelled.
+func (s *Server) Run(ctx context.Context) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.server.Serve(s.listener)
+	}()
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	// Pending requests are not able to emit the events anymore, so they finish immediately.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.gracefulShutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(shutdownCtx); err != nil {
+		s.server.Close()
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package httpingest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func gzipEncode(t *testing.T, data []byte) []byte {
+	var encoded bytes.Buffer
+	writer := gzip.NewWriter(&encoded)
+	_, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return encoded.Bytes()
+}
+
+func TestReadBody(t *testing.T) {
+	testCases := []struct {
+		name            string
+		body            []byte
+		contentEncoding string
+		expBody         []byte
+		expErr          bool
+		expTooLarge     bool
+	}{
+		{name: "plain", body: []byte("0123456789"), expBody: []byte("0123456789")},
+		{name: "gzip", body: gzipEncode(t, []byte("0123456789")), contentEncoding: "gzip", expBody: []byte("0123456789")},
+		{name: "invalid gzip", body: []byte("0123456789"), contentEncoding: "gzip", expErr: true},
+		{name: "plain too large", body: bytes.Repeat([]byte("a"), 101), expErr: true, expTooLarge: true},
+		{name: "decompressed gzip too large", body: gzipEncode(t, bytes.Repeat([]byte("a"), 1024)), contentEncoding: "gzip", expErr: true, expTooLarge: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tc.body))
+			if tc.contentEncoding != "" {
+				request.Header.Set("Content-Encoding", tc.contentEncoding)
+			}
+			body, err := ReadBody(request, 100)
+			if tc.expErr {
+				assert.Error(t, err)
+				var maxBytesErr *http.MaxBytesError
+				assert.Equal(t, tc.expTooLarge, errors.As(err, &maxBytesErr))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expBody, body)
+		})
+	}
+}
+
+func TestServer_Run(t *testing.T) {
+	requestStarted := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(requestStarted)
+		<-r.Context().Done()
+	})
+	server, err := Listen("127.0.0.1:0", handler, 100*time.Millisecond)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error)
+	go func() {
+		runErr <- server.Run(ctx)
+	}()
+	go func() {
+		response, err := http.Get("http://" + server.Addr().String())
+		if err == nil {
+			response.Body.Close()
+		}
+	}()
+	<-requestStarted
+	cancel()
+	select {
+	case err := <-runErr:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the pending request did not finish within the graceful shutdown timeout")
+	}
+}
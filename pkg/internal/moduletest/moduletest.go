@@ -0,0 +1,60 @@
+// Package moduletest provides helpers shared by the tests of the pipeline modules.
+package moduletest
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/pipeline"
+	"github.com/stretchr/testify/assert"
+)
+
+// CollectEvents reads the given number of events from the channel and sends them at once to the returned channel.
+func CollectEvents(ch <-chan *event.Raw, count int) <-chan []*event.Raw {
+	collected := make(chan []*event.Raw)
+	go func() {
+		var events []*event.Raw
+		for i := 0; i < count; i++ {
+			events = append(events, <-ch)
+		}
+		collected <- events
+	}()
+	return collected
+}
+
+// FreeAddress returns a local TCP address which is free to listen on.
+func FreeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// Run runs the module in the background until the returned stop function is called.
+// The stop function checks that the module finished without error and closed its output channel.
+func Run(t *testing.T, module pipeline.RawEventProducerModule) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error)
+	go func() {
+		runErr <- module.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		assert.NoError(t, <-runErr)
+		_, ok := <-module.OutputChannel()
+		assert.False(t, ok, "output channel must be closed")
+	}
+}
+
+// DeadLetterSink records the payloads and reasons of the rejected events.
+type DeadLetterSink struct {
+	Payloads []interface{}
+	Reasons  []string
+}
+
+func (s *DeadLetterSink) Reject(payload interface{}, reason string) {
+	s.Payloads = append(s.Payloads, payload)
+	s.Reasons = append(s.Reasons, reason)
+}
@@ -0,0 +1,34 @@
+// Package shutdown provides handling of the ingestion requests interrupted by shutdown of the module.
+package shutdown
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error reports that the module is shutting down, it is converted to the Unavailable status if returned by the gRPC handlers.
+type Error struct {
+	Module fmt.Stringer
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s is shutting down", e.Module)
+}
+
+// GRPCStatus returns the Unavailable status so the gRPC clients retry the request.
+func (e *Error) GRPCStatus() *status.Status {
+	return status.New(codes.Unavailable, e.Error())
+}
+
+// RejectRemaining returns number of the rejected events of the request interrupted by shutdown of the module after the accepted events.
+// The total is number of the events of the request which were not rejected for other reasons.
+// Client can retry the whole request if nothing was accepted yet, so the Error is returned to refuse the request.
+// Otherwise the rest of the events is rejected so the already accepted ones are not duplicated by the retry.
+func RejectRemaining(module fmt.Stringer, accepted, total int) (int, error) {
+	if accepted == 0 {
+		return 0, &Error{Module: module}
+	}
+	return total - accepted, nil
+}
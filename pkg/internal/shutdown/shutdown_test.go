@@ -0,0 +1,40 @@
+package shutdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testModule struct{}
+
+func (testModule) String() string {
+	return "testModule"
+}
+
+func TestRejectRemaining(t *testing.T) {
+	testCases := []struct {
+		name        string
+		accepted    int
+		total       int
+		expRejected int
+		expErr      bool
+	}{
+		{name: "nothing accepted", accepted: 0, total: 3, expRejected: 0, expErr: true},
+		{name: "partially accepted", accepted: 1, total: 3, expRejected: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rejected, err := RejectRemaining(testModule{}, tc.accepted, tc.total)
+			assert.Equal(t, tc.expRejected, rejected)
+			if tc.expErr {
+				assert.EqualError(t, err, "testModule is shutting down")
+				assert.Equal(t, codes.Unavailable, status.Code(err))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
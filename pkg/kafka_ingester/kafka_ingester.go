@@ -28,27 +28,6 @@ const (
 )
 
 var (
-	kafkaConnectionInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "kafka_connection_info",
-		Help: "Metadata metric with information about Kafka connection",
-	}, []string{"brokers", "group_id", "topic"})
-	messagesReadTotal = prometheus.NewCounter(prometheus.CounterOpts{
-		Name: "kafka_messages_read_total",
-		Help: "Total number of messages read from Kafka.",
-	})
-	malformedMessagesTotal = prometheus.NewCounter(
-		prometheus.CounterOpts{
-			Name: "malformed_messages_total",
-			Help: "Total number of invalid Kafka messages that failed to parse.",
-		},
-	)
-	kafkaReadErrorsTotal = prometheus.NewCounter(
-		prometheus.CounterOpts{
-			Name: "kafka_read_errors_total",
-			Help: "Total number of errors encountered when reading messages from Kafka.",
-		},
-	)
-
 	fallbackStartOffsetMapping = map[string]int64{
 		"LastOffset":  kafka.LastOffset,
 		"FirstOffset": kafka.FirstOffset,
@@ -59,6 +38,37 @@ type IngestedEventV1 struct {
 	Metadata          stringmap.StringMap            `json:"metadata"`
 	SloClassification *IngestedEventV1Classification `json:"slo_classification"`
 	Quantity          *float64                       `json:"quantity"`
+	// Timestamp is time when the event occurred, time of the Kafka message is used if not specified.
+	Timestamp *time.Time `json:"timestamp"`
+}
+
+// ToRaw returns the raw event, the defaultTimestamp is used if the timestamp of the ingested event is not specified.
+func (e IngestedEventV1) ToRaw(defaultTimestamp time.Time) *event.Raw {
+	var quantity float64
+	if e.Quantity == nil {
+		// Quantity not specified in the ingested data, default to 1
+		quantity = 1
+	} else {
+		quantity = *e.Quantity
+	}
+	var classification *event.SloClassification
+	if e.SloClassification != nil {
+		classification = &event.SloClassification{
+			Domain: e.SloClassification.Domain,
+			App:    e.SloClassification.App,
+			Class:  e.SloClassification.Class,
+		}
+	}
+	timestamp := defaultTimestamp
+	if e.Timestamp != nil {
+		timestamp = *e.Timestamp
+	}
+	return &event.Raw{
+		Metadata:          e.Metadata,
+		Quantity:          quantity,
+		SloClassification: classification,
+		Timestamp:         timestamp,
+	}
 }
 
 type IngestedEventV1Classification struct {
@@ -67,7 +77,8 @@ type IngestedEventV1Classification struct {
 	Class  string `json:"class"`
 }
 
-type kafkaIngesterConfig struct {
+// KafkaIngesterConfig is configuration of the Kafka ingester module.
+type KafkaIngesterConfig struct {
 	LogKafkaEvents      bool
 	LogKafkaErrors      bool
 	Brokers             []string
@@ -80,12 +91,16 @@ type kafkaIngesterConfig struct {
 }
 
 type KafkaIngester struct {
-	kafkaReader     *kafka.Reader
-	observer        pipeline.EventProcessingDurationObserver
-	outputChannel   chan *event.Raw
-	shutdownChannel chan struct{}
-	logger          logrus.FieldLogger
-	done            bool
+	kafkaReader    *kafka.Reader
+	observer       pipeline.EventProcessingDurationObserver
+	deadLetterSink pipeline.DeadLetterSink
+	outputChannel  chan *event.Raw
+	logger         logrus.FieldLogger
+
+	kafkaConnectionInfo    *prometheus.GaugeVec
+	messagesReadTotal      prometheus.Counter
+	malformedMessagesTotal prometheus.Counter
+	kafkaReadErrorsTotal   prometheus.Counter
 }
 
 func (k *KafkaIngester) String() string {
@@ -93,7 +108,7 @@ func (k *KafkaIngester) String() string {
 }
 
 func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*KafkaIngester, error) {
-	var config kafkaIngesterConfig
+	var config KafkaIngesterConfig
 	viperConfig.SetDefault("logKafkaErrors", true)
 	viperConfig.SetDefault("commitInterval", 0)
 	viperConfig.SetDefault("retentionTime", 24*time.Hour)
@@ -108,7 +123,7 @@ func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*KafkaIn
 }
 
 // New returns an instance of KafkaIngester.
-func New(config kafkaIngesterConfig, logger logrus.FieldLogger) (*KafkaIngester, error) {
+func New(config KafkaIngesterConfig, logger logrus.FieldLogger) (*KafkaIngester, error) {
 	var kafkaLogger, kafkaErrorLogger logrus.FieldLogger
 	if config.LogKafkaEvents {
 		kafkaLogger = logger
@@ -129,19 +144,33 @@ func New(config kafkaIngesterConfig, logger logrus.FieldLogger) (*KafkaIngester,
 			ErrorLogger:    kafkaErrorLogger,
 		})
 
-	kafkaConnectionInfo.With(prometheus.Labels{
+	ingester := KafkaIngester{
+		outputChannel: make(chan *event.Raw),
+		logger:        logger,
+		kafkaReader:   reader,
+		kafkaConnectionInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kafka_connection_info",
+			Help: "Metadata metric with information about Kafka connection",
+		}, []string{"brokers", "group_id", "topic"}),
+		messagesReadTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "kafka_messages_read_total",
+			Help: "Total number of messages read from Kafka.",
+		}),
+		malformedMessagesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "malformed_messages_total",
+			Help: "Total number of invalid Kafka messages that failed to parse.",
+		}),
+		kafkaReadErrorsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "kafka_read_errors_total",
+			Help: "Total number of errors encountered when reading messages from Kafka.",
+		}),
+	}
+	ingester.kafkaConnectionInfo.With(prometheus.Labels{
 		"brokers":  strings.Join(config.Brokers, ","),
 		"group_id": config.GroupID,
 		"topic":    config.Topic,
 	}).Set(1)
-
-	return &KafkaIngester{
-		outputChannel:   make(chan *event.Raw),
-		shutdownChannel: make(chan struct{}),
-		done:            false,
-		logger:          logger,
-		kafkaReader:     reader,
-	}, nil
+	return &ingester, nil
 }
 
 func (k *KafkaIngester) RegisterEventProcessingDurationObserver(observer pipeline.EventProcessingDurationObserver) {
@@ -154,8 +183,18 @@ func (k *KafkaIngester) observeDuration(start time.Time) {
 	}
 }
 
+func (k *KafkaIngester) SetDeadLetterSink(sink pipeline.DeadLetterSink) {
+	k.deadLetterSink = sink
+}
+
+func (k *KafkaIngester) reject(m kafka.Message, err error) {
+	if k.deadLetterSink != nil {
+		k.deadLetterSink.Reject(string(m.Value), err.Error())
+	}
+}
+
 func (k *KafkaIngester) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
-	toRegister := []prometheus.Collector{kafkaConnectionInfo, messagesReadTotal, malformedMessagesTotal}
+	toRegister := []prometheus.Collector{k.kafkaConnectionInfo, k.messagesReadTotal, k.malformedMessagesTotal}
 	for _, collector := range toRegister {
 		if err := wrappedRegistry.Register(collector); err != nil {
 			return fmt.Errorf("error registering metric %s: %w", collector, err)
@@ -164,54 +203,50 @@ func (k *KafkaIngester) RegisterMetrics(_, wrappedRegistry prometheus.Registerer
 	return nil
 }
 
-func (k *KafkaIngester) Done() bool {
-	return k.done
-}
-
 func (k *KafkaIngester) OutputChannel() chan *event.Raw {
 	return k.outputChannel
 }
 
-// Run starts to tail the associated file, feeding events to output channel.
-func (k *KafkaIngester) Run() {
-	ctx, cancel := context.WithCancel(context.Background())
+// CheckHealth reports the ingester as unhealthy if the Kafka reader encountered errors and read no messages since the last check.
+func (k *KafkaIngester) CheckHealth() error {
+	stats := k.kafkaReader.Stats()
+	if stats.Errors > 0 && stats.Messages == 0 {
+		return fmt.Errorf("failed to read messages from Kafka, %d errors since the last check", stats.Errors)
+	}
+	return nil
+}
 
-	// Goroutine handling shutdown signal
-	go func() {
-		defer func() {
-			close(k.outputChannel)
-			k.done = true
-		}()
-		<-k.shutdownChannel
-		cancel()
-		k.kafkaReader.Close()
+// Run reads the Kafka messages feeding events to output channel until the context is cancelled.
+func (k *KafkaIngester) Run(ctx context.Context) error {
+	defer func() {
+		if err := k.kafkaReader.Close(); err != nil {
+			k.logger.Errorf("failed to close Kafka reader: %v", err)
+		}
+		close(k.outputChannel)
 	}()
-
-	// Main goroutine for reading messages from Kafka
-	go func() {
-		for {
-			m, err := k.kafkaReader.ReadMessage(ctx)
-			start := time.Now()
-			if err != nil {
-				if errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) {
-					return
-				}
-				k.logger.Errorf("error while reading message from Kafka: %w", err)
-				kafkaReadErrorsTotal.Inc()
-				continue
+	for {
+		m, err := k.kafkaReader.ReadMessage(ctx)
+		start := time.Now()
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) {
+				return nil
 			}
-			k.logger.Debug(m)
-			messagesReadTotal.Inc()
-			e, err := processMessage(m)
-			if err != nil {
-				k.logger.Errorf("Error while parsing the message: %w", err)
-				malformedMessagesTotal.Inc()
-			} else {
-				k.outputChannel <- e
-			}
-			k.observeDuration(start)
+			k.logger.Errorf("error while reading message from Kafka: %v", err)
+			k.kafkaReadErrorsTotal.Inc()
+			continue
 		}
-	}()
+		k.logger.Debug(m)
+		k.messagesReadTotal.Inc()
+		e, err := processMessage(m)
+		if err != nil {
+			k.logger.Errorf("Error while parsing the message: %v", err)
+			k.malformedMessagesTotal.Inc()
+			k.reject(m, err)
+		} else {
+			k.outputChannel <- e
+		}
+		k.observeDuration(start)
+	}
 }
 
 func getSchemaVersionFromHeaders(headers []kafka.Header) (string, bool) {
@@ -242,31 +277,5 @@ func processEventV1(m kafka.Message) (*event.Raw, error) {
 	if err != nil {
 		return nil, err
 	}
-	var quantity float64
-	if ingestedEvent.Quantity == nil {
-		// Quantity not specified in the ingested data, default to 1
-		quantity = 1
-	} else {
-		quantity = *ingestedEvent.Quantity
-	}
-	var classification *event.SloClassification
-	if ingestedEvent.SloClassification != nil {
-		classification = &event.SloClassification{
-			Domain: ingestedEvent.SloClassification.Domain,
-			App:    ingestedEvent.SloClassification.App,
-			Class:  ingestedEvent.SloClassification.Class,
-		}
-	}
-	outputEvent := event.Raw{
-		Metadata:          ingestedEvent.Metadata,
-		Quantity:          quantity,
-		SloClassification: classification,
-	}
-	return &outputEvent, nil
-}
-
-func (k *KafkaIngester) Stop() {
-	if !k.done {
-		k.shutdownChannel <- struct{}{}
-	}
+	return ingestedEvent.ToRaw(m.Time), nil
 }
@@ -2,6 +2,7 @@ package kafka_ingester
 
 import (
 	"testing"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 
@@ -37,6 +38,24 @@ func Test_processMessage(t *testing.T) {
 			},
 			OutputEvent: &event.Raw{Quantity: 1, Metadata: stringmap.StringMap{"foo": "bar"}},
 		},
+		{
+			Name: "Event timestamp from the message time",
+			KafkaMessage: kafka.Message{
+				Topic: "topic",
+				Time:  time.Unix(1600000000, 0),
+				Value: []byte(`{}`),
+			},
+			OutputEvent: &event.Raw{Quantity: 1, Timestamp: time.Unix(1600000000, 0)},
+		},
+		{
+			Name: "Event timestamp overriding the message time",
+			KafkaMessage: kafka.Message{
+				Topic: "topic",
+				Time:  time.Unix(1600000000, 0),
+				Value: []byte(`{"timestamp": "2020-09-13T12:26:30Z"}`),
+			},
+			OutputEvent: &event.Raw{Quantity: 1, Timestamp: time.Date(2020, 9, 13, 12, 26, 30, 0, time.UTC)},
+		},
 		{
 			Name: "Event with classification",
 			KafkaMessage: kafka.Message{
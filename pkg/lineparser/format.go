@@ -0,0 +1,168 @@
+package lineparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-logfmt/logfmt"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+)
+
+const (
+	regexpFormat = "regexp"
+	jsonFormat   = "json"
+	logfmtFormat = "logfmt"
+)
+
+// lineParser parses the line to the fields which are used as the event metadata.
+type lineParser interface {
+	parse(line string) (stringmap.StringMap, error)
+}
+
+func newLineParser(config Config) (lineParser, error) {
+	emptyGroupRegexp, err := regexp.Compile(config.EmptyGroupRE)
+	if err != nil {
+		return nil, fmt.Errorf("error while compiling the empty group matching RE ('%s'): %w", config.EmptyGroupRE, err)
+	}
+	// Regexp is the default format.
+	if config.Format == "" {
+		config.Format = regexpFormat
+	}
+	if config.Preset != "" {
+		if config.Format != regexpFormat || config.LoglineParseRegexp != "" || config.TimestampField != "" {
+			return nil, fmt.Errorf("preset cannot be combined with the line parse RE, timestamp field or format other than %s", regexpFormat)
+		}
+		return newPresetParser(config.Preset, emptyGroupRegexp)
+	}
+	if config.Format != regexpFormat && config.LoglineParseRegexp != "" {
+		return nil, fmt.Errorf("line parse RE can be used only with the %s format", regexpFormat)
+	}
+	switch config.Format {
+	case regexpFormat:
+		lineParseRegexp, err := regexp.Compile(config.LoglineParseRegexp)
+		if err != nil {
+			return nil, fmt.Errorf("error while compiling the line parse RE ('%s'): %w", config.LoglineParseRegexp, err)
+		}
+		if config.TimestampField != "" && lineParseRegexp.SubexpIndex(config.TimestampField) < 0 {
+			return nil, fmt.Errorf("timestamp field %s is not defined in the line parse RE", config.TimestampField)
+		}
+		return &regexpParser{lineParseRegexp: lineParseRegexp, emptyGroupRegexp: emptyGroupRegexp}, nil
+	case jsonFormat:
+		return &jsonParser{keySeparator: config.JSONKeySeparator, emptyGroupRegexp: emptyGroupRegexp}, nil
+	case logfmtFormat:
+		return &logfmtParser{emptyGroupRegexp: emptyGroupRegexp}, nil
+	default:
+		return nil, fmt.Errorf("unknown line format '%s', expected one of %s, %s, %s", config.Format, regexpFormat, jsonFormat, logfmtFormat)
+	}
+}
+
+type regexpParser struct {
+	lineParseRegexp  *regexp.Regexp
+	emptyGroupRegexp *regexp.Regexp
+}
+
+func (p *regexpParser) parse(line string) (stringmap.StringMap, error) {
+	lineData, err := parseLine(p.lineParseRegexp, p.emptyGroupRegexp, line)
+	if err != nil {
+		return nil, &MalformedLineError{Reason: ReasonRegexpMismatch, Err: err}
+	}
+	return lineData, nil
+}
+
+// jsonParser parses line containing JSON object, nested objects and arrays are flattened
+// joining the keys (or indexes of the array items) using the key separator.
+type jsonParser struct {
+	keySeparator     string
+	emptyGroupRegexp *regexp.Regexp
+}
+
+func (p *jsonParser) parse(line string) (stringmap.StringMap, error) {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	// Numbers are kept as they are written in the line.
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil, &MalformedLineError{Reason: ReasonInvalidJSON, Err: fmt.Errorf("unable to parse line as JSON object: %w", err)}
+	}
+	if object == nil {
+		return nil, &MalformedLineError{Reason: ReasonInvalidJSON, Err: fmt.Errorf("line is not a JSON object")}
+	}
+	if decoder.More() {
+		return nil, &MalformedLineError{Reason: ReasonInvalidJSON, Err: fmt.Errorf("unexpected data after the JSON object")}
+	}
+	lineData := stringmap.StringMap{}
+	Flatten(lineData, "", p.keySeparator, object, p.emptyGroupRegexp.MatchString)
+	return lineData, nil
+}
+
+// Flatten adds the decoded JSON or msgpack value to the metadata under the key. Nested maps and arrays are flattened
+// joining the key and keys (or indexes of the array items) using the key separator.
+// Scalar values are converted to strings, null to empty string, and values for which the isEmpty returns true are omitted.
+func Flatten(metadata stringmap.StringMap, key, keySeparator string, value interface{}, isEmpty func(string) bool) {
+	prefix := key
+	if key != "" {
+		prefix += keySeparator
+	}
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for k, v := range typedValue {
+			Flatten(metadata, prefix+k, keySeparator, v, isEmpty)
+		}
+	case []interface{}:
+		for i, v := range typedValue {
+			Flatten(metadata, prefix+strconv.Itoa(i), keySeparator, v, isEmpty)
+		}
+	default:
+		stringValue := scalarToString(typedValue)
+		if !isEmpty(stringValue) {
+			metadata[key] = stringValue
+		}
+	}
+}
+
+// scalarToString converts the scalar value to string, null is converted to empty string.
+func scalarToString(value interface{}) string {
+	switch typedValue := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typedValue
+	case json.Number:
+		return typedValue.String()
+	case bool:
+		return strconv.FormatBool(typedValue)
+	case float64:
+		return strconv.FormatFloat(typedValue, 'f', -1, 64)
+	default:
+		return fmt.Sprint(typedValue)
+	}
+}
+
+// logfmtParser parses line in the logfmt format, keys without value have empty value.
+type logfmtParser struct {
+	emptyGroupRegexp *regexp.Regexp
+}
+
+func (p *logfmtParser) parse(line string) (stringmap.StringMap, error) {
+	decoder := logfmt.NewDecoder(bytes.NewBufferString(line))
+	lineData := stringmap.StringMap{}
+	for decoder.ScanRecord() {
+		for decoder.ScanKeyval() {
+			value := string(decoder.Value())
+			if !p.emptyGroupRegexp.MatchString(value) {
+				lineData[string(decoder.Key())] = value
+			}
+		}
+	}
+	if err := decoder.Err(); err != nil {
+		return nil, &MalformedLineError{Reason: ReasonInvalidLogfmt, Err: fmt.Errorf("unable to parse line as logfmt: %w", err)}
+	}
+	if strings.TrimSpace(line) == "" {
+		return nil, &MalformedLineError{Reason: ReasonInvalidLogfmt, Err: fmt.Errorf("line contains no key-value pairs")}
+	}
+	return lineData, nil
+}
@@ -0,0 +1,138 @@
+package lineparser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newLineParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		expErr bool
+	}{
+		{name: "default format is regexp", config: Config{LoglineParseRegexp: `^(?P<time>\S+)$`, TimestampField: "time"}},
+		{name: "timestamp field missing in regexp", config: Config{Format: regexpFormat, LoglineParseRegexp: `^(?P<time>\S+)$`, TimestampField: "ts"}, expErr: true},
+		{name: "json format", config: Config{Format: jsonFormat, TimestampField: "ts"}},
+		{name: "logfmt format", config: Config{Format: logfmtFormat}},
+		{name: "regexp with structured format", config: Config{Format: jsonFormat, LoglineParseRegexp: `^(?P<time>\S+)$`}, expErr: true},
+		{name: "unknown format", config: Config{Format: "xml"}, expErr: true},
+		{name: "invalid empty group RE", config: Config{Format: jsonFormat, EmptyGroupRE: "("}, expErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newLineParser(tt.config)
+			assert.Equal(t, tt.expErr, err != nil, err)
+		})
+	}
+}
+
+func TestLineParsers(t *testing.T) {
+	tests := []struct {
+		config    Config
+		line      string
+		expData   stringmap.StringMap
+		expReason string
+	}{
+		{
+			config:  Config{Format: regexpFormat, LoglineParseRegexp: `^(?P<method>\S+) (?P<path>\S+)$`, EmptyGroupRE: "^-$"},
+			line:    "GET -",
+			expData: stringmap.StringMap{"method": "GET"},
+		},
+		{
+			config:    Config{Format: regexpFormat, LoglineParseRegexp: `^(?P<method>\S+) (?P<path>\S+)$`, EmptyGroupRE: "^-$"},
+			line:      "GET",
+			expReason: ReasonRegexpMismatch,
+		},
+		{
+			config:  Config{Format: jsonFormat, JSONKeySeparator: ".", EmptyGroupRE: "^$"},
+			line:    `{"method": "GET", "status": 200, "duration": 0.12, "cached": false, "user": null, "request": {"headers": {"host": "example.com"}}, "tags": ["a", "b"]}`,
+			expData: stringmap.StringMap{"method": "GET", "status": "200", "duration": "0.12", "cached": "false", "request.headers.host": "example.com", "tags.0": "a", "tags.1": "b"},
+		},
+		{
+			config:  Config{Format: jsonFormat, JSONKeySeparator: "_", EmptyGroupRE: "^$"},
+			line:    `{"request": {"size": 1e3}}`,
+			expData: stringmap.StringMap{"request_size": "1e3"},
+		},
+		{
+			config:    Config{Format: jsonFormat, JSONKeySeparator: ".", EmptyGroupRE: "^$"},
+			line:      `{"method": "GET"`,
+			expReason: ReasonInvalidJSON,
+		},
+		{
+			config:    Config{Format: jsonFormat, JSONKeySeparator: ".", EmptyGroupRE: "^$"},
+			line:      `["GET"]`,
+			expReason: ReasonInvalidJSON,
+		},
+		{
+			config:    Config{Format: jsonFormat, JSONKeySeparator: ".", EmptyGroupRE: "^$"},
+			line:      `{"method": "GET"} {"method": "POST"}`,
+			expReason: ReasonInvalidJSON,
+		},
+		{
+			config:  Config{Format: logfmtFormat, EmptyGroupRE: "^-$"},
+			line:    `method=GET path="/api/v1 x" user=- cached`,
+			expData: stringmap.StringMap{"method": "GET", "path": "/api/v1 x", "cached": ""},
+		},
+		{
+			config:    Config{Format: logfmtFormat, EmptyGroupRE: "^$"},
+			line:      `method="GET`,
+			expReason: ReasonInvalidLogfmt,
+		},
+		{
+			config:    Config{Format: logfmtFormat, EmptyGroupRE: "^$"},
+			line:      "  ",
+			expReason: ReasonInvalidLogfmt,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", i, tt.config.Format), func(t *testing.T) {
+			parser, err := newLineParser(tt.config)
+			assert.NoError(t, err)
+			data, err := parser.parse(tt.line)
+			if tt.expReason != "" {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expReason, MalformedLineReason(err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expData, data)
+		})
+	}
+}
+
+func Test_selectFields(t *testing.T) {
+	lineData := stringmap.StringMap{"request.method": "GET", "status": "200", "host": "example.com"}
+	assert.Equal(t, lineData, selectFields(nil, lineData))
+	fields := []FieldConfig{{Path: "request.method", MetadataKey: "method"}, {Path: "status"}, {Path: "missing"}}
+	assert.Equal(t, stringmap.StringMap{"method": "GET", "status": "200"}, selectFields(fields, lineData))
+}
+
+func TestFlatten(t *testing.T) {
+	metadata := stringmap.StringMap{}
+	Flatten(metadata, "", "_", map[string]interface{}{
+		"method":     "GET",
+		"status":     int64(200),
+		"duration":   0.25,
+		"size":       1e21,
+		"cached":     false,
+		"empty":      "",
+		"null":       nil,
+		"kubernetes": map[string]interface{}{"pod_name": "web", "labels": map[string]interface{}{"app": "web"}},
+		"hosts":      []interface{}{"a", "b"},
+	}, func(value string) bool { return value == "" })
+	assert.Equal(t, stringmap.StringMap{
+		"method":                "GET",
+		"status":                "200",
+		"duration":              "0.25",
+		"size":                  "1000000000000000000000",
+		"cached":                "false",
+		"kubernetes_pod_name":   "web",
+		"kubernetes_labels_app": "web",
+		"hosts_0":               "a",
+		"hosts_1":               "b",
+	}, metadata)
+}
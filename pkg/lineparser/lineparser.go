@@ -0,0 +1,162 @@
+// Package lineparser parses log lines to the metadata of events, it is shared by the modules reading logs.
+package lineparser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/spf13/viper"
+)
+
+// Reasons of the malformed lines used as a label of the malformed_lines_total metric.
+const (
+	ReasonRegexpMismatch   = "regexpMismatch"
+	ReasonInvalidJSON      = "invalidJson"
+	ReasonInvalidLogfmt    = "invalidLogfmt"
+	ReasonInvalidTimestamp = "invalidTimestamp"
+)
+
+// MalformedLineError is returned for lines which cannot be processed.
+type MalformedLineError struct {
+	// Reason is short camelCase reason of the failure.
+	Reason string
+	Err    error
+}
+
+func (e *MalformedLineError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *MalformedLineError) Unwrap() error {
+	return e.Err
+}
+
+// MalformedLineReason returns reason why the line failed to be processed.
+func MalformedLineReason(err error) string {
+	var malformedErr *MalformedLineError
+	if errors.As(err, &malformedErr) {
+		return malformedErr.Reason
+	}
+	return "unknown"
+}
+
+// Config is configuration of the line parser, modules embed it to their configuration.
+type Config struct {
+	// Format of the lines, one of regexp (default), json or logfmt.
+	Format string
+	// JSONKeySeparator joins keys of the nested JSON objects and indexes of arrays when flattening them to metadata keys.
+	JSONKeySeparator string
+	// Preset is name of the predefined format of the lines of a well known server, see the presets.
+	// It sets the line parse RE and the timestamp of the events, metadata keys are the same as those of the envoyAccessLogServer.
+	Preset string
+	// Fields selects the parsed fields to be used as the event metadata, all the fields are used if empty.
+	Fields             []FieldConfig
+	LoglineParseRegexp string
+	EmptyGroupRE       string
+	// TimestampField is name of the parsed field (group of the LoglineParseRegexp or key of the structured line) containing time of the event, if empty the event has no timestamp.
+	TimestampField string
+	// TimestampFormat is format of the TimestampField, see event.ParseTimestamp.
+	TimestampFormat string
+}
+
+// SetDefaults sets the default values of the Config to the viper configuration of the module.
+func SetDefaults(viperConfig *viper.Viper) {
+	viperConfig.SetDefault("Format", regexpFormat)
+	viperConfig.SetDefault("JSONKeySeparator", ".")
+	viperConfig.SetDefault("EmptyGroupRE", "^$")
+	viperConfig.SetDefault("TimestampFormat", "02/Jan/2006:15:04:05 -0700")
+}
+
+// FieldConfig selects field of the parsed line to be used as the event metadata.
+type FieldConfig struct {
+	// Path is key of the parsed field, keys of nested JSON objects are joined using the JSONKeySeparator.
+	Path string
+	// MetadataKey is the event metadata key of the field, defaults to the Path.
+	MetadataKey string
+}
+
+// selectFields returns only the configured fields of the parsed line, all the fields if none is configured.
+func selectFields(fields []FieldConfig, lineData stringmap.StringMap) stringmap.StringMap {
+	if len(fields) == 0 {
+		return lineData
+	}
+	selected := stringmap.StringMap{}
+	for _, field := range fields {
+		value, ok := lineData[field.Path]
+		if !ok {
+			continue
+		}
+		if field.MetadataKey == "" {
+			selected[field.Path] = value
+		} else {
+			selected[field.MetadataKey] = value
+		}
+	}
+	return selected
+}
+
+// Parser parses lines to the metadata and time of the events.
+type Parser struct {
+	parser          lineParser
+	fields          []FieldConfig
+	timestampField  string
+	timestampFormat string
+}
+
+// New returns Parser for the given configuration.
+func New(config Config) (*Parser, error) {
+	parser, err := newLineParser(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Preset != "" {
+		config.TimestampField = presetTimestampField
+		config.TimestampFormat = presetTimestampFormat
+	}
+	return &Parser{
+		parser:          parser,
+		fields:          config.Fields,
+		timestampField:  config.TimestampField,
+		timestampFormat: config.TimestampFormat,
+	}, nil
+}
+
+// Parse returns metadata of the line and time of the event, the time is zero if no timestamp field is configured.
+func (p *Parser) Parse(line string) (stringmap.StringMap, time.Time, error) {
+	lineData, err := p.parser.parse(line)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var timestamp time.Time
+	if p.timestampField != "" {
+		timestamp, err = event.ParseTimestamp(lineData[p.timestampField], p.timestampFormat)
+		if err != nil {
+			return nil, time.Time{}, &MalformedLineError{Reason: ReasonInvalidTimestamp, Err: err}
+		}
+	}
+	return selectFields(p.fields, lineData), timestamp, nil
+}
+
+// parseLine parses the given line, producing a RequestEvent instance
+// - lineParseRegexp is used to parse the line
+// - if content of any of the matched named groups matches emptyGroupRegexp, it is replaced by an empty string "".
+func parseLine(lineParseRegexp, emptyGroupRegexp *regexp.Regexp, line string) (map[string]string, error) {
+	lineData := make(map[string]string)
+
+	match := lineParseRegexp.FindStringSubmatch(line)
+	if len(match) != len(lineParseRegexp.SubexpNames()) {
+		return nil, fmt.Errorf("unable to parse line")
+	}
+	for i, name := range lineParseRegexp.SubexpNames() {
+		if i == 0 || name == "" || emptyGroupRegexp.MatchString(match[i]) {
+			continue
+		}
+		lineData[name] = match[i]
+	}
+
+	return lineData, nil
+}
@@ -0,0 +1,304 @@
+package lineparser
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	lineParseRegexp   = `^(?P<ip>[A-Fa-f0-9.:]{4,50}) \S+ \S+ \[(?P<time>.*?)\] "(?P<request>.*?)" (?P<statusCode>\d+) \d+ "(?P<referer>.*?)" uag="(?P<userAgent>[^"]+)" "[^"]+" ua="[^"]+" rt="(?P<requestDuration>\d+(\.\d+)??)"(?: frpc-status="(?P<frpcStatus>\d*|-)")?(?: slo-domain="(?P<sloDomain>[^"]*)")?(?: slo-app="(?P<sloApp>[^"]*)")?(?: slo-class="(?P<sloClass>[^"]*)")?(?: slo-endpoint="(?P<sloEndpoint>[^"]*)")?(?: slo-result="(?P<sloResult>[^"]*)")?`
+	emptyGroupRegexp  = `^-$`
+	requestLineFormat = `{ip} - - [{time}] "{request}" {statusCode} 79 "-" uag="-" "-" ua="10.66.112.78:80" rt="{requestDuration}" frpc-status="{frpcStatus}" slo-domain="{sloDomain}" slo-app="{sloApp}" slo-class="{sloClass}" slo-endpoint="{sloEndpoint}" slo-result="{sloResult}"`
+	// provided to getRequestLine, this returns a considered-valid line.
+	requestLineFormatMapValid = map[string]string{
+		"time":            "12/Nov/2019:10:20:07 +0100",
+		"ip":              "34.65.133.58",
+		"request":         "GET /robots.txt HTTP/1.1",
+		"statusCode":      "200",
+		"requestDuration": "0.123", // in s, as logged by nginx
+		"sloClass":        "-",
+		"sloDomain":       "-",
+		"sloApp":          "-",
+		"sloResult":       "-",
+		"sloEndpoint":     "-",
+		"frpcStatus":      "-",
+	}
+)
+
+// return request line formatted using the provided formatMap.
+func getRequestLine(formatMap map[string]string) (requestLine string) {
+	requestLine = requestLineFormat
+	for k, v := range formatMap {
+		requestLine = strings.ReplaceAll(requestLine, fmt.Sprintf("{%s}", k), v)
+	}
+	return requestLine
+}
+
+type parseLineTest struct {
+	// lineContentMapping is to be used to generate the request log line via getRequestLine func
+	// (see it for what the defaults are, so that you dont have to fill them for every test case)
+	lineContentMapping map[string]string
+	isLineValid        bool
+}
+
+func Test_ParseLineAndBuildEvent(t *testing.T) {
+	testTable := []parseLineTest{
+		// ipv4
+		{map[string]string{
+			"time":            "12/Nov/2019:10:20:07 +0100",
+			"ip":              "34.65.133.58",
+			"request":         "GET /robots.txt HTTP/1.1",
+			"statusCode":      "200",
+			"requestDuration": "0.123", // in s, as logged by nginx
+			"sloClass":        "-",
+			"sloDomain":       "-",
+			"sloApp":          "-",
+			"sloResult":       "-",
+			"sloEndpoint":     "-",
+			"frpcStatus":      "-",
+			"userAgent":       "-",
+			"referer":         "-",
+		}, true},
+		// ipv6
+		{map[string]string{
+			"time":            "12/Nov/2019:10:20:07 +0100",
+			"ip":              "2001:718:801:230::1",
+			"request":         "GET /robots.txt HTTP/1.1",
+			"statusCode":      "200",
+			"requestDuration": "0.123", // in s, as logged by nginx
+			"sloClass":        "-",
+			"sloDomain":       "-",
+			"sloApp":          "-",
+			"sloResult":       "-",
+			"sloEndpoint":     "-",
+			"frpcStatus":      "-",
+			"userAgent":       "-",
+			"referer":         "-",
+		}, true},
+		// invalid time
+		{map[string]string{
+			"time":            "32/Nov/2019:25:20:07 +0100",
+			"ip":              "2001:718:801:230::1",
+			"request":         "GET /robots.txt HTTP/1.1",
+			"statusCode":      "200x",
+			"requestDuration": "0.123", // in s, as logged by nginx
+			"sloClass":        "-",
+			"sloDomain":       "-",
+			"sloApp":          "-",
+			"sloResult":       "-",
+			"sloEndpoint":     "-",
+			"frpcStatus":      "-",
+			"userAgent":       "-",
+			"referer":         "-",
+		}, false},
+		// invalid request
+		{map[string]string{
+			"time":            "12/Nov/2019:10:20:07 +0100",
+			"ip":              "2001:718:801:230::1",
+			"request":         "invalid-request[eof]",
+			"statusCode":      "200x",
+			"requestDuration": "0.123", // in s, as logged by nginx
+			"sloClass":        "-",
+			"sloDomain":       "-",
+			"sloApp":          "-",
+			"sloResult":       "-",
+			"sloEndpoint":     "-",
+			"frpcStatus":      "-",
+			"userAgent":       "-",
+			"referer":         "-",
+		}, false},
+		// request without protocol
+		{map[string]string{
+			"time":            "12/Nov/2019:10:20:07 +0100",
+			"ip":              "2001:718:801:230::1",
+			"request":         "GET /robots.txt",
+			"statusCode":      "301",
+			"requestDuration": "0.123", // in s, as logged by nginx
+			"sloClass":        "-",
+			"sloDomain":       "-",
+			"sloApp":          "-",
+			"sloResult":       "-",
+			"sloEndpoint":     "-",
+			"frpcStatus":      "-",
+			"userAgent":       "-",
+			"referer":         "-",
+		}, true},
+		// http2.0 proto request
+		{map[string]string{
+			"time":            "12/Nov/2019:10:20:07 +0100",
+			"ip":              "2001:718:801:230::1",
+			"request":         "GET /robots.txt HTTP/2.0",
+			"statusCode":      "200",
+			"requestDuration": "0.123", // in s, as logged by nginx
+			"sloClass":        "-",
+			"sloDomain":       "-",
+			"sloApp":          "-",
+			"sloResult":       "-",
+			"sloEndpoint":     "-",
+			"frpcStatus":      "-",
+			"userAgent":       "-",
+			"referer":         "-",
+		}, true},
+		// zero status code
+		{map[string]string{
+			"time":            "12/Nov/2019:10:20:07 +0100",
+			"ip":              "2001:718:801:230::1",
+			"request":         "GET /robots.txt HTTP/1.1",
+			"statusCode":      "0",
+			"requestDuration": "0.123", // in s, as logged by nginx
+			"sloClass":        "-",
+			"sloDomain":       "-",
+			"sloApp":          "-",
+			"sloResult":       "-",
+			"sloEndpoint":     "-",
+			"frpcStatus":      "-",
+			"userAgent":       "-",
+			"referer":         "-",
+		}, true},
+		// invalid status code
+		{map[string]string{
+			"time":            "12/Nov/2019:10:20:07 +0100",
+			"ip":              "2001:718:801:230::1",
+			"request":         "GET /robots.txt HTTP/1.1",
+			"statusCode":      "xxx",
+			"requestDuration": "0.123", // in s, as logged by nginx
+			"sloClass":        "-",
+			"sloDomain":       "-",
+			"sloApp":          "-",
+			"sloResult":       "-",
+			"sloEndpoint":     "-",
+			"frpcStatus":      "-",
+			"userAgent":       "-",
+			"referer":         "-",
+		}, false},
+		// classified event
+		{map[string]string{
+			"time":            "12/Nov/2019:10:20:07 +0100",
+			"ip":              "2001:718:801:230::1",
+			"request":         "GET /robots.txt HTTP/1.1",
+			"statusCode":      "200",
+			"requestDuration": "0.123", // in s, as logged by nginx
+			"sloClass":        "critical",
+			"sloDomain":       "userportal",
+			"sloApp":          "frontend-api",
+			"sloResult":       "success",
+			"sloEndpoint":     "AdInventoryManagerInterestsQuery",
+			"frpcStatus":      "-",
+			"userAgent":       "-",
+			"referer":         "-",
+		}, true},
+	}
+
+	lineParseRegexpCompiled := regexp.MustCompile(lineParseRegexp)
+	emptyGroupRegexpCompiled := regexp.MustCompile(emptyGroupRegexp)
+	for _, test := range testTable {
+		requestLine := getRequestLine(test.lineContentMapping)
+
+		data, err := parseLine(lineParseRegexpCompiled, emptyGroupRegexpCompiled, requestLine)
+		if err != nil {
+			if test.isLineValid {
+				t.Fatalf("unable to parse request line '%s': %v", requestLine, err)
+			} else {
+				// the tested line is marked as not valid, Err is expected
+				continue
+			}
+		}
+		parsedEvent := &event.Raw{Metadata: data}
+
+		var expectedEvent *event.Raw
+
+		if test.isLineValid {
+			// line is considered valid, build the expectedEvent struct in order to compare it to the parsed one
+
+			// first, drop all data which matches emptyGroupRegexpCompiled, as they should not be included in the data provided to buildEvent
+			for k, v := range test.lineContentMapping {
+				if emptyGroupRegexpCompiled.MatchString(v) {
+					delete(test.lineContentMapping, k)
+				}
+			}
+			expectedEvent = &event.Raw{Metadata: test.lineContentMapping}
+			if !reflect.DeepEqual(expectedEvent, parsedEvent) {
+				t.Errorf("Unexpected result of parse line: %s\nGot: %+v\nExpected: %+v", requestLine, parsedEvent, expectedEvent)
+			}
+		}
+	}
+}
+
+func Test_ParseLine(t *testing.T) {
+	testTable := []parseLineTest{
+		{
+			lineContentMapping: map[string]string{
+				"time":            "12/Nov/2019:10:20:07 +0100",
+				"ip":              "34.65.133.58",
+				"request":         "GET /robots.txt HTTP/1.1",
+				"statusCode":      "200",
+				"requestDuration": "0.123", // in s, as logged by nginx
+				"sloClass":        "-",
+				"sloDomain":       "-",
+				"sloApp":          "-",
+				"sloResult":       "-",
+				"sloEndpoint":     "-",
+				"frpcStatus":      "-",
+			},
+			isLineValid: true,
+		},
+	}
+	lineParseRegexpCompiled := regexp.MustCompile(lineParseRegexp)
+	emptyGroupRegexpCompiled := regexp.MustCompile(emptyGroupRegexp)
+
+	for _, test := range testTable {
+		requestLine := getRequestLine(test.lineContentMapping)
+		data, err := parseLine(lineParseRegexpCompiled, emptyGroupRegexpCompiled, requestLine)
+		if err != nil {
+			t.Fatalf("unable to parse request line '%s': %v", requestLine, err)
+		}
+		for k, v := range test.lineContentMapping {
+			if !emptyGroupRegexpCompiled.MatchString(v) {
+				continue
+			}
+			// test that empty group was correctly replaced by an empty string
+			if _, ok := data[k]; ok {
+				t.Errorf("Content named group '%s':'%s' should not have been included in the resulting stringmap (as value matches emptyGroupRegexp: '%s'): %+v", k, v, emptyGroupRegexp, data)
+			}
+		}
+	}
+}
+
+func TestParser_timestamp(t *testing.T) {
+	tests := []struct {
+		name            string
+		timestampField  string
+		timestampFormat string
+		time            string
+		expTimestamp    time.Time
+		expErr          bool
+	}{
+		{name: "no timestamp field", time: "12/Nov/2019:10:20:07 +0100"},
+		{name: "valid timestamp", timestampField: "time", timestampFormat: "02/Jan/2006:15:04:05 -0700", time: "12/Nov/2019:10:20:07 +0100", expTimestamp: time.Date(2019, 11, 12, 9, 20, 7, 0, time.UTC)},
+		{name: "unix timestamp", timestampField: "time", timestampFormat: event.UnixTimestampFormat, time: "1573550407", expTimestamp: time.Date(2019, 11, 12, 9, 20, 7, 0, time.UTC)},
+		{name: "invalid timestamp", timestampField: "time", timestampFormat: time.RFC3339, time: "12/Nov/2019:10:20:07 +0100", expErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := New(Config{LoglineParseRegexp: lineParseRegexp, EmptyGroupRE: emptyGroupRegexp, TimestampField: tt.timestampField, TimestampFormat: tt.timestampFormat})
+			assert.NoError(t, err)
+			lineContent := map[string]string{}
+			for k, v := range requestLineFormatMapValid {
+				lineContent[k] = v
+			}
+			lineContent["time"] = tt.time
+			_, timestamp, err := parser.Parse(getRequestLine(lineContent))
+			assert.Equal(t, tt.expErr, err != nil, err)
+			if err == nil {
+				assert.True(t, tt.expTimestamp.Equal(timestamp), "expected %s, got %s", tt.expTimestamp, timestamp)
+			}
+		})
+	}
+}
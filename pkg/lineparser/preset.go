@@ -0,0 +1,171 @@
+package lineparser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+)
+
+// presetTimestampField is the metadata key of the time of the event parsed using a preset, same as in the envoyAccessLogServer.
+const presetTimestampField = "startTime"
+
+// presetTimestampFormat is the format the presetTimestampField is normalized to in UTC, exactly as the envoyAccessLogServer formats it.
+const presetTimestampFormat = time.RFC3339
+
+// preset is a predefined format of the log lines of a well known server.
+// Names of the regexp groups follow the metadata keys of the envoyAccessLogServer module,
+// so the same rules can be used regardless of the source of the events.
+type preset struct {
+	lineParseRegexp string
+	// timestampLayout is the layout of the presetTimestampField in the line, time without the zone is in the local time.
+	timestampLayout string
+	// renamedGroups maps the regexp groups to the metadata keys which are not valid group names.
+	renamedGroups map[string]string
+	// durationGroups maps the regexp groups containing number of the duration units to the unit.
+	// The durations are converted to the Go duration format in nanoseconds, negative durations are considered unknown and omitted.
+	durationGroups map[string]time.Duration
+}
+
+// Parts of the regular expressions shared by the presets.
+const (
+	presetRequestRE  = `"(?:(?P<requestMethod>[A-Z]+) (?P<path>\S+)(?: (?P<protocolVersion>HTTP/[0-9.]+))?|[^"]*)"`
+	presetCommonRE   = `^(?P<downstreamRemoteAddress>\S+) \S+ (?P<remoteUser>\S+) \[(?P<startTime>[^\]]+)\] ` + presetRequestRE + ` (?P<responseCode>\d{3}) (?P<responseBodyBytes>\d+|-)`
+	presetCombinedRE = presetCommonRE + ` "(?P<referer>[^"]*)" "(?P<userAgent>[^"]*)"`
+)
+
+const clfTimestampLayout = "02/Jan/2006:15:04:05 -0700"
+
+var presets = map[string]preset{
+	// Apache Common Log Format `%h %l %u %t "%r" %>s %b`.
+	"apacheCommon": {
+		lineParseRegexp: presetCommonRE,
+		timestampLayout: clfTimestampLayout,
+	},
+	// Apache Combined Log Format `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-agent}i"`.
+	"apacheCombined": {
+		lineParseRegexp: presetCombinedRE,
+		timestampLayout: clfTimestampLayout,
+	},
+	// Nginx predefined `combined` log_format, any additional fields at the end of the line are ignored.
+	"nginxCombined": {
+		lineParseRegexp: presetCombinedRE,
+		timestampLayout: clfTimestampLayout,
+	},
+	// Nginx `slo` log_format with the SLO classification headers of the response, see the examples/nginx_proxy.
+	"nginxSlo": {
+		lineParseRegexp: presetCommonRE + ` "(?P<referer>[^"]*)" uag="(?P<userAgent>[^"]*)" "(?P<forwardedFor>[^"]*)" ua="\[?(?P<upstreamRemoteAddress>[^"]*?)\]?(?::(?P<upstreamRemotePort>\d+))?" rt="(?P<timeToLastDownstreamTxByte>[0-9.]+)".*? ignore-slo="(?P<ignoreSlo>[^"]*)" slo-domain="(?P<sloDomain>[^"]*)" slo-app="(?P<sloApp>[^"]*)" slo-class="(?P<sloClass>[^"]*)" slo-endpoint="(?P<sloEndpoint>[^"]*)" slo-result="(?P<sloResult>[^"]*)"`,
+		timestampLayout: clfTimestampLayout,
+		renamedGroups: map[string]string{
+			"ignoreSlo":   "sent_http_ignore-slo",
+			"sloDomain":   "sent_http_slo-domain",
+			"sloApp":      "sent_http_slo-app",
+			"sloClass":    "sent_http_slo-class",
+			"sloEndpoint": "sent_http_slo-endpoint",
+			"sloResult":   "sent_http_slo-result",
+		},
+		durationGroups: map[string]time.Duration{"timeToLastDownstreamTxByte": time.Second},
+	},
+	// HAProxy `option httplog` format, optionally prefixed by the syslog header.
+	"haproxyHttp": {
+		lineParseRegexp: `(?:^|: )(?P<downstreamRemoteAddress>[0-9A-Fa-f.:]+):(?P<downstreamRemotePort>\d+) \[(?P<startTime>[^\]]+)\] (?P<frontendName>\S+) (?P<upstreamCluster>[^\s/]+)/(?P<upstreamHost>\S+) (?P<timeToLastRxByte>-?\d+)/(?P<waitTime>-?\d+)/(?P<connectTime>-?\d+)/(?P<responseTime>-?\d+)/\+?(?P<timeToLastDownstreamTxByte>-?\d+) (?P<responseCode>-?\d+) \+?(?P<sentBytes>\d+) \S+ \S+ (?P<terminationState>\S+) \S+ \S+ (?:\{[^}]*\} )*` + presetRequestRE,
+		timestampLayout: "02/Jan/2006:15:04:05.000",
+		durationGroups: map[string]time.Duration{
+			"timeToLastRxByte":           time.Millisecond,
+			"waitTime":                   time.Millisecond,
+			"connectTime":                time.Millisecond,
+			"responseTime":               time.Millisecond,
+			"timeToLastDownstreamTxByte": time.Millisecond,
+		},
+	},
+	// Envoy default format of the file access log.
+	"envoy": {
+		lineParseRegexp: `^\[(?P<startTime>[^\]]+)\] ` + presetRequestRE + ` (?P<responseCode>\d+) (?P<responseFlags>\S+) (?P<requestBodyBytes>\d+) (?P<responseBodyBytes>\d+) (?P<timeToLastDownstreamTxByte>\d+) (?P<upstreamServiceTime>\d+|-) "(?P<forwardedFor>[^"]*)" "(?P<userAgent>[^"]*)" "(?P<requestId>[^"]*)" "(?P<authority>[^"]*)" "(?:\w+://)?\[?(?P<upstreamRemoteAddress>[^"]*?)\]?(?::(?P<upstreamRemotePort>\d+))?"`,
+		timestampLayout: time.RFC3339Nano,
+		renamedGroups:   map[string]string{"upstreamServiceTime": "sent_http_x-envoy-upstream-service-time"},
+		durationGroups:  map[string]time.Duration{"timeToLastDownstreamTxByte": time.Millisecond},
+	},
+}
+
+// protocolVersions maps the protocol in the log line to the protocol version reported by the envoyAccessLogServer.
+var protocolVersions = map[string]string{
+	"HTTP/1.0": "HTTP10",
+	"HTTP/1.1": "HTTP11",
+	"HTTP/2":   "HTTP2",
+	"HTTP/2.0": "HTTP2",
+	"HTTP/3":   "HTTP3",
+	"HTTP/3.0": "HTTP3",
+}
+
+func presetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// presetParser parses lines using the preset and normalizes the values to the format of the envoyAccessLogServer.
+type presetParser struct {
+	regexpParser
+	preset preset
+}
+
+func newPresetParser(name string, emptyGroupRegexp *regexp.Regexp) (*presetParser, error) {
+	p, ok := presets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset '%s', expected one of %s", name, strings.Join(presetNames(), ", "))
+	}
+	return &presetParser{
+		regexpParser: regexpParser{lineParseRegexp: regexp.MustCompile(p.lineParseRegexp), emptyGroupRegexp: emptyGroupRegexp},
+		preset:       p,
+	}, nil
+}
+
+func (p *presetParser) parse(line string) (stringmap.StringMap, error) {
+	lineData, err := p.regexpParser.parse(line)
+	if err != nil {
+		return nil, err
+	}
+	// Optional groups which did not participate in the match are omitted.
+	for key, value := range lineData {
+		if value == "" {
+			delete(lineData, key)
+		}
+	}
+	if value, ok := lineData[presetTimestampField]; ok {
+		timestamp, err := time.ParseInLocation(p.preset.timestampLayout, value, time.Local)
+		if err != nil {
+			return nil, &MalformedLineError{Reason: ReasonInvalidTimestamp, Err: fmt.Errorf("invalid timestamp: %w", err)}
+		}
+		lineData[presetTimestampField] = timestamp.UTC().Format(presetTimestampFormat)
+	}
+	if version, ok := protocolVersions[lineData["protocolVersion"]]; ok {
+		lineData["protocolVersion"] = version
+	}
+	for group, unit := range p.preset.durationGroups {
+		value, ok := lineData[group]
+		if !ok {
+			continue
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || number < 0 {
+			delete(lineData, group)
+			continue
+		}
+		lineData[group] = fmt.Sprint(int64(math.Round(number*float64(unit)))) + "ns"
+	}
+	for group, key := range p.preset.renamedGroups {
+		if value, ok := lineData[group]; ok {
+			delete(lineData, group)
+			lineData[key] = value
+		}
+	}
+	return lineData, nil
+}
@@ -0,0 +1,118 @@
+package lineparser
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresets(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = local }()
+
+	tests := []struct {
+		preset  string
+		line    string
+		expData stringmap.StringMap
+	}{
+		{
+			preset: "apacheCommon",
+			line:   `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`,
+			expData: stringmap.StringMap{
+				"downstreamRemoteAddress": "127.0.0.1", "remoteUser": "frank", "startTime": "2000-10-10T20:55:36Z",
+				"requestMethod": "GET", "path": "/apache_pb.gif", "protocolVersion": "HTTP10", "responseCode": "200", "responseBodyBytes": "2326",
+			},
+		},
+		{
+			preset: "nginxCombined",
+			line:   `2a02:598::1 - - [24/Apr/2020:13:49:54 +0200] "POST /api/v1/items?id=1 HTTP/2.0" 503 - "-" "curl/7.74.0" rt=0.001`,
+			expData: stringmap.StringMap{
+				"downstreamRemoteAddress": "2a02:598::1", "startTime": "2020-04-24T11:49:54Z",
+				"requestMethod": "POST", "path": "/api/v1/items?id=1", "protocolVersion": "HTTP2", "responseCode": "503", "userAgent": "curl/7.74.0",
+			},
+		},
+		{
+			preset: "nginxCombined",
+			line:   `127.0.0.1 - - [24/Apr/2020:13:49:54 +0200] "\x16\x03\x01" 400 157 "-" "-"`,
+			expData: stringmap.StringMap{
+				"downstreamRemoteAddress": "127.0.0.1", "startTime": "2020-04-24T11:49:54Z", "responseCode": "400", "responseBodyBytes": "157",
+			},
+		},
+		{
+			preset: "nginxSlo",
+			line:   `127.0.0.1 - - [24/Apr/2020:13:49:54 +0200] "GET /img2/icons/i-goods.svg HTTP/1.1" 200 781 "https://www.sklik.cz/" uag="Mozilla/5.0" "127.0.0.1" ua="127.0.0.1:80" rt="0.001" uct="0.000" uht="0.001" urt="0.001" cc="static" occ="-" url="781" ourl="-" ignore-slo="-" slo-domain="userportal" slo-app="frontend" slo-class="critical" slo-endpoint="-" slo-result="success"`,
+			expData: stringmap.StringMap{
+				"downstreamRemoteAddress": "127.0.0.1", "startTime": "2020-04-24T11:49:54Z",
+				"requestMethod": "GET", "path": "/img2/icons/i-goods.svg", "protocolVersion": "HTTP11", "responseCode": "200", "responseBodyBytes": "781",
+				"referer": "https://www.sklik.cz/", "userAgent": "Mozilla/5.0", "forwardedFor": "127.0.0.1",
+				"upstreamRemoteAddress": "127.0.0.1", "upstreamRemotePort": "80", "timeToLastDownstreamTxByte": "1000000ns",
+				"sent_http_slo-domain": "userportal", "sent_http_slo-app": "frontend", "sent_http_slo-class": "critical", "sent_http_slo-result": "success",
+			},
+		},
+		{
+			preset: "haproxyHttp",
+			line:   `Feb  6 12:14:14 localhost haproxy[14389]: 10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 {1wt.eu} {} "GET /index.html HTTP/1.1"`,
+			expData: stringmap.StringMap{
+				"downstreamRemoteAddress": "10.0.1.2", "downstreamRemotePort": "33317", "startTime": "2009-02-06T12:14:14Z",
+				"frontendName": "http-in", "upstreamCluster": "static", "upstreamHost": "srv1",
+				"timeToLastRxByte": "10000000ns", "waitTime": "0ns", "connectTime": "30000000ns", "responseTime": "69000000ns", "timeToLastDownstreamTxByte": "109000000ns",
+				"responseCode": "200", "sentBytes": "2750", "terminationState": "----",
+				"requestMethod": "GET", "path": "/index.html", "protocolVersion": "HTTP11",
+			},
+		},
+		{
+			preset: "haproxyHttp",
+			line:   `10.0.1.2:33319 [06/Feb/2009:12:14:14.655] http-in static/<NOSRV> -1/-1/-1/-1/8490 -1 0 - - CR-- 2/2/2/0/0 0/0 "<BADREQ>"`,
+			expData: stringmap.StringMap{
+				"downstreamRemoteAddress": "10.0.1.2", "downstreamRemotePort": "33319", "startTime": "2009-02-06T12:14:14Z",
+				"frontendName": "http-in", "upstreamCluster": "static", "upstreamHost": "<NOSRV>",
+				"timeToLastDownstreamTxByte": "8490000000ns", "responseCode": "-1", "sentBytes": "0", "terminationState": "CR--",
+			},
+		},
+		{
+			preset: "envoy",
+			line:   `[2016-04-15T20:17:00.310Z] "POST /api/v1/locations HTTP/2" 204 - 154 0 226 100 "10.0.35.28" "nsq2http" "cc21d9b0-cf5c-432b-8c7e-98aeb7988cd2" "locations" "tcp://10.0.2.1:80"`,
+			expData: stringmap.StringMap{
+				"startTime": "2016-04-15T20:17:00Z", "requestMethod": "POST", "path": "/api/v1/locations", "protocolVersion": "HTTP2",
+				"responseCode": "204", "requestBodyBytes": "154", "responseBodyBytes": "0", "timeToLastDownstreamTxByte": "226000000ns",
+				"sent_http_x-envoy-upstream-service-time": "100", "forwardedFor": "10.0.35.28", "userAgent": "nsq2http",
+				"requestId": "cc21d9b0-cf5c-432b-8c7e-98aeb7988cd2", "authority": "locations", "upstreamRemoteAddress": "10.0.2.1", "upstreamRemotePort": "80",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			parser, err := newPresetParser(tt.preset, regexp.MustCompile(`^-$`))
+			assert.NoError(t, err)
+			data, err := parser.parse(tt.line)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expData, data)
+		})
+	}
+}
+
+func Test_newLineParser_preset(t *testing.T) {
+	_, err := newLineParser(Config{Preset: "nginxCombined"})
+	assert.NoError(t, err)
+	_, err = newLineParser(Config{Preset: "iis"})
+	assert.Error(t, err)
+	_, err = newLineParser(Config{Preset: "nginxCombined", LoglineParseRegexp: "^$"})
+	assert.Error(t, err)
+	_, err = newLineParser(Config{Preset: "nginxCombined", Format: jsonFormat})
+	assert.Error(t, err)
+	_, err = newLineParser(Config{Preset: "nginxCombined", TimestampField: "time"})
+	assert.Error(t, err)
+}
+
+func TestParser_preset(t *testing.T) {
+	parser, err := New(Config{Preset: "nginxCombined", EmptyGroupRE: "^-$"})
+	assert.NoError(t, err)
+	metadata, timestamp, err := parser.Parse(`127.0.0.1 - - [24/Apr/2020:13:49:54 +0200] "GET / HTTP/1.1" 200 10 "-" "curl/7.74.0"`)
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2020, 4, 24, 11, 49, 54, 0, time.UTC).Equal(timestamp))
+	assert.Equal(t, "GET", metadata["requestMethod"])
+}
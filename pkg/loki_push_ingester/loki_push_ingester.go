@@ -0,0 +1,252 @@
+package loki_push_ingester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/internal/httpingest"
+	"github.com/seznam/slo-exporter/pkg/lineparser"
+	"github.com/seznam/slo-exporter/pkg/pipeline"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// pushPath is the path of the Loki push API.
+const pushPath = "/loki/api/v1/push"
+
+// LokiPushIngesterConfig is configuration of the Loki push ingester module.
+type LokiPushIngesterConfig struct {
+	// Address to listen on for the HTTP push requests.
+	Address string
+	// GracefulShutdownTimeout limits how long to wait for the pending requests during shutdown.
+	GracefulShutdownTimeout time.Duration
+	// MaxRequestBytes limits size of the push request body, decompressed size for the gzip encoded JSON requests.
+	MaxRequestBytes int64
+	// Config of the parser of the log lines.
+	lineparser.Config `mapstructure:",squash"`
+}
+
+// LokiPushIngester produces events from the log entries pushed using the Loki push API.
+type LokiPushIngester struct {
+	address                 string
+	gracefulShutdownTimeout time.Duration
+	maxRequestBytes         int64
+	parser                  *lineparser.Parser
+	outputChannel           chan *event.Raw
+	observer                pipeline.EventProcessingDurationObserver
+	deadLetterSink          pipeline.DeadLetterSink
+	logger                  logrus.FieldLogger
+
+	requestsTotal       *prometheus.CounterVec
+	entriesTotal        *prometheus.CounterVec
+	malformedLinesTotal *prometheus.CounterVec
+}
+
+func (l *LokiPushIngester) String() string {
+	return "lokiPushIngester"
+}
+
+func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*LokiPushIngester, error) {
+	viperConfig.SetDefault("Address", ":3100")
+	viperConfig.SetDefault("GracefulShutdownTimeout", 5*time.Second)
+	viperConfig.SetDefault("MaxRequestBytes", 10*1024*1024)
+	lineparser.SetDefaults(viperConfig)
+	var config LokiPushIngesterConfig
+	if err := viperConfig.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return New(config, logger)
+}
+
+// New returns an instance of LokiPushIngester.
+func New(config LokiPushIngesterConfig, logger logrus.FieldLogger) (*LokiPushIngester, error) {
+	if config.MaxRequestBytes <= 0 {
+		return nil, fmt.Errorf("maximum request size must be positive, got %d", config.MaxRequestBytes)
+	}
+	parser, err := lineparser.New(config.Config)
+	if err != nil {
+		return nil, err
+	}
+	return &LokiPushIngester{
+		address:                 config.Address,
+		gracefulShutdownTimeout: config.GracefulShutdownTimeout,
+		maxRequestBytes:         config.MaxRequestBytes,
+		parser:                  parser,
+		outputChannel:           make(chan *event.Raw),
+		logger:                  logger,
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "push_requests_total",
+			Help: "Total number of push requests by the format and HTTP status code of the response.",
+		}, []string{"format", "code"}),
+		entriesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "processed_entries_total",
+			Help: "Total number of processed log entries by the format of the push request.",
+		}, []string{"format"}),
+		malformedLinesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "malformed_lines_total",
+			Help: "Total number of lines of the log entries that failed to parse by the reason.",
+		}, []string{"reason"}),
+	}, nil
+}
+
+func (l *LokiPushIngester) RegisterEventProcessingDurationObserver(observer pipeline.EventProcessingDurationObserver) {
+	l.observer = observer
+}
+
+func (l *LokiPushIngester) observeDuration(start time.Time) {
+	if l.observer != nil {
+		l.observer.Observe(time.Since(start).Seconds())
+	}
+}
+
+func (l *LokiPushIngester) SetDeadLetterSink(sink pipeline.DeadLetterSink) {
+	l.deadLetterSink = sink
+}
+
+func (l *LokiPushIngester) reject(line string, err error) {
+	if l.deadLetterSink != nil {
+		l.deadLetterSink.Reject(line, err.Error())
+	}
+}
+
+func (l *LokiPushIngester) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
+	toRegister := []prometheus.Collector{l.requestsTotal, l.entriesTotal, l.malformedLinesTotal}
+	for _, collector := range toRegister {
+		if err := wrappedRegistry.Register(collector); err != nil {
+			return fmt.Errorf("error registering metric %s: %w", collector, err)
+		}
+	}
+	return nil
+}
+
+func (l *LokiPushIngester) OutputChannel() chan *event.Raw {
+	return l.outputChannel
+}
+
+// Run serves the push requests feeding events to output channel until the context is cancelled.
+func (l *LokiPushIngester) Run(ctx context.Context) error {
+	defer close(l.outputChannel)
+	mux := http.NewServeMux()
+	mux.Handle(pushPath, l.pushHandler(ctx))
+	server, err := httpingest.Listen(l.address, mux, l.gracefulShutdownTimeout)
+	if err != nil {
+		return fmt.Errorf("error while starting the %s: %w", l, err)
+	}
+	l.logger.Infof("listening for Loki push requests on %s", server.Addr())
+	if err := server.Run(ctx); err != nil {
+		return fmt.Errorf("%s HTTP server fatal error: %w", l, err)
+	}
+	return nil
+}
+
+// pushHandler handles the push requests, the events are emitted until the ctx is cancelled.
+func (l *LokiPushIngester) pushHandler(ctx context.Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := "protobuf"
+		contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if contentType == "application/json" {
+			format = "json"
+		}
+		code, err := l.push(ctx, r, format)
+		l.requestsTotal.WithLabelValues(format, fmt.Sprint(code)).Inc()
+		if err != nil {
+			if code >= http.StatusInternalServerError {
+				l.logger.Errorf("failed to process the push request: %v", err)
+			} else {
+				l.logger.WithField("remoteAddress", r.RemoteAddr).Warnf("invalid push request: %v", err)
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+		w.WriteHeader(code)
+	})
+}
+
+// push decodes the push request and emits events of its entries, returns HTTP status code of the response.
+func (l *LokiPushIngester) push(ctx context.Context, r *http.Request, format string) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method)
+	}
+	body, err := l.readBody(r, format)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return http.StatusRequestEntityTooLarge, err
+		}
+		return http.StatusBadRequest, err
+	}
+	var streams []stream
+	if format == "json" {
+		streams, err = decodeJSONPushRequest(body)
+	} else {
+		streams, err = decodeProtoPushRequest(body)
+	}
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	for _, s := range streams {
+		for _, e := range s.entries {
+			newEvent := l.processEntry(format, s, e)
+			if newEvent == nil {
+				continue
+			}
+			select {
+			case l.outputChannel <- newEvent:
+			case <-r.Context().Done():
+				return http.StatusServiceUnavailable, r.Context().Err()
+			case <-ctx.Done():
+				return http.StatusServiceUnavailable, fmt.Errorf("%s is shutting down", l)
+			}
+		}
+	}
+	return http.StatusNoContent, nil
+}
+
+// readBody reads the request body, the protobuf is compressed using snappy and JSON can be gzip encoded.
+func (l *LokiPushIngester) readBody(r *http.Request, format string) ([]byte, error) {
+	body, err := httpingest.ReadBody(r, l.maxRequestBytes)
+	if err != nil {
+		return nil, err
+	}
+	if format == "json" {
+		return body, nil
+	}
+	decodedLength, err := snappy.DecodedLen(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy compressed body: %w", err)
+	}
+	if int64(decodedLength) > l.maxRequestBytes {
+		return nil, &http.MaxBytesError{Limit: l.maxRequestBytes}
+	}
+	body, err = snappy.Decode(nil, body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy compressed body: %w", err)
+	}
+	return body, nil
+}
+
+// processEntry returns event of the entry, its line is parsed using the line parser. Returns nil if the line is malformed.
+func (l *LokiPushIngester) processEntry(format string, s stream, e entry) *event.Raw {
+	start := time.Now()
+	defer l.observeDuration(start)
+	l.entriesTotal.WithLabelValues(format).Inc()
+	metadata, timestamp, err := l.parser.Parse(e.line)
+	if err != nil {
+		l.malformedLinesTotal.WithLabelValues(lineparser.MalformedLineReason(err)).Inc()
+		l.logger.WithField("line", e.line).Errorf("err (%+v) while parsing line", err)
+		l.reject(e.line, err)
+		return nil
+	}
+	// Time of the entry is used unless the time is parsed from the line.
+	if timestamp.IsZero() {
+		timestamp = e.timestamp
+	}
+	return &event.Raw{Metadata: s.labels.Merge(e.structuredMetadata).Merge(metadata), Quantity: 1, Timestamp: timestamp}
+}
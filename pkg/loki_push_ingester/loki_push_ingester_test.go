@@ -0,0 +1,131 @@
+package loki_push_ingester
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/internal/moduletest"
+	"github.com/seznam/slo-exporter/pkg/lineparser"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestIngester(t *testing.T, address string) *LokiPushIngester {
+	ingester, err := New(LokiPushIngesterConfig{
+		Address:                 address,
+		GracefulShutdownTimeout: time.Second,
+		MaxRequestBytes:         1024,
+		Config:                  lineparser.Config{Format: "logfmt", EmptyGroupRE: "^$"},
+	}, logrus.New())
+	assert.NoError(t, err)
+	return ingester
+}
+
+func gzipped(t *testing.T, data string) []byte {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	_, err := writer.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func TestLokiPushIngester_pushHandler(t *testing.T) {
+	timestamp := time.Unix(1704103200, 123456789)
+	ingester := newTestIngester(t, "")
+	handler := ingester.pushHandler(context.Background())
+
+	collected := moduletest.CollectEvents(ingester.OutputChannel(), 2)
+	request := httptest.NewRequest(http.MethodPost, pushPath, bytes.NewReader(snappy.Encode(nil, protoPushRequest(`{job="nginx"}`, timestamp, "method=GET", "method=POST"))))
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, []*event.Raw{
+		{Metadata: stringmap.StringMap{"job": "nginx", "traceID": "abc", "method": "GET"}, Quantity: 1, Timestamp: timestamp},
+		{Metadata: stringmap.StringMap{"job": "nginx", "traceID": "abc", "method": "POST"}, Quantity: 1, Timestamp: timestamp},
+	}, <-collected)
+	assert.Equal(t, 2.0, testutil.ToFloat64(ingester.entriesTotal.WithLabelValues("protobuf")))
+
+	// Malformed line is skipped.
+	collected = moduletest.CollectEvents(ingester.OutputChannel(), 1)
+	request = httptest.NewRequest(http.MethodPost, pushPath, bytes.NewReader(gzipped(t, `{"streams": [{"stream": {"job": "nginx"}, "values": [["1704103200123456789", "method=\"GET"], ["1704103200123456789", "job=haproxy"]]}]}`)))
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	request.Header.Set("Content-Encoding", "gzip")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, []*event.Raw{{Metadata: stringmap.StringMap{"job": "haproxy"}, Quantity: 1, Timestamp: timestamp}}, <-collected)
+	assert.Equal(t, 1.0, testutil.ToFloat64(ingester.malformedLinesTotal.WithLabelValues(lineparser.ReasonInvalidLogfmt)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(ingester.requestsTotal.WithLabelValues("json", "204")))
+}
+
+func TestLokiPushIngester_pushHandlerInvalidRequests(t *testing.T) {
+	ingester := newTestIngester(t, "")
+	handler := ingester.pushHandler(context.Background())
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        []byte
+		expCode     int
+	}{
+		{name: "invalid method", method: http.MethodGet, contentType: "application/json", expCode: http.StatusMethodNotAllowed},
+		{name: "invalid JSON", method: http.MethodPost, contentType: "application/json", body: []byte("{"), expCode: http.StatusBadRequest},
+		{name: "not snappy compressed", method: http.MethodPost, contentType: "application/x-protobuf", body: protoPushRequest(`{job="nginx"}`, time.Now(), "method=GET"), expCode: http.StatusBadRequest},
+		{name: "invalid protobuf", method: http.MethodPost, contentType: "application/x-protobuf", body: snappy.Encode(nil, []byte("not a protobuf")), expCode: http.StatusBadRequest},
+		{name: "too large body", method: http.MethodPost, contentType: "application/json", body: []byte(strings.Repeat(" ", 2048)), expCode: http.StatusRequestEntityTooLarge},
+		{name: "too large decoded body", method: http.MethodPost, contentType: "application/x-protobuf", body: snappy.Encode(nil, bytes.Repeat([]byte{0}, 2048)), expCode: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, pushPath, bytes.NewReader(tt.body))
+			request.Header.Set("Content-Type", tt.contentType)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, tt.expCode, recorder.Code)
+		})
+	}
+}
+
+func TestLokiPushIngester_Run(t *testing.T) {
+	address := moduletest.FreeAddress(t)
+	ingester := newTestIngester(t, address)
+	stop := moduletest.Run(t, ingester)
+
+	collected := moduletest.CollectEvents(ingester.OutputChannel(), 1)
+	body := `{"streams": [{"stream": {"job": "nginx"}, "values": [["1704103200123456789", "method=GET"]]}]}`
+	assert.Eventually(t, func() bool {
+		response, err := http.Post("http://"+address+pushPath, "application/json", strings.NewReader(body))
+		if err != nil {
+			return false
+		}
+		response.Body.Close()
+		return response.StatusCode == http.StatusNoContent
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, <-collected, 1)
+
+	// Request blocked on emitting the event is terminated by the shutdown.
+	responseCode := make(chan int)
+	go func() {
+		response, err := http.Post("http://"+address+pushPath, "application/json", strings.NewReader(body))
+		assert.NoError(t, err)
+		response.Body.Close()
+		responseCode <- response.StatusCode
+	}()
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(ingester.entriesTotal.WithLabelValues("json")) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	stop()
+	assert.Equal(t, http.StatusServiceUnavailable, <-responseCode)
+}
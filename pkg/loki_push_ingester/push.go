@@ -0,0 +1,220 @@
+package loki_push_ingester
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// stream is a stream of the log entries sharing the same labels.
+type stream struct {
+	labels  stringmap.StringMap
+	entries []entry
+}
+
+// entry is a single line of the stream.
+type entry struct {
+	timestamp          time.Time
+	line               string
+	structuredMetadata stringmap.StringMap
+}
+
+// parseLabels parses labels of the stream in the Prometheus format, e.g. `{job="nginx", host="lb01"}`.
+func parseLabels(labels string) (stringmap.StringMap, error) {
+	parsed, err := parser.ParseMetric(labels)
+	if err != nil {
+		return nil, fmt.Errorf("invalid labels of the stream '%s': %w", labels, err)
+	}
+	return stringmap.NewFromLabels(parsed), nil
+}
+
+// jsonPushRequest is the push request in the JSON format
+// `{"streams": [{"stream": {"label": "value"}, "values": [["<unix epoch in nanoseconds>", "<line>", {"key": "value"}]]}]}`.
+type jsonPushRequest struct {
+	Streams []struct {
+		Stream map[string]string   `json:"stream"`
+		Values [][]json.RawMessage `json:"values"`
+	} `json:"streams"`
+}
+
+func decodeJSONPushRequest(data []byte) ([]stream, error) {
+	var request jsonPushRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil, fmt.Errorf("invalid JSON push request: %w", err)
+	}
+	streams := make([]stream, 0, len(request.Streams))
+	for _, jsonStream := range request.Streams {
+		s := stream{labels: jsonStream.Stream}
+		for _, value := range jsonStream.Values {
+			if len(value) < 2 || len(value) > 3 {
+				return nil, fmt.Errorf("entry has %d values, expected timestamp, line and optional structured metadata", len(value))
+			}
+			var timestamp, line string
+			if err := json.Unmarshal(value[0], &timestamp); err != nil {
+				return nil, fmt.Errorf("invalid timestamp of the entry: %w", err)
+			}
+			nanoseconds, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp of the entry: %w", err)
+			}
+			if err := json.Unmarshal(value[1], &line); err != nil {
+				return nil, fmt.Errorf("invalid line of the entry: %w", err)
+			}
+			e := entry{timestamp: time.Unix(0, nanoseconds), line: line}
+			if len(value) == 3 {
+				if err := json.Unmarshal(value[2], &e.structuredMetadata); err != nil {
+					return nil, fmt.Errorf("invalid structured metadata of the entry: %w", err)
+				}
+			}
+			s.entries = append(s.entries, e)
+		}
+		streams = append(streams, s)
+	}
+	return streams, nil
+}
+
+// protoField is a field of the protobuf message, value is set for the varint fields and bytes for the length delimited ones.
+type protoField struct {
+	number protowire.Number
+	typ    protowire.Type
+	value  uint64
+	bytes  []byte
+}
+
+// consumeProtoFields calls fn for each field of the protobuf message, fields of other types than varint and bytes are skipped.
+func consumeProtoFields(data []byte, fn func(field protoField) error) error {
+	for len(data) > 0 {
+		number, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		field := protoField{number: number, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			field.value, n = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			field.bytes, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(number, typ, data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if typ != protowire.VarintType && typ != protowire.BytesType {
+			continue
+		}
+		if err := fn(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeProtoPushRequest decodes the push request in the protobuf format of the logproto.PushRequest message.
+func decodeProtoPushRequest(data []byte) ([]stream, error) {
+	var streams []stream
+	err := consumeProtoFields(data, func(field protoField) error {
+		if field.number != 1 || field.typ != protowire.BytesType {
+			return nil
+		}
+		s, err := decodeProtoStream(field.bytes)
+		if err != nil {
+			return err
+		}
+		streams = append(streams, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid protobuf push request: %w", err)
+	}
+	return streams, nil
+}
+
+// decodeProtoStream decodes the logproto.StreamAdapter message `{labels = 1; entries = 2}`.
+func decodeProtoStream(data []byte) (stream, error) {
+	var s stream
+	err := consumeProtoFields(data, func(field protoField) error {
+		if field.typ != protowire.BytesType {
+			return nil
+		}
+		switch field.number {
+		case 1:
+			labels, err := parseLabels(string(field.bytes))
+			if err != nil {
+				return err
+			}
+			s.labels = labels
+		case 2:
+			e, err := decodeProtoEntry(field.bytes)
+			if err != nil {
+				return err
+			}
+			s.entries = append(s.entries, e)
+		}
+		return nil
+	})
+	return s, err
+}
+
+// decodeProtoEntry decodes the logproto.EntryAdapter message `{timestamp = 1; line = 2; structuredMetadata = 3}`.
+func decodeProtoEntry(data []byte) (entry, error) {
+	var e entry
+	var seconds, nanoseconds int64
+	err := consumeProtoFields(data, func(field protoField) error {
+		if field.typ != protowire.BytesType {
+			return nil
+		}
+		switch field.number {
+		case 1:
+			// google.protobuf.Timestamp `{seconds = 1; nanos = 2}`.
+			return consumeProtoFields(field.bytes, func(field protoField) error {
+				if field.typ != protowire.VarintType {
+					return nil
+				}
+				switch field.number {
+				case 1:
+					seconds = int64(field.value)
+				case 2:
+					nanoseconds = int64(int32(field.value))
+				}
+				return nil
+			})
+		case 2:
+			e.line = string(field.bytes)
+		case 3:
+			// logproto.LabelPairAdapter `{name = 1; value = 2}`.
+			var name, value string
+			err := consumeProtoFields(field.bytes, func(field protoField) error {
+				switch {
+				case field.typ != protowire.BytesType:
+				case field.number == 1:
+					name = string(field.bytes)
+				case field.number == 2:
+					value = string(field.bytes)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if name == "" {
+				return errors.New("structured metadata without name")
+			}
+			if e.structuredMetadata == nil {
+				e.structuredMetadata = stringmap.StringMap{}
+			}
+			e.structuredMetadata[name] = value
+		}
+		return nil
+	})
+	e.timestamp = time.Unix(seconds, nanoseconds)
+	return e, err
+}
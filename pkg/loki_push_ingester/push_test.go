@@ -0,0 +1,91 @@
+package loki_push_ingester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func appendBytesField(message []byte, number protowire.Number, value []byte) []byte {
+	message = protowire.AppendTag(message, number, protowire.BytesType)
+	return protowire.AppendBytes(message, value)
+}
+
+func appendVarintField(message []byte, number protowire.Number, value uint64) []byte {
+	message = protowire.AppendTag(message, number, protowire.VarintType)
+	return protowire.AppendVarint(message, value)
+}
+
+// protoPushRequest encodes the logproto.PushRequest with single stream.
+func protoPushRequest(labels string, timestamp time.Time, lines ...string) []byte {
+	var protoTimestamp []byte
+	protoTimestamp = appendVarintField(protoTimestamp, 1, uint64(timestamp.Unix()))
+	protoTimestamp = appendVarintField(protoTimestamp, 2, uint64(timestamp.Nanosecond()))
+	var labelPair []byte
+	labelPair = appendBytesField(labelPair, 1, []byte("traceID"))
+	labelPair = appendBytesField(labelPair, 2, []byte("abc"))
+
+	var protoStream []byte
+	protoStream = appendBytesField(protoStream, 1, []byte(labels))
+	for _, line := range lines {
+		var protoEntry []byte
+		protoEntry = appendBytesField(protoEntry, 1, protoTimestamp)
+		protoEntry = appendBytesField(protoEntry, 2, []byte(line))
+		protoEntry = appendBytesField(protoEntry, 3, labelPair)
+		protoStream = appendBytesField(protoStream, 2, protoEntry)
+	}
+	// Hash of the stream is not used.
+	protoStream = appendVarintField(protoStream, 3, 42)
+	return appendBytesField(nil, 1, protoStream)
+}
+
+func Test_decodeJSONPushRequest(t *testing.T) {
+	timestamp := time.Unix(1704103200, 123456789)
+	tests := []struct {
+		name       string
+		data       string
+		expStreams []stream
+		expErr     bool
+	}{
+		{
+			name: "valid request",
+			data: `{"streams": [{"stream": {"job": "nginx"}, "values": [["1704103200123456789", "method=GET"], ["1704103200123456789", "method=POST", {"traceID": "abc"}]]}]}`,
+			expStreams: []stream{{labels: stringmap.StringMap{"job": "nginx"}, entries: []entry{
+				{timestamp: timestamp, line: "method=GET"},
+				{timestamp: timestamp, line: "method=POST", structuredMetadata: stringmap.StringMap{"traceID": "abc"}},
+			}}},
+		},
+		{name: "empty request", data: `{}`, expStreams: []stream{}},
+		{name: "invalid JSON", data: `{"streams": `, expErr: true},
+		{name: "missing line", data: `{"streams": [{"stream": {}, "values": [["1704103200123456789"]]}]}`, expErr: true},
+		{name: "invalid timestamp", data: `{"streams": [{"stream": {}, "values": [["yesterday", "method=GET"]]}]}`, expErr: true},
+		{name: "numeric timestamp", data: `{"streams": [{"stream": {}, "values": [[1704103200123456789, "method=GET"]]}]}`, expErr: true},
+		{name: "invalid structured metadata", data: `{"streams": [{"stream": {}, "values": [["1704103200123456789", "method=GET", "abc"]]}]}`, expErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streams, err := decodeJSONPushRequest([]byte(tt.data))
+			assert.Equal(t, tt.expErr, err != nil, err)
+			assert.Equal(t, tt.expStreams, streams)
+		})
+	}
+}
+
+func Test_decodeProtoPushRequest(t *testing.T) {
+	timestamp := time.Unix(1704103200, 123456789)
+	streams, err := decodeProtoPushRequest(protoPushRequest(`{job="nginx", host="lb\"01"}`, timestamp, "method=GET", "method=POST"))
+	assert.NoError(t, err)
+	expEntry := entry{timestamp: timestamp, structuredMetadata: stringmap.StringMap{"traceID": "abc"}}
+	expGetEntry, expPostEntry := expEntry, expEntry
+	expGetEntry.line = "method=GET"
+	expPostEntry.line = "method=POST"
+	assert.Equal(t, []stream{{labels: stringmap.StringMap{"job": "nginx", "host": `lb"01`}, entries: []entry{expGetEntry, expPostEntry}}}, streams)
+
+	_, err = decodeProtoPushRequest(protoPushRequest(`job="nginx"`, timestamp, "method=GET"))
+	assert.Error(t, err)
+	_, err = decodeProtoPushRequest([]byte("not a protobuf"))
+	assert.Error(t, err)
+}
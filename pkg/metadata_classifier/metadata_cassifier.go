@@ -1,6 +1,7 @@
 package metadata_classifier
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -11,12 +12,8 @@ import (
 	"github.com/spf13/viper"
 )
 
-var processedEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
-	Name: "processed_events_total",
-	Help: "Total number of processed events by operation.",
-}, []string{"operation"})
-
-type metadataClassifierConfig struct {
+// MetadataClassifierConfig is configuration of the metadata classifier module.
+type MetadataClassifierConfig struct {
 	SloDomainMetadataKey   string
 	SloClassMetadataKey    string
 	SloAppMetadataKey      string
@@ -32,23 +29,17 @@ type MetadataClassifier struct {
 	logger                 logrus.FieldLogger
 	inputChannel           chan *event.Raw
 	outputChannel          chan *event.Raw
-	done                   bool
+	processedEventsTotal   *prometheus.CounterVec
 }
 
 func (e *MetadataClassifier) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
-	return wrappedRegistry.Register(processedEventsTotal)
+	return wrappedRegistry.Register(e.processedEventsTotal)
 }
 
 func (e *MetadataClassifier) String() string {
 	return "metadataClassifier"
 }
 
-func (e *MetadataClassifier) Done() bool {
-	return e.done
-}
-
-func (e *MetadataClassifier) Stop() {}
-
 func (e *MetadataClassifier) SetInputChannel(channel chan *event.Raw) {
 	e.inputChannel = channel
 }
@@ -58,7 +49,7 @@ func (e *MetadataClassifier) OutputChannel() chan *event.Raw {
 }
 
 func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*MetadataClassifier, error) {
-	var config metadataClassifierConfig
+	var config MetadataClassifierConfig
 	viperConfig.SetDefault("OverrideExistingValues", true)
 	if err := viperConfig.UnmarshalExact(&config); err != nil {
 		return nil, fmt.Errorf("failed to load configuration: %w", err)
@@ -66,7 +57,7 @@ func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*Metadat
 	return NewFromConfig(config, logger)
 }
 
-func NewFromConfig(config metadataClassifierConfig, logger logrus.FieldLogger) (*MetadataClassifier, error) {
+func NewFromConfig(config MetadataClassifierConfig, logger logrus.FieldLogger) (*MetadataClassifier, error) {
 	filter := MetadataClassifier{
 		overrideExistingValues: config.OverrideExistingValues,
 		domainKey:              config.SloDomainMetadataKey,
@@ -74,8 +65,11 @@ func NewFromConfig(config metadataClassifierConfig, logger logrus.FieldLogger) (
 		appKey:                 config.SloAppMetadataKey,
 		outputChannel:          make(chan *event.Raw),
 		inputChannel:           make(chan *event.Raw),
-		done:                   false,
-		logger:                 logger,
+		processedEventsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "processed_events_total",
+			Help: "Total number of processed events by operation.",
+		}, []string{"operation"}),
+		logger: logger,
 	}
 	return &filter, nil
 }
@@ -112,25 +106,21 @@ func (e *MetadataClassifier) generateSloClassification(toBeClassified *event.Raw
 	return newClassification
 }
 
-func (e *MetadataClassifier) Run() {
-	go func() {
-		defer func() {
-			close(e.outputChannel)
-			e.done = true
-		}()
-		for newEvent := range e.inputChannel {
-			start := time.Now()
-			if !e.overrideExistingValues && newEvent.IsClassified() {
-				processedEventsTotal.WithLabelValues("skipped").Inc()
-			} else {
-				newClassification := e.generateSloClassification(newEvent)
-				newEvent.SloClassification = &newClassification
-				processedEventsTotal.WithLabelValues("generated-slo-classification").Inc()
-				e.logger.WithField("event", newEvent).WithField("slo-classification", newClassification).Debug("classified new event")
-			}
-			e.outputChannel <- newEvent
-			e.observeDuration(start)
+func (e *MetadataClassifier) Run(_ context.Context) error {
+	defer close(e.outputChannel)
+	for newEvent := range e.inputChannel {
+		start := time.Now()
+		if !e.overrideExistingValues && newEvent.IsClassified() {
+			e.processedEventsTotal.WithLabelValues("skipped").Inc()
+		} else {
+			newClassification := e.generateSloClassification(newEvent)
+			newEvent.SloClassification = &newClassification
+			e.processedEventsTotal.WithLabelValues("generated-slo-classification").Inc()
+			e.logger.WithField("event", newEvent).WithField("slo-classification", newClassification).Debug("classified new event")
 		}
-		e.logger.Info("input channel closed, finishing")
-	}()
+		e.outputChannel <- newEvent
+		e.observeDuration(start)
+	}
+	e.logger.Info("input channel closed, finishing")
+	return nil
 }
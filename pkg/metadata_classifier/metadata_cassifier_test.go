@@ -13,7 +13,7 @@ func TestMetadataClassifier_generateSloClassification(t *testing.T) {
 	testCases := []struct {
 		name   string
 		event  event.Raw
-		config metadataClassifierConfig
+		config MetadataClassifierConfig
 		result event.SloClassification
 	}{
 		{
@@ -22,7 +22,7 @@ func TestMetadataClassifier_generateSloClassification(t *testing.T) {
 				Metadata:          stringmap.StringMap{"domain": "domain", "class": "class", "app": "app"},
 				SloClassification: &event.SloClassification{Domain: "", Class: "", App: ""},
 			},
-			config: metadataClassifierConfig{SloDomainMetadataKey: "domain", SloClassMetadataKey: "class", SloAppMetadataKey: "app", OverrideExistingValues: true},
+			config: MetadataClassifierConfig{SloDomainMetadataKey: "domain", SloClassMetadataKey: "class", SloAppMetadataKey: "app", OverrideExistingValues: true},
 			result: event.SloClassification{Domain: "domain", Class: "class", App: "app"},
 		},
 		{
@@ -31,7 +31,7 @@ func TestMetadataClassifier_generateSloClassification(t *testing.T) {
 				Metadata:          stringmap.StringMap{"domain": "domain", "class": "class", "app": "app"},
 				SloClassification: &event.SloClassification{Domain: "xxx", Class: "xxx", App: "xxx"},
 			},
-			config: metadataClassifierConfig{SloDomainMetadataKey: "domain", SloClassMetadataKey: "class", SloAppMetadataKey: "app", OverrideExistingValues: true},
+			config: MetadataClassifierConfig{SloDomainMetadataKey: "domain", SloClassMetadataKey: "class", SloAppMetadataKey: "app", OverrideExistingValues: true},
 			result: event.SloClassification{Domain: "domain", Class: "class", App: "app"},
 		},
 		{
@@ -40,7 +40,7 @@ func TestMetadataClassifier_generateSloClassification(t *testing.T) {
 				Metadata:          stringmap.StringMap{"domain": "domain", "class": "class", "app": "app"},
 				SloClassification: &event.SloClassification{Domain: "xxx", Class: "xxx", App: "xxx"},
 			},
-			config: metadataClassifierConfig{SloDomainMetadataKey: "domain", SloClassMetadataKey: "class", SloAppMetadataKey: "app", OverrideExistingValues: false},
+			config: MetadataClassifierConfig{SloDomainMetadataKey: "domain", SloClassMetadataKey: "class", SloAppMetadataKey: "app", OverrideExistingValues: false},
 			result: event.SloClassification{Domain: "xxx", Class: "xxx", App: "xxx"},
 		},
 		{
@@ -49,7 +49,7 @@ func TestMetadataClassifier_generateSloClassification(t *testing.T) {
 				Metadata:          stringmap.StringMap{"domain": "domain", "class": "class"},
 				SloClassification: &event.SloClassification{Domain: "xxx", Class: "xxx", App: "xxx"},
 			},
-			config: metadataClassifierConfig{SloDomainMetadataKey: "domain", SloClassMetadataKey: "class", SloAppMetadataKey: "app", OverrideExistingValues: true},
+			config: MetadataClassifierConfig{SloDomainMetadataKey: "domain", SloClassMetadataKey: "class", SloAppMetadataKey: "app", OverrideExistingValues: true},
 			result: event.SloClassification{Domain: "domain", Class: "class", App: "xxx"},
 		},
 	}
@@ -0,0 +1,56 @@
+// Package modules registers the module types distributed with slo-exporter, so they can be used in the pipeline configuration.
+package modules
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/seznam/slo-exporter/pkg/dynamic_classifier"
+	"github.com/seznam/slo-exporter/pkg/envoy_access_log_server"
+	"github.com/seznam/slo-exporter/pkg/event_key_generator"
+	"github.com/seznam/slo-exporter/pkg/event_metadata_renamer"
+	"github.com/seznam/slo-exporter/pkg/fluent_forward_ingester"
+	"github.com/seznam/slo-exporter/pkg/grpc_ingester"
+	"github.com/seznam/slo-exporter/pkg/http_ingester"
+	"github.com/seznam/slo-exporter/pkg/kafka_ingester"
+	"github.com/seznam/slo-exporter/pkg/loki_push_ingester"
+	"github.com/seznam/slo-exporter/pkg/metadata_classifier"
+	"github.com/seznam/slo-exporter/pkg/otlp_logs_ingester"
+	"github.com/seznam/slo-exporter/pkg/otlp_traces_ingester"
+	"github.com/seznam/slo-exporter/pkg/pipeline"
+	"github.com/seznam/slo-exporter/pkg/prometheus_exporter"
+	"github.com/seznam/slo-exporter/pkg/prometheus_ingester"
+	"github.com/seznam/slo-exporter/pkg/relabel"
+	"github.com/seznam/slo-exporter/pkg/slo_event_producer"
+	"github.com/seznam/slo-exporter/pkg/statistical_classifier"
+	"github.com/seznam/slo-exporter/pkg/synthetic_prober"
+	"github.com/seznam/slo-exporter/pkg/syslog_ingester"
+	"github.com/seznam/slo-exporter/pkg/tailer"
+)
+
+// RegisterBuiltinTypes registers all the built-in module types using pipeline.RegisterModuleType.
+// The appVersion is used by modules identifying themselves to other services.
+func RegisterBuiltinTypes(appVersion string) {
+	pipeline.RegisterModuleType("tailer", pipeline.Constructor(tailer.NewFromViper))
+	pipeline.RegisterModuleType("prometheusIngester", func(viperConfig *viper.Viper, logger logrus.FieldLogger) (pipeline.Module, error) {
+		return prometheus_ingester.NewFromViper(viperConfig, logger, appVersion)
+	})
+	pipeline.RegisterModuleType("kafkaIngester", pipeline.Constructor(kafka_ingester.NewFromViper))
+	pipeline.RegisterModuleType("envoyAccessLogServer", pipeline.Constructor(envoy_access_log_server.NewFromViper))
+	pipeline.RegisterModuleType("syslogIngester", pipeline.Constructor(syslog_ingester.NewFromViper))
+	pipeline.RegisterModuleType("fluentForwardIngester", pipeline.Constructor(fluent_forward_ingester.NewFromViper))
+	pipeline.RegisterModuleType("lokiPushIngester", pipeline.Constructor(loki_push_ingester.NewFromViper))
+	pipeline.RegisterModuleType("otlpLogsIngester", pipeline.Constructor(otlp_logs_ingester.NewFromViper))
+	pipeline.RegisterModuleType("otlpTracesIngester", pipeline.Constructor(otlp_traces_ingester.NewFromViper))
+	pipeline.RegisterModuleType("httpIngester", pipeline.Constructor(http_ingester.NewFromViper))
+	pipeline.RegisterModuleType("grpcIngester", pipeline.Constructor(grpc_ingester.NewFromViper))
+	pipeline.RegisterModuleType("syntheticProber", pipeline.Constructor(synthetic_prober.NewFromViper))
+	pipeline.RegisterModuleType("eventMetadataRenamer", pipeline.Constructor(event_metadata_renamer.NewFromViper))
+	pipeline.RegisterModuleType("relabel", pipeline.Constructor(relabel.NewFromViper))
+	pipeline.RegisterModuleType("eventKeyGenerator", pipeline.Constructor(event_key_generator.NewFromViper))
+	pipeline.RegisterModuleType("metadataClassifier", pipeline.Constructor(metadata_classifier.NewFromViper))
+	pipeline.RegisterModuleType("dynamicClassifier", pipeline.Constructor(dynamic_classifier.NewFromViper))
+	pipeline.RegisterModuleType("statisticalClassifier", pipeline.Constructor(statistical_classifier.NewFromViper))
+	pipeline.RegisterModuleType("sloEventProducer", pipeline.Constructor(slo_event_producer.NewFromViper))
+	pipeline.RegisterModuleType("prometheusExporter", pipeline.Constructor(prometheus_exporter.NewFromViper))
+}
@@ -0,0 +1,48 @@
+package otlp
+
+import (
+	"encoding/base64"
+	"strconv"
+
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// AddAttributes adds the attributes to the metadata, keys of the attributes are prefixed with the prefix.
+func AddAttributes(metadata stringmap.StringMap, prefix, keySeparator string, attributes []*commonv1.KeyValue) {
+	for _, attribute := range attributes {
+		AddValue(metadata, prefix+attribute.GetKey(), keySeparator, attribute.GetValue())
+	}
+}
+
+// AddValue adds the value to the metadata under the key. Arrays and key-value lists are flattened
+// joining the key and indexes (or keys) of the items using the key separator, values which are not set are omitted.
+func AddValue(metadata stringmap.StringMap, key, keySeparator string, value *commonv1.AnyValue) {
+	switch typedValue := value.GetValue().(type) {
+	case *commonv1.AnyValue_StringValue:
+		metadata[key] = typedValue.StringValue
+	case *commonv1.AnyValue_BoolValue:
+		metadata[key] = strconv.FormatBool(typedValue.BoolValue)
+	case *commonv1.AnyValue_IntValue:
+		metadata[key] = strconv.FormatInt(typedValue.IntValue, 10)
+	case *commonv1.AnyValue_DoubleValue:
+		metadata[key] = strconv.FormatFloat(typedValue.DoubleValue, 'f', -1, 64)
+	case *commonv1.AnyValue_BytesValue:
+		metadata[key] = base64.StdEncoding.EncodeToString(typedValue.BytesValue)
+	case *commonv1.AnyValue_ArrayValue:
+		for i, item := range typedValue.ArrayValue.GetValues() {
+			AddValue(metadata, joinKeys(key, keySeparator, strconv.Itoa(i)), keySeparator, item)
+		}
+	case *commonv1.AnyValue_KvlistValue:
+		for _, item := range typedValue.KvlistValue.GetValues() {
+			AddValue(metadata, joinKeys(key, keySeparator, item.GetKey()), keySeparator, item.GetValue())
+		}
+	}
+}
+
+func joinKeys(key, keySeparator, nestedKey string) string {
+	if key == "" {
+		return nestedKey
+	}
+	return key + keySeparator + nestedKey
+}
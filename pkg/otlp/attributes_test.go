@@ -0,0 +1,44 @@
+package otlp
+
+import (
+	"testing"
+
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/stretchr/testify/assert"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+func stringValue(value string) *commonv1.AnyValue {
+	return &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: value}}
+}
+
+func TestAddAttributes(t *testing.T) {
+	metadata := stringmap.StringMap{}
+	AddAttributes(metadata, "resource.", "_", []*commonv1.KeyValue{
+		{Key: "service.name", Value: stringValue("web")},
+		{Key: "retry", Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_BoolValue{BoolValue: true}}},
+		{Key: "status", Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_IntValue{IntValue: 200}}},
+		{Key: "duration", Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_DoubleValue{DoubleValue: 0.25}}},
+		{Key: "id", Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_BytesValue{BytesValue: []byte{1, 2, 3}}}},
+		{Key: "hosts", Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_ArrayValue{ArrayValue: &commonv1.ArrayValue{Values: []*commonv1.AnyValue{stringValue("a"), stringValue("b")}}}}},
+		{Key: "http", Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_KvlistValue{KvlistValue: &commonv1.KeyValueList{Values: []*commonv1.KeyValue{{Key: "method", Value: stringValue("GET")}}}}}},
+		{Key: "unset", Value: &commonv1.AnyValue{}},
+		{Key: "nil"},
+	})
+	assert.Equal(t, stringmap.StringMap{
+		"resource.service.name": "web",
+		"resource.retry":        "true",
+		"resource.status":       "200",
+		"resource.duration":     "0.25",
+		"resource.id":           "AQID",
+		"resource.hosts_0":      "a",
+		"resource.hosts_1":      "b",
+		"resource.http_method":  "GET",
+	}, metadata)
+}
+
+func TestAddValue(t *testing.T) {
+	metadata := stringmap.StringMap{}
+	AddValue(metadata, "", ".", &commonv1.AnyValue{Value: &commonv1.AnyValue_KvlistValue{KvlistValue: &commonv1.KeyValueList{Values: []*commonv1.KeyValue{{Key: "method", Value: stringValue("GET")}}}}})
+	assert.Equal(t, stringmap.StringMap{"method": "GET"}, metadata)
+}
@@ -0,0 +1,61 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// hexEncodedFields are the fields of the OTLP messages which are hex encoded in the OTLP/HTTP JSON encoding
+// instead of base64 used for the bytes fields by the protobuf JSON mapping, protojson accepts both original and lowerCamelCase field names.
+var hexEncodedFields = map[string]bool{
+	"traceId":        true,
+	"trace_id":       true,
+	"spanId":         true,
+	"span_id":        true,
+	"parentSpanId":   true,
+	"parent_span_id": true,
+}
+
+// hexIDsToBase64 converts the hex encoded trace and span IDs of the OTLP/HTTP JSON request to base64, so the request can be decoded using protojson.
+func hexIDsToBase64(body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// Numbers are kept as they are, the 64-bit integers would lose precision as float64.
+	decoder.UseNumber()
+	var request interface{}
+	if err := decoder.Decode(&request); err != nil {
+		return nil, err
+	}
+	if err := convertHexIDs(request); err != nil {
+		return nil, err
+	}
+	return json.Marshal(request)
+}
+
+func convertHexIDs(value interface{}) error {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for key, item := range typedValue {
+			if id, ok := item.(string); ok && hexEncodedFields[key] {
+				decoded, err := hex.DecodeString(id)
+				if err != nil {
+					return fmt.Errorf("invalid hex encoded %s '%s': %w", key, id, err)
+				}
+				typedValue[key] = base64.StdEncoding.EncodeToString(decoded)
+				continue
+			}
+			if err := convertHexIDs(item); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range typedValue {
+			if err := convertHexIDs(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	collogsv1 "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestHexIDsToBase64(t *testing.T) {
+	testCases := []struct {
+		name        string
+		body        string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name:     "trace and span IDs",
+			body:     `{"spans": [{"traceId": "5b8efff798038103d269b633813fc60c", "span_id": "eee19b7ec3c1b174", "parentSpanId": ""}]}`,
+			expected: `{"spans": [{"traceId": "W47/95gDgQPSabYzgT/GDA==", "span_id": "7uGbfsPBsXQ=", "parentSpanId": ""}]}`,
+		},
+		{
+			name:     "numbers and other fields are kept",
+			body:     `{"timeUnixNano": "1544712660300000000", "droppedAttributesCount": 18446744073709551615, "attributes": [{"key": "traceId", "value": {"stringValue": "not hex"}}]}`,
+			expected: `{"timeUnixNano": "1544712660300000000", "droppedAttributesCount": 18446744073709551615, "attributes": [{"key": "traceId", "value": {"stringValue": "not hex"}}]}`,
+		},
+		{name: "invalid hex", body: `{"traceId": "W47/95gDgQPSabYzgT/GDA=="}`, expectedErr: true},
+		{name: "invalid JSON", body: `{"traceId":`, expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			converted, err := hexIDsToBase64([]byte(tc.body))
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.JSONEq(t, tc.expected, string(converted))
+		})
+	}
+}
+
+func TestHexIDsToBase64_protojson(t *testing.T) {
+	converted, err := hexIDsToBase64([]byte(`{"resourceLogs": [{"scopeLogs": [{"logRecords": [{"traceId": "5b8efff798038103d269b633813fc60c", "spanId": "eee19b7ec3c1b174"}]}]}]}`))
+	assert.NoError(t, err)
+	request := &collogsv1.ExportLogsServiceRequest{}
+	assert.NoError(t, protojson.Unmarshal(converted, request))
+	record := request.GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()[0]
+	assert.Equal(t, []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c}, record.GetTraceId())
+	assert.Equal(t, []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74}, record.GetSpanId())
+}
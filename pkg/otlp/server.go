@@ -0,0 +1,257 @@
+// Package otlp implements the OTLP/gRPC and OTLP/HTTP receiver shared by the modules ingesting OpenTelemetry data.
+package otlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/internal/httpingest"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Content types of the OTLP/HTTP requests.
+const (
+	protobufContentType = "application/x-protobuf"
+	jsonContentType     = "application/json"
+)
+
+// ServerConfig is configuration of the OTLP receiver, modules embed it to their configuration.
+type ServerConfig struct {
+	// GRPCAddress to listen on for the OTLP/gRPC requests, gRPC is disabled if empty.
+	GRPCAddress string
+	// HTTPAddress to listen on for the OTLP/HTTP requests, HTTP is disabled if empty.
+	HTTPAddress string
+	// GracefulShutdownTimeout limits how long to wait for the pending requests during shutdown.
+	GracefulShutdownTimeout time.Duration
+	// MaxRequestBytes limits size of the request, decompressed size for the gzip encoded requests.
+	MaxRequestBytes int
+}
+
+// SetDefaults sets the default values of the ServerConfig to the viper configuration of the module.
+func SetDefaults(viperConfig *viper.Viper) {
+	viperConfig.SetDefault("GRPCAddress", ":4317")
+	viperConfig.SetDefault("HTTPAddress", ":4318")
+	viperConfig.SetDefault("GracefulShutdownTimeout", 5*time.Second)
+	viperConfig.SetDefault("MaxRequestBytes", 4*1024*1024)
+}
+
+// ExportFunc processes the decoded export request and returns the response.
+// Error should be created using the grpc status package, its code is converted to the HTTP status code.
+type ExportFunc func(ctx context.Context, request proto.Message) (proto.Message, error)
+
+// Server receives the OTLP export requests over gRPC and HTTP.
+type Server struct {
+	grpcAddress             string
+	httpAddress             string
+	gracefulShutdownTimeout time.Duration
+	maxRequestBytes         int
+	grpcServer              *grpc.Server
+	httpMux                 *http.ServeMux
+	logger                  logrus.FieldLogger
+}
+
+// NewServer returns Server, the services have to be registered before it is run.
+func NewServer(config ServerConfig, logger logrus.FieldLogger) (*Server, error) {
+	if config.GRPCAddress == "" && config.HTTPAddress == "" {
+		return nil, fmt.Errorf("at least one of the gRPC and HTTP addresses has to be configured")
+	}
+	if config.MaxRequestBytes <= 0 {
+		return nil, fmt.Errorf("maximum request size must be positive, got %d", config.MaxRequestBytes)
+	}
+	return &Server{
+		grpcAddress:             config.GRPCAddress,
+		httpAddress:             config.HTTPAddress,
+		gracefulShutdownTimeout: config.GracefulShutdownTimeout,
+		maxRequestBytes:         config.MaxRequestBytes,
+		grpcServer:              grpc.NewServer(grpc.MaxRecvMsgSize(config.MaxRequestBytes)),
+		httpMux:                 http.NewServeMux(),
+		logger:                  logger,
+	}, nil
+}
+
+// GRPCServer returns the gRPC server to register the services to.
+func (s *Server) GRPCServer() *grpc.Server {
+	return s.grpcServer
+}
+
+// HandleExport registers the OTLP/HTTP handler of the export requests at the path, e.g. `/v1/logs`.
+func (s *Server) HandleExport(path string, newRequest func() proto.Message, export ExportFunc) {
+	s.httpMux.Handle(path, &exportHandler{
+		maxRequestBytes: int64(s.maxRequestBytes),
+		newRequest:      newRequest,
+		export:          export,
+		logger:          s.logger,
+	})
+}
+
+// Run serves the requests until the context is cancelled.
+func (s *Server) Run(ctx context.Context) error {
+	var grpcListener net.Listener
+	var httpServer *httpingest.Server
+	var err error
+	if s.grpcAddress != "" {
+		if grpcListener, err = net.Listen("tcp", s.grpcAddress); err != nil {
+			return fmt.Errorf("failed to listen for the OTLP/gRPC requests: %w", err)
+		}
+		s.logger.Infof("listening for the OTLP/gRPC requests on %s", grpcListener.Addr())
+	}
+	if s.httpAddress != "" {
+		if httpServer, err = httpingest.Listen(s.httpAddress, s.httpMux, s.gracefulShutdownTimeout); err != nil {
+			if grpcListener != nil {
+				grpcListener.Close()
+			}
+			return fmt.Errorf("failed to listen for the OTLP/HTTP requests: %w", err)
+		}
+		s.logger.Infof("listening for the OTLP/HTTP requests on %s", httpServer.Addr())
+	}
+
+	grpcErr := make(chan error, 1)
+	if grpcListener != nil {
+		go func() {
+			if err := s.grpcServer.Serve(grpcListener); err != nil {
+				grpcErr <- err
+			}
+		}()
+	}
+	// The HTTP server is shut down also if the gRPC server fails.
+	httpCtx, stopHTTP := context.WithCancel(ctx)
+	defer stopHTTP()
+	var httpStopped chan error
+	if httpServer != nil {
+		httpStopped = make(chan error, 1)
+		go func() {
+			httpStopped <- httpServer.Run(httpCtx)
+		}()
+	}
+	var result error
+	select {
+	case err := <-grpcErr:
+		result = fmt.Errorf("OTLP server fatal error: %w", err)
+	case err := <-httpStopped:
+		result = fmt.Errorf("OTLP server fatal error: %w", err)
+		httpStopped = nil
+	case <-ctx.Done():
+	}
+	stopHTTP()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.gracefulShutdownTimeout)
+	defer cancel()
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	if httpStopped != nil {
+		<-httpStopped
+	}
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		s.grpcServer.Stop()
+	}
+	return result
+}
+
+// exportHandler handles the OTLP/HTTP export requests encoded as protobuf or JSON.
+type exportHandler struct {
+	maxRequestBytes int64
+	newRequest      func() proto.Message
+	export          ExportFunc
+	logger          logrus.FieldLogger
+}
+
+func (h *exportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if r.Method != http.MethodPost {
+		h.writeError(w, contentType, http.StatusMethodNotAllowed, status.Newf(codes.InvalidArgument, "method %s is not allowed", r.Method))
+		return
+	}
+	if contentType != protobufContentType && contentType != jsonContentType {
+		h.writeError(w, protobufContentType, http.StatusUnsupportedMediaType, status.Newf(codes.InvalidArgument, "unsupported content type '%s', expected %s or %s", contentType, protobufContentType, jsonContentType))
+		return
+	}
+	body, err := httpingest.ReadBody(r, h.maxRequestBytes)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.writeError(w, contentType, http.StatusRequestEntityTooLarge, status.New(codes.InvalidArgument, err.Error()))
+		} else {
+			h.writeError(w, contentType, http.StatusBadRequest, status.New(codes.InvalidArgument, err.Error()))
+		}
+		return
+	}
+	request := h.newRequest()
+	if contentType == jsonContentType {
+		if body, err = hexIDsToBase64(body); err == nil {
+			err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, request)
+		}
+	} else {
+		err = proto.Unmarshal(body, request)
+	}
+	if err != nil {
+		h.writeError(w, contentType, http.StatusBadRequest, status.Newf(codes.InvalidArgument, "invalid request: %v", err))
+		return
+	}
+	response, err := h.export(r.Context(), request)
+	if err != nil {
+		exportStatus := status.Convert(err)
+		h.writeError(w, contentType, httpStatusCode(exportStatus.Code()), exportStatus)
+		return
+	}
+	h.write(w, contentType, http.StatusOK, response)
+}
+
+// httpStatusCode returns the HTTP status code of the failed export, the retryable codes are converted to 503 so the clients retry the request.
+func httpStatusCode(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unavailable, codes.Canceled, codes.DeadlineExceeded, codes.Aborted:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError writes the google.rpc.Status message as required by the OTLP/HTTP specification.
+func (h *exportHandler) writeError(w http.ResponseWriter, contentType string, code int, errStatus *status.Status) {
+	if code >= http.StatusInternalServerError {
+		h.logger.Errorf("failed to process the OTLP request: %s", errStatus.Message())
+	} else {
+		h.logger.Warnf("invalid OTLP request: %s", errStatus.Message())
+	}
+	h.write(w, contentType, code, errStatus.Proto())
+}
+
+func (h *exportHandler) write(w http.ResponseWriter, contentType string, code int, message proto.Message) {
+	var body []byte
+	var err error
+	if contentType == jsonContentType {
+		body, err = protojson.Marshal(message)
+	} else {
+		contentType = protobufContentType
+		body, err = proto.Marshal(message)
+	}
+	if err != nil {
+		h.logger.Errorf("failed to encode the OTLP response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	if _, err := w.Write(body); err != nil {
+		h.logger.Debugf("failed to write the OTLP response: %v", err)
+	}
+}
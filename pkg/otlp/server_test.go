@@ -0,0 +1,98 @@
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestNewServer(t *testing.T) {
+	_, err := NewServer(ServerConfig{GRPCAddress: ":4317", MaxRequestBytes: 1024}, logrus.New())
+	assert.NoError(t, err)
+	_, err = NewServer(ServerConfig{MaxRequestBytes: 1024}, logrus.New())
+	assert.Error(t, err)
+	_, err = NewServer(ServerConfig{HTTPAddress: ":4318"}, logrus.New())
+	assert.Error(t, err)
+}
+
+func TestExportHandler(t *testing.T) {
+	handler := &exportHandler{
+		maxRequestBytes: 1024,
+		newRequest:      func() proto.Message { return &wrapperspb.StringValue{} },
+		export: func(_ context.Context, request proto.Message) (proto.Message, error) {
+			value := request.(*wrapperspb.StringValue).GetValue()
+			if value == "unavailable" {
+				return nil, status.Error(codes.Unavailable, "shutting down")
+			}
+			return wrapperspb.String("echo " + value), nil
+		},
+		logger: logrus.New(),
+	}
+	protobufBody, err := proto.Marshal(wrapperspb.String("protobuf"))
+	assert.NoError(t, err)
+	unavailableBody, err := proto.Marshal(wrapperspb.String("unavailable"))
+	assert.NoError(t, err)
+	var gzipBody bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipBody)
+	_, err = gzipWriter.Write(protobufBody)
+	assert.NoError(t, err)
+	assert.NoError(t, gzipWriter.Close())
+
+	tests := []struct {
+		name            string
+		method          string
+		contentType     string
+		contentEncoding string
+		body            []byte
+		expCode         int
+		expContentType  string
+		expResponse     proto.Message
+	}{
+		{name: "protobuf", method: http.MethodPost, contentType: "application/x-protobuf", body: protobufBody, expCode: http.StatusOK, expContentType: "application/x-protobuf", expResponse: wrapperspb.String("echo protobuf")},
+		{name: "JSON", method: http.MethodPost, contentType: "application/json; charset=utf-8", body: []byte(`"json"`), expCode: http.StatusOK, expContentType: "application/json", expResponse: wrapperspb.String("echo json")},
+		{name: "gzip", method: http.MethodPost, contentType: "application/x-protobuf", contentEncoding: "gzip", body: gzipBody.Bytes(), expCode: http.StatusOK, expContentType: "application/x-protobuf", expResponse: wrapperspb.String("echo protobuf")},
+		{name: "invalid method", method: http.MethodGet, contentType: "application/x-protobuf", expCode: http.StatusMethodNotAllowed, expContentType: "application/x-protobuf"},
+		{name: "unsupported content type", method: http.MethodPost, contentType: "text/plain", body: []byte("text"), expCode: http.StatusUnsupportedMediaType, expContentType: "application/x-protobuf"},
+		{name: "invalid JSON", method: http.MethodPost, contentType: "application/json", body: []byte(`{`), expCode: http.StatusBadRequest, expContentType: "application/json"},
+		{name: "invalid gzip", method: http.MethodPost, contentType: "application/x-protobuf", contentEncoding: "gzip", body: protobufBody, expCode: http.StatusBadRequest, expContentType: "application/x-protobuf"},
+		{name: "too large", method: http.MethodPost, contentType: "application/json", body: bytes.Repeat([]byte(" "), 2048), expCode: http.StatusRequestEntityTooLarge, expContentType: "application/json"},
+		{name: "unavailable", method: http.MethodPost, contentType: "application/x-protobuf", body: unavailableBody, expCode: http.StatusServiceUnavailable, expContentType: "application/x-protobuf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, "/v1/logs", bytes.NewReader(tt.body))
+			request.Header.Set("Content-Type", tt.contentType)
+			request.Header.Set("Content-Encoding", tt.contentEncoding)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, tt.expCode, recorder.Code)
+			assert.Equal(t, tt.expContentType, recorder.Header().Get("Content-Type"))
+			var response proto.Message = &spb.Status{}
+			if tt.expResponse != nil {
+				response = &wrapperspb.StringValue{}
+			}
+			if tt.expContentType == "application/json" {
+				assert.NoError(t, protojson.Unmarshal(recorder.Body.Bytes(), response))
+			} else {
+				assert.NoError(t, proto.Unmarshal(recorder.Body.Bytes(), response))
+			}
+			if tt.expResponse != nil {
+				assert.True(t, proto.Equal(tt.expResponse, response), response)
+			} else {
+				assert.NotEmpty(t, response.(*spb.Status).GetMessage())
+			}
+		})
+	}
+}
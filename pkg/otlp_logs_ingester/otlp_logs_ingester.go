@@ -0,0 +1,250 @@
+package otlp_logs_ingester
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/internal/shutdown"
+	"github.com/seznam/slo-exporter/pkg/otlp"
+	"github.com/seznam/slo-exporter/pkg/pipeline"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	collogsv1 "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// logsPath is the path of the OTLP/HTTP logs export.
+const logsPath = "/v1/logs"
+
+// Metadata keys of the trace context of the log record.
+const (
+	traceIDKey = "traceId"
+	spanIDKey  = "spanId"
+)
+
+// Reasons of the rejected log records used as a label of the rejected_log_records_total metric.
+const (
+	reasonEmptyRecord  = "emptyRecord"
+	reasonShuttingDown = "shuttingDown"
+)
+
+// OtlpLogsIngesterConfig is configuration of the OTLP logs ingester module.
+type OtlpLogsIngesterConfig struct {
+	// Config of the OTLP/gRPC and OTLP/HTTP server.
+	otlp.ServerConfig `mapstructure:",squash"`
+	// ResourceAttributePrefix prefixes the metadata keys of the resource attributes.
+	ResourceAttributePrefix string
+	// ScopeAttributePrefix prefixes the metadata keys of the instrumentation scope attributes.
+	ScopeAttributePrefix string
+	// AttributePrefix prefixes the metadata keys of the log record attributes.
+	AttributePrefix string
+	// BodyKey is the metadata key of the body, keys of the body which is a map are prefixed with it.
+	BodyKey string
+	// KeySeparator joins keys of the nested maps and indexes of arrays when flattening them to metadata keys.
+	KeySeparator string
+}
+
+// OtlpLogsIngester produces events from the log records exported using the OTLP.
+type OtlpLogsIngester struct {
+	server                  *otlp.Server
+	resourceAttributePrefix string
+	scopeAttributePrefix    string
+	attributePrefix         string
+	bodyKey                 string
+	keySeparator            string
+	outputChannel           chan *event.Raw
+	observer                pipeline.EventProcessingDurationObserver
+	deadLetterSink          pipeline.DeadLetterSink
+	logger                  logrus.FieldLogger
+
+	logRecordsTotal         prometheus.Counter
+	rejectedLogRecordsTotal *prometheus.CounterVec
+}
+
+func (o *OtlpLogsIngester) String() string {
+	return "otlpLogsIngester"
+}
+
+func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*OtlpLogsIngester, error) {
+	otlp.SetDefaults(viperConfig)
+	viperConfig.SetDefault("ResourceAttributePrefix", "resource.")
+	viperConfig.SetDefault("ScopeAttributePrefix", "scope.")
+	viperConfig.SetDefault("AttributePrefix", "")
+	viperConfig.SetDefault("BodyKey", "body")
+	viperConfig.SetDefault("KeySeparator", ".")
+	var config OtlpLogsIngesterConfig
+	if err := viperConfig.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return New(config, logger)
+}
+
+// New returns an instance of OtlpLogsIngester.
+func New(config OtlpLogsIngesterConfig, logger logrus.FieldLogger) (*OtlpLogsIngester, error) {
+	server, err := otlp.NewServer(config.ServerConfig, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &OtlpLogsIngester{
+		server:                  server,
+		resourceAttributePrefix: config.ResourceAttributePrefix,
+		scopeAttributePrefix:    config.ScopeAttributePrefix,
+		attributePrefix:         config.AttributePrefix,
+		bodyKey:                 config.BodyKey,
+		keySeparator:            config.KeySeparator,
+		outputChannel:           make(chan *event.Raw),
+		logger:                  logger,
+		logRecordsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "processed_log_records_total",
+			Help: "Total number of processed log records.",
+		}),
+		rejectedLogRecordsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rejected_log_records_total",
+			Help: "Total number of log records rejected in the partial success response by the reason.",
+		}, []string{"reason"}),
+	}, nil
+}
+
+func (o *OtlpLogsIngester) RegisterEventProcessingDurationObserver(observer pipeline.EventProcessingDurationObserver) {
+	o.observer = observer
+}
+
+func (o *OtlpLogsIngester) observeDuration(start time.Time) {
+	if o.observer != nil {
+		o.observer.Observe(time.Since(start).Seconds())
+	}
+}
+
+func (o *OtlpLogsIngester) SetDeadLetterSink(sink pipeline.DeadLetterSink) {
+	o.deadLetterSink = sink
+}
+
+func (o *OtlpLogsIngester) reject(record *logsv1.LogRecord, reason string, err error) {
+	o.rejectedLogRecordsTotal.WithLabelValues(reason).Inc()
+	if o.deadLetterSink != nil {
+		payload, _ := protojson.Marshal(record)
+		o.deadLetterSink.Reject(string(payload), err.Error())
+	}
+}
+
+func (o *OtlpLogsIngester) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
+	toRegister := []prometheus.Collector{o.logRecordsTotal, o.rejectedLogRecordsTotal}
+	for _, collector := range toRegister {
+		if err := wrappedRegistry.Register(collector); err != nil {
+			return fmt.Errorf("error registering metric %s: %w", collector, err)
+		}
+	}
+	return nil
+}
+
+func (o *OtlpLogsIngester) OutputChannel() chan *event.Raw {
+	return o.outputChannel
+}
+
+// Run serves the OTLP logs export feeding events to output channel until the context is cancelled.
+func (o *OtlpLogsIngester) Run(ctx context.Context) error {
+	defer close(o.outputChannel)
+	service := &logsService{ingester: o, ctx: ctx}
+	collogsv1.RegisterLogsServiceServer(o.server.GRPCServer(), service)
+	o.server.HandleExport(logsPath, func() proto.Message { return &collogsv1.ExportLogsServiceRequest{} }, func(ctx context.Context, request proto.Message) (proto.Message, error) {
+		return service.Export(ctx, request.(*collogsv1.ExportLogsServiceRequest))
+	})
+	if err := o.server.Run(ctx); err != nil {
+		return fmt.Errorf("%s failed: %w", o, err)
+	}
+	return nil
+}
+
+// logsService implements the OTLP logs service, the events are emitted until the ctx is cancelled.
+type logsService struct {
+	collogsv1.UnimplementedLogsServiceServer
+	ingester *OtlpLogsIngester
+	ctx      context.Context
+}
+
+// Export emits events of the log records, records which cannot be processed are reported in the partial success of the response.
+func (s *logsService) Export(ctx context.Context, request *collogsv1.ExportLogsServiceRequest) (*collogsv1.ExportLogsServiceResponse, error) {
+	o := s.ingester
+	var total, emitted, rejected int64
+	var rejectErr error
+	shuttingDown := false
+	for _, resourceLogs := range request.GetResourceLogs() {
+		resourceMetadata := stringmap.StringMap{}
+		otlp.AddAttributes(resourceMetadata, o.resourceAttributePrefix, o.keySeparator, resourceLogs.GetResource().GetAttributes())
+		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+			scopeMetadata := resourceMetadata.Copy()
+			otlp.AddAttributes(scopeMetadata, o.scopeAttributePrefix, o.keySeparator, scopeLogs.GetScope().GetAttributes())
+			for _, record := range scopeLogs.GetLogRecords() {
+				total++
+				if shuttingDown {
+					continue
+				}
+				newEvent, err := o.newEvent(scopeMetadata, record)
+				if err != nil {
+					rejected++
+					rejectErr = err
+					o.reject(record, reasonEmptyRecord, err)
+					continue
+				}
+				select {
+				case o.outputChannel <- newEvent:
+					emitted++
+				case <-ctx.Done():
+					return nil, status.FromContextError(ctx.Err()).Err()
+				case <-s.ctx.Done():
+					shuttingDown = true
+				}
+			}
+		}
+	}
+	if shuttingDown {
+		remaining, err := shutdown.RejectRemaining(o, int(emitted), int(total-rejected))
+		if err != nil {
+			return nil, err
+		}
+		o.rejectedLogRecordsTotal.WithLabelValues(reasonShuttingDown).Add(float64(remaining))
+		rejected += int64(remaining)
+		rejectErr = &shutdown.Error{Module: o}
+	}
+	response := &collogsv1.ExportLogsServiceResponse{}
+	if rejected > 0 {
+		response.PartialSuccess = &collogsv1.ExportLogsPartialSuccess{RejectedLogRecords: rejected, ErrorMessage: rejectErr.Error()}
+	}
+	return response, nil
+}
+
+// newEvent returns event of the log record with metadata of the resource and scope.
+func (o *OtlpLogsIngester) newEvent(scopeMetadata stringmap.StringMap, record *logsv1.LogRecord) (*event.Raw, error) {
+	start := time.Now()
+	defer o.observeDuration(start)
+	o.logRecordsTotal.Inc()
+	if len(record.GetAttributes()) == 0 && record.GetBody().GetValue() == nil {
+		return nil, fmt.Errorf("log record has neither attributes nor body")
+	}
+	metadata := scopeMetadata.Copy()
+	otlp.AddAttributes(metadata, o.attributePrefix, o.keySeparator, record.GetAttributes())
+	otlp.AddValue(metadata, o.bodyKey, o.keySeparator, record.GetBody())
+	if len(record.GetTraceId()) > 0 {
+		metadata[traceIDKey] = hex.EncodeToString(record.GetTraceId())
+	}
+	if len(record.GetSpanId()) > 0 {
+		metadata[spanIDKey] = hex.EncodeToString(record.GetSpanId())
+	}
+	timestamp := record.GetTimeUnixNano()
+	if timestamp == 0 {
+		timestamp = record.GetObservedTimeUnixNano()
+	}
+	var eventTime time.Time
+	if timestamp != 0 {
+		eventTime = time.Unix(0, int64(timestamp))
+	}
+	return &event.Raw{Metadata: metadata, Quantity: 1, Timestamp: eventTime}, nil
+}
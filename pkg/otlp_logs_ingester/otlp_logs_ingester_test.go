@@ -0,0 +1,159 @@
+package otlp_logs_ingester
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/internal/moduletest"
+	"github.com/seznam/slo-exporter/pkg/otlp"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	collogsv1 "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcev1 "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func newTestIngester(t *testing.T, grpcAddress, httpAddress string) *OtlpLogsIngester {
+	ingester, err := New(OtlpLogsIngesterConfig{
+		ServerConfig: otlp.ServerConfig{
+			GRPCAddress:             grpcAddress,
+			HTTPAddress:             httpAddress,
+			GracefulShutdownTimeout: time.Second,
+			MaxRequestBytes:         1024 * 1024,
+		},
+		ResourceAttributePrefix: "resource.",
+		ScopeAttributePrefix:    "scope.",
+		BodyKey:                 "body",
+		KeySeparator:            ".",
+	}, logrus.New())
+	assert.NoError(t, err)
+	return ingester
+}
+
+func stringAttribute(key, value string) *commonv1.KeyValue {
+	return &commonv1.KeyValue{Key: key, Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: value}}}
+}
+
+func exportRequest(records ...*logsv1.LogRecord) *collogsv1.ExportLogsServiceRequest {
+	return &collogsv1.ExportLogsServiceRequest{ResourceLogs: []*logsv1.ResourceLogs{{
+		Resource: &resourcev1.Resource{Attributes: []*commonv1.KeyValue{stringAttribute("service.name", "web")}},
+		ScopeLogs: []*logsv1.ScopeLogs{{
+			Scope:      &commonv1.InstrumentationScope{Name: "access", Attributes: []*commonv1.KeyValue{stringAttribute("version", "1")}},
+			LogRecords: records,
+		}},
+	}}}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(OtlpLogsIngesterConfig{}, logrus.New())
+	assert.Error(t, err)
+}
+
+func TestLogsService_Export(t *testing.T) {
+	ingester := newTestIngester(t, ":4317", "")
+	deadLetterSink := &moduletest.DeadLetterSink{}
+	ingester.SetDeadLetterSink(deadLetterSink)
+	service := &logsService{ingester: ingester, ctx: context.Background()}
+
+	collected := moduletest.CollectEvents(ingester.OutputChannel(), 2)
+	response, err := service.Export(context.Background(), exportRequest(
+		&logsv1.LogRecord{
+			TimeUnixNano: 1704103200123456789,
+			Attributes:   []*commonv1.KeyValue{stringAttribute("method", "GET"), stringAttribute("service.name", "api")},
+			Body:         &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: "GET /"}},
+		},
+		&logsv1.LogRecord{},
+		&logsv1.LogRecord{
+			ObservedTimeUnixNano: 1704103200000000000,
+			Body: &commonv1.AnyValue{Value: &commonv1.AnyValue_KvlistValue{KvlistValue: &commonv1.KeyValueList{Values: []*commonv1.KeyValue{
+				stringAttribute("status", "200"),
+			}}}},
+		},
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, []*event.Raw{
+		{
+			Metadata:  stringmap.StringMap{"resource.service.name": "web", "scope.version": "1", "method": "GET", "service.name": "api", "body": "GET /"},
+			Quantity:  1,
+			Timestamp: time.Unix(0, 1704103200123456789),
+		},
+		{
+			Metadata:  stringmap.StringMap{"resource.service.name": "web", "scope.version": "1", "body.status": "200"},
+			Quantity:  1,
+			Timestamp: time.Unix(1704103200, 0),
+		},
+	}, <-collected)
+	assert.Equal(t, int64(1), response.GetPartialSuccess().GetRejectedLogRecords())
+	assert.NotEmpty(t, response.GetPartialSuccess().GetErrorMessage())
+	assert.Equal(t, []interface{}{"{}"}, deadLetterSink.Payloads)
+	assert.Equal(t, 3.0, testutil.ToFloat64(ingester.logRecordsTotal))
+	assert.Equal(t, 1.0, testutil.ToFloat64(ingester.rejectedLogRecordsTotal.WithLabelValues(reasonEmptyRecord)))
+}
+
+func TestLogsService_ExportShuttingDown(t *testing.T) {
+	ingester := newTestIngester(t, ":4317", "")
+	ctx, cancel := context.WithCancel(context.Background())
+	service := &logsService{ingester: ingester, ctx: ctx}
+	record := &logsv1.LogRecord{Body: &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: "GET /"}}}
+
+	// Rest of the records is rejected if some of them were already emitted.
+	go func() {
+		<-ingester.OutputChannel()
+		cancel()
+	}()
+	response, err := service.Export(context.Background(), exportRequest(record, record, record))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), response.GetPartialSuccess().GetRejectedLogRecords())
+	assert.Equal(t, 2.0, testutil.ToFloat64(ingester.rejectedLogRecordsTotal.WithLabelValues(reasonShuttingDown)))
+
+	// Whole request can be retried if none of the records was emitted.
+	_, err = service.Export(context.Background(), exportRequest(record))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestOtlpLogsIngester_Run(t *testing.T) {
+	grpcAddress, httpAddress := moduletest.FreeAddress(t), moduletest.FreeAddress(t)
+	ingester := newTestIngester(t, grpcAddress, httpAddress)
+	stop := moduletest.Run(t, ingester)
+
+	collected := moduletest.CollectEvents(ingester.OutputChannel(), 1)
+	body := `{"resourceLogs": [{"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "web"}}]}, "scopeLogs": [{"logRecords": [{"timeUnixNano": "1704103200123456789", "traceId": "5b8efff798038103d269b633813fc60c", "spanId": "eee19b7ec3c1b174", "body": {"stringValue": "GET /"}}]}]}]}`
+	assert.Eventually(t, func() bool {
+		response, err := http.Post("http://"+httpAddress+logsPath, "application/json", strings.NewReader(body))
+		if err != nil {
+			return false
+		}
+		response.Body.Close()
+		return response.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []*event.Raw{{
+		Metadata:  stringmap.StringMap{"resource.service.name": "web", "body": "GET /", "traceId": "5b8efff798038103d269b633813fc60c", "spanId": "eee19b7ec3c1b174"},
+		Quantity:  1,
+		Timestamp: time.Unix(0, 1704103200123456789),
+	}}, <-collected)
+
+	connection, err := grpc.NewClient(grpcAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer connection.Close()
+	collected = moduletest.CollectEvents(ingester.OutputChannel(), 1)
+	response, err := collogsv1.NewLogsServiceClient(connection).Export(context.Background(), exportRequest(&logsv1.LogRecord{Attributes: []*commonv1.KeyValue{stringAttribute("method", "GET")}}))
+	assert.NoError(t, err)
+	assert.Nil(t, response.GetPartialSuccess())
+	assert.Equal(t, []*event.Raw{{
+		Metadata: stringmap.StringMap{"resource.service.name": "web", "scope.version": "1", "method": "GET"},
+		Quantity: 1,
+	}}, <-collected)
+
+	stop()
+}
@@ -0,0 +1,343 @@
+package otlp_traces_ingester
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/internal/shutdown"
+	"github.com/seznam/slo-exporter/pkg/otlp"
+	"github.com/seznam/slo-exporter/pkg/pipeline"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	coltracev1 "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracev1 "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// tracesPath is the path of the OTLP/HTTP traces export.
+const tracesPath = "/v1/traces"
+
+// Reasons of the rejected spans used as a label of the rejected_spans_total metric.
+const (
+	reasonInvalidSpan  = "invalidSpan"
+	reasonShuttingDown = "shuttingDown"
+)
+
+// Metadata keys of the span fields.
+const (
+	traceIDKey       = "traceId"
+	spanIDKey        = "spanId"
+	parentSpanIDKey  = "parentSpanId"
+	spanNameKey      = "spanName"
+	spanKindKey      = "spanKind"
+	statusCodeKey    = "statusCode"
+	statusMessageKey = "statusMessage"
+	durationKey      = "duration"
+)
+
+// semanticConventionAliases maps the deprecated HTTP semantic convention attributes to the current ones,
+// so the rules work with spans of both older and newer instrumentations.
+var semanticConventionAliases = map[string]string{
+	"http.method":      "http.request.method",
+	"http.status_code": "http.response.status_code",
+}
+
+// AttributeFilter selects the spans by value of the metadata key.
+type AttributeFilter struct {
+	// Key of the metadata, i.e. the attribute key including its prefix.
+	Key string
+	// Regexp which has to match the whole value, missing key is matched as an empty string.
+	Regexp string
+}
+
+// OtlpTracesIngesterConfig is configuration of the OTLP traces ingester module.
+type OtlpTracesIngesterConfig struct {
+	// Config of the OTLP/gRPC and OTLP/HTTP server.
+	otlp.ServerConfig `mapstructure:",squash"`
+	// SpanKinds of the spans to produce events of, e.g. SERVER or CONSUMER.
+	SpanKinds []string
+	// AttributeFilters select the spans, all of them have to match.
+	AttributeFilters []AttributeFilter
+	// ResourceAttributePrefix prefixes the metadata keys of the resource attributes.
+	ResourceAttributePrefix string
+	// ScopeAttributePrefix prefixes the metadata keys of the instrumentation scope attributes.
+	ScopeAttributePrefix string
+	// AttributePrefix prefixes the metadata keys of the span attributes.
+	AttributePrefix string
+	// KeySeparator joins keys of the nested maps and indexes of arrays when flattening them to metadata keys.
+	KeySeparator string
+}
+
+// attributeFilter is the compiled AttributeFilter.
+type attributeFilter struct {
+	key    string
+	regexp *regexp.Regexp
+}
+
+func (f attributeFilter) matches(metadata stringmap.StringMap) bool {
+	return f.regexp.MatchString(metadata[f.key])
+}
+
+// OtlpTracesIngester produces events from the spans exported using the OTLP.
+type OtlpTracesIngester struct {
+	server                  *otlp.Server
+	spanKinds               map[tracev1.Span_SpanKind]bool
+	attributeFilters        []attributeFilter
+	resourceAttributePrefix string
+	scopeAttributePrefix    string
+	attributePrefix         string
+	keySeparator            string
+	outputChannel           chan *event.Raw
+	observer                pipeline.EventProcessingDurationObserver
+	deadLetterSink          pipeline.DeadLetterSink
+	logger                  logrus.FieldLogger
+
+	spansTotal         prometheus.Counter
+	filteredSpansTotal prometheus.Counter
+	rejectedSpansTotal *prometheus.CounterVec
+}
+
+func (o *OtlpTracesIngester) String() string {
+	return "otlpTracesIngester"
+}
+
+func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*OtlpTracesIngester, error) {
+	otlp.SetDefaults(viperConfig)
+	viperConfig.SetDefault("SpanKinds", []string{"SERVER", "CONSUMER"})
+	viperConfig.SetDefault("ResourceAttributePrefix", "resource.")
+	viperConfig.SetDefault("ScopeAttributePrefix", "scope.")
+	viperConfig.SetDefault("AttributePrefix", "")
+	viperConfig.SetDefault("KeySeparator", ".")
+	var config OtlpTracesIngesterConfig
+	if err := viperConfig.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return New(config, logger)
+}
+
+// New returns an instance of OtlpTracesIngester.
+func New(config OtlpTracesIngesterConfig, logger logrus.FieldLogger) (*OtlpTracesIngester, error) {
+	if len(config.SpanKinds) == 0 {
+		return nil, fmt.Errorf("at least one span kind has to be configured")
+	}
+	spanKinds := make(map[tracev1.Span_SpanKind]bool, len(config.SpanKinds))
+	for _, kind := range config.SpanKinds {
+		value, ok := tracev1.Span_SpanKind_value["SPAN_KIND_"+strings.ToUpper(kind)]
+		if !ok {
+			return nil, fmt.Errorf("unknown span kind '%s', possible options are: SERVER, CONSUMER, CLIENT, PRODUCER, INTERNAL and UNSPECIFIED", kind)
+		}
+		spanKinds[tracev1.Span_SpanKind(value)] = true
+	}
+	attributeFilters := make([]attributeFilter, 0, len(config.AttributeFilters))
+	for _, filter := range config.AttributeFilters {
+		if filter.Key == "" {
+			return nil, fmt.Errorf("key of the attribute filter must not be empty")
+		}
+		re, err := regexp.Compile("^(?:" + filter.Regexp + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp of the attribute filter for key '%s': %w", filter.Key, err)
+		}
+		attributeFilters = append(attributeFilters, attributeFilter{key: filter.Key, regexp: re})
+	}
+	server, err := otlp.NewServer(config.ServerConfig, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &OtlpTracesIngester{
+		server:                  server,
+		spanKinds:               spanKinds,
+		attributeFilters:        attributeFilters,
+		resourceAttributePrefix: config.ResourceAttributePrefix,
+		scopeAttributePrefix:    config.ScopeAttributePrefix,
+		attributePrefix:         config.AttributePrefix,
+		keySeparator:            config.KeySeparator,
+		outputChannel:           make(chan *event.Raw),
+		logger:                  logger,
+		spansTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "processed_spans_total",
+			Help: "Total number of processed spans.",
+		}),
+		filteredSpansTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "filtered_spans_total",
+			Help: "Total number of spans dropped because of not matching the span kinds or attribute filters.",
+		}),
+		rejectedSpansTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rejected_spans_total",
+			Help: "Total number of spans rejected in the partial success response by the reason.",
+		}, []string{"reason"}),
+	}, nil
+}
+
+func (o *OtlpTracesIngester) RegisterEventProcessingDurationObserver(observer pipeline.EventProcessingDurationObserver) {
+	o.observer = observer
+}
+
+func (o *OtlpTracesIngester) observeDuration(start time.Time) {
+	if o.observer != nil {
+		o.observer.Observe(time.Since(start).Seconds())
+	}
+}
+
+func (o *OtlpTracesIngester) SetDeadLetterSink(sink pipeline.DeadLetterSink) {
+	o.deadLetterSink = sink
+}
+
+func (o *OtlpTracesIngester) reject(span *tracev1.Span, reason string, err error) {
+	o.rejectedSpansTotal.WithLabelValues(reason).Inc()
+	if o.deadLetterSink != nil {
+		payload, _ := protojson.Marshal(span)
+		o.deadLetterSink.Reject(string(payload), err.Error())
+	}
+}
+
+func (o *OtlpTracesIngester) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
+	toRegister := []prometheus.Collector{o.spansTotal, o.filteredSpansTotal, o.rejectedSpansTotal}
+	for _, collector := range toRegister {
+		if err := wrappedRegistry.Register(collector); err != nil {
+			return fmt.Errorf("error registering metric %s: %w", collector, err)
+		}
+	}
+	return nil
+}
+
+func (o *OtlpTracesIngester) OutputChannel() chan *event.Raw {
+	return o.outputChannel
+}
+
+// Run serves the OTLP traces export feeding events to output channel until the context is cancelled.
+func (o *OtlpTracesIngester) Run(ctx context.Context) error {
+	defer close(o.outputChannel)
+	service := &traceService{ingester: o, ctx: ctx}
+	coltracev1.RegisterTraceServiceServer(o.server.GRPCServer(), service)
+	o.server.HandleExport(tracesPath, func() proto.Message { return &coltracev1.ExportTraceServiceRequest{} }, func(ctx context.Context, request proto.Message) (proto.Message, error) {
+		return service.Export(ctx, request.(*coltracev1.ExportTraceServiceRequest))
+	})
+	if err := o.server.Run(ctx); err != nil {
+		return fmt.Errorf("%s failed: %w", o, err)
+	}
+	return nil
+}
+
+// traceService implements the OTLP trace service, the events are emitted until the ctx is cancelled.
+type traceService struct {
+	coltracev1.UnimplementedTraceServiceServer
+	ingester *OtlpTracesIngester
+	ctx      context.Context
+}
+
+// Export emits events of the selected spans, spans which cannot be processed are reported in the partial success of the response.
+func (s *traceService) Export(ctx context.Context, request *coltracev1.ExportTraceServiceRequest) (*coltracev1.ExportTraceServiceResponse, error) {
+	o := s.ingester
+	var total, accepted, rejected int64
+	var rejectErr error
+	shuttingDown := false
+	for _, resourceSpans := range request.GetResourceSpans() {
+		resourceMetadata := stringmap.StringMap{}
+		otlp.AddAttributes(resourceMetadata, o.resourceAttributePrefix, o.keySeparator, resourceSpans.GetResource().GetAttributes())
+		for _, scopeSpans := range resourceSpans.GetScopeSpans() {
+			scopeMetadata := resourceMetadata.Copy()
+			otlp.AddAttributes(scopeMetadata, o.scopeAttributePrefix, o.keySeparator, scopeSpans.GetScope().GetAttributes())
+			for _, span := range scopeSpans.GetSpans() {
+				total++
+				if shuttingDown {
+					continue
+				}
+				newEvent, err := o.newEvent(scopeMetadata, span)
+				if err != nil {
+					rejected++
+					rejectErr = err
+					o.reject(span, reasonInvalidSpan, err)
+					continue
+				}
+				if newEvent == nil {
+					accepted++
+					continue
+				}
+				select {
+				case o.outputChannel <- newEvent:
+					accepted++
+				case <-ctx.Done():
+					return nil, status.FromContextError(ctx.Err()).Err()
+				case <-s.ctx.Done():
+					shuttingDown = true
+				}
+			}
+		}
+	}
+	if shuttingDown {
+		remaining, err := shutdown.RejectRemaining(o, int(accepted), int(total-rejected))
+		if err != nil {
+			return nil, err
+		}
+		o.rejectedSpansTotal.WithLabelValues(reasonShuttingDown).Add(float64(remaining))
+		rejected += int64(remaining)
+		rejectErr = &shutdown.Error{Module: o}
+	}
+	response := &coltracev1.ExportTraceServiceResponse{}
+	if rejected > 0 {
+		response.PartialSuccess = &coltracev1.ExportTracePartialSuccess{RejectedSpans: rejected, ErrorMessage: rejectErr.Error()}
+	}
+	return response, nil
+}
+
+// newEvent returns event of the span with metadata of the resource and scope. Returns nil if the span is filtered out.
+func (o *OtlpTracesIngester) newEvent(scopeMetadata stringmap.StringMap, span *tracev1.Span) (*event.Raw, error) {
+	start := time.Now()
+	defer o.observeDuration(start)
+	o.spansTotal.Inc()
+	if !o.spanKinds[span.GetKind()] {
+		o.filteredSpansTotal.Inc()
+		return nil, nil
+	}
+	if len(span.GetTraceId()) != 16 || len(span.GetSpanId()) != 8 {
+		return nil, fmt.Errorf("span has invalid trace ID or span ID")
+	}
+	if span.GetEndTimeUnixNano() < span.GetStartTimeUnixNano() {
+		return nil, fmt.Errorf("span ends before it starts")
+	}
+	metadata := scopeMetadata.Copy()
+	attributes := stringmap.StringMap{}
+	otlp.AddAttributes(attributes, "", o.keySeparator, span.GetAttributes())
+	for deprecatedKey, key := range semanticConventionAliases {
+		if value, ok := attributes[deprecatedKey]; ok {
+			if _, ok := attributes[key]; !ok {
+				attributes[key] = value
+			}
+		}
+	}
+	for key, value := range attributes {
+		metadata[o.attributePrefix+key] = value
+	}
+	metadata[traceIDKey] = hex.EncodeToString(span.GetTraceId())
+	metadata[spanIDKey] = hex.EncodeToString(span.GetSpanId())
+	if len(span.GetParentSpanId()) > 0 {
+		metadata[parentSpanIDKey] = hex.EncodeToString(span.GetParentSpanId())
+	}
+	metadata[spanNameKey] = span.GetName()
+	metadata[spanKindKey] = strings.TrimPrefix(span.GetKind().String(), "SPAN_KIND_")
+	metadata[statusCodeKey] = strings.TrimPrefix(span.GetStatus().GetCode().String(), "STATUS_CODE_")
+	if span.GetStatus().GetMessage() != "" {
+		metadata[statusMessageKey] = span.GetStatus().GetMessage()
+	}
+	metadata[durationKey] = time.Duration(span.GetEndTimeUnixNano() - span.GetStartTimeUnixNano()).String()
+	for _, filter := range o.attributeFilters {
+		if !filter.matches(metadata) {
+			o.filteredSpansTotal.Inc()
+			return nil, nil
+		}
+	}
+	var eventTime time.Time
+	if span.GetEndTimeUnixNano() != 0 {
+		eventTime = time.Unix(0, int64(span.GetEndTimeUnixNano()))
+	}
+	return &event.Raw{Metadata: metadata, Quantity: 1, Timestamp: eventTime}, nil
+}
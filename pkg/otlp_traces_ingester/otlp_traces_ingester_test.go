@@ -0,0 +1,239 @@
+package otlp_traces_ingester
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/internal/moduletest"
+	"github.com/seznam/slo-exporter/pkg/otlp"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	coltracev1 "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcev1 "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracev1 "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	traceID = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	spanID  = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+)
+
+func newTestConfig(grpcAddress, httpAddress string) OtlpTracesIngesterConfig {
+	return OtlpTracesIngesterConfig{
+		ServerConfig: otlp.ServerConfig{
+			GRPCAddress:             grpcAddress,
+			HTTPAddress:             httpAddress,
+			GracefulShutdownTimeout: time.Second,
+			MaxRequestBytes:         1024 * 1024,
+		},
+		SpanKinds:               []string{"SERVER", "consumer"},
+		ResourceAttributePrefix: "resource.",
+		ScopeAttributePrefix:    "scope.",
+		KeySeparator:            ".",
+	}
+}
+
+func newTestIngester(t *testing.T, config OtlpTracesIngesterConfig) *OtlpTracesIngester {
+	ingester, err := New(config, logrus.New())
+	assert.NoError(t, err)
+	return ingester
+}
+
+func stringAttribute(key, value string) *commonv1.KeyValue {
+	return &commonv1.KeyValue{Key: key, Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: value}}}
+}
+
+func intAttribute(key string, value int64) *commonv1.KeyValue {
+	return &commonv1.KeyValue{Key: key, Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_IntValue{IntValue: value}}}
+}
+
+func serverSpan(route string, attributes ...*commonv1.KeyValue) *tracev1.Span {
+	return &tracev1.Span{
+		TraceId:           traceID,
+		SpanId:            spanID,
+		Name:              "GET " + route,
+		Kind:              tracev1.Span_SPAN_KIND_SERVER,
+		StartTimeUnixNano: 1704103200000000000,
+		EndTimeUnixNano:   1704103200250000000,
+		Attributes:        append([]*commonv1.KeyValue{stringAttribute("http.route", route)}, attributes...),
+	}
+}
+
+func exportRequest(spans ...*tracev1.Span) *coltracev1.ExportTraceServiceRequest {
+	return &coltracev1.ExportTraceServiceRequest{ResourceSpans: []*tracev1.ResourceSpans{{
+		Resource: &resourcev1.Resource{Attributes: []*commonv1.KeyValue{stringAttribute("service.name", "web")}},
+		ScopeSpans: []*tracev1.ScopeSpans{{
+			Scope: &commonv1.InstrumentationScope{Name: "http", Attributes: []*commonv1.KeyValue{stringAttribute("version", "1")}},
+			Spans: spans,
+		}},
+	}}}
+}
+
+func TestNew(t *testing.T) {
+	config := newTestConfig(":4317", "")
+	config.SpanKinds = []string{"SERVER", "BACKEND"}
+	_, err := New(config, logrus.New())
+	assert.Error(t, err)
+
+	config = newTestConfig(":4317", "")
+	config.SpanKinds = nil
+	_, err = New(config, logrus.New())
+	assert.Error(t, err)
+
+	config = newTestConfig(":4317", "")
+	config.AttributeFilters = []AttributeFilter{{Key: "http.route", Regexp: "("}}
+	_, err = New(config, logrus.New())
+	assert.Error(t, err)
+}
+
+func TestNewFromViper(t *testing.T) {
+	viperConfig := viper.New()
+	viperConfig.SetConfigType("yaml")
+	assert.NoError(t, viperConfig.ReadConfig(strings.NewReader(`
+spanKinds: ["SERVER"]
+attributeFilters:
+  - key: "resource.service.name"
+    regexp: "web|api"
+`)))
+	ingester, err := NewFromViper(viperConfig, logrus.New())
+	assert.NoError(t, err)
+	assert.Equal(t, map[tracev1.Span_SpanKind]bool{tracev1.Span_SPAN_KIND_SERVER: true}, ingester.spanKinds)
+	assert.Len(t, ingester.attributeFilters, 1)
+	assert.Equal(t, "resource.service.name", ingester.attributeFilters[0].key)
+}
+
+func TestTraceService_Export(t *testing.T) {
+	config := newTestConfig(":4317", "")
+	config.AttributeFilters = []AttributeFilter{{Key: "http.route", Regexp: "/api/.*"}}
+	ingester := newTestIngester(t, config)
+	deadLetterSink := &moduletest.DeadLetterSink{}
+	ingester.SetDeadLetterSink(deadLetterSink)
+	service := &traceService{ingester: ingester, ctx: context.Background()}
+
+	clientSpan := serverSpan("/api/users")
+	clientSpan.Kind = tracev1.Span_SPAN_KIND_CLIENT
+	invalidSpan := serverSpan("/api/users")
+	invalidSpan.SpanId = nil
+	failedSpan := serverSpan("/api/orders", stringAttribute("http.request.method", "POST"), intAttribute("http.response.status_code", 500))
+	failedSpan.ParentSpanId = []byte{8, 7, 6, 5, 4, 3, 2, 1}
+	failedSpan.Status = &tracev1.Status{Code: tracev1.Status_STATUS_CODE_ERROR, Message: "internal error"}
+
+	collected := moduletest.CollectEvents(ingester.OutputChannel(), 2)
+	response, err := service.Export(context.Background(), exportRequest(
+		serverSpan("/api/users", stringAttribute("http.method", "GET"), intAttribute("http.status_code", 200)),
+		serverSpan("/health"),
+		clientSpan,
+		invalidSpan,
+		failedSpan,
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, []*event.Raw{
+		{
+			Metadata: stringmap.StringMap{
+				"resource.service.name": "web", "scope.version": "1",
+				"http.route": "/api/users", "http.method": "GET", "http.request.method": "GET", "http.status_code": "200", "http.response.status_code": "200",
+				"traceId": "0102030405060708090a0b0c0d0e0f10", "spanId": "0102030405060708", "spanName": "GET /api/users", "spanKind": "SERVER",
+				"statusCode": "UNSET", "duration": "250ms",
+			},
+			Quantity:  1,
+			Timestamp: time.Unix(0, 1704103200250000000),
+		},
+		{
+			Metadata: stringmap.StringMap{
+				"resource.service.name": "web", "scope.version": "1",
+				"http.route": "/api/orders", "http.request.method": "POST", "http.response.status_code": "500",
+				"traceId": "0102030405060708090a0b0c0d0e0f10", "spanId": "0102030405060708", "parentSpanId": "0807060504030201", "spanName": "GET /api/orders", "spanKind": "SERVER",
+				"statusCode": "ERROR", "statusMessage": "internal error", "duration": "250ms",
+			},
+			Quantity:  1,
+			Timestamp: time.Unix(0, 1704103200250000000),
+		},
+	}, <-collected)
+	assert.Equal(t, int64(1), response.GetPartialSuccess().GetRejectedSpans())
+	assert.Len(t, deadLetterSink.Payloads, 1)
+	assert.Equal(t, 5.0, testutil.ToFloat64(ingester.spansTotal))
+	assert.Equal(t, 2.0, testutil.ToFloat64(ingester.filteredSpansTotal))
+	assert.Equal(t, 1.0, testutil.ToFloat64(ingester.rejectedSpansTotal.WithLabelValues(reasonInvalidSpan)))
+}
+
+func TestTraceService_ExportShuttingDown(t *testing.T) {
+	ingester := newTestIngester(t, newTestConfig(":4317", ""))
+	ctx, cancel := context.WithCancel(context.Background())
+	service := &traceService{ingester: ingester, ctx: ctx}
+	span := serverSpan("/api/users")
+
+	// Rest of the spans is rejected if some of them were already emitted.
+	go func() {
+		<-ingester.OutputChannel()
+		cancel()
+	}()
+	response, err := service.Export(context.Background(), exportRequest(span, span, span))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), response.GetPartialSuccess().GetRejectedSpans())
+	assert.Equal(t, 2.0, testutil.ToFloat64(ingester.rejectedSpansTotal.WithLabelValues(reasonShuttingDown)))
+
+	// Whole request can be retried if none of the spans was emitted.
+	_, err = service.Export(context.Background(), exportRequest(span))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestOtlpTracesIngester_Run(t *testing.T) {
+	grpcAddress, httpAddress := moduletest.FreeAddress(t), moduletest.FreeAddress(t)
+	ingester := newTestIngester(t, newTestConfig(grpcAddress, httpAddress))
+	stop := moduletest.Run(t, ingester)
+
+	collected := moduletest.CollectEvents(ingester.OutputChannel(), 1)
+	body, err := proto.Marshal(exportRequest(serverSpan("/api/users")))
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		response, err := http.Post("http://"+httpAddress+tracesPath, "application/x-protobuf", bytes.NewReader(body))
+		if err != nil {
+			return false
+		}
+		response.Body.Close()
+		return response.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "/api/users", (<-collected)[0].Metadata["http.route"])
+
+	connection, err := grpc.NewClient(grpcAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer connection.Close()
+	collected = moduletest.CollectEvents(ingester.OutputChannel(), 1)
+	response, err := coltracev1.NewTraceServiceClient(connection).Export(context.Background(), exportRequest(serverSpan("/api/orders")))
+	assert.NoError(t, err)
+	assert.Nil(t, response.GetPartialSuccess())
+	assert.Equal(t, "/api/orders", (<-collected)[0].Metadata["http.route"])
+
+	// Trace and span IDs are hex encoded in the OTLP/HTTP JSON encoding.
+	collected = moduletest.CollectEvents(ingester.OutputChannel(), 1)
+	jsonBody := `{"resourceSpans": [{"scopeSpans": [{"spans": [{"traceId": "5b8efff798038103d269b633813fc60c", "spanId": "eee19b7ec3c1b174", "parentSpanId": "eee19b7ec3c1b173", "name": "GET /api/users", "kind": 2, "startTimeUnixNano": "1704103200000000000", "endTimeUnixNano": "1704103200250000000"}]}]}]}`
+	httpResponse, err := http.Post("http://"+httpAddress+tracesPath, "application/json", strings.NewReader(jsonBody))
+	assert.NoError(t, err)
+	responseBody, err := io.ReadAll(httpResponse.Body)
+	assert.NoError(t, err)
+	httpResponse.Body.Close()
+	assert.Equal(t, http.StatusOK, httpResponse.StatusCode)
+	assert.JSONEq(t, `{}`, string(responseBody), "no span is rejected")
+	metadata := (<-collected)[0].Metadata
+	assert.Equal(t, "5b8efff798038103d269b633813fc60c", metadata[traceIDKey])
+	assert.Equal(t, "eee19b7ec3c1b174", metadata[spanIDKey])
+	assert.Equal(t, "eee19b7ec3c1b173", metadata[parentSpanIDKey])
+	assert.Equal(t, "250ms", metadata[durationKey])
+
+	stop()
+}
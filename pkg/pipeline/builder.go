@@ -0,0 +1,71 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+type builderEntry struct {
+	entry  config.PipelineEntry
+	module Module
+}
+
+// Builder assembles the pipeline from already initialized modules.
+// It allows to embed the slo-exporter pipeline in other programs without the configuration file.
+// The same rules apply as for the pipeline defined in the configuration file.
+type Builder struct {
+	entries      []builderEntry
+	stallTimeout time.Duration
+	logger       logrus.FieldLogger
+}
+
+func NewBuilder(logger logrus.FieldLogger) *Builder {
+	return &Builder{logger: logger}
+}
+
+// WithStallTimeout sets how long the pipeline may process no events before it is considered not alive, see Manager.RegisterHealthChecks.
+func (b *Builder) WithStallTimeout(timeout time.Duration) *Builder {
+	b.stallTimeout = timeout
+	return b
+}
+
+// Add appends the module to the pipeline.
+// The entry defines name of the module instance, its inputs and other pipeline options, its Type is used only to describe the module.
+func (b *Builder) Add(entry config.PipelineEntry, module Module) *Builder {
+	b.entries = append(b.entries, builderEntry{entry: entry, module: module})
+	return b
+}
+
+// Build validates the pipeline, links the modules together and returns manager to run it.
+func (b *Builder) Build() (*Manager, error) {
+	manager := Manager{
+		pipeline:     []*pipelineItem{},
+		stallTimeout: b.stallTimeout,
+		logger:       b.logger,
+	}
+	for i, builderEntry := range b.entries {
+		newPipelineItem, err := newPipelineItem(builderEntry.entry, builderEntry.module)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create pipeline module: %w", err)
+		}
+		manager.observeModuleEventProcessingDuration(newPipelineItem)
+		inputs := builderEntry.entry.Inputs
+		// Ingester with no explicitly defined inputs is linked to the preceding module.
+		if len(inputs) == 0 && i > 0 && isIngester(newPipelineItem.module) {
+			inputs = []string{b.entries[i-1].entry.Name}
+		}
+		if err := manager.addModuleToPipeline(newPipelineItem, inputs); err != nil {
+			return nil, err
+		}
+		manager.pipelineConfig = append(manager.pipelineConfig, builderEntry.entry)
+	}
+	for _, item := range manager.pipeline {
+		if isProducer(item.module) && len(item.outputs) == 0 {
+			return nil, fmt.Errorf("events produced by module %s are not consumed by any other module", item.name)
+		}
+	}
+	return &manager, nil
+}
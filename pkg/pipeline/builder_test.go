@@ -0,0 +1,73 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/seznam/slo-exporter/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func(b *Builder) *Builder
+		expErr  bool
+		expLink map[string][]string
+	}{
+		{
+			name: "ingester linked to the preceding module",
+			build: func(b *Builder) *Builder {
+				return b.Add(config.PipelineEntry{Name: "producer"}, testRawProducer{}).
+					Add(config.PipelineEntry{Name: "ingester"}, testRawIngester{})
+			},
+			expLink: map[string][]string{"ingester": {"producer"}},
+		},
+		{
+			name: "explicit inputs",
+			build: func(b *Builder) *Builder {
+				return b.Add(config.PipelineEntry{Name: "rawProducer"}, testRawProducer{}).
+					Add(config.PipelineEntry{Name: "sloProducer"}, testSloProducer{}).
+					Add(config.PipelineEntry{Name: "rawIngester", Inputs: []string{"rawProducer"}}, testRawIngester{}).
+					Add(config.PipelineEntry{Name: "sloIngester", Inputs: []string{"sloProducer"}}, testSloIngester{})
+			},
+			expLink: map[string][]string{"rawIngester": {"rawProducer"}, "sloIngester": {"sloProducer"}},
+		},
+		{
+			name: "concurrency",
+			build: func(b *Builder) *Builder {
+				return b.Add(config.PipelineEntry{Name: "producer"}, testRawProducer{}).
+					Add(config.PipelineEntry{Name: "ingester", Concurrency: 2}, &testConcurrentRawIngester{})
+			},
+			expLink: map[string][]string{"ingester": {"producer"}},
+		},
+		{
+			name: "nil module",
+			build: func(b *Builder) *Builder {
+				return b.Add(config.PipelineEntry{Name: "producer"}, nil)
+			},
+			expErr: true,
+		},
+		{
+			name: "producer output not consumed",
+			build: func(b *Builder) *Builder {
+				return b.Add(config.PipelineEntry{Name: "producer"}, testRawProducer{})
+			},
+			expErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, err := tt.build(NewBuilder(logrus.New())).Build()
+			assert.Equal(t, tt.expErr, err != nil, err)
+			if err != nil {
+				return
+			}
+			for name, inputs := range tt.expLink {
+				item, ok := manager.pipelineItem(name)
+				assert.True(t, ok)
+				assert.Equal(t, inputs, itemNames(item.inputs))
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/prober"
+)
+
+// stallDetector reports the pipeline as stalled if no events passed between the modules for the given time.
+type stallDetector struct {
+	timeout      time.Duration
+	eventsTotal  func() uint64
+	lastTotal    uint64
+	lastProgress time.Time
+	mtx          sync.Mutex
+}
+
+func newStallDetector(timeout time.Duration, eventsTotal func() uint64) *stallDetector {
+	return &stallDetector{
+		timeout:      timeout,
+		eventsTotal:  eventsTotal,
+		lastTotal:    eventsTotal(),
+		lastProgress: time.Now(),
+	}
+}
+
+func (d *stallDetector) check() error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if total := d.eventsTotal(); total != d.lastTotal {
+		d.lastTotal = total
+		d.lastProgress = time.Now()
+		return nil
+	}
+	if sinceProgress := time.Since(d.lastProgress); sinceProgress > d.timeout {
+		return fmt.Errorf("no events processed for %s", sinceProgress.Round(time.Second))
+	}
+	return nil
+}
+
+// eventsTotal returns number of events produced by all the modules of the pipeline.
+func (m *Manager) eventsTotal() uint64 {
+	var total uint64
+	for _, item := range m.pipeline {
+		total += item.eventsOut.Load()
+	}
+	return total
+}
+
+// RegisterHealthChecks adds health checks of the modules to the readiness probe.
+// If the stall timeout is configured, liveness probe fails once no events pass through the pipeline for that long.
+func (m *Manager) RegisterHealthChecks(readiness, liveness *prober.Prober) {
+	for _, item := range m.pipeline {
+		healthCheckedModule, ok := item.module.(HealthCheckedModule)
+		if !ok {
+			continue
+		}
+		item := item
+		readiness.AddCheck(item.name, func() error {
+			// Finished modules are handled by the pipeline lifecycle.
+			if item.finished.Load() {
+				return nil
+			}
+			return healthCheckedModule.CheckHealth()
+		})
+	}
+	if m.stallTimeout > 0 {
+		liveness.AddCheck("pipeline", newStallDetector(m.stallTimeout, m.eventsTotal).check)
+	}
+}
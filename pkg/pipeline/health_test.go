@@ -0,0 +1,53 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/seznam/slo-exporter/pkg/prober"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_stallDetector(t *testing.T) {
+	var eventsTotal uint64
+	detector := newStallDetector(50*time.Millisecond, func() uint64 { return eventsTotal })
+	assert.NoError(t, detector.check())
+	time.Sleep(60 * time.Millisecond)
+	assert.ErrorContains(t, detector.check(), "no events processed")
+	eventsTotal++
+	assert.NoError(t, detector.check())
+}
+
+type testHealthCheckedRawProducer struct {
+	testChannelRawProducer
+	err error
+}
+
+func (t *testHealthCheckedRawProducer) CheckHealth() error {
+	return t.err
+}
+
+func TestManager_RegisterHealthChecks(t *testing.T) {
+	readiness, err := prober.NewReadiness(prometheus.NewRegistry(), logrus.New())
+	assert.NoError(t, err)
+	liveness, err := prober.NewLiveness(prometheus.NewRegistry(), logrus.New())
+	assert.NoError(t, err)
+	readiness.Ok()
+
+	producer := &testHealthCheckedRawProducer{err: errors.New("broken")}
+	manager := Manager{logger: logrus.New(), stallTimeout: time.Hour}
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "producer", module: producer}, nil))
+	manager.RegisterHealthChecks(readiness, liveness)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go readiness.RunChecks(ctx, 10*time.Millisecond)
+	go liveness.RunChecks(ctx, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return readiness.IsOk() != nil }, time.Second, 10*time.Millisecond)
+	assert.ErrorContains(t, readiness.IsOk(), "producer: broken")
+	assert.NoError(t, liveness.IsOk())
+}
@@ -0,0 +1,211 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+)
+
+type runner interface {
+	run()
+}
+
+// queue is a link between two modules buffering the events passed between them.
+type queue interface {
+	// link returns names of the modules connected by the queue.
+	link() (from, to string)
+	length() int
+	capacity() int
+}
+
+// eventQueue passes events from a single producer to a single ingester.
+type eventQueue[T any] struct {
+	from    *pipelineItem
+	to      *pipelineItem
+	channel chan T
+}
+
+func newEventQueue[T any](from, to *pipelineItem) *eventQueue[T] {
+	return &eventQueue[T]{from: from, to: to, channel: make(chan T, to.bufferSize)}
+}
+
+func (q *eventQueue[T]) link() (string, string) {
+	return q.from.name, q.to.name
+}
+
+func (q *eventQueue[T]) push(newEvent T) {
+	q.channel <- newEvent
+	q.to.eventsIn.Inc()
+}
+
+func (q *eventQueue[T]) length() int {
+	return len(q.channel)
+}
+
+func (q *eventQueue[T]) capacity() int {
+	return cap(q.channel)
+}
+
+// eventInput merges events from all the queues linked to a single ingester into its input channel.
+type eventInput[T any] struct {
+	channel chan T
+	queues  []*eventQueue[T]
+}
+
+// run closes the input channel once all the linked queues are closed.
+func (i *eventInput[T]) run() {
+	wg := sync.WaitGroup{}
+	for _, q := range i.queues {
+		wg.Add(1)
+		go func(q *eventQueue[T]) {
+			defer wg.Done()
+			for newEvent := range q.channel {
+				i.channel <- newEvent
+			}
+		}(q)
+	}
+	wg.Wait()
+	close(i.channel)
+}
+
+// eventLink forwards events of a single producer to all the ingesters linked to it.
+// If there is more than one ingester, each of them receives its own copy of the event.
+type eventLink[T any] struct {
+	producer      *pipelineItem
+	source        <-chan T
+	queues        []*eventQueue[T]
+	copyEvent     func(T) T
+	eventMetadata func(T) stringmap.StringMap
+
+	// lagObserver observes delay between the occurrence and ingestion of the event, set only for the source modules.
+	lagObserver    prometheus.Observer
+	eventTimestamp func(T) time.Time
+}
+
+func (l *eventLink[T]) observeLag(newEvent T) {
+	if l.lagObserver == nil {
+		return
+	}
+	if timestamp := l.eventTimestamp(newEvent); !timestamp.IsZero() {
+		l.lagObserver.Observe(time.Since(timestamp).Seconds())
+	}
+}
+
+func (l *eventLink[T]) run() {
+	defer func() {
+		for _, q := range l.queues {
+			close(q.channel)
+		}
+	}()
+	events := make([]T, len(l.queues))
+	for newEvent := range l.source {
+		l.producer.eventsOut.Inc()
+		l.observeLag(newEvent)
+		if l.producer.tap.active() {
+			l.producer.tap.publish(l.eventMetadata(newEvent), func() ([]byte, error) {
+				return json.Marshal(newEvent)
+			})
+		}
+		// Copies must be done before passing the event further since the ingester can modify it.
+		events[0] = newEvent
+		for i := 1; i < len(l.queues); i++ {
+			events[i] = l.copyEvent(newEvent)
+		}
+		for i, q := range l.queues {
+			q.push(events[i])
+		}
+	}
+}
+
+func copyRawEvent(e *event.Raw) *event.Raw {
+	return e.Copy()
+}
+
+func copySloEvent(e *event.Slo) *event.Slo {
+	eventCopy := e.Copy()
+	return &eventCopy
+}
+
+func rawEventMetadata(e *event.Raw) stringmap.StringMap {
+	return e.Metadata.Merge(e.GetSloMetadata())
+}
+
+func sloEventMetadata(e *event.Slo) stringmap.StringMap {
+	classification := event.SloClassification{Domain: e.Domain, App: e.App, Class: e.Class}
+	return e.OriginalEvent.Metadata.Merge(e.Metadata).Merge(classification.GetMetadata())
+}
+
+func rawEventTimestamp(e *event.Raw) time.Time {
+	return e.Timestamp
+}
+
+func sloEventTimestamp(e *event.Slo) time.Time {
+	return e.Timestamp
+}
+
+// eventLinks links all the producers and ingesters of single event type.
+type eventLinks[T any] struct {
+	copyEvent      func(T) T
+	eventMetadata  func(T) stringmap.StringMap
+	eventTimestamp func(T) time.Time
+	links          []*eventLink[T]
+	ingesters      []*pipelineItem
+	ingesterQueue  map[*pipelineItem][]*eventQueue[T]
+}
+
+func newEventLinks[T any](copyEvent func(T) T, eventMetadata func(T) stringmap.StringMap, eventTimestamp func(T) time.Time) *eventLinks[T] {
+	return &eventLinks[T]{copyEvent: copyEvent, eventMetadata: eventMetadata, eventTimestamp: eventTimestamp, ingesterQueue: map[*pipelineItem][]*eventQueue[T]{}}
+}
+
+// addProducer creates queues from the producer to all its outputs.
+// Ingestion lag is observed only for the producers with no inputs since those are the sources of the events.
+func (l *eventLinks[T]) addProducer(producer *pipelineItem, source <-chan T) {
+	link := &eventLink[T]{producer: producer, source: source, copyEvent: l.copyEvent, eventMetadata: l.eventMetadata, eventTimestamp: l.eventTimestamp}
+	if len(producer.inputs) == 0 {
+		link.lagObserver = eventIngestionLagSeconds.WithLabelValues(producer.name)
+	}
+	for _, next := range producer.outputs {
+		q := newEventQueue[T](producer, next)
+		link.queues = append(link.queues, q)
+		if _, ok := l.ingesterQueue[next]; !ok {
+			l.ingesters = append(l.ingesters, next)
+		}
+		l.ingesterQueue[next] = append(l.ingesterQueue[next], q)
+	}
+	l.links = append(l.links, link)
+}
+
+// link sets input channels of all the ingesters and returns runners forwarding the events.
+// Ingester linked to single producer reads directly from the queue, otherwise the queues are merged.
+func (l *eventLinks[T]) link(setInput func(Module, chan T)) []runner {
+	runners := []runner{}
+	for _, ingester := range l.ingesters {
+		queues := l.ingesterQueue[ingester]
+		if len(queues) == 1 {
+			setInput(ingester.module, queues[0].channel)
+			continue
+		}
+		input := &eventInput[T]{channel: make(chan T), queues: queues}
+		setInput(ingester.module, input.channel)
+		runners = append(runners, input)
+	}
+	for _, link := range l.links {
+		runners = append(runners, link)
+	}
+	return runners
+}
+
+func (l *eventLinks[T]) queues() []queue {
+	queues := []queue{}
+	for _, link := range l.links {
+		for _, q := range link.queues {
+			queues = append(queues, q)
+		}
+	}
+	return queues
+}
@@ -3,14 +3,20 @@ package pipeline
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-multierror"
 	"github.com/iancoleman/strcase"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/seznam/slo-exporter/pkg/config"
+	"github.com/seznam/slo-exporter/pkg/dead_letter"
+	"github.com/seznam/slo-exporter/pkg/event"
 	"github.com/sirupsen/logrus"
+	"go.uber.org/atomic"
 )
 
 var eventProcessingDurationSeconds = prometheus.NewHistogramVec(
@@ -22,83 +28,288 @@ var eventProcessingDurationSeconds = prometheus.NewHistogramVec(
 	[]string{"module"},
 )
 
-func NewManager(moduleFactory moduleFactoryFunction, cfg *config.Config, logger logrus.FieldLogger) (*Manager, error) {
-	manager := Manager{
-		pipeline: []pipelineItem{},
-		logger:   logger,
-	}
-	// Initialize the pipeline and link it together.
-	for _, moduleName := range cfg.Pipeline {
-		newPipelineItem, err := manager.newPipelineItem(moduleName, cfg, moduleFactory)
+var eventIngestionLagSeconds = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "event_ingestion_lag_seconds",
+		Help:    "Histogram of delay between the occurrence of the event and its ingestion per source module.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 10),
+	},
+	[]string{"module"},
+)
+
+var (
+	linkQueueLengthDesc = prometheus.NewDesc(
+		"pipeline_link_queue_length",
+		"Number of events waiting in the queue between two modules.",
+		[]string{"from", "to"}, nil,
+	)
+	linkQueueCapacityDesc = prometheus.NewDesc(
+		"pipeline_link_queue_capacity",
+		"Capacity of the queue between two modules.",
+		[]string{"from", "to"}, nil,
+	)
+)
+
+// NewManager creates the pipeline defined in the configuration, modules are created using the moduleFactory.
+func NewManager(moduleFactory ModuleFactoryFunction, cfg *config.Config, logger logrus.FieldLogger) (*Manager, error) {
+	builder := NewBuilder(logger).WithStallTimeout(cfg.PipelineStallTimeout)
+	for _, entry := range cfg.Pipeline {
+		newModule, err := newModule(entry, cfg, moduleFactory, logger)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create pipeline module: %w", err)
 		}
-		manager.observeModuleEventProcessingDuration(newPipelineItem)
-		if err := manager.addModuleToPipelineEnd(newPipelineItem); err != nil {
-			return nil, err
-		}
+		builder.Add(entry, newModule)
 	}
-	return &manager, nil
+	return builder.Build()
 }
 
 type pipelineItem struct {
-	name   string
-	module Module
+	name       string
+	moduleType string
+	module     Module
+	bufferSize int
+	inputs     []*pipelineItem
+	outputs    []*pipelineItem
+	// eventsIn counts events passed to the input queues of the module.
+	eventsIn atomic.Uint64
+	// eventsOut counts events read from the output of the module.
+	eventsOut atomic.Uint64
+	// tap mirrors events read from the output of the module.
+	tap tap
+
+	// stopTimeout limits how long the pipeline waits for the module to finish once it should stop.
+	stopTimeout time.Duration
+	// Lifecycle of the module, initialized once the pipeline is started.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// done is closed once Run of the module returned, err is set before if it failed.
+	done     chan struct{}
+	err      error
+	finished atomic.Bool
+	// stopped is closed once the module finished or exceeded its stop timeout, stopErr is set before in the latter case.
+	stopped chan struct{}
+	stopErr error
 }
 
 type Manager struct {
-	pipeline []pipelineItem
-	logger   logrus.FieldLogger
+	pipeline       []*pipelineItem
+	pipelineConfig []config.PipelineEntry
+	reloadMtx      sync.Mutex
+	queues         []queue
+	queuesMtx      sync.RWMutex
+	stallTimeout   time.Duration
+	logger         logrus.FieldLogger
+
+	// Lifecycle of the pipeline, initialized once it is started.
+	lifecycleMtx  sync.Mutex
+	started       bool
+	stopRequested chan struct{}
+	stopOnce      sync.Once
+	errors        chan error
+	done          chan struct{}
 }
 
 func (m *Manager) StartPipeline() error {
 	m.logger.Info("starting pipeline... ")
-
 	if len(m.pipeline) == 0 {
 		return fmt.Errorf("failed to execute the empty pipeline (no pipeline modules defined in the config)")
 	}
+	m.lifecycleMtx.Lock()
+	defer m.lifecycleMtx.Unlock()
+	if m.started {
+		return fmt.Errorf("pipeline is already started")
+	}
+	m.started = true
+	m.stopRequested = make(chan struct{})
+	m.errors = make(chan error, len(m.pipeline))
+	m.done = make(chan struct{})
+	for _, item := range m.pipeline {
+		item.ctx, item.cancel = context.WithCancel(context.Background())
+		item.done = make(chan struct{})
+		item.stopped = make(chan struct{})
+	}
+	// Links need to be running before the modules since they set input channels of the modules.
+	for _, linkRunner := range m.linkPipeline() {
+		go linkRunner.run()
+	}
 	pipelineSchema := make([]string, 0, len(m.pipeline))
 	for _, pipelineItem := range m.pipeline {
-		pipelineItem.module.Run()
-		pipelineSchema = append(pipelineSchema, pipelineItem.name)
+		go m.runModule(pipelineItem)
+		go m.superviseModule(pipelineItem)
+		for _, input := range pipelineItem.inputs {
+			pipelineSchema = append(pipelineSchema, input.name+" -> "+pipelineItem.name)
+		}
 	}
-	m.logger.Info("pipeline schema: " + strings.Join(pipelineSchema, " -> "))
+	go func() {
+		for _, item := range m.pipeline {
+			<-item.done
+		}
+		close(m.done)
+	}()
+	m.logger.Info("pipeline schema: " + strings.Join(pipelineSchema, ", "))
 	m.logger.Info("pipeline started")
 	return nil
 }
 
-func (m *Manager) StopPipeline(ctx context.Context) chan struct{} {
-	m.pipeline[0].module.Stop()
-	stoppedChan := make(chan struct{})
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				m.logger.Warn("Shutdown context expired before pipeline managed to process all events.")
-				return
-			default:
-				if m.Done() {
-					close(stoppedChan)
-					m.logger.Info("Pipeline finished processing all events and successfully stopped.")
-					return
-				}
-			}
-			time.Sleep(10 * time.Millisecond)
-		}
+// runModule runs the module and reports its failure.
+func (m *Manager) runModule(item *pipelineItem) {
+	defer func() {
+		item.finished.Store(true)
+		close(item.done)
 	}()
-	return stoppedChan
+	if err := item.module.Run(item.ctx); err != nil {
+		item.err = fmt.Errorf("module %s failed: %w", item.name, err)
+		// The channel has capacity for all the modules, so it never blocks.
+		m.errors <- item.err
+		return
+	}
+	m.logger.WithField("module", item.name).Debug("module finished")
 }
 
-func (m *Manager) Done() bool {
-	for _, pipelineItem := range m.pipeline {
-		if !pipelineItem.module.Done() {
-			return false
+// superviseModule cancels context of the module once it should stop and waits until it finishes or exceeds its stop timeout.
+// Module with no inputs should stop once the pipeline is stopped, other modules once all their inputs stopped.
+func (m *Manager) superviseModule(item *pipelineItem) {
+	defer close(item.stopped)
+	if len(item.inputs) == 0 {
+		select {
+		case <-m.stopRequested:
+		case <-item.done:
+		}
+	}
+	for _, input := range item.inputs {
+		<-input.stopped
+	}
+	item.cancel()
+	var timeout <-chan time.Time
+	if item.stopTimeout > 0 {
+		timer := time.NewTimer(item.stopTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-item.done:
+	case <-timeout:
+		item.stopErr = fmt.Errorf("module %s did not stop within %s", item.name, item.stopTimeout)
+		m.logger.Error(item.stopErr)
+	}
+}
+
+// StopPipeline stops all the modules which are not ingesting any events and waits until the rest of the pipeline
+// processes all the events. Returns error if any module failed, exceeded its stop timeout or the context expires.
+func (m *Manager) StopPipeline(ctx context.Context) error {
+	m.lifecycleMtx.Lock()
+	if !m.started {
+		m.lifecycleMtx.Unlock()
+		return nil
+	}
+	m.stopOnce.Do(func() { close(m.stopRequested) })
+	m.lifecycleMtx.Unlock()
+	var errs error
+	for _, item := range m.pipeline {
+		select {
+		case <-item.stopped:
+		case <-ctx.Done():
+			return fmt.Errorf("shutdown context expired before pipeline managed to process all events: %w", ctx.Err())
 		}
+		if item.stopErr != nil {
+			errs = multierror.Append(errs, item.stopErr)
+			continue
+		}
+		if item.err != nil {
+			errs = multierror.Append(errs, item.err)
+		}
+	}
+	if errs != nil {
+		return errs
 	}
-	return true
+	m.logger.Info("Pipeline finished processing all events and successfully stopped.")
+	return nil
+}
+
+// Done returns channel which is closed once all the modules finished.
+func (m *Manager) Done() <-chan struct{} {
+	m.lifecycleMtx.Lock()
+	defer m.lifecycleMtx.Unlock()
+	return m.done
 }
 
-func (m *Manager) observeModuleEventProcessingDuration(item pipelineItem) {
+// Errors returns channel receiving errors of the failed modules.
+func (m *Manager) Errors() <-chan error {
+	m.lifecycleMtx.Lock()
+	defer m.lifecycleMtx.Unlock()
+	return m.errors
+}
+
+// ReloadConfig passes the new configuration to all the modules which support reloading it.
+// The configuration is applied only if all the modules manage to load it. Changes of the pipeline structure require restart.
+func (m *Manager) ReloadConfig(cfg *config.Config) error {
+	m.reloadMtx.Lock()
+	defer m.reloadMtx.Unlock()
+	if !reflect.DeepEqual(m.pipelineConfig, cfg.Pipeline) {
+		return fmt.Errorf("pipeline structure cannot be changed without restart")
+	}
+	var errs error
+	applyFunctions := []func() error{}
+	for _, item := range m.pipeline {
+		reloadableModule, ok := item.module.(ReloadableModule)
+		if !ok {
+			continue
+		}
+		moduleConfig, err := cfg.ModuleConfig(item.name)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("failed to load configuration for module %s: %w", item.name, err))
+			continue
+		}
+		apply, err := reloadableModule.PrepareReload(moduleConfig)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("failed to reload configuration of module %s: %w", item.name, err))
+			continue
+		}
+		applyFunctions = append(applyFunctions, apply)
+	}
+	if errs != nil {
+		return errs
+	}
+	for _, apply := range applyFunctions {
+		if err := apply(); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("failed to apply the reloaded configuration: %w", errs)
+	}
+	m.logger.Infof("reloaded configuration of %d modules", len(applyFunctions))
+	return nil
+}
+
+// SetDeadLetterSink passes the sink to all the modules able to record the events they reject.
+// Each module records the events under its own name as the stage.
+func (m *Manager) SetDeadLetterSink(sink *dead_letter.Sink) {
+	for _, item := range m.pipeline {
+		if deadLetterModule, ok := item.module.(DeadLetterModule); ok {
+			deadLetterModule.SetDeadLetterSink(sink.ForStage(item.name))
+		}
+	}
+}
+
+// SetReplayRecorder sets the recorder to all the modules able to record their metrics, it fails if there is no such module.
+func (m *Manager) SetReplayRecorder(recorder ReplayRecorder) error {
+	replayable := false
+	for _, item := range m.pipeline {
+		if replayableModule, ok := item.module.(ReplayableModule); ok {
+			if err := replayableModule.SetReplayRecorder(recorder); err != nil {
+				return fmt.Errorf("failed to set replay recorder of the module %s: %w", item.name, err)
+			}
+			replayable = true
+		}
+	}
+	if !replayable {
+		return fmt.Errorf("pipeline contains no module able to record metrics of the replayed events")
+	}
+	return nil
+}
+
+func (m *Manager) observeModuleEventProcessingDuration(item *pipelineItem) {
 	observableModule, ok := item.module.(ObservableModule)
 	if ok {
 		observableModule.RegisterEventProcessingDurationObserver(eventProcessingDurationSeconds.WithLabelValues(item.name))
@@ -109,6 +320,12 @@ func (m *Manager) RegisterPrometheusMetrics(rootRegistry, wrappedRegistry promet
 	if err := rootRegistry.Register(eventProcessingDurationSeconds); err != nil {
 		return err
 	}
+	if err := rootRegistry.Register(eventIngestionLagSeconds); err != nil {
+		return err
+	}
+	if err := wrappedRegistry.Register(m); err != nil {
+		return err
+	}
 	m.logger.Info("registering Prometheus metrics of pipeline modules")
 	for _, m := range m.pipeline {
 		promModule, ok := m.module.(PrometheusInstrumentedModule)
@@ -123,6 +340,22 @@ func (m *Manager) RegisterPrometheusMetrics(rootRegistry, wrappedRegistry promet
 	return nil
 }
 
+// Describe implements prometheus.Collector exposing state of the queues between modules.
+func (m *Manager) Describe(ch chan<- *prometheus.Desc) {
+	ch <- linkQueueLengthDesc
+	ch <- linkQueueCapacityDesc
+}
+
+func (m *Manager) Collect(ch chan<- prometheus.Metric) {
+	m.queuesMtx.RLock()
+	defer m.queuesMtx.RUnlock()
+	for _, q := range m.queues {
+		from, to := q.link()
+		ch <- prometheus.MustNewConstMetric(linkQueueLengthDesc, prometheus.GaugeValue, float64(q.length()), from, to)
+		ch <- prometheus.MustNewConstMetric(linkQueueCapacityDesc, prometheus.GaugeValue, float64(q.capacity()), from, to)
+	}
+}
+
 func (m *Manager) RegisterWebInterface(router *mux.Router) {
 	for _, m := range m.pipeline {
 		webInterfaceModule, ok := m.module.(WebInterfaceModule)
@@ -131,6 +364,8 @@ func (m *Manager) RegisterWebInterface(router *mux.Router) {
 		}
 		webInterfaceModule.RegisterInMux(router.PathPrefix("/" + m.name).Subrouter())
 	}
+	router.HandleFunc("/pipeline", m.handlePipelineStatus)
+	router.HandleFunc("/pipeline/tap", m.handleTap)
 }
 
 func isProducer(module Module) bool {
@@ -153,6 +388,7 @@ func isIngester(module Module) bool {
 	return false
 }
 
+// linkModules checks that the previous module can be linked with the next one.
 func linkModules(previous, next Module) error {
 	// We can link only previous producer with next ingester.
 	if !isProducer(previous) {
@@ -162,63 +398,141 @@ func linkModules(previous, next Module) error {
 		return fmt.Errorf("trying to link module %s to previous module but it is not an ingester", next)
 	}
 	// Check if event types of producer end ingester matches.
-	switch previousRaw := previous.(type) {
+	switch previous.(type) {
 	case RawEventProducerModule:
-		nextRaw, ok := next.(RawEventIngesterModule)
-		if !ok {
+		if _, ok := next.(RawEventIngesterModule); !ok {
 			return fmt.Errorf("trying to link raw event producer %s with slo event ingester %s", previous, next)
 		}
-		nextRaw.SetInputChannel(previousRaw.OutputChannel())
 	case SloEventProducerModule:
-		nextRaw, ok := next.(SloEventIngesterModule)
-		if !ok {
+		if _, ok := next.(SloEventIngesterModule); !ok {
 			return fmt.Errorf("trying to link SLO event producer %s with raw event ingester %s", previous, next)
 		}
-		nextRaw.SetInputChannel(previousRaw.OutputChannel())
 	}
 	return nil
 }
 
-func (m *Manager) lastPipelineItem() pipelineItem {
+func (m *Manager) pipelineItem(name string) (*pipelineItem, bool) {
+	for _, item := range m.pipeline {
+		if item.name == name {
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+func (m *Manager) lastPipelineItem() *pipelineItem {
 	return m.pipeline[len(m.pipeline)-1]
 }
 
-func (m *Manager) linkModuleWithPipelineEnd(nextModule Module) error {
-	// If it is first module to be in the pipeline, just check it's not an ingester and add it there.
-	if len(m.pipeline) == 0 {
-		if isIngester(nextModule) {
-			return fmt.Errorf("ingester module %s cannot be at the at the beginning of the pipeline", nextModule)
+// addModuleToPipeline adds new module to the pipeline and links it to the already added modules of given names.
+func (m *Manager) addModuleToPipeline(newItem *pipelineItem, inputs []string) error {
+	if _, ok := m.pipelineItem(newItem.name); ok {
+		return fmt.Errorf("module %s is defined in the pipeline multiple times", newItem.name)
+	}
+	if len(inputs) == 0 && isIngester(newItem.module) {
+		return fmt.Errorf("ingester module %s has no input, it cannot be at the beginning of the pipeline", newItem.name)
+	}
+	if len(inputs) > 0 && !isIngester(newItem.module) {
+		return fmt.Errorf("module %s is not an ingester, it cannot have any inputs", newItem.name)
+	}
+	for _, inputName := range inputs {
+		inputItem, ok := m.pipelineItem(inputName)
+		if !ok {
+			return fmt.Errorf("input %s of module %s must be defined in the pipeline before it", inputName, newItem.name)
 		}
-		return nil
+		// Link modules together.
+		if err := linkModules(inputItem.module, newItem.module); err != nil {
+			return fmt.Errorf("failed to link modules: %w", err)
+		}
+		newItem.inputs = append(newItem.inputs, inputItem)
+		inputItem.outputs = append(inputItem.outputs, newItem)
 	}
+	m.pipeline = append(m.pipeline, newItem)
+	return nil
+}
 
-	previousPipelineItem := m.lastPipelineItem()
-	// Link modules together.
-	if err := linkModules(previousPipelineItem.module, nextModule); err != nil {
-		return fmt.Errorf("failed to link modules: %w", err)
+// addModuleToPipelineEnd adds new module to the pipeline and links it to the last module in the pipeline.
+func (m *Manager) addModuleToPipelineEnd(newItem *pipelineItem) error {
+	var inputs []string
+	if len(m.pipeline) > 0 && isIngester(newItem.module) {
+		inputs = []string{m.lastPipelineItem().name}
 	}
-	return nil
+	return m.addModuleToPipeline(newItem, inputs)
 }
 
-func (m *Manager) addModuleToPipelineEnd(newItem pipelineItem) error {
-	if err := m.linkModuleWithPipelineEnd(newItem.module); err != nil {
-		return err
+// linkPipeline sets input channels of all the ingesters and returns runners forwarding the events between modules.
+func (m *Manager) linkPipeline() []runner {
+	rawLinks := newEventLinks(copyRawEvent, rawEventMetadata, rawEventTimestamp)
+	sloLinks := newEventLinks(copySloEvent, sloEventMetadata, sloEventTimestamp)
+	for _, item := range m.pipeline {
+		if len(item.outputs) == 0 {
+			continue
+		}
+		switch producer := item.module.(type) {
+		case RawEventProducerModule:
+			rawLinks.addProducer(item, producer.OutputChannel())
+		case SloEventProducerModule:
+			sloLinks.addProducer(item, producer.OutputChannel())
+		}
 	}
-	m.pipeline = append(m.pipeline, newItem)
-	return nil
+	m.queuesMtx.Lock()
+	m.queues = append(rawLinks.queues(), sloLinks.queues()...)
+	m.queuesMtx.Unlock()
+	runners := rawLinks.link(func(next Module, c chan *event.Raw) {
+		next.(RawEventIngesterModule).SetInputChannel(c)
+	})
+	return append(runners, sloLinks.link(func(next Module, c chan *event.Slo) {
+		next.(SloEventIngesterModule).SetInputChannel(c)
+	})...)
 }
 
-func (m *Manager) newPipelineItem(moduleName string, cfg *config.Config, factoryFunction moduleFactoryFunction) (pipelineItem, error) {
-	moduleConfig, err := cfg.ModuleConfig(moduleName)
+// newModule creates the module of the pipeline entry using its configuration.
+func newModule(entry config.PipelineEntry, cfg *config.Config, factoryFunction ModuleFactoryFunction, logger logrus.FieldLogger) (Module, error) {
+	if entry.Name == "" {
+		return nil, fmt.Errorf("pipeline entry of type %q is missing the module name", entry.Type)
+	}
+	moduleConfig, err := cfg.ModuleConfig(entry.Name)
 	if err != nil {
-		return pipelineItem{}, fmt.Errorf("failed to load configuration for module %s: %w", moduleName, err)
+		return nil, fmt.Errorf("failed to load configuration for module %s: %w", entry.Name, err)
 	}
-	newModule, err := factoryFunction(moduleName, m.logger.WithField("component", moduleName), moduleConfig)
+	newModule, err := factoryFunction(entry.ModuleType(), logger.WithField("component", entry.Name), moduleConfig)
 	if err != nil {
-		return pipelineItem{}, fmt.Errorf("failed to initialize module %s from config: %w", moduleName, err)
+		return nil, fmt.Errorf("failed to initialize module %s of type %s from config: %w", entry.Name, entry.ModuleType(), err)
+	}
+	return newModule, nil
+}
+
+func newPipelineItem(entry config.PipelineEntry, newModule Module) (*pipelineItem, error) {
+	if entry.Name == "" {
+		return nil, fmt.Errorf("pipeline entry of type %q is missing the module name", entry.Type)
+	}
+	if newModule == nil {
+		return nil, fmt.Errorf("module %s is nil", entry.Name)
+	}
+	if entry.BufferSize < 0 {
+		return nil, fmt.Errorf("buffer size of module %s cannot be negative", entry.Name)
+	}
+	if entry.BufferSize > 0 && !isIngester(newModule) {
+		return nil, fmt.Errorf("module %s is not an ingester, it cannot have buffer size configured", entry.Name)
+	}
+	if entry.StopTimeout < 0 {
+		return nil, fmt.Errorf("stop timeout of module %s cannot be negative", entry.Name)
+	}
+	if entry.Concurrency < 0 {
+		return nil, fmt.Errorf("concurrency of module %s cannot be negative", entry.Name)
+	}
+	if entry.Concurrency > 0 {
+		concurrentModule, ok := newModule.(ConcurrentModule)
+		if !ok {
+			return nil, fmt.Errorf("module %s of type %s does not support concurrent processing", entry.Name, entry.ModuleType())
+		}
+		concurrentModule.SetConcurrency(entry.Concurrency)
 	}
-	return pipelineItem{
-		name:   moduleName,
-		module: newModule,
+	return &pipelineItem{
+		name:        entry.Name,
+		moduleType:  entry.ModuleType(),
+		module:      newModule,
+		bufferSize:  entry.BufferSize,
+		stopTimeout: entry.StopTimeout,
 	}, nil
 }
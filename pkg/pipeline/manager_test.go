@@ -2,20 +2,24 @@ package pipeline
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/seznam/slo-exporter/pkg/config"
 	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
 type testModule struct {
-	done bool
+	output chan *event.Raw
 }
 
 func (t *testModule) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
@@ -26,24 +30,18 @@ func (t *testModule) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) e
 	return nil
 }
 
-func (t *testModule) Run() {
-	t.done = false
-}
-
-func (t *testModule) Stop() {
-	t.done = true
-}
-
-func (t *testModule) Done() bool {
-	return t.done
+func (t *testModule) Run(ctx context.Context) error {
+	<-ctx.Done()
+	close(t.output)
+	return nil
 }
 
 func (t *testModule) OutputChannel() chan *event.Raw {
-	return make(chan *event.Raw)
+	return t.output
 }
 
 func newEmptyManager() (*Manager, error) {
-	manager, err := NewManager(testModuleFactory, &config.Config{Pipeline: []string{}}, logrus.New())
+	manager, err := NewManager(testModuleFactory, &config.Config{Pipeline: []config.PipelineEntry{}}, logrus.New())
 	if err != nil {
 		return nil, err
 	}
@@ -55,7 +53,7 @@ func newTestManager() (*Manager, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := manager.addModuleToPipelineEnd(pipelineItem{name: "test_module", module: &testModule{}}); err != nil {
+	if err := manager.addModuleToPipelineEnd(&pipelineItem{name: "test_module", module: &testModule{output: make(chan *event.Raw)}}); err != nil {
 		return nil, err
 	}
 	return manager, nil
@@ -91,32 +89,253 @@ func TestManager_StartPipeline(t *testing.T) {
 	manager, err := newTestManager()
 	assert.NoError(t, err)
 	assert.NoError(t, manager.StartPipeline())
-	assert.False(t, manager.Done())
+	assert.Error(t, manager.StartPipeline(), "pipeline cannot be started twice")
+	select {
+	case <-manager.Done():
+		t.Fatal("pipeline must not finish before it is stopped")
+	case <-time.After(10 * time.Millisecond):
+	}
 }
 
 func TestManager_StopPipeline(t *testing.T) {
 	manager, err := newTestManager()
 	assert.NoError(t, err)
-	ctx := context.Background()
-	<-manager.StopPipeline(ctx)
-	assert.True(t, manager.Done())
+	assert.NoError(t, manager.StopPipeline(context.Background()), "stopping pipeline which is not started is a no-op")
+	assert.NoError(t, manager.StartPipeline())
+	assert.NoError(t, manager.StopPipeline(context.Background()))
+	<-manager.Done()
+}
+
+// testFailingRawProducer fails once it is started.
+type testFailingRawProducer struct {
+	output chan *event.Raw
+}
+
+func (t *testFailingRawProducer) Run(_ context.Context) error {
+	close(t.output)
+	return fmt.Errorf("listen failed")
+}
+
+func (t *testFailingRawProducer) OutputChannel() chan *event.Raw {
+	return t.output
+}
+
+// testStuckRawIngester never finishes.
+type testStuckRawIngester struct {
+	testRawIngester
+}
+
+func (t testStuckRawIngester) Run(_ context.Context) error {
+	select {}
+}
+
+func TestManager_ModuleFailure(t *testing.T) {
+	manager := Manager{logger: logrus.New()}
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "producer", module: &testFailingRawProducer{output: make(chan *event.Raw)}}, nil))
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "ingester", module: &testCollectingRawIngester{}}, []string{"producer"}))
+	assert.NoError(t, manager.StartPipeline())
+
+	err := <-manager.Errors()
+	assert.EqualError(t, err, "module producer failed: listen failed")
+	<-manager.Done()
+	assert.ErrorContains(t, manager.StopPipeline(context.Background()), "module producer failed: listen failed")
+}
+
+func TestManager_StopTimeout(t *testing.T) {
+	manager := Manager{logger: logrus.New()}
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "producer", module: &testChannelRawProducer{output: make(chan *event.Raw)}}, nil))
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "ingester", module: testStuckRawIngester{}, stopTimeout: 10 * time.Millisecond}, []string{"producer"}))
+	assert.NoError(t, manager.StartPipeline())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.ErrorContains(t, manager.StopPipeline(ctx), "module ingester did not stop within 10ms")
+}
+
+func TestManager_StopPipelineContextExpired(t *testing.T) {
+	manager := Manager{logger: logrus.New()}
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "producer", module: &testChannelRawProducer{output: make(chan *event.Raw)}}, nil))
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "ingester", module: testStuckRawIngester{}}, []string{"producer"}))
+	assert.NoError(t, manager.StartPipeline())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, manager.StopPipeline(ctx), context.DeadlineExceeded)
 }
 
-func TestManager_linkModuleWithPipeline(t *testing.T) {
+func TestManager_addModuleToPipeline(t *testing.T) {
 	tests := []struct {
-		manager    Manager
+		name       string
+		pipeline   []*pipelineItem
 		nextModule Module
+		inputs     []string
 		wantErr    bool
 	}{
-		{manager: Manager{pipeline: []pipelineItem{}}, nextModule: testRawProducer{}, wantErr: false},
-		{manager: Manager{pipeline: []pipelineItem{}}, nextModule: testRawIngester{}, wantErr: true},
+		{name: "producer at the beginning", nextModule: testRawProducer{}, wantErr: false},
+		{name: "ingester at the beginning", nextModule: testRawIngester{}, wantErr: true},
+		{name: "producer with inputs", pipeline: []*pipelineItem{{name: "raw", module: testRawProducer{}}}, nextModule: testRawProducer{}, inputs: []string{"raw"}, wantErr: true},
+		{name: "unknown input", pipeline: []*pipelineItem{{name: "raw", module: testRawProducer{}}}, nextModule: testRawIngester{}, inputs: []string{"foo"}, wantErr: true},
+		{name: "duplicate module name", pipeline: []*pipelineItem{{name: "next", module: testRawProducer{}}}, nextModule: testRawIngester{}, inputs: []string{"next"}, wantErr: true},
+		{name: "mismatching event type", pipeline: []*pipelineItem{{name: "slo", module: testSloProducer{}}}, nextModule: testRawIngester{}, inputs: []string{"slo"}, wantErr: true},
+		{name: "fan-in", pipeline: []*pipelineItem{{name: "raw1", module: testRawProducer{}}, {name: "raw2", module: testRawProducer{}}}, nextModule: testRawIngester{}, inputs: []string{"raw1", "raw2"}, wantErr: false},
 	}
 	for _, tt := range tests {
-		err := tt.manager.linkModuleWithPipelineEnd(tt.nextModule)
-		assert.Equal(t, tt.wantErr, err != nil)
+		t.Run(tt.name, func(t *testing.T) {
+			manager := Manager{pipeline: tt.pipeline}
+			err := manager.addModuleToPipeline(&pipelineItem{name: "next", module: tt.nextModule}, tt.inputs)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
 	}
 }
 
+func TestNewManager(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline []config.PipelineEntry
+		wantErr  bool
+	}{
+		{name: "linear pipeline", pipeline: []config.PipelineEntry{{Name: "testRawProducer"}, {Name: "testRawIngester"}}, wantErr: false},
+		{name: "producer output not consumed", pipeline: []config.PipelineEntry{{Name: "testRawProducer"}}, wantErr: true},
+		{name: "ingester linked to explicit input", pipeline: []config.PipelineEntry{{Name: "testRawProducer"}, {Name: "testSloProducer"}, {Name: "testRawIngester", Inputs: []string{"testRawProducer"}}, {Name: "testSloIngester", Inputs: []string{"testSloProducer"}}}, wantErr: false},
+		{name: "ingester implicitly linked to mismatching producer", pipeline: []config.PipelineEntry{{Name: "testRawProducer"}, {Name: "testSloProducer"}, {Name: "testRawIngester"}}, wantErr: true},
+		{name: "multiple instances of the same module type", pipeline: []config.PipelineEntry{{Name: "testRawProducer"}, {Name: "first", Type: "testRawIngester"}, {Name: "second", Type: "testRawIngester", Inputs: []string{"testRawProducer"}}}, wantErr: false},
+		{name: "duplicate instance name", pipeline: []config.PipelineEntry{{Name: "testRawProducer"}, {Name: "first", Type: "testRawIngester"}, {Name: "first", Type: "testRawIngester", Inputs: []string{"testRawProducer"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(viper.Reset)
+			for _, entry := range tt.pipeline {
+				viper.Set("modules."+entry.Name, map[string]interface{}{})
+			}
+			_, err := NewManager(testModuleFactory, &config.Config{Pipeline: tt.pipeline}, logrus.New())
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestManager_FanOutFanIn(t *testing.T) {
+	firstProducer := &testChannelRawProducer{output: make(chan *event.Raw)}
+	secondProducer := &testChannelRawProducer{output: make(chan *event.Raw)}
+	firstIngester := &testCollectingRawIngester{}
+	secondIngester := &testCollectingRawIngester{}
+	manager := Manager{logger: logrus.New()}
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "firstProducer", module: firstProducer}, nil))
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "secondProducer", module: secondProducer}, nil))
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "firstIngester", module: firstIngester}, []string{"firstProducer", "secondProducer"}))
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "secondIngester", module: secondIngester}, []string{"secondProducer"}))
+	assert.NoError(t, manager.StartPipeline())
+
+	firstProducer.output <- &event.Raw{Metadata: stringmap.StringMap{"source": "first"}, Quantity: 1}
+	secondProducer.output <- &event.Raw{Metadata: stringmap.StringMap{"source": "second"}, Quantity: 1}
+	assert.NoError(t, manager.StopPipeline(context.Background()))
+
+	assert.ElementsMatch(t, []string{"first", "second"}, firstIngester.sources())
+	assert.ElementsMatch(t, []string{"second"}, secondIngester.sources())
+	// Ingesters sharing the producer must receive distinct copies of the event.
+	firstIngester.eventsMtx.Lock()
+	for _, e := range firstIngester.events {
+		e.Metadata["source"] = "modified"
+	}
+	firstIngester.eventsMtx.Unlock()
+	assert.ElementsMatch(t, []string{"second"}, secondIngester.sources())
+}
+
+func TestManager_ReloadConfig(t *testing.T) {
+	pipelineConfig := []config.PipelineEntry{{Name: "producer"}, {Name: "first"}, {Name: "second", Inputs: []string{"producer"}}}
+	tests := []struct {
+		name          string
+		pipeline      []config.PipelineEntry
+		moduleConfigs map[string]map[string]interface{}
+		expReloaded   bool
+		expErr        bool
+	}{
+		{name: "reload all modules", pipeline: pipelineConfig, moduleConfigs: map[string]map[string]interface{}{"first": {}, "second": {}}, expReloaded: true},
+		{name: "invalid configuration of single module", pipeline: pipelineConfig, moduleConfigs: map[string]map[string]interface{}{"first": {}, "second": {"invalid": true}}, expErr: true},
+		{name: "missing configuration of single module", pipeline: pipelineConfig, moduleConfigs: map[string]map[string]interface{}{"first": {}}, expErr: true},
+		{name: "changed pipeline structure", pipeline: pipelineConfig[:2], moduleConfigs: map[string]map[string]interface{}{"first": {}, "second": {}}, expErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(viper.Reset)
+			for name, moduleConfig := range tt.moduleConfigs {
+				viper.Set("modules."+name, moduleConfig)
+			}
+			first, second := &testReloadableRawIngester{}, &testReloadableRawIngester{}
+			manager := Manager{pipelineConfig: pipelineConfig, logger: logrus.New()}
+			assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "producer", module: testRawProducer{}}, nil))
+			assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "first", module: first}, []string{"producer"}))
+			assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "second", module: second}, []string{"producer"}))
+
+			err := manager.ReloadConfig(&config.Config{Pipeline: tt.pipeline})
+			assert.Equal(t, tt.expErr, err != nil, err)
+			assert.Equal(t, tt.expReloaded, first.reloaded.Load())
+			assert.Equal(t, tt.expReloaded, second.reloaded.Load())
+		})
+	}
+}
+
+func TestManager_LinkQueueMetrics(t *testing.T) {
+	producer := &testChannelRawProducer{output: make(chan *event.Raw)}
+	manager := Manager{logger: logrus.New()}
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "producer", module: producer}, nil))
+	// The ingester never reads its input, so the events stay in the queue.
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "ingester", module: testRawIngester{}, bufferSize: 3}, []string{"producer"}))
+	assert.NoError(t, manager.StartPipeline())
+
+	producer.output <- &event.Raw{}
+	producer.output <- &event.Raw{}
+	expectedMetrics := `
+# HELP pipeline_link_queue_capacity Capacity of the queue between two modules.
+# TYPE pipeline_link_queue_capacity gauge
+pipeline_link_queue_capacity{from="producer",to="ingester"} 3
+# HELP pipeline_link_queue_length Number of events waiting in the queue between two modules.
+# TYPE pipeline_link_queue_length gauge
+pipeline_link_queue_length{from="producer",to="ingester"} 2
+`
+	assert.Eventually(t, func() bool {
+		return testutil.CollectAndCompare(&manager, strings.NewReader(expectedMetrics)) == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestManager_IngestionLag(t *testing.T) {
+	producer := &testChannelRawProducer{output: make(chan *event.Raw)}
+	ingester := &testCollectingRawIngester{}
+	manager := Manager{logger: logrus.New()}
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "lagProducer", module: producer}, nil))
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "lagIngester", module: ingester}, []string{"lagProducer"}))
+	assert.NoError(t, manager.StartPipeline())
+
+	producer.output <- &event.Raw{Timestamp: time.Now().Add(-time.Minute)}
+	// Events with unknown time of occurrence are not observed.
+	producer.output <- &event.Raw{}
+	assert.NoError(t, manager.StopPipeline(context.Background()))
+
+	metric := &dto.Metric{}
+	assert.NoError(t, eventIngestionLagSeconds.WithLabelValues("lagProducer").(prometheus.Histogram).Write(metric))
+	assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount())
+	assert.GreaterOrEqual(t, metric.GetHistogram().GetSampleSum(), time.Minute.Seconds())
+}
+
+type testReplayableRawIngester struct {
+	testRawIngester
+	err error
+}
+
+func (t testReplayableRawIngester) SetReplayRecorder(ReplayRecorder) error {
+	return t.err
+}
+
+func TestManager_SetReplayRecorder(t *testing.T) {
+	manager, err := newTestManager()
+	assert.NoError(t, err)
+	assert.Error(t, manager.SetReplayRecorder(nil), "pipeline has no replayable module")
+
+	manager.pipeline = append(manager.pipeline, &pipelineItem{name: "replayable", module: testReplayableRawIngester{}})
+	assert.NoError(t, manager.SetReplayRecorder(nil))
+	manager.pipeline = append(manager.pipeline, &pipelineItem{name: "failing", module: testReplayableRawIngester{err: fmt.Errorf("registration failed")}})
+	assert.ErrorContains(t, manager.SetReplayRecorder(nil), "registration failed")
+}
+
 func Test_isIngester(t *testing.T) {
 	tests := []struct {
 		module Module
@@ -166,18 +385,47 @@ func Test_linkModules(t *testing.T) {
 
 func Test_newPipelineItem(t *testing.T) {
 	tests := []struct {
-		moduleName string
-		config     *viper.Viper
-		expErr     bool
+		name         string
+		entry        config.PipelineEntry
+		moduleConfig map[string]interface{}
+		expType      string
+		expErr       bool
 	}{
-		{moduleName: "foo", config: viper.New(), expErr: true},
-		{moduleName: "testRawIngester", config: viper.New(), expErr: true},
+		{name: "unknown module", entry: config.PipelineEntry{Name: "foo"}, moduleConfig: map[string]interface{}{}, expErr: true},
+		{name: "missing module config", entry: config.PipelineEntry{Name: "testRawIngester"}, expErr: true},
+		{name: "missing module name", entry: config.PipelineEntry{Type: "testRawIngester"}, moduleConfig: map[string]interface{}{}, expErr: true},
+		{name: "type derived from name", entry: config.PipelineEntry{Name: "testRawIngester"}, moduleConfig: map[string]interface{}{}, expType: "testRawIngester"},
+		{name: "named instance", entry: config.PipelineEntry{Name: "foo", Type: "testRawIngester"}, moduleConfig: map[string]interface{}{}, expType: "testRawIngester"},
+		{name: "buffered ingester", entry: config.PipelineEntry{Name: "testRawIngester", BufferSize: 10}, moduleConfig: map[string]interface{}{}, expType: "testRawIngester"},
+		{name: "buffered producer", entry: config.PipelineEntry{Name: "testRawProducer", BufferSize: 10}, moduleConfig: map[string]interface{}{}, expErr: true},
+		{name: "negative buffer size", entry: config.PipelineEntry{Name: "testRawIngester", BufferSize: -1}, moduleConfig: map[string]interface{}{}, expErr: true},
+		{name: "concurrent module", entry: config.PipelineEntry{Name: "testConcurrentRawIngester", Concurrency: 4}, moduleConfig: map[string]interface{}{}, expType: "testConcurrentRawIngester"},
+		{name: "concurrency of unsupported module", entry: config.PipelineEntry{Name: "testRawIngester", Concurrency: 4}, moduleConfig: map[string]interface{}{}, expErr: true},
+		{name: "negative concurrency", entry: config.PipelineEntry{Name: "testConcurrentRawIngester", Concurrency: -1}, moduleConfig: map[string]interface{}{}, expErr: true},
+		{name: "stop timeout", entry: config.PipelineEntry{Name: "testRawIngester", StopTimeout: time.Second}, moduleConfig: map[string]interface{}{}, expType: "testRawIngester"},
+		{name: "negative stop timeout", entry: config.PipelineEntry{Name: "testRawIngester", StopTimeout: -time.Second}, moduleConfig: map[string]interface{}{}, expErr: true},
 	}
 
 	for _, tt := range tests {
-		m, err := newTestManager()
-		assert.NoError(t, err)
-		_, err = m.newPipelineItem(tt.moduleName, config.New(logrus.New()), testModuleFactory)
-		assert.Equal(t, tt.expErr, err != nil)
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(viper.Reset)
+			if tt.moduleConfig != nil {
+				viper.Set("modules."+tt.entry.Name, tt.moduleConfig)
+			}
+			var item *pipelineItem
+			module, err := newModule(tt.entry, config.New(logrus.New()), testModuleFactory, logrus.New())
+			if err == nil {
+				item, err = newPipelineItem(tt.entry, module)
+			}
+			assert.Equal(t, tt.expErr, err != nil, err)
+			if err == nil {
+				assert.Equal(t, tt.entry.Name, item.name)
+				assert.Equal(t, tt.expType, item.moduleType)
+				assert.Equal(t, tt.entry.BufferSize, item.bufferSize)
+				if concurrentModule, ok := item.module.(*testConcurrentRawIngester); ok {
+					assert.Equal(t, tt.entry.Concurrency, concurrentModule.concurrency)
+				}
+			}
+		})
 	}
 }
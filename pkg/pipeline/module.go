@@ -1,6 +1,10 @@
 package pipeline
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/seznam/slo-exporter/pkg/event"
@@ -8,18 +12,23 @@ import (
 	"github.com/spf13/viper"
 )
 
-type moduleFactoryFunction func(moduleName string, logger logrus.FieldLogger, conf *viper.Viper) (Module, error)
+// ModuleFactoryFunction creates new instance of the module type, see NewRegisteredModule.
+type ModuleFactoryFunction func(moduleType string, logger logrus.FieldLogger, conf *viper.Viper) (Module, error)
 
-type ModuleConstructor func(viperConfig *viper.Viper) (Module, error)
+// ModuleConstructor creates new module instance from its configuration, see RegisterModuleType.
+type ModuleConstructor func(viperConfig *viper.Viper, logger logrus.FieldLogger) (Module, error)
 
 type EventProcessingDurationObserver interface {
 	Observe(float64)
 }
 
+// Module is a single stage of the pipeline.
 type Module interface {
-	Run()
-	Stop()
-	Done() bool
+	// Run blocks until the module finishes and returns error if it failed.
+	// Producer must close its output channel before returning.
+	// Module with no inputs stops once the context is cancelled, module ingesting events finishes once its input channel is closed.
+	// Context of the ingesting module is cancelled when all its inputs finish, so it can stop any background work.
+	Run(ctx context.Context) error
 }
 
 type ObservableModule interface {
@@ -31,6 +40,56 @@ type PrometheusInstrumentedModule interface {
 	RegisterMetrics(rootRegistry prometheus.Registerer, wrappedRegistry prometheus.Registerer) error
 }
 
+// ReloadableModule is able to replace part of its configuration while running.
+type ReloadableModule interface {
+	Module
+	// PrepareReload loads the new configuration and returns function which applies it.
+	// If error is returned, the module must stay untouched.
+	PrepareReload(viperConfig *viper.Viper) (apply func() error, err error)
+}
+
+// ConcurrentModule is able to process events using multiple workers at once.
+type ConcurrentModule interface {
+	Module
+	// SetConcurrency sets number of workers processing the events, it is called before the module is started.
+	SetConcurrency(workers int)
+}
+
+// DeadLetterSink records events rejected by a module together with the reason.
+type DeadLetterSink interface {
+	Reject(payload interface{}, reason string)
+}
+
+// DeadLetterModule passes the events it rejects to the dead-letter sink.
+type DeadLetterModule interface {
+	Module
+	// SetDeadLetterSink sets the sink for the rejected events, it is called before the module is started.
+	SetDeadLetterSink(sink DeadLetterSink)
+}
+
+// HealthCheckedModule is able to report whether it works properly.
+type HealthCheckedModule interface {
+	Module
+	// CheckHealth returns reason why the module does not work properly or nil if it does.
+	// It is called periodically while the module is running, and the module is considered not ready while it fails.
+	CheckHealth() error
+}
+
+// ReplayRecorder records samples of the metrics at the time of the replayed historical events.
+type ReplayRecorder interface {
+	// Record gathers the metrics and stores their samples with the given timestamp.
+	Record(gatherer prometheus.Gatherer, timestamp time.Time) error
+	// Resolution is the interval between two recorded samples.
+	Resolution() time.Duration
+}
+
+// ReplayableModule is able to record its metrics at the time of the replayed events instead of exposing them.
+type ReplayableModule interface {
+	Module
+	// SetReplayRecorder sets the recorder of the metrics, it is called before the module is started.
+	SetReplayRecorder(recorder ReplayRecorder) error
+}
+
 type WebInterfaceModule interface {
 	Module
 	RegisterInMux(router *mux.Router)
@@ -77,3 +136,17 @@ type ProcessorModule interface {
 	RawEventIngester
 	RawEventProducer
 }
+
+// RunConcurrently runs the worker function in the given number of goroutines and waits for all of them to finish.
+// At least one worker is always started.
+func RunConcurrently(workers int, worker func()) {
+	wg := sync.WaitGroup{}
+	for i := 0; i < max(workers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker()
+		}()
+	}
+	wg.Wait()
+}
@@ -1,17 +1,24 @@
 package pipeline
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"testing"
 
 	"github.com/seznam/slo-exporter/pkg/event"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
 )
 
-func testModuleFactory(moduleName string, _ logrus.FieldLogger, _ *viper.Viper) (Module, error) {
-	switch moduleName {
+func testModuleFactory(moduleType string, _ logrus.FieldLogger, _ *viper.Viper) (Module, error) {
+	switch moduleType {
 	case "testRawIngester":
 		return testRawIngester{}, nil
+	case "testConcurrentRawIngester":
+		return &testConcurrentRawIngester{}, nil
 	case "testRawProducer":
 		return testRawProducer{}, nil
 	case "testSloIngester":
@@ -19,30 +26,47 @@ func testModuleFactory(moduleName string, _ logrus.FieldLogger, _ *viper.Viper)
 	case "testSloProducer":
 		return testSloProducer{}, nil
 	default:
-		return nil, fmt.Errorf("unknown module %s", moduleName)
+		return nil, fmt.Errorf("unknown module type %s", moduleType)
 	}
 }
 
 type testRawIngester struct{}
 
-func (t testRawIngester) Run() {}
+func (t testRawIngester) Run(_ context.Context) error {
+	return nil
+}
 
-func (t testRawIngester) Stop() {}
+func (t testRawIngester) SetInputChannel(chan *event.Raw) {}
 
-func (t testRawIngester) Done() bool {
-	return false
+type testConcurrentRawIngester struct {
+	testRawIngester
+	concurrency int
 }
 
-func (t testRawIngester) SetInputChannel(chan *event.Raw) {}
+func (t *testConcurrentRawIngester) SetConcurrency(workers int) {
+	t.concurrency = workers
+}
 
-type testRawProducer struct{}
+type testReloadableRawIngester struct {
+	testRawIngester
+	reloaded atomic.Bool
+}
 
-func (t testRawProducer) Run() {}
+func (t *testReloadableRawIngester) PrepareReload(conf *viper.Viper) (func() error, error) {
+	if conf.GetBool("invalid") {
+		return nil, fmt.Errorf("invalid configuration")
+	}
+	return func() error {
+		t.reloaded.Store(true)
+		return nil
+	}, nil
+}
 
-func (t testRawProducer) Stop() {}
+type testRawProducer struct{}
 
-func (t testRawProducer) Done() bool {
-	return false
+func (t testRawProducer) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
 }
 
 func (t testRawProducer) OutputChannel() chan *event.Raw {
@@ -51,26 +75,73 @@ func (t testRawProducer) OutputChannel() chan *event.Raw {
 
 type testSloIngester struct{}
 
-func (t testSloIngester) Run() {}
-
-func (t testSloIngester) Stop() {}
-
-func (t testSloIngester) Done() bool {
-	return false
+func (t testSloIngester) Run(_ context.Context) error {
+	return nil
 }
 
 func (t testSloIngester) SetInputChannel(chan *event.Slo) {}
 
 type testSloProducer struct{}
 
-func (t testSloProducer) Run() {}
-
-func (t testSloProducer) Stop() {}
-
-func (t testSloProducer) Done() bool {
-	return false
+func (t testSloProducer) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
 }
 
 func (t testSloProducer) OutputChannel() chan *event.Slo {
 	return make(chan *event.Slo)
 }
+
+// testChannelRawProducer passes events written to its output channel by the test until the pipeline is stopped.
+type testChannelRawProducer struct {
+	output chan *event.Raw
+}
+
+func (t *testChannelRawProducer) Run(ctx context.Context) error {
+	<-ctx.Done()
+	close(t.output)
+	return nil
+}
+
+func (t *testChannelRawProducer) OutputChannel() chan *event.Raw {
+	return t.output
+}
+
+type testCollectingRawIngester struct {
+	input     chan *event.Raw
+	events    []*event.Raw
+	eventsMtx sync.Mutex
+}
+
+func (t *testCollectingRawIngester) Run(_ context.Context) error {
+	for e := range t.input {
+		t.eventsMtx.Lock()
+		t.events = append(t.events, e)
+		t.eventsMtx.Unlock()
+	}
+	return nil
+}
+
+func (t *testCollectingRawIngester) SetInputChannel(c chan *event.Raw) {
+	t.input = c
+}
+
+func (t *testCollectingRawIngester) sources() []string {
+	t.eventsMtx.Lock()
+	defer t.eventsMtx.Unlock()
+	sources := []string{}
+	for _, e := range t.events {
+		sources = append(sources, e.Metadata["source"])
+	}
+	return sources
+}
+
+func TestRunConcurrently(t *testing.T) {
+	for _, workers := range []int{0, 1, 4} {
+		var started atomic.Int64
+		RunConcurrently(workers, func() {
+			started.Inc()
+		})
+		assert.Equal(t, int64(max(workers, 1)), started.Load())
+	}
+}
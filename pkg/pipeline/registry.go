@@ -0,0 +1,63 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var (
+	moduleTypes    = map[string]ModuleConstructor{}
+	moduleTypesMtx sync.RWMutex
+)
+
+// RegisterModuleType makes the module type available to be used in the pipeline configuration.
+// It is meant to be called during the program initialization and panics if the type is already registered.
+func RegisterModuleType(moduleType string, constructor ModuleConstructor) {
+	moduleTypesMtx.Lock()
+	defer moduleTypesMtx.Unlock()
+	if moduleType == "" || constructor == nil {
+		panic("module type must have a name and a constructor")
+	}
+	if _, ok := moduleTypes[moduleType]; ok {
+		panic(fmt.Sprintf("module type %s is already registered", moduleType))
+	}
+	moduleTypes[moduleType] = constructor
+}
+
+// RegisteredModuleTypes returns sorted names of all the registered module types.
+func RegisteredModuleTypes() []string {
+	moduleTypesMtx.RLock()
+	defer moduleTypesMtx.RUnlock()
+	names := make([]string, 0, len(moduleTypes))
+	for name := range moduleTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewRegisteredModule creates new instance of the registered module type, it is the module factory to be passed to the NewManager.
+func NewRegisteredModule(moduleType string, logger logrus.FieldLogger, conf *viper.Viper) (Module, error) {
+	moduleTypesMtx.RLock()
+	constructor, ok := moduleTypes[moduleType]
+	moduleTypesMtx.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown module type %s", moduleType)
+	}
+	return constructor(conf, logger)
+}
+
+// Constructor adapts the NewFromViper function of a module package to the ModuleConstructor.
+func Constructor[T Module](newFromViper func(viperConfig *viper.Viper, logger logrus.FieldLogger) (T, error)) ModuleConstructor {
+	return func(viperConfig *viper.Viper, logger logrus.FieldLogger) (Module, error) {
+		module, err := newFromViper(viperConfig, logger)
+		if err != nil {
+			return nil, err
+		}
+		return module, nil
+	}
+}
@@ -0,0 +1,38 @@
+package pipeline
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterModuleType(t *testing.T) {
+	RegisterModuleType("testRegisteredRawProducer", func(_ *viper.Viper, _ logrus.FieldLogger) (Module, error) {
+		return testRawProducer{}, nil
+	})
+	assert.Contains(t, RegisteredModuleTypes(), "testRegisteredRawProducer")
+	assert.Panics(t, func() {
+		RegisterModuleType("testRegisteredRawProducer", func(_ *viper.Viper, _ logrus.FieldLogger) (Module, error) {
+			return testRawProducer{}, nil
+		})
+	})
+
+	module, err := NewRegisteredModule("testRegisteredRawProducer", logrus.New(), viper.New())
+	assert.NoError(t, err)
+	assert.Equal(t, testRawProducer{}, module)
+	_, err = NewRegisteredModule("testUnknownModule", logrus.New(), viper.New())
+	assert.Error(t, err)
+}
+
+func TestConstructor(t *testing.T) {
+	constructor := Constructor(func(_ *viper.Viper, _ logrus.FieldLogger) (*testConcurrentRawIngester, error) {
+		return nil, errors.New("invalid configuration")
+	})
+	module, err := constructor(viper.New(), logrus.New())
+	assert.Error(t, err)
+	// Typed nil pointer must not be returned as non-nil module.
+	assert.Nil(t, module)
+}
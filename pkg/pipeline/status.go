@@ -0,0 +1,166 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	rawEventType = "raw"
+	sloEventType = "slo"
+)
+
+type moduleStatus struct {
+	Name            string   `json:"name"`
+	Type            string   `json:"type"`
+	Interfaces      []string `json:"interfaces"`
+	InputEventType  string   `json:"input_event_type,omitempty"`
+	OutputEventType string   `json:"output_event_type,omitempty"`
+	Inputs          []string `json:"inputs"`
+	Outputs         []string `json:"outputs"`
+	Done            bool     `json:"done"`
+	EventsIn        uint64   `json:"events_in"`
+	EventsOut       uint64   `json:"events_out"`
+	Backlog         int      `json:"backlog"`
+}
+
+type linkStatus struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Backlog  int    `json:"backlog"`
+	Capacity int    `json:"capacity"`
+}
+
+type pipelineStatus struct {
+	Modules []moduleStatus `json:"modules"`
+	Links   []linkStatus   `json:"links"`
+}
+
+// moduleInterfaces lists all the optional interfaces implemented by the module.
+func moduleInterfaces(module Module) []string {
+	interfaces := []string{}
+	checks := []struct {
+		name       string
+		implements bool
+	}{
+		{name: "rawEventProducer", implements: implements[RawEventProducerModule](module)},
+		{name: "sloEventProducer", implements: implements[SloEventProducerModule](module)},
+		{name: "rawEventIngester", implements: implements[RawEventIngesterModule](module)},
+		{name: "sloEventIngester", implements: implements[SloEventIngesterModule](module)},
+		{name: "observable", implements: implements[ObservableModule](module)},
+		{name: "prometheusInstrumented", implements: implements[PrometheusInstrumentedModule](module)},
+		{name: "webInterface", implements: implements[WebInterfaceModule](module)},
+		{name: "reloadable", implements: implements[ReloadableModule](module)},
+		{name: "concurrent", implements: implements[ConcurrentModule](module)},
+		{name: "deadLetter", implements: implements[DeadLetterModule](module)},
+		{name: "healthChecked", implements: implements[HealthCheckedModule](module)},
+		{name: "replayable", implements: implements[ReplayableModule](module)},
+	}
+	for _, check := range checks {
+		if check.implements {
+			interfaces = append(interfaces, check.name)
+		}
+	}
+	return interfaces
+}
+
+func implements[T any](module Module) bool {
+	_, ok := module.(T)
+	return ok
+}
+
+func inputEventType(module Module) string {
+	switch module.(type) {
+	case RawEventIngesterModule:
+		return rawEventType
+	case SloEventIngesterModule:
+		return sloEventType
+	}
+	return ""
+}
+
+func outputEventType(module Module) string {
+	switch module.(type) {
+	case RawEventProducerModule:
+		return rawEventType
+	case SloEventProducerModule:
+		return sloEventType
+	}
+	return ""
+}
+
+func itemNames(items []*pipelineItem) []string {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.name
+	}
+	return names
+}
+
+// status returns current state of the pipeline modules and links between them.
+func (m *Manager) status() pipelineStatus {
+	m.queuesMtx.RLock()
+	defer m.queuesMtx.RUnlock()
+	backlogs := map[string]int{}
+	links := []linkStatus{}
+	for _, q := range m.queues {
+		from, to := q.link()
+		backlogs[to] += q.length()
+		links = append(links, linkStatus{From: from, To: to, Backlog: q.length(), Capacity: q.capacity()})
+	}
+	modules := make([]moduleStatus, len(m.pipeline))
+	for i, item := range m.pipeline {
+		modules[i] = moduleStatus{
+			Name:            item.name,
+			Type:            item.moduleType,
+			Interfaces:      moduleInterfaces(item.module),
+			InputEventType:  inputEventType(item.module),
+			OutputEventType: outputEventType(item.module),
+			Inputs:          itemNames(item.inputs),
+			Outputs:         itemNames(item.outputs),
+			Done:            item.finished.Load(),
+			EventsIn:        item.eventsIn.Load(),
+			EventsOut:       item.eventsOut.Load(),
+			Backlog:         backlogs[item.name],
+		}
+	}
+	return pipelineStatus{Modules: modules, Links: links}
+}
+
+// writeDot writes the pipeline status as a graph in the Graphviz DOT format.
+func (s pipelineStatus) writeDot(w io.Writer) error {
+	lines := []string{"digraph pipeline {"}
+	for _, module := range s.Modules {
+		label := fmt.Sprintf("%s (%s)\nin: %d, out: %d, backlog: %d, done: %t", module.Name, module.Type, module.EventsIn, module.EventsOut, module.Backlog, module.Done)
+		lines = append(lines, fmt.Sprintf("  %q [shape=box, label=%q];", module.Name, label))
+	}
+	for _, link := range s.Links {
+		lines = append(lines, fmt.Sprintf("  %q -> %q [label=%q];", link.From, link.To, fmt.Sprintf("%d/%d", link.Backlog, link.Capacity)))
+	}
+	lines = append(lines, "}", "")
+	_, err := io.WriteString(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// handlePipelineStatus returns the pipeline status in JSON or in DOT format if requested using the `format` URL parameter.
+func (m *Manager) handlePipelineStatus(w http.ResponseWriter, req *http.Request) {
+	status := m.status()
+	var err error
+	switch format := req.URL.Query().Get("format"); format {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(status)
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		err = status.writeDot(w)
+	default:
+		http.Error(w, "unsupported format "+format+", use json or dot", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		m.logger.Errorf("error writing response: %v", err)
+	}
+}
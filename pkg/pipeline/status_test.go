@@ -0,0 +1,55 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager_handlePipelineStatus(t *testing.T) {
+	producer := &testChannelRawProducer{output: make(chan *event.Raw)}
+	ingester := &testCollectingRawIngester{}
+	manager := Manager{logger: logrus.New()}
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "producer", moduleType: "testChannelRawProducer", module: producer}, nil))
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "ingester", moduleType: "testCollectingRawIngester", module: ingester, bufferSize: 5}, []string{"producer"}))
+	assert.NoError(t, manager.StartPipeline())
+	producer.output <- &event.Raw{}
+	producer.output <- &event.Raw{}
+	assert.NoError(t, manager.StopPipeline(context.Background()))
+
+	expectedStatus := pipelineStatus{
+		Modules: []moduleStatus{
+			{Name: "producer", Type: "testChannelRawProducer", Interfaces: []string{"rawEventProducer"}, OutputEventType: "raw", Inputs: []string{}, Outputs: []string{"ingester"}, Done: true, EventsOut: 2},
+			{Name: "ingester", Type: "testCollectingRawIngester", Interfaces: []string{"rawEventIngester"}, InputEventType: "raw", Inputs: []string{"producer"}, Outputs: []string{}, Done: true, EventsIn: 2},
+		},
+		Links: []linkStatus{{From: "producer", To: "ingester", Capacity: 5}},
+	}
+	expectedDot := `digraph pipeline {
+  "producer" [shape=box, label="producer (testChannelRawProducer)\nin: 0, out: 2, backlog: 0, done: true"];
+  "ingester" [shape=box, label="ingester (testCollectingRawIngester)\nin: 2, out: 0, backlog: 0, done: true"];
+  "producer" -> "ingester" [label="0/5"];
+}
+`
+
+	recorder := httptest.NewRecorder()
+	manager.handlePipelineStatus(recorder, httptest.NewRequest(http.MethodGet, "/pipeline", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var status pipelineStatus
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.Equal(t, expectedStatus, status)
+
+	recorder = httptest.NewRecorder()
+	manager.handlePipelineStatus(recorder, httptest.NewRequest(http.MethodGet, "/pipeline?format=dot", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, expectedDot, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	manager.handlePipelineStatus(recorder, httptest.NewRequest(http.MethodGet, "/pipeline?format=foo", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
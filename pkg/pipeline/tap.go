@@ -0,0 +1,188 @@
+package pipeline
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"go.uber.org/atomic"
+)
+
+const (
+	defaultTapRate       = 10
+	tapSubscriberBufSize = 100
+)
+
+// metadataFilter matches value of the metadata key exactly or using regular expression.
+type metadataFilter struct {
+	key    string
+	value  string
+	regexp *regexp.Regexp
+}
+
+// newMetadataFilter parses filter in format `key=value` or `key=~regexp`.
+func newMetadataFilter(filter string) (metadataFilter, error) {
+	key, value, ok := strings.Cut(filter, "=")
+	if !ok || key == "" {
+		return metadataFilter{}, fmt.Errorf("invalid filter %q, expected key=value or key=~regexp", filter)
+	}
+	if !strings.HasPrefix(value, "~") {
+		return metadataFilter{key: key, value: value}, nil
+	}
+	// Anchor the regexp same as Prometheus does.
+	re, err := regexp.Compile("^(?:" + strings.TrimPrefix(value, "~") + ")$")
+	if err != nil {
+		return metadataFilter{}, fmt.Errorf("invalid regexp of filter %q: %w", filter, err)
+	}
+	return metadataFilter{key: key, regexp: re}, nil
+}
+
+func (f metadataFilter) matches(metadata stringmap.StringMap) bool {
+	if f.regexp != nil {
+		return f.regexp.MatchString(metadata[f.key])
+	}
+	return metadata[f.key] == f.value
+}
+
+// tapSubscriber receives events matching all its filters, at most one per the interval.
+type tapSubscriber struct {
+	filters  []metadataFilter
+	interval time.Duration
+	lastSent time.Time
+	events   chan []byte
+}
+
+func (s *tapSubscriber) matches(metadata stringmap.StringMap) bool {
+	for _, f := range s.filters {
+		if !f.matches(metadata) {
+			return false
+		}
+	}
+	return true
+}
+
+// tap mirrors events read from output of a single module to its subscribers.
+// It is a no-op unless there is any subscriber.
+type tap struct {
+	subscribersCount atomic.Int64
+	subscribersMtx   sync.Mutex
+	subscribers      map[*tapSubscriber]struct{}
+}
+
+func (t *tap) active() bool {
+	return t.subscribersCount.Load() > 0
+}
+
+func (t *tap) subscribe(s *tapSubscriber) {
+	t.subscribersMtx.Lock()
+	defer t.subscribersMtx.Unlock()
+	if t.subscribers == nil {
+		t.subscribers = map[*tapSubscriber]struct{}{}
+	}
+	t.subscribers[s] = struct{}{}
+	t.subscribersCount.Inc()
+}
+
+func (t *tap) unsubscribe(s *tapSubscriber) {
+	t.subscribersMtx.Lock()
+	defer t.subscribersMtx.Unlock()
+	delete(t.subscribers, s)
+	t.subscribersCount.Dec()
+}
+
+// publish passes the event to all the matching subscribers which are not rate limited.
+// The event is serialized only if needed and it is dropped for the subscribers not keeping up, so it never blocks.
+func (t *tap) publish(metadata stringmap.StringMap, marshal func() ([]byte, error)) {
+	t.subscribersMtx.Lock()
+	defer t.subscribersMtx.Unlock()
+	var data []byte
+	now := time.Now()
+	for s := range t.subscribers {
+		if now.Sub(s.lastSent) < s.interval || !s.matches(metadata) {
+			continue
+		}
+		if data == nil {
+			var err error
+			if data, err = marshal(); err != nil {
+				return
+			}
+			data = append(data, '\n')
+		}
+		select {
+		case s.events <- data:
+			s.lastSent = now
+		default:
+		}
+	}
+}
+
+func newTapSubscriber(req *http.Request) (*tapSubscriber, error) {
+	rate := defaultTapRate
+	if rateParam := req.URL.Query().Get("rate"); rateParam != "" {
+		var err error
+		rate, err = strconv.Atoi(rateParam)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid rate %q, expected positive number of events per second", rateParam)
+		}
+	}
+	subscriber := &tapSubscriber{
+		interval: time.Second / time.Duration(rate),
+		events:   make(chan []byte, tapSubscriberBufSize),
+	}
+	for _, filterParam := range req.URL.Query()["filter"] {
+		filter, err := newMetadataFilter(filterParam)
+		if err != nil {
+			return nil, err
+		}
+		subscriber.filters = append(subscriber.filters, filter)
+	}
+	return subscriber, nil
+}
+
+// handleTap streams events read from output of the module given by the `after` URL parameter as JSON lines.
+func (m *Manager) handleTap(w http.ResponseWriter, req *http.Request) {
+	moduleName := req.URL.Query().Get("after")
+	item, ok := m.pipelineItem(moduleName)
+	if !ok {
+		http.Error(w, "unknown module "+moduleName, http.StatusNotFound)
+		return
+	}
+	if len(item.outputs) == 0 {
+		http.Error(w, "module "+moduleName+" does not pass events to any other module", http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	subscriber, err := newTapSubscriber(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	item.tap.subscribe(subscriber)
+	defer item.tap.unsubscribe(subscriber)
+	m.logger.WithField("module", moduleName).Info("started tapping events")
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-req.Context().Done():
+			m.logger.WithField("module", moduleName).Info("stopped tapping events")
+			return
+		case data := <-subscriber.events:
+			if _, err := w.Write(data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newMetadataFilter(t *testing.T) {
+	tests := []struct {
+		filter    string
+		metadata  stringmap.StringMap
+		expResult bool
+		expErr    bool
+	}{
+		{filter: "foo=bar", metadata: stringmap.StringMap{"foo": "bar"}, expResult: true},
+		{filter: "foo=bar", metadata: stringmap.StringMap{"foo": "barbar"}, expResult: false},
+		{filter: "foo=", metadata: stringmap.StringMap{}, expResult: true},
+		{filter: "foo=~ba.", metadata: stringmap.StringMap{"foo": "bar"}, expResult: true},
+		{filter: "foo=~ba", metadata: stringmap.StringMap{"foo": "bar"}, expResult: false},
+		{filter: "foo=~(", expErr: true},
+		{filter: "foo", expErr: true},
+		{filter: "=bar", expErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			filter, err := newMetadataFilter(tt.filter)
+			assert.Equal(t, tt.expErr, err != nil, err)
+			if err == nil {
+				assert.Equal(t, tt.expResult, filter.matches(tt.metadata))
+			}
+		})
+	}
+}
+
+func TestManager_handleTap(t *testing.T) {
+	producer := &testChannelRawProducer{output: make(chan *event.Raw)}
+	manager := Manager{logger: logrus.New()}
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "producer", module: producer}, nil))
+	assert.NoError(t, manager.addModuleToPipeline(&pipelineItem{name: "ingester", module: &testCollectingRawIngester{}}, []string{"producer"}))
+	assert.NoError(t, manager.StartPipeline())
+	defer func() { assert.NoError(t, manager.StopPipeline(context.Background())) }()
+	server := httptest.NewServer(http.HandlerFunc(manager.handleTap))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?after=foo")
+	assert.NoError(t, err)
+	assert.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "?after=producer&filter=foo=bar")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	item, _ := manager.pipelineItem("producer")
+	assert.Eventually(t, item.tap.active, time.Second, 10*time.Millisecond)
+
+	producer.output <- &event.Raw{Metadata: stringmap.StringMap{"foo": "baz"}, Quantity: 1}
+	producer.output <- &event.Raw{Metadata: stringmap.StringMap{"foo": "bar"}, Quantity: 2}
+	scanner := bufio.NewScanner(resp.Body)
+	assert.True(t, scanner.Scan())
+	var tappedEvent event.Raw
+	assert.NoError(t, json.Unmarshal(scanner.Bytes(), &tappedEvent))
+	assert.Equal(t, event.Raw{Metadata: stringmap.StringMap{"foo": "bar"}, Quantity: 2}, tappedEvent)
+}
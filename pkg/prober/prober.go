@@ -1,24 +1,37 @@
 package prober
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 )
 
+// Reasons of the explicitly set status used as the reason label of the probe_status metric,
+// the error itself is not used so the cardinality of the label is bounded.
+const (
+	ReasonInitializing = "initializing"
+	ReasonShuttingDown = "shuttingDown"
+	ReasonNotOk        = "notOk"
+)
+
 var (
-	ErrDefault = fmt.Errorf("initializing")
+	ErrDefault      = fmt.Errorf("initializing")
+	ErrShuttingDown = fmt.Errorf("shutting down")
 
 	status = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "probe_status",
-			Help: "Status of the probes",
+			Help: "Status of the probes, reason label contains names of the failing checks or reason of the explicitly set status.",
 		},
-		[]string{"probe"},
+		[]string{"probe", "reason"},
 	)
 )
 
@@ -56,10 +69,25 @@ func newProber(registry prometheus.Registerer, logger logrus.FieldLogger, name s
 	return &p, nil
 }
 
+// CheckFunc returns reason why the checked component is not healthy or nil if it is.
+type CheckFunc func() error
+
+type check struct {
+	name  string
+	check CheckFunc
+}
+
 // Prober is struct holding information about status.
 type Prober struct {
-	name      string
-	status    error
+	name   string
+	status error
+	checks []check
+	// checksStatus and failingChecks hold result of the last evaluation of the checks.
+	checksStatus  error
+	failingChecks []string
+	// reason is the current value of the reason label of the probe_status metric.
+	reason    string
+	metricSet bool
 	statusMtx sync.Mutex
 	logger    logrus.FieldLogger
 }
@@ -74,17 +102,67 @@ func (p *Prober) NotOk(err error) {
 	p.setStatus(err)
 }
 
+// AddCheck adds named check which has to pass for the Prober to be ok.
+// Checks are evaluated periodically once the RunChecks is called.
+func (p *Prober) AddCheck(name string, checkFunc CheckFunc) {
+	p.statusMtx.Lock()
+	defer p.statusMtx.Unlock()
+	p.checks = append(p.checks, check{name: name, check: checkFunc})
+}
+
+// RunChecks evaluates the checks in given interval until the context is cancelled.
+func (p *Prober) RunChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.evaluateChecks()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Prober) evaluateChecks() {
+	p.statusMtx.Lock()
+	checks := p.checks
+	p.statusMtx.Unlock()
+
+	var checksStatus error
+	var failingChecks []string
+	for _, c := range checks {
+		if err := c.check(); err != nil {
+			checksStatus = multierror.Append(checksStatus, fmt.Errorf("%s: %w", c.name, err))
+			failingChecks = append(failingChecks, c.name)
+		}
+	}
+
+	p.statusMtx.Lock()
+	defer p.statusMtx.Unlock()
+	p.checksStatus = checksStatus
+	p.failingChecks = failingChecks
+	p.updateMetric()
+}
+
 // IsOk returns reason why Prober is not ok. If it is it returns nil.
 func (p *Prober) IsOk() error {
 	p.statusMtx.Lock()
 	defer p.statusMtx.Unlock()
-	return p.status
+	return p.currentStatus()
+}
+
+func (p *Prober) currentStatus() error {
+	if p.status != nil {
+		return p.status
+	}
+	return p.checksStatus
 }
 
 // Allows to use Prober in HTTP life-cycle endpoints.
 func (p *Prober) HandleFunc(w http.ResponseWriter, _ *http.Request) {
-	if p.IsOk() != nil {
-		http.Error(w, p.IsOk().Error(), http.StatusServiceUnavailable)
+	if err := p.IsOk(); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
 		return
 	}
 	if _, err := w.Write([]byte("OK")); err != nil {
@@ -95,13 +173,46 @@ func (p *Prober) HandleFunc(w http.ResponseWriter, _ *http.Request) {
 func (p *Prober) setStatus(err error) {
 	p.statusMtx.Lock()
 	defer p.statusMtx.Unlock()
-	if p.status != nil && err == nil {
+	p.status = err
+	p.updateMetric()
+}
+
+// statusReason returns the reason label of the explicitly set status.
+func statusReason(err error) string {
+	switch {
+	case errors.Is(err, ErrDefault):
+		return ReasonInitializing
+	case errors.Is(err, ErrShuttingDown):
+		return ReasonShuttingDown
+	default:
+		return ReasonNotOk
+	}
+}
+
+// updateMetric sets the probe_status metric according to the current status, it must be called with the statusMtx locked.
+func (p *Prober) updateMetric() {
+	var reason string
+	switch {
+	case p.status != nil:
+		reason = statusReason(p.status)
+	case p.checksStatus != nil:
+		reason = strings.Join(p.failingChecks, ",")
+	}
+	if p.metricSet && reason == p.reason {
+		return
+	}
+	switch {
+	case reason == "" && p.reason != "":
 		p.logger.Infof("changing %s status to ok", p.name)
-		status.WithLabelValues(p.name).Set(1)
+	case reason != "":
+		p.logger.Warnf("changing %s status to not ok, reason: %+v", p.name, p.currentStatus())
 	}
-	if p.status == nil && err != nil {
-		p.logger.Warnf("changing %s status to not ok, reason: %+v", p.name, err)
-		status.WithLabelValues(p.name).Set(0)
+	p.reason = reason
+	p.metricSet = true
+	status.DeletePartialMatch(prometheus.Labels{"probe": p.name})
+	if reason == "" {
+		status.WithLabelValues(p.name, reason).Set(1)
+	} else {
+		status.WithLabelValues(p.name, reason).Set(0)
 	}
-	p.status = err
 }
@@ -1,11 +1,14 @@
 package prober
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -44,3 +47,36 @@ func TestProber_HandleFunc(t *testing.T) {
 	handler.ServeHTTP(rr, req)
 	assert.Equal(t, http.StatusOK, rr.Code)
 }
+
+func TestProber_Checks(t *testing.T) {
+	p, err := NewLiveness(prometheus.NewRegistry(), logrus.New())
+	assert.NoError(t, err)
+	var checkErr error
+	p.AddCheck("foo", func() error { return checkErr })
+	p.AddCheck("bar", func() error { return nil })
+
+	p.evaluateChecks()
+	assert.NoError(t, p.IsOk())
+	assert.Equal(t, 1.0, testutil.ToFloat64(status.WithLabelValues("liveness", "")))
+
+	checkErr = errors.New("broken")
+	p.evaluateChecks()
+	assert.ErrorContains(t, p.IsOk(), "foo: broken")
+	assert.Equal(t, 0.0, testutil.ToFloat64(status.WithLabelValues("liveness", "foo")))
+	assert.Equal(t, 1, testutil.CollectAndCount(status.MustCurryWith(prometheus.Labels{"probe": "liveness"})))
+
+	// Explicitly set status takes precedence over the checks.
+	p.NotOk(ErrDefault)
+	assert.Equal(t, ErrDefault, p.IsOk())
+	assert.Equal(t, 0.0, testutil.ToFloat64(status.WithLabelValues("liveness", ReasonInitializing)))
+	p.NotOk(fmt.Errorf("unexpected failure %d", 42))
+	assert.Equal(t, 0.0, testutil.ToFloat64(status.WithLabelValues("liveness", ReasonNotOk)), "free-form error is not used as the reason")
+	assert.Equal(t, 1, testutil.CollectAndCount(status.MustCurryWith(prometheus.Labels{"probe": "liveness"})))
+	p.Ok()
+	assert.ErrorContains(t, p.IsOk(), "foo: broken")
+
+	checkErr = nil
+	p.evaluateChecks()
+	assert.NoError(t, p.IsOk())
+	assert.Equal(t, 1.0, testutil.ToFloat64(status.WithLabelValues("liveness", "")))
+}
@@ -42,8 +42,8 @@ func (v *aggregatedCounterVector) register(registry prometheus.Registerer) error
 	return nil
 }
 
-func (v *aggregatedCounterVector) addWithExemplar(value float64, labels, exemplarLabels stringmap.StringMap) {
-	v.vector.addWithExemplar(value, labels.Without(v.labelsToDrop), exemplarLabels)
+func (v *aggregatedCounterVector) addWithExemplar(value float64, labels, exemplarLabels stringmap.StringMap, exemplarTimestamp time.Time) {
+	v.vector.addWithExemplar(value, labels.Without(v.labelsToDrop), exemplarLabels, exemplarTimestamp)
 }
 
 type aggregatedCounterVectorSet struct {
@@ -64,12 +64,12 @@ func (s *aggregatedCounterVectorSet) inc(labels stringmap.StringMap) {
 }
 
 func (s *aggregatedCounterVectorSet) add(value float64, labels stringmap.StringMap) {
-	s.addWithExemplar(value, labels, stringmap.StringMap{})
+	s.addWithExemplar(value, labels, stringmap.StringMap{}, time.Time{})
 }
 
-func (s *aggregatedCounterVectorSet) addWithExemplar(value float64, labels, exemplarLabels stringmap.StringMap) {
+func (s *aggregatedCounterVectorSet) addWithExemplar(value float64, labels, exemplarLabels stringmap.StringMap, exemplarTimestamp time.Time) {
 	for _, metric := range s.aggregatedMetrics {
-		metric.addWithExemplar(value, labels, exemplarLabels)
+		metric.addWithExemplar(value, labels, exemplarLabels, exemplarTimestamp)
 	}
 }
 
@@ -91,10 +91,14 @@ type counter struct {
 	exemplar    *dto.Exemplar
 }
 
-func (e *counter) addWithExemplar(value float64, exemplarLabels stringmap.StringMap) {
+// addWithExemplar increases the counter, the exemplar timestamp defaults to the current time if zero.
+func (e *counter) addWithExemplar(value float64, exemplarLabels stringmap.StringMap, exemplarTimestamp time.Time) {
 	e.value += value
 	if len(exemplarLabels) > 0 {
-		exemplar, err := newExemplar(value, time.Now(), prometheus.Labels(exemplarLabels))
+		if exemplarTimestamp.IsZero() {
+			exemplarTimestamp = time.Now()
+		}
+		exemplar, err := newExemplar(value, exemplarTimestamp, prometheus.Labels(exemplarLabels))
 		if err != nil {
 			return
 		}
@@ -110,7 +114,7 @@ type counterVector struct {
 	logger   logrus.FieldLogger
 }
 
-func (e *counterVector) addWithExemplar(value float64, labels, exemplarLabels stringmap.StringMap) {
+func (e *counterVector) addWithExemplar(value float64, labels, exemplarLabels stringmap.StringMap, exemplarTimestamp time.Time) {
 	e.mtx.Lock()
 	defer e.mtx.Unlock()
 	labelsString := labels.String()
@@ -124,7 +128,7 @@ func (e *counterVector) addWithExemplar(value float64, labels, exemplarLabels st
 		}
 		e.counters[labelsString] = newCounter
 	}
-	newCounter.addWithExemplar(value, exemplarLabels)
+	newCounter.addWithExemplar(value, exemplarLabels, exemplarTimestamp)
 }
 
 // We do not know the labels beforehand, so we disable registration time checks by not sending any result to channel.
@@ -1,6 +1,7 @@
 package prometheus_exporter
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -17,28 +18,8 @@ const (
 	metricHelp = "Total number of SLO events exported with it's result and metadata."
 )
 
-var (
-	errorsTotal = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name:        "errors_total",
-			Help:        "Errors occurred during application runtime",
-			ConstLabels: prometheus.Labels{"app": "slo_exporter"},
-		},
-		[]string{"type"})
-	eventKeys = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name:        "event_keys",
-			Help:        "Number of known unique event keys",
-			ConstLabels: prometheus.Labels{"app": "slo_exporter"},
-		})
-	eventKeyCardinalityLimit = prometheus.NewGauge(prometheus.GaugeOpts{
-		Name:        "event_keys_limit",
-		Help:        "Event keys cardinality limit",
-		ConstLabels: prometheus.Labels{"app": "slo_exporter"},
-	})
-)
-
-type labelsNamesConfig struct {
+// LabelsNamesConfig is configuration of names of the labels of the exported metrics.
+type LabelsNamesConfig struct {
 	Result    string
 	SloDomain string
 	SloClass  string
@@ -46,26 +27,41 @@ type labelsNamesConfig struct {
 	EventKey  string
 }
 
-type prometheusExporterConfig struct {
+// PrometheusExporterConfig is configuration of the Prometheus exporter module.
+type PrometheusExporterConfig struct {
 	MetricName                  string
-	LabelNames                  labelsNamesConfig
+	LabelNames                  LabelsNamesConfig
 	MaximumUniqueEventKeys      int
 	ExceededKeyLimitPlaceholder string
 	ExemplarMetadataKeys        []string
+	// MaximumEventAge is the age of the event after which it is dropped instead of being exported, zero disables the check.
+	MaximumEventAge time.Duration
 }
 
 type PrometheusSloEventExporter struct {
 	aggregatedMetricsSet        *aggregatedCounterVectorSet
 	metricName                  string
-	labelNames                  labelsNamesConfig
+	labelNames                  LabelsNamesConfig
 	eventKeyLimit               int
 	exceededKeyLimitPlaceholder string
 	exemplarMetadataKeys        []string
+	maximumEventAge             time.Duration
 	eventKeyCache               map[string]int
 	observer                    pipeline.EventProcessingDurationObserver
+	deadLetterSink              pipeline.DeadLetterSink
+
+	// replayRecorder records the metrics every replay step when replaying historical events.
+	replayRecorder pipeline.ReplayRecorder
+	replayRegistry *prometheus.Registry
+	replayStepEnd  time.Time
+	replayTime     time.Time
+
+	errorsTotal              *prometheus.CounterVec
+	eventKeys                prometheus.Gauge
+	eventKeyCardinalityLimit prometheus.Gauge
+	lateEventsTotal          prometheus.Counter
 
 	inputChannel chan *event.Slo
-	done         bool
 	logger       logrus.FieldLogger
 }
 
@@ -78,8 +74,17 @@ func (e InvalidSloEventResultError) Error() string {
 	return fmt.Sprintf("result '%s' is not valid. Expected one of: %+v", e.result, e.validResults)
 }
 
+type LateSloEventError struct {
+	age        time.Duration
+	maximumAge time.Duration
+}
+
+func (e LateSloEventError) Error() string {
+	return fmt.Sprintf("event is %s old, maximum allowed age is %s", e.age, e.maximumAge)
+}
+
 func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*PrometheusSloEventExporter, error) {
-	config := prometheusExporterConfig{}
+	config := PrometheusExporterConfig{}
 	viperConfig.SetDefault("MetricName", "slo_events_total")
 	viperConfig.SetDefault("LabelNames.Result", "result")
 	viperConfig.SetDefault("LabelNames.SloDomain", "slo_domain")
@@ -94,14 +99,11 @@ func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*Prometh
 	return New(config, logger)
 }
 
-func New(config prometheusExporterConfig, logger logrus.FieldLogger) (*PrometheusSloEventExporter, error) {
-	// initialize and register Prometheus metrics
-	eventKeyCardinalityLimit.Set(float64(config.MaximumUniqueEventKeys))
-
+func New(config PrometheusExporterConfig, logger logrus.FieldLogger) (*PrometheusSloEventExporter, error) {
 	aggregationLabels := []string{config.LabelNames.SloDomain, config.LabelNames.SloClass, config.LabelNames.SloApp, config.LabelNames.EventKey}
 	newAggregatedMetricsSet := newAggregatedCounterVectorSet(config.MetricName, metricHelp, aggregationLabels, logger, config.ExemplarMetadataKeys)
 
-	return &PrometheusSloEventExporter{
+	exporter := PrometheusSloEventExporter{
 		aggregatedMetricsSet: newAggregatedMetricsSet,
 		metricName:           config.MetricName,
 		labelNames:           config.LabelNames,
@@ -111,17 +113,43 @@ func New(config prometheusExporterConfig, logger logrus.FieldLogger) (*Prometheu
 		eventKeyCache:               map[string]int{},
 
 		exemplarMetadataKeys: config.ExemplarMetadataKeys,
+		maximumEventAge:      config.MaximumEventAge,
+
+		errorsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "errors_total",
+				Help:        "Errors occurred during application runtime",
+				ConstLabels: prometheus.Labels{"app": "slo_exporter"},
+			},
+			[]string{"type"}),
+		eventKeys: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name:        "event_keys",
+				Help:        "Number of known unique event keys",
+				ConstLabels: prometheus.Labels{"app": "slo_exporter"},
+			}),
+		eventKeyCardinalityLimit: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "event_keys_limit",
+			Help:        "Event keys cardinality limit",
+			ConstLabels: prometheus.Labels{"app": "slo_exporter"},
+		}),
+		lateEventsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "late_events_total",
+			Help: "Total number of events dropped since they were older than the maximum event age.",
+		}),
 
 		logger:   logger,
 		observer: nil,
-	}, nil
+	}
+	exporter.eventKeyCardinalityLimit.Set(float64(config.MaximumUniqueEventKeys))
+	return &exporter, nil
 }
 
 func (e *PrometheusSloEventExporter) RegisterMetrics(rootRegistry, wrappedRegistry prometheus.Registerer) error {
 	if err := e.aggregatedMetricsSet.register(rootRegistry); err != nil {
 		return err
 	}
-	toRegister := []prometheus.Collector{eventKeyCardinalityLimit, errorsTotal, eventKeys}
+	toRegister := []prometheus.Collector{e.eventKeyCardinalityLimit, e.errorsTotal, e.eventKeys, e.lateEventsTotal}
 	for _, metric := range toRegister {
 		if err := wrappedRegistry.Register(metric); err != nil {
 			return err
@@ -134,35 +162,81 @@ func (e *PrometheusSloEventExporter) String() string {
 	return "prometheusExporter"
 }
 
-func (e *PrometheusSloEventExporter) Stop() {}
-
-func (e *PrometheusSloEventExporter) Done() bool {
-	return e.done
-}
-
 func (e *PrometheusSloEventExporter) SetInputChannel(channel chan *event.Slo) {
 	e.inputChannel = channel
 }
 
-func (e *PrometheusSloEventExporter) Run() {
-	go func() {
-		for newEvent := range e.inputChannel {
-			start := time.Now()
-			e.logger.Debugf("processing event %s", newEvent)
-			err := e.processEvent(newEvent)
-			if err != nil {
-				e.logger.Errorf("unable to process slo event: %+v", err)
-				if errors.As(err, &InvalidSloEventResultError{}) {
-					errorsTotal.With(prometheus.Labels{"type": "InvalidResult"}).Inc()
-				} else {
-					errorsTotal.With(prometheus.Labels{"type": "Unknown"}).Inc()
-				}
+func (e *PrometheusSloEventExporter) Run(_ context.Context) error {
+	for newEvent := range e.inputChannel {
+		start := time.Now()
+		e.logger.Debugf("processing event %s", newEvent)
+		if err := e.recordReplaySteps(newEvent.Timestamp); err != nil {
+			e.logger.Errorf("failed to record replayed metrics: %v", err)
+		}
+		err := e.processEvent(newEvent)
+		var lateEventErr *LateSloEventError
+		if errors.As(err, &lateEventErr) {
+			e.logger.WithField("event", newEvent).Debug(err)
+			e.lateEventsTotal.Inc()
+			e.reject(newEvent, err)
+		} else if err != nil {
+			e.logger.Errorf("unable to process slo event: %+v", err)
+			if errors.As(err, &InvalidSloEventResultError{}) {
+				e.errorsTotal.With(prometheus.Labels{"type": "InvalidResult"}).Inc()
+			} else {
+				e.errorsTotal.With(prometheus.Labels{"type": "Unknown"}).Inc()
 			}
-			e.observeDuration(start)
 		}
-		e.logger.Info("input channel closed, finishing")
-		e.done = true
-	}()
+		e.observeDuration(start)
+	}
+	e.logger.Info("input channel closed, finishing")
+	if e.replayRecorder != nil && !e.replayStepEnd.IsZero() {
+		if err := e.replayRecorder.Record(e.replayRegistry, e.replayStepEnd); err != nil {
+			return fmt.Errorf("failed to record replayed metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetReplayRecorder makes the exporter record its metrics at the end of each replay step instead of exposing them only.
+// Time of the replay is driven by the timestamps of the events, events with unknown timestamp are counted in the current step.
+func (e *PrometheusSloEventExporter) SetReplayRecorder(recorder pipeline.ReplayRecorder) error {
+	replayRegistry := prometheus.NewRegistry()
+	if err := e.aggregatedMetricsSet.register(replayRegistry); err != nil {
+		return fmt.Errorf("failed to register metrics to the replay registry: %w", err)
+	}
+	e.replayRecorder = recorder
+	e.replayRegistry = replayRegistry
+	return nil
+}
+
+// recordReplaySteps records the metrics for all the replay steps which ended before the event occurred.
+func (e *PrometheusSloEventExporter) recordReplaySteps(eventTime time.Time) error {
+	if e.replayRecorder == nil || eventTime.IsZero() {
+		return nil
+	}
+	if eventTime.After(e.replayTime) {
+		e.replayTime = eventTime
+	}
+	resolution := e.replayRecorder.Resolution()
+	if e.replayStepEnd.IsZero() {
+		e.replayStepEnd = eventTime.Truncate(resolution).Add(resolution)
+	}
+	for !eventTime.Before(e.replayStepEnd) {
+		if err := e.replayRecorder.Record(e.replayRegistry, e.replayStepEnd); err != nil {
+			return err
+		}
+		e.replayStepEnd = e.replayStepEnd.Add(resolution)
+	}
+	return nil
+}
+
+// now returns the current time or time of the latest event when replaying historical events.
+func (e *PrometheusSloEventExporter) now() time.Time {
+	if e.replayRecorder != nil {
+		return e.replayTime
+	}
+	return time.Now()
 }
 
 func (e *PrometheusSloEventExporter) RegisterEventProcessingDurationObserver(observer pipeline.EventProcessingDurationObserver) {
@@ -175,6 +249,24 @@ func (e *PrometheusSloEventExporter) observeDuration(start time.Time) {
 	}
 }
 
+func (e *PrometheusSloEventExporter) SetDeadLetterSink(sink pipeline.DeadLetterSink) {
+	e.deadLetterSink = sink
+}
+
+func (e *PrometheusSloEventExporter) reject(sloEvent *event.Slo, err error) {
+	if e.deadLetterSink != nil {
+		e.deadLetterSink.Reject(sloEvent, err.Error())
+	}
+}
+
+// eventAge returns how long ago the event occurred, zero if the time of occurrence is unknown.
+func (e *PrometheusSloEventExporter) eventAge(sloEvent *event.Slo) time.Duration {
+	if sloEvent.Timestamp.IsZero() {
+		return 0
+	}
+	return e.now().Sub(sloEvent.Timestamp)
+}
+
 func (e *PrometheusSloEventExporter) isCardinalityExceeded(eventKey string) bool {
 	if e.eventKeyLimit == 0 {
 		// unlimited
@@ -185,7 +277,7 @@ func (e *PrometheusSloEventExporter) isCardinalityExceeded(eventKey string) bool
 		return true
 	}
 	e.eventKeyCache[eventKey]++
-	eventKeys.Set(float64(len(e.eventKeyCache)))
+	e.eventKeys.Set(float64(len(e.eventKeyCache)))
 	return false
 }
 
@@ -220,6 +312,9 @@ func (e *PrometheusSloEventExporter) processEvent(newEvent *event.Slo) error {
 	if !e.isValidResult(newEvent.Result) {
 		return &InvalidSloEventResultError{string(newEvent.Result), event.PossibleResults}
 	}
+	if age := e.eventAge(newEvent); e.maximumEventAge > 0 && age > e.maximumEventAge {
+		return &LateSloEventError{age: age, maximumAge: e.maximumEventAge}
+	}
 
 	labels := e.labelsFromEvent(newEvent)
 
@@ -233,7 +328,7 @@ func (e *PrometheusSloEventExporter) processEvent(newEvent *event.Slo) error {
 	// add result to metadata
 	labels[e.labelNames.Result] = string(newEvent.Result)
 	if len(e.exemplarMetadataKeys) > 0 {
-		e.aggregatedMetricsSet.addWithExemplar(newEvent.Quantity, labels, newEvent.OriginalEvent.Metadata.Select(e.exemplarMetadataKeys))
+		e.aggregatedMetricsSet.addWithExemplar(newEvent.Quantity, labels, newEvent.OriginalEvent.Metadata.Select(e.exemplarMetadataKeys), newEvent.Timestamp)
 	} else {
 		e.aggregatedMetricsSet.add(newEvent.Quantity, labels)
 	}
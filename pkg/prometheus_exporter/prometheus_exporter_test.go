@@ -1,21 +1,24 @@
 package prometheus_exporter
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/internal/moduletest"
 	"github.com/seznam/slo-exporter/pkg/stringmap"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
-var conf = prometheusExporterConfig{
+var conf = PrometheusExporterConfig{
 	MetricName: "slo_events_total",
-	LabelNames: labelsNamesConfig{
+	LabelNames: LabelsNamesConfig{
 		Result:    "result",
 		SloDomain: "slo_domain",
 		SloClass:  "slo_class",
@@ -86,6 +89,81 @@ func Test_PrometheusSloEventExporter_processEvent(t *testing.T) {
 	}
 }
 
+func Test_PrometheusSloEventExporter_lateEvents(t *testing.T) {
+	lateConf := conf
+	lateConf.MaximumEventAge = time.Minute
+	exporter, err := New(lateConf, logrus.New())
+	assert.NoError(t, err)
+	sink := &moduletest.DeadLetterSink{}
+	exporter.SetDeadLetterSink(sink)
+	input := make(chan *event.Slo, 3)
+	exporter.SetInputChannel(input)
+
+	input <- &event.Slo{Key: "recent", Result: event.Success, Quantity: 1, Timestamp: time.Now()}
+	input <- &event.Slo{Key: "unknown", Result: event.Success, Quantity: 1}
+	input <- &event.Slo{Key: "late", Result: event.Success, Quantity: 1, Timestamp: time.Now().Add(-time.Hour)}
+	close(input)
+	assert.NoError(t, exporter.Run(context.Background()))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(exporter.lateEventsTotal))
+	assert.Len(t, sink.Reasons, 1)
+	assert.Equal(t, 2, len(exporter.eventKeyCache))
+}
+
+type testReplayRecorder struct {
+	timestamps []time.Time
+	values     []float64
+}
+
+func (r *testReplayRecorder) Record(gatherer prometheus.Gatherer, timestamp time.Time) error {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	total := 0.0
+	for _, family := range families {
+		if family.GetName() != aggregatedMetricName(conf.MetricName, conf.LabelNames.SloDomain) {
+			continue
+		}
+		for _, metric := range family.Metric {
+			total += metric.GetCounter().GetValue()
+		}
+	}
+	r.timestamps = append(r.timestamps, timestamp)
+	r.values = append(r.values, total)
+	return nil
+}
+
+func (r *testReplayRecorder) Resolution() time.Duration {
+	return time.Minute
+}
+
+func Test_PrometheusSloEventExporter_replay(t *testing.T) {
+	replayConf := conf
+	replayConf.MaximumEventAge = time.Hour
+	exporter, err := New(replayConf, logrus.New())
+	assert.NoError(t, err)
+	recorder := &testReplayRecorder{}
+	assert.NoError(t, exporter.SetReplayRecorder(recorder))
+	input := make(chan *event.Slo, 5)
+	exporter.SetInputChannel(input)
+
+	start := time.Date(2020, 1, 1, 10, 0, 30, 0, time.UTC)
+	input <- &event.Slo{Key: "a", Result: event.Success, Quantity: 1, Timestamp: start}
+	input <- &event.Slo{Key: "a", Result: event.Success, Quantity: 1, Timestamp: start.Add(10 * time.Second)}
+	// Event with unknown timestamp is counted in the current step.
+	input <- &event.Slo{Key: "a", Result: event.Success, Quantity: 1}
+	input <- &event.Slo{Key: "a", Result: event.Success, Quantity: 1, Timestamp: start.Add(2 * time.Minute)}
+	// Event is late compared to the latest replayed event, not the current time.
+	input <- &event.Slo{Key: "a", Result: event.Success, Quantity: 1, Timestamp: start.Add(-time.Hour)}
+	close(input)
+	assert.NoError(t, exporter.Run(context.Background()))
+
+	assert.Equal(t, []time.Time{start.Add(30 * time.Second), start.Add(90 * time.Second), start.Add(150 * time.Second)}, recorder.timestamps)
+	assert.Equal(t, []float64{3, 3, 4}, recorder.values)
+	assert.Equal(t, 1.0, testutil.ToFloat64(exporter.lateEventsTotal))
+}
+
 func Test_PrometheusSloEventExporter_isValidResult(t *testing.T) {
 	exporter, err := New(conf, logrus.New())
 	if err != nil {
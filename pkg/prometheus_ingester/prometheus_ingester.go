@@ -28,37 +28,45 @@ const (
 	defaultStaleness = time.Minute * 5
 )
 
-var (
-	unsupportedQueryResultType = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "unsupported_query_result_type_total",
-		Help: "Total number of Query results with not supported type.",
-	}, []string{"result_type"})
-	prometheusQueryFail = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "query_fails_total",
-		Help: "Total number of Query fails.",
-	}, []string{"query_type"})
-	prometheusQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-		Name:    "query_duration_seconds",
-		Help:    "Duration of queries on the Prometheus API.",
-		Buckets: prometheus.ExponentialBuckets(0.05, 3, 5),
-	}, []string{"query_type"})
-)
+type queryMetrics struct {
+	unsupportedQueryResultType *prometheus.CounterVec
+	prometheusQueryFail        *prometheus.CounterVec
+	prometheusQueryDuration    *prometheus.HistogramVec
+}
+
+func newQueryMetrics() *queryMetrics {
+	return &queryMetrics{
+		unsupportedQueryResultType: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "unsupported_query_result_type_total",
+			Help: "Total number of Query results with not supported type.",
+		}, []string{"result_type"}),
+		prometheusQueryFail: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "query_fails_total",
+			Help: "Total number of Query fails.",
+		}, []string{"query_type"}),
+		prometheusQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "query_duration_seconds",
+			Help:    "Duration of queries on the Prometheus API.",
+			Buckets: prometheus.ExponentialBuckets(0.05, 3, 5),
+		}, []string{"query_type"}),
+	}
+}
 
-type queryType string
+type QueryType string
 
 const (
-	simpleQueryType    queryType = "simple"
-	counterQueryType   queryType = "counter_increase"
-	histogramQueryType queryType = "histogram_increase"
+	SimpleQueryType    QueryType = "simple"
+	CounterQueryType   QueryType = "counter_increase"
+	HistogramQueryType QueryType = "histogram_increase"
 )
 
-var validQueryTypes = []queryType{
-	simpleQueryType,
-	counterQueryType,
-	histogramQueryType,
+var validQueryTypes = []QueryType{
+	SimpleQueryType,
+	CounterQueryType,
+	HistogramQueryType,
 }
 
-func validateQueryType(queryType queryType) error {
+func validateQueryType(queryType QueryType) error {
 	for _, validQueryType := range validQueryTypes {
 		if queryType == validQueryType {
 			return nil
@@ -67,28 +75,29 @@ func validateQueryType(queryType queryType) error {
 	return fmt.Errorf("unknown query type specified: %s, valid types are %s", queryType, validQueryTypes)
 }
 
-type queryOptions struct {
+// QueryOptions is configuration of single query executed by the Prometheus ingester.
+type QueryOptions struct {
 	Query            string
 	Interval         time.Duration
 	Offset           time.Duration
 	DropLabels       []string
 	AdditionalLabels stringmap.StringMap
-	Type             queryType
+	Type             QueryType
 	ResultAsQuantity *bool
 }
 
-type httpHeaderValueFromEnv struct {
+type HTTPHeaderValueFromEnv struct {
 	Name        string
 	ValuePrefix string
 }
 
-type httpHeader struct {
+type HTTPHeader struct {
 	Name         string
-	ValueFromEnv *httpHeaderValueFromEnv
+	ValueFromEnv *HTTPHeaderValueFromEnv
 	Value        *string
 }
 
-func (h *httpHeader) getValue() (string, error) {
+func (h *HTTPHeader) getValue() (string, error) {
 	if h.Name == "" {
 		return "", fmt.Errorf("header name must be set")
 	}
@@ -111,9 +120,9 @@ func (h *httpHeader) getValue() (string, error) {
 	return *h.Value, nil
 }
 
-type httpHeaders []httpHeader
+type HTTPHeaders []HTTPHeader
 
-func (hs httpHeaders) toMap() (map[string]string, error) {
+func (hs HTTPHeaders) toMap() (map[string]string, error) {
 	headersMap := map[string]string{}
 	for _, h := range hs {
 		value, err := h.getValue()
@@ -126,24 +135,24 @@ func (hs httpHeaders) toMap() (map[string]string, error) {
 	return headersMap, nil
 }
 
+// PrometheusIngesterConfig is configuration of the Prometheus ingester module.
 type PrometheusIngesterConfig struct {
 	APIURL       string
 	RoundTripper http.RoundTripper
-	HTTPHeaders  httpHeaders
-	Queries      []queryOptions
+	HTTPHeaders  HTTPHeaders
+	Queries      []QueryOptions
 	QueryTimeout time.Duration
 	Staleness    time.Duration
 }
 
 type PrometheusIngester struct {
-	queryExecutors  []*queryExecutor
-	queryTimeout    time.Duration
-	client          api.Client
-	api             v1.API
-	shutdownChannel chan struct{}
-	outputChannel   chan *event.Raw
-	logger          logrus.FieldLogger
-	done            bool
+	queryExecutors []*queryExecutor
+	queryTimeout   time.Duration
+	client         api.Client
+	api            v1.API
+	outputChannel  chan *event.Raw
+	metrics        *queryMetrics
+	logger         logrus.FieldLogger
 }
 
 func (i *PrometheusIngester) String() string {
@@ -151,7 +160,7 @@ func (i *PrometheusIngester) String() string {
 }
 
 func (i *PrometheusIngester) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
-	toRegister := []prometheus.Collector{unsupportedQueryResultType, prometheusQueryFail, prometheusQueryDuration}
+	toRegister := []prometheus.Collector{i.metrics.unsupportedQueryResultType, i.metrics.prometheusQueryFail, i.metrics.prometheusQueryDuration}
 	for _, metric := range toRegister {
 		if err := wrappedRegistry.Register(metric); err != nil {
 			return err
@@ -160,14 +169,6 @@ func (i *PrometheusIngester) RegisterMetrics(_, wrappedRegistry prometheus.Regis
 	return nil
 }
 
-func (i *PrometheusIngester) Stop() {
-	close(i.shutdownChannel)
-}
-
-func (i *PrometheusIngester) Done() bool {
-	return i.done
-}
-
 func (i *PrometheusIngester) OutputChannel() chan *event.Raw {
 	return i.outputChannel
 }
@@ -180,7 +181,7 @@ func NewFromViper(viperAppConfig *viper.Viper, logger logrus.FieldLogger, appVer
 	}
 
 	userAgent := "slo-exporter/" + appVersion
-	config.HTTPHeaders = append(config.HTTPHeaders, httpHeader{
+	config.HTTPHeaders = append(config.HTTPHeaders, HTTPHeader{
 		Name:  "user-agent",
 		Value: &userAgent,
 	})
@@ -230,14 +231,13 @@ func New(initConfig PrometheusIngesterConfig, logger logrus.FieldLogger) (*Prome
 	}
 
 	ingester = PrometheusIngester{
-		queryExecutors:  []*queryExecutor{},
-		queryTimeout:    initConfig.QueryTimeout,
-		client:          client,
-		api:             v1.NewAPI(client),
-		outputChannel:   make(chan *event.Raw),
-		done:            false,
-		shutdownChannel: make(chan struct{}),
-		logger:          logger,
+		queryExecutors: []*queryExecutor{},
+		queryTimeout:   initConfig.QueryTimeout,
+		client:         client,
+		api:            v1.NewAPI(client),
+		outputChannel:  make(chan *event.Raw),
+		metrics:        newQueryMetrics(),
+		logger:         logger,
 	}
 
 	for _, q := range initConfig.Queries {
@@ -246,9 +246,9 @@ func New(initConfig PrometheusIngesterConfig, logger logrus.FieldLogger) (*Prome
 		}
 		if q.ResultAsQuantity == nil {
 			switch q.Type {
-			case counterQueryType:
+			case CounterQueryType:
 				q.ResultAsQuantity = newTrue()
-			case histogramQueryType:
+			case HistogramQueryType:
 				q.ResultAsQuantity = newTrue()
 			default:
 				q.ResultAsQuantity = newFalse()
@@ -266,6 +266,7 @@ func New(initConfig PrometheusIngesterConfig, logger logrus.FieldLogger) (*Prome
 					metrics: make(map[model.Fingerprint]model.SamplePair),
 				},
 				staleness: initConfig.Staleness,
+				metrics:   ingester.metrics,
 			},
 		)
 	}
@@ -273,25 +274,35 @@ func New(initConfig PrometheusIngesterConfig, logger logrus.FieldLogger) (*Prome
 	return &ingester, nil
 }
 
-func (i *PrometheusIngester) Run() {
-	go func() {
-		queriesContext, queriesContextCancel := context.WithCancel(context.Background())
-		defer func() {
-			close(i.outputChannel)
-			i.done = true
-		}()
-
-		var wg sync.WaitGroup
-		// Start all queries
-		for _, queryExecutor := range i.queryExecutors {
-			wg.Add(1)
-			go queryExecutor.run(queriesContext, &wg)
+// CheckHealth reports the ingester as unhealthy if the last execution of all its queries failed.
+func (i *PrometheusIngester) CheckHealth() error {
+	var lastErr error
+	for _, queryExecutor := range i.queryExecutors {
+		lastErr = queryExecutor.lastQueryErr.Load()
+		if lastErr == nil {
+			return nil
 		}
+	}
+	if lastErr == nil {
+		return nil
+	}
+	return fmt.Errorf("all %d queries failed, last error: %w", len(i.queryExecutors), lastErr)
+}
 
-		<-i.shutdownChannel
-		queriesContextCancel()
-		i.logger.Info("received shutdown request, waiting for all current ongoing request to finish")
-		wg.Wait()
-		i.logger.Info("all done, finishing")
-	}()
+// Run executes the queries periodically until the context is cancelled.
+func (i *PrometheusIngester) Run(ctx context.Context) error {
+	defer close(i.outputChannel)
+
+	var wg sync.WaitGroup
+	// Start all queries
+	for _, queryExecutor := range i.queryExecutors {
+		wg.Add(1)
+		go queryExecutor.run(ctx, &wg)
+	}
+
+	<-ctx.Done()
+	i.logger.Info("received shutdown request, waiting for all current ongoing request to finish")
+	wg.Wait()
+	i.logger.Info("all done, finishing")
+	return nil
 }
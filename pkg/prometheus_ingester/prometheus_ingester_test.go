@@ -94,7 +94,7 @@ func HTTPRequestsToString(rawResults []*event.Raw) []string {
 
 type modelTypeIngestTestCase struct {
 	prometheusResult model.Value
-	query            queryOptions
+	query            QueryOptions
 	eventsProduced   []*event.Raw
 }
 
@@ -130,27 +130,31 @@ func Test_Ingests_Various_ModelTypes(t *testing.T) {
 					},
 				},
 			},
-			query: queryOptions{
+			query: QueryOptions{
 				Query:            "1",
-				Type:             simpleQueryType,
+				Type:             SimpleQueryType,
 				ResultAsQuantity: newFalse(),
 			},
 			eventsProduced: []*event.Raw{
 				{
-					Metadata: metricStringMap.Merge(stringmap.StringMap{metadataValueKey: "1", metadataTimestampKey: "0"}),
-					Quantity: 1,
+					Metadata:  metricStringMap.Merge(stringmap.StringMap{metadataValueKey: "1", metadataTimestampKey: "0"}),
+					Quantity:  1,
+					Timestamp: model.Time(1).Time(),
 				},
 				{
-					Metadata: metricStringMap.Merge(stringmap.StringMap{metadataValueKey: "2", metadataTimestampKey: "0"}),
-					Quantity: 1,
+					Metadata:  metricStringMap.Merge(stringmap.StringMap{metadataValueKey: "2", metadataTimestampKey: "0"}),
+					Quantity:  1,
+					Timestamp: model.Time(1).Time(),
 				},
 				{
-					Metadata: metricStringMap.Merge(stringmap.StringMap{metadataValueKey: "3", metadataTimestampKey: "0"}),
-					Quantity: 1,
+					Metadata:  metricStringMap.Merge(stringmap.StringMap{metadataValueKey: "3", metadataTimestampKey: "0"}),
+					Quantity:  1,
+					Timestamp: model.Time(1).Time(),
 				},
 				{
-					Metadata: metricStringMap.Merge(stringmap.StringMap{metadataValueKey: "4", metadataTimestampKey: "0"}),
-					Quantity: 1,
+					Metadata:  metricStringMap.Merge(stringmap.StringMap{metadataValueKey: "4", metadataTimestampKey: "0"}),
+					Quantity:  1,
+					Timestamp: model.Time(1).Time(),
 				},
 			},
 		},
@@ -168,17 +172,19 @@ func Test_Ingests_Various_ModelTypes(t *testing.T) {
 					Value:     model.SampleValue(2),
 				},
 			},
-			query: queryOptions{
-				Type:             simpleQueryType,
+			query: QueryOptions{
+				Type:             SimpleQueryType,
 				ResultAsQuantity: newFalse(),
 			}, eventsProduced: []*event.Raw{
 				{
-					Metadata: metricStringMap.Merge(stringmap.StringMap{metadataValueKey: "1", metadataTimestampKey: "0"}),
-					Quantity: 1,
+					Metadata:  metricStringMap.Merge(stringmap.StringMap{metadataValueKey: "1", metadataTimestampKey: "0"}),
+					Quantity:  1,
+					Timestamp: model.Time(1).Time(),
 				},
 				{
-					Metadata: metricStringMap.Merge(stringmap.StringMap{metadataValueKey: "2", metadataTimestampKey: "0"}),
-					Quantity: 1,
+					Metadata:  metricStringMap.Merge(stringmap.StringMap{metadataValueKey: "2", metadataTimestampKey: "0"}),
+					Quantity:  1,
+					Timestamp: model.Time(1).Time(),
 				},
 			},
 		},
@@ -188,13 +194,14 @@ func Test_Ingests_Various_ModelTypes(t *testing.T) {
 				Timestamp: model.Time(1),
 				Value:     model.SampleValue(1),
 			},
-			query: queryOptions{
-				Type:             simpleQueryType,
+			query: QueryOptions{
+				Type:             SimpleQueryType,
 				ResultAsQuantity: newFalse(),
 			}, eventsProduced: []*event.Raw{
 				{
-					Metadata: stringmap.NewFromMetric(make(model.Metric)).Merge(stringmap.StringMap{metadataValueKey: "1", metadataTimestampKey: "0"}),
-					Quantity: 1,
+					Metadata:  stringmap.NewFromMetric(make(model.Metric)).Merge(stringmap.StringMap{metadataValueKey: "1", metadataTimestampKey: "0"}),
+					Quantity:  1,
+					Timestamp: model.Time(1).Time(),
 				},
 			},
 		},
@@ -222,7 +229,7 @@ func Test_Ingests_Various_ModelTypes(t *testing.T) {
 
 type labelAddOrDropTestCase struct {
 	prometheusResult model.Value
-	query            queryOptions
+	query            QueryOptions
 	eventsProduced   []*event.Raw
 }
 
@@ -231,9 +238,9 @@ func Test_Add_Or_Drop_Labels(t *testing.T) {
 	testCases := []labelAddOrDropTestCase{
 		{
 			// Tests addition of non-existent label
-			query: queryOptions{
+			query: QueryOptions{
 				AdditionalLabels: map[string]string{"a": "1"},
-				Type:             simpleQueryType,
+				Type:             SimpleQueryType,
 				ResultAsQuantity: newFalse(),
 			},
 			prometheusResult: model.Vector{
@@ -245,16 +252,17 @@ func Test_Add_Or_Drop_Labels(t *testing.T) {
 			},
 			eventsProduced: []*event.Raw{
 				{
-					Metadata: m.Merge(stringmap.StringMap{"a": "1", "job": "kubernetes", "locality": "nagano", "__name__": "test_metric", metadataValueKey: "1", metadataTimestampKey: "0"}),
-					Quantity: 1,
+					Metadata:  m.Merge(stringmap.StringMap{"a": "1", "job": "kubernetes", "locality": "nagano", "__name__": "test_metric", metadataValueKey: "1", metadataTimestampKey: "0"}),
+					Quantity:  1,
+					Timestamp: model.Time(1).Time(),
 				},
 			},
 		},
 		{
 			// Tests addition of existent label
-			query: queryOptions{
+			query: QueryOptions{
 				AdditionalLabels: map[string]string{"locality": "osaka"},
-				Type:             simpleQueryType,
+				Type:             SimpleQueryType,
 				ResultAsQuantity: newFalse(),
 			},
 			prometheusResult: model.Vector{
@@ -266,16 +274,17 @@ func Test_Add_Or_Drop_Labels(t *testing.T) {
 			},
 			eventsProduced: []*event.Raw{
 				{
-					Metadata: m.Merge(stringmap.StringMap{"job": "kubernetes", "locality": "osaka", "__name__": "test_metric", metadataValueKey: "1", metadataTimestampKey: "0"}),
-					Quantity: 1,
+					Metadata:  m.Merge(stringmap.StringMap{"job": "kubernetes", "locality": "osaka", "__name__": "test_metric", metadataValueKey: "1", metadataTimestampKey: "0"}),
+					Quantity:  1,
+					Timestamp: model.Time(1).Time(),
 				},
 			},
 		},
 		{
 			// Tests dropping existing label
-			query: queryOptions{
+			query: QueryOptions{
 				DropLabels:       []string{"job"},
-				Type:             simpleQueryType,
+				Type:             SimpleQueryType,
 				ResultAsQuantity: newFalse(),
 			},
 			prometheusResult: model.Vector{
@@ -287,16 +296,17 @@ func Test_Add_Or_Drop_Labels(t *testing.T) {
 			},
 			eventsProduced: []*event.Raw{
 				{
-					Metadata: m.Merge(stringmap.StringMap{"locality": "nagano", "__name__": "test_metric", metadataValueKey: "1", metadataTimestampKey: "0"}),
-					Quantity: 1,
+					Metadata:  m.Merge(stringmap.StringMap{"locality": "nagano", "__name__": "test_metric", metadataValueKey: "1", metadataTimestampKey: "0"}),
+					Quantity:  1,
+					Timestamp: model.Time(1).Time(),
 				},
 			},
 		},
 		{
 			// Tests dropping non-existing label
-			query: queryOptions{
+			query: QueryOptions{
 				DropLabels:       []string{"a"},
-				Type:             simpleQueryType,
+				Type:             SimpleQueryType,
 				ResultAsQuantity: newFalse(),
 			},
 			prometheusResult: model.Vector{
@@ -308,19 +318,20 @@ func Test_Add_Or_Drop_Labels(t *testing.T) {
 			},
 			eventsProduced: []*event.Raw{
 				{
-					Metadata: m.Merge(stringmap.StringMap{"job": "kubernetes", "locality": "nagano", "__name__": "test_metric", metadataValueKey: "1", metadataTimestampKey: "0"}),
-					Quantity: 1,
+					Metadata:  m.Merge(stringmap.StringMap{"job": "kubernetes", "locality": "nagano", "__name__": "test_metric", metadataValueKey: "1", metadataTimestampKey: "0"}),
+					Quantity:  1,
+					Timestamp: model.Time(1).Time(),
 				},
 			},
 		},
 		{
 			// Tests that dropping the label being added does not drop the added label :shrug:
-			query: queryOptions{
+			query: QueryOptions{
 				DropLabels: []string{"job"},
 				AdditionalLabels: map[string]string{
 					"job": "openshift",
 				},
-				Type:             simpleQueryType,
+				Type:             SimpleQueryType,
 				ResultAsQuantity: newFalse(),
 			},
 			prometheusResult: model.Vector{
@@ -332,8 +343,9 @@ func Test_Add_Or_Drop_Labels(t *testing.T) {
 			},
 			eventsProduced: []*event.Raw{
 				{
-					Metadata: m.Merge(stringmap.StringMap{"job": "openshift", "locality": "nagano", "__name__": "test_metric", metadataValueKey: "1", metadataTimestampKey: "0"}),
-					Quantity: 1,
+					Metadata:  m.Merge(stringmap.StringMap{"job": "openshift", "locality": "nagano", "__name__": "test_metric", metadataValueKey: "1", metadataTimestampKey: "0"}),
+					Quantity:  1,
+					Timestamp: model.Time(1).Time(),
 				},
 			},
 		},
@@ -376,11 +388,11 @@ func TestIngesterScalar_Interval_run(t *testing.T) {
 	ingester, err := New(PrometheusIngesterConfig{
 		RoundTripper: roundTripper,
 		QueryTimeout: 400 * time.Millisecond,
-		Queries: []queryOptions{
+		Queries: []QueryOptions{
 			{
 				Query:            "1",
 				Interval:         interval,
-				Type:             simpleQueryType,
+				Type:             SimpleQueryType,
 				ResultAsQuantity: newFalse(),
 			},
 		},
@@ -402,11 +414,7 @@ func TestIngesterScalar_Interval_run(t *testing.T) {
 	// The whole test should take no longer than two seconds (we have only 500ms of intentionally blocking time)
 	ctx, cancelFunc := context.WithTimeout(context.Background(), runFor)
 	defer cancelFunc()
-	go func() {
-		<-ctx.Done()
-		ingester.shutdownChannel <- struct{}{}
-	}()
-	ingester.Run()
+	assert.NoError(t, ingester.Run(ctx))
 
 	<-done
 	assert.Equal(t, expectedEventsCount, len(genEvents), "Running query every '%v' for '%v' was expected to generate %v events", interval, runFor, expectedEventsCount)
@@ -448,9 +456,9 @@ func TestGetQueryWithRangeSelector(t *testing.T) {
 	testCases := []testCase{
 		{
 			&queryExecutor{
-				Query: queryOptions{
+				Query: QueryOptions{
 					Query:            query,
-					Type:             counterQueryType,
+					Type:             CounterQueryType,
 					Interval:         interval,
 					ResultAsQuantity: newTrue(),
 				},
@@ -461,9 +469,9 @@ func TestGetQueryWithRangeSelector(t *testing.T) {
 		},
 		{
 			&queryExecutor{
-				Query: queryOptions{
+				Query: QueryOptions{
 					Query:            query,
-					Type:             counterQueryType,
+					Type:             CounterQueryType,
 					Interval:         interval,
 					ResultAsQuantity: newTrue(),
 				},
@@ -541,8 +549,9 @@ func Test_processMetricsIncrease(t *testing.T) {
 			},
 			expectedEvents: []*event.Raw{
 				{
-					Metadata: stringmap.NewFromMetric(x).Merge(stringmap.StringMap{metadataValueKey: "10", metadataTimestampKey: fmt.Sprintf("%d", ts.Add(time.Minute*2).Unix())}),
-					Quantity: 10,
+					Metadata:  stringmap.NewFromMetric(x).Merge(stringmap.StringMap{metadataValueKey: "10", metadataTimestampKey: fmt.Sprintf("%d", ts.Add(time.Minute*2).Unix())}),
+					Quantity:  10,
+					Timestamp: ts.Add(time.Minute * 2).Time(),
 				},
 			},
 		},
@@ -600,8 +609,9 @@ func Test_processMetricsIncrease(t *testing.T) {
 			},
 			expectedEvents: []*event.Raw{
 				{
-					Metadata: stringmap.NewFromMetric(x).Merge(stringmap.StringMap{metadataValueKey: "10", metadataTimestampKey: fmt.Sprintf("%d", ts.Add(time.Minute*4).Unix())}),
-					Quantity: 10,
+					Metadata:  stringmap.NewFromMetric(x).Merge(stringmap.StringMap{metadataValueKey: "10", metadataTimestampKey: fmt.Sprintf("%d", ts.Add(time.Minute*4).Unix())}),
+					Quantity:  10,
+					Timestamp: ts.Add(time.Minute * 4).Time(),
 				},
 			},
 		},
@@ -610,9 +620,9 @@ func Test_processMetricsIncrease(t *testing.T) {
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
 			q := &queryExecutor{
-				Query: queryOptions{
+				Query: QueryOptions{
 					Interval:         time.Second * 20,
-					Type:             counterQueryType,
+					Type:             CounterQueryType,
 					ResultAsQuantity: newTrue(),
 				},
 				staleness:      testCase.staleness,
@@ -688,11 +698,11 @@ func Test_processHistogramIncrease(t *testing.T) {
 				},
 			},
 			expectedEvents: []*event.Raw{
-				{Metadata: stringmap.StringMap{"__name__": "histogram_bucket", "foo": "bar", "le": "1", metadataTimestampKey: ts.String(), metadataHistogramMinValue: "-Inf", metadataHistogramMaxValue: "1", metadataValueKey: "2"}, Quantity: 2},
-				{Metadata: stringmap.StringMap{"__name__": "histogram_bucket", "foo": "bar", "le": "3", metadataTimestampKey: ts.String(), metadataHistogramMinValue: "1", metadataHistogramMaxValue: "3", metadataValueKey: "6"}, Quantity: 6},
-				{Metadata: stringmap.StringMap{"__name__": "histogram_bucket", "foo": "bar", "le": "+Inf", metadataTimestampKey: ts.String(), metadataHistogramMinValue: "6", metadataHistogramMaxValue: "+Inf", metadataValueKey: "2"}, Quantity: 2},
-				{Metadata: stringmap.StringMap{"__name__": "histogram_bucket", "foo": "xxx", "le": "0.5", metadataTimestampKey: ts.String(), metadataHistogramMinValue: "-Inf", metadataHistogramMaxValue: "0.5", metadataValueKey: "2"}, Quantity: 2},
-				{Metadata: stringmap.StringMap{"__name__": "histogram_bucket", "foo": "xxx", "le": "+Inf", metadataTimestampKey: ts.String(), metadataHistogramMinValue: "0.5", metadataHistogramMaxValue: "+Inf", metadataValueKey: "8"}, Quantity: 8},
+				{Metadata: stringmap.StringMap{"__name__": "histogram_bucket", "foo": "bar", "le": "1", metadataTimestampKey: ts.String(), metadataHistogramMinValue: "-Inf", metadataHistogramMaxValue: "1", metadataValueKey: "2"}, Quantity: 2, Timestamp: ts.Time()},
+				{Metadata: stringmap.StringMap{"__name__": "histogram_bucket", "foo": "bar", "le": "3", metadataTimestampKey: ts.String(), metadataHistogramMinValue: "1", metadataHistogramMaxValue: "3", metadataValueKey: "6"}, Quantity: 6, Timestamp: ts.Time()},
+				{Metadata: stringmap.StringMap{"__name__": "histogram_bucket", "foo": "bar", "le": "+Inf", metadataTimestampKey: ts.String(), metadataHistogramMinValue: "6", metadataHistogramMaxValue: "+Inf", metadataValueKey: "2"}, Quantity: 2, Timestamp: ts.Time()},
+				{Metadata: stringmap.StringMap{"__name__": "histogram_bucket", "foo": "xxx", "le": "0.5", metadataTimestampKey: ts.String(), metadataHistogramMinValue: "-Inf", metadataHistogramMaxValue: "0.5", metadataValueKey: "2"}, Quantity: 2, Timestamp: ts.Time()},
+				{Metadata: stringmap.StringMap{"__name__": "histogram_bucket", "foo": "xxx", "le": "+Inf", metadataTimestampKey: ts.String(), metadataHistogramMinValue: "0.5", metadataHistogramMaxValue: "+Inf", metadataValueKey: "8"}, Quantity: 8, Timestamp: ts.Time()},
 			},
 		},
 	}
@@ -700,8 +710,8 @@ func Test_processHistogramIncrease(t *testing.T) {
 	for _, testCase := range testCases {
 		q := &queryExecutor{
 			eventsChan: make(chan *event.Raw),
-			Query: queryOptions{
-				Type:             histogramQueryType,
+			Query: QueryOptions{
+				Type:             HistogramQueryType,
 				ResultAsQuantity: newTrue(),
 			},
 			previousResult: resultFromSampleStreams(testCase.ts, testCase.data, 0),
@@ -775,7 +785,7 @@ httpHeaders:
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var headers struct {
-				HTTPHeaders httpHeaders
+				HTTPHeaders HTTPHeaders
 			}
 			v := viper.New()
 			v.SetConfigType("yaml")
@@ -814,7 +824,7 @@ func Test_httpHeader_getValue(t *testing.T) {
 
 	type fields struct {
 		Name         string
-		ValueFromEnv *httpHeaderValueFromEnv
+		ValueFromEnv *HTTPHeaderValueFromEnv
 		Value        *string
 	}
 	tests := []struct {
@@ -824,24 +834,24 @@ func Test_httpHeader_getValue(t *testing.T) {
 		wantErr bool
 	}{
 		{name: "value", fields: fields{Name: headerName, Value: &headerValue}, want: headerValue},
-		{name: "valueFromEnv", fields: fields{Name: headerName, ValueFromEnv: &httpHeaderValueFromEnv{Name: envName}}, want: headerValueFromEnvValue},
+		{name: "valueFromEnv", fields: fields{Name: headerName, ValueFromEnv: &HTTPHeaderValueFromEnv{Name: envName}}, want: headerValueFromEnvValue},
 		{
 			name: "valueFromEnv with prefix",
 			fields: fields{
 				Name:         headerName,
-				ValueFromEnv: &httpHeaderValueFromEnv{Name: envName, ValuePrefix: headerValueFromEnvPrefix},
+				ValueFromEnv: &HTTPHeaderValueFromEnv{Name: envName, ValuePrefix: headerValueFromEnvPrefix},
 			},
 			want: headerValueFromEnvPrefix + headerValueFromEnvValue,
 		},
-		{name: "valueFromEnv non existing env", fields: fields{Name: headerName, ValueFromEnv: &httpHeaderValueFromEnv{Name: nonExistingEnv}}, wantErr: true},
-		{name: "valueFromEnv no env name set", fields: fields{Name: headerName, ValueFromEnv: &httpHeaderValueFromEnv{}}, wantErr: true},
+		{name: "valueFromEnv non existing env", fields: fields{Name: headerName, ValueFromEnv: &HTTPHeaderValueFromEnv{Name: nonExistingEnv}}, wantErr: true},
+		{name: "valueFromEnv no env name set", fields: fields{Name: headerName, ValueFromEnv: &HTTPHeaderValueFromEnv{}}, wantErr: true},
 		{name: "header name not set", fields: fields{Name: "", Value: &headerValue}, wantErr: true},
 		{name: "no value neither valueFromEnv set", fields: fields{Name: headerName}, wantErr: true},
-		{name: "value and valueFromEnv", fields: fields{Name: headerName, ValueFromEnv: &httpHeaderValueFromEnv{}, Value: &headerValue}, wantErr: true},
+		{name: "value and valueFromEnv", fields: fields{Name: headerName, ValueFromEnv: &HTTPHeaderValueFromEnv{}, Value: &headerValue}, wantErr: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			h := &httpHeader{
+			h := &HTTPHeader{
 				Name:         tt.fields.Name,
 				ValueFromEnv: tt.fields.ValueFromEnv,
 				Value:        tt.fields.Value,
@@ -859,12 +869,12 @@ func Test_httpHeader_getValue(t *testing.T) {
 func Test_queryOffset(t *testing.T) {
 	type testCase struct {
 		name      string
-		queryOpts queryOptions
+		queryOpts QueryOptions
 	}
 
 	cases := []testCase{
-		{name: "no offset expected", queryOpts: queryOptions{Query: "up", Interval: time.Second, Type: "simple"}},
-		{name: "offset expected", queryOpts: queryOptions{Query: "up", Interval: time.Second, Type: "simple", Offset: time.Minute}},
+		{name: "no offset expected", queryOpts: QueryOptions{Query: "up", Interval: time.Second, Type: "simple"}},
+		{name: "offset expected", queryOpts: QueryOptions{Query: "up", Interval: time.Second, Type: "simple", Offset: time.Minute}},
 	}
 
 	for _, tc := range cases {
@@ -879,7 +889,7 @@ func Test_queryOffset(t *testing.T) {
 			ingester, err := New(PrometheusIngesterConfig{
 				RoundTripper: roundTripper,
 				QueryTimeout: 400 * time.Millisecond,
-				Queries: []queryOptions{
+				Queries: []QueryOptions{
 					tc.queryOpts,
 				},
 			}, logrus.New())
@@ -892,3 +902,18 @@ func Test_queryOffset(t *testing.T) {
 		})
 	}
 }
+
+func TestPrometheusIngester_CheckHealth(t *testing.T) {
+	first, second := &queryExecutor{}, &queryExecutor{}
+	ingester := PrometheusIngester{queryExecutors: []*queryExecutor{first, second}}
+	assert.NoError(t, ingester.CheckHealth())
+
+	first.lastQueryErr.Store(fmt.Errorf("connection refused"))
+	assert.NoError(t, ingester.CheckHealth())
+
+	second.lastQueryErr.Store(fmt.Errorf("timeout"))
+	assert.ErrorContains(t, ingester.CheckHealth(), "all 2 queries failed")
+
+	first.lastQueryErr.Store(nil)
+	assert.NoError(t, ingester.CheckHealth())
+}
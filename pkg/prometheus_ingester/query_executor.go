@@ -21,7 +21,7 @@ import (
 )
 
 type queryExecutor struct {
-	Query             queryOptions
+	Query             QueryOptions
 	queryTimeout      time.Duration
 	eventsChan        chan *event.Raw
 	logger            logrus.FieldLogger
@@ -30,6 +30,10 @@ type queryExecutor struct {
 	previousResult    queryResult
 	previousResultMtx sync.RWMutex
 	staleness         time.Duration
+	metrics           *queryMetrics
+
+	// lastQueryErr holds error of the last query execution, nil if it succeeded or no query was executed yet.
+	lastQueryErr atomic.Error
 }
 
 type queryResult struct {
@@ -88,11 +92,11 @@ func (q *queryExecutor) execute(ctx context.Context, ts time.Time) (model.Value,
 		query    string
 	)
 	switch q.Query.Type {
-	case histogramQueryType:
+	case HistogramQueryType:
 		query = q.withRangeSelector(ts)
-	case counterQueryType:
+	case CounterQueryType:
 		query = q.withRangeSelector(ts)
-	case simpleQueryType:
+	case SimpleQueryType:
 		query = q.Query.Query
 	default:
 		return nil, ts, fmt.Errorf("unknown query type: '%s'", q.Query.Type)
@@ -100,7 +104,7 @@ func (q *queryExecutor) execute(ctx context.Context, ts time.Time) (model.Value,
 	start := time.Now()
 	result, warnings, err = q.api.Query(timeoutCtx, query, ts)
 	duration := time.Since(start)
-	prometheusQueryDuration.WithLabelValues(string(q.Query.Type)).Observe(duration.Seconds())
+	q.metrics.prometheusQueryDuration.WithLabelValues(string(q.Query.Type)).Observe(duration.Seconds())
 	q.logger.WithField("query", query).WithField("timestamp", ts).WithField("duration", duration).Debug("executed query")
 	if len(warnings) > 0 {
 		q.logger.WithField("query", query).Warnf("warnings in query execution: %+v", warnings)
@@ -115,7 +119,7 @@ func (q *queryExecutor) run(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	// make sure that this metric is exposed even when no errors have been experienced
-	prometheusQueryFail.WithLabelValues(string(q.Query.Type)).Add(0)
+	q.metrics.prometheusQueryFail.WithLabelValues(string(q.Query.Type)).Add(0)
 
 	for {
 		select {
@@ -126,8 +130,9 @@ func (q *queryExecutor) run(ctx context.Context, wg *sync.WaitGroup) {
 				continue
 			}
 			result, queryTS, err := q.execute(ctx, time.Now())
+			q.lastQueryErr.Store(err)
 			if err != nil {
-				prometheusQueryFail.WithLabelValues(string(q.Query.Type)).Inc()
+				q.metrics.prometheusQueryFail.WithLabelValues(string(q.Query.Type)).Inc()
 				q.logger.WithField("query", q.Query.Query).Errorf("failed querying Prometheus: '%+v'", err)
 				continue
 			}
@@ -143,7 +148,7 @@ func (q *queryExecutor) run(ctx context.Context, wg *sync.WaitGroup) {
 
 func (q *queryExecutor) ProcessResult(result model.Value, ts time.Time) error {
 	switch q.Query.Type {
-	case histogramQueryType:
+	case HistogramQueryType:
 		switch r := result.(type) {
 		case model.Matrix:
 			if err := q.processHistogramIncrease(r, ts); err != nil {
@@ -151,19 +156,19 @@ func (q *queryExecutor) ProcessResult(result model.Value, ts time.Time) error {
 			}
 			return nil
 		default:
-			unsupportedQueryResultType.WithLabelValues(result.Type().String()).Inc()
+			q.metrics.unsupportedQueryResultType.WithLabelValues(result.Type().String()).Inc()
 			return fmt.Errorf("unsupported Prometheus value type '%s' for query type '%s'", result.Type().String(), q.Query.Type)
 		}
-	case counterQueryType:
+	case CounterQueryType:
 		switch r := result.(type) {
 		case model.Matrix:
 			q.processCountersIncrease(r, ts)
 			return nil
 		default:
-			unsupportedQueryResultType.WithLabelValues(result.Type().String()).Inc()
+			q.metrics.unsupportedQueryResultType.WithLabelValues(result.Type().String()).Inc()
 			return fmt.Errorf("unsupported Prometheus value type '%s' for query type '%s'", result.Type().String(), q.Query.Type)
 		}
-	case simpleQueryType:
+	case SimpleQueryType:
 		switch r := result.(type) {
 		case model.Matrix:
 			return q.processMatrixResult(r)
@@ -172,7 +177,7 @@ func (q *queryExecutor) ProcessResult(result model.Value, ts time.Time) error {
 		case *model.Scalar:
 			return q.processScalarResult(r)
 		default:
-			unsupportedQueryResultType.WithLabelValues(result.Type().String()).Inc()
+			q.metrics.unsupportedQueryResultType.WithLabelValues(result.Type().String()).Inc()
 			return fmt.Errorf("unsupported Prometheus value type '%s' for query type '%s'", result.Type().String(), q.Query.Type)
 		}
 	default:
@@ -193,8 +198,9 @@ func (q *queryExecutor) emitEvent(ts time.Time, result float64, metadata stringm
 		return
 	}
 	e := &event.Raw{
-		Metadata: metadata,
-		Quantity: quantity,
+		Metadata:  metadata,
+		Quantity:  quantity,
+		Timestamp: ts,
 	}
 	e.Metadata = e.Metadata.Merge(stringmap.StringMap{
 		metadataValueKey:     fmt.Sprintf("%g", result),
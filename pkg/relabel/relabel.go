@@ -1,7 +1,9 @@
 package relabel
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -15,12 +17,15 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var droppedEventsTotal = prometheus.NewCounter(prometheus.CounterOpts{
-	Name: "dropped_events_total",
-	Help: "Total number of dropped events.",
-})
-
 func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*EventRelabelManager, error) {
+	relabelConf, err := relabelConfigFromViper(viperConfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromConfig(relabelConf, logger)
+}
+
+func relabelConfigFromViper(viperConfig *viper.Viper) ([]relabel.Config, error) {
 	// Viper unmarshal the nested structure to nested structure of interface{} types.
 	// Prometheus relabel uses classic YAML unmarshalling so we marshall the structure to YAML again and then let
 	// Prometheus code validate it and unmarshall it.
@@ -32,7 +37,7 @@ func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*EventRe
 	if err := yaml.UnmarshalStrict(marshalledConfig, &relabelConf); err != nil {
 		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
-	return NewFromConfig(relabelConf, logger)
+	return relabelConf, nil
 }
 
 // New returns requestNormalizer which allows to add Key to RequestEvent.
@@ -40,30 +45,33 @@ func NewFromConfig(relabelConfig []relabel.Config, logger logrus.FieldLogger) (*
 	relabelManager := EventRelabelManager{
 		relabelConfig: relabelConfig,
 		outputChannel: make(chan *event.Raw),
-		logger:        logger,
+		droppedEventsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "dropped_events_total",
+			Help: "Total number of dropped events.",
+		}),
+		logger: logger,
 	}
 	return &relabelManager, nil
 }
 
 type EventRelabelManager struct {
-	relabelConfig []relabel.Config
-	observer      pipeline.EventProcessingDurationObserver
-	inputChannel  chan *event.Raw
-	outputChannel chan *event.Raw
-	done          bool
-	logger        logrus.FieldLogger
+	relabelConfig      []relabel.Config
+	relabelConfigMtx   sync.RWMutex
+	observer           pipeline.EventProcessingDurationObserver
+	deadLetterSink     pipeline.DeadLetterSink
+	inputChannel       chan *event.Raw
+	outputChannel      chan *event.Raw
+	droppedEventsTotal prometheus.Counter
+	concurrency        int
+	logger             logrus.FieldLogger
 }
 
 func (r *EventRelabelManager) String() string {
 	return "relabel"
 }
 
-func (r *EventRelabelManager) Done() bool {
-	return r.done
-}
-
 func (r *EventRelabelManager) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
-	return wrappedRegistry.Register(droppedEventsTotal)
+	return wrappedRegistry.Register(r.droppedEventsTotal)
 }
 
 func (r *EventRelabelManager) SetInputChannel(channel chan *event.Raw) {
@@ -74,7 +82,33 @@ func (r *EventRelabelManager) OutputChannel() chan *event.Raw {
 	return r.outputChannel
 }
 
-func (r *EventRelabelManager) Stop() {}
+func (r *EventRelabelManager) SetConcurrency(workers int) {
+	r.concurrency = workers
+}
+
+// PrepareReload loads new relabel configs which replace the current ones once applied.
+func (r *EventRelabelManager) PrepareReload(viperConfig *viper.Viper) (func() error, error) {
+	relabelConf, err := relabelConfigFromViper(viperConfig)
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		r.relabelConfigMtx.Lock()
+		defer r.relabelConfigMtx.Unlock()
+		r.relabelConfig = relabelConf
+		return nil
+	}, nil
+}
+
+func (r *EventRelabelManager) SetDeadLetterSink(sink pipeline.DeadLetterSink) {
+	r.deadLetterSink = sink
+}
+
+func (r *EventRelabelManager) reject(e *event.Raw, reason string) {
+	if r.deadLetterSink != nil {
+		r.deadLetterSink.Reject(e, reason)
+	}
+}
 
 func (r *EventRelabelManager) RegisterEventProcessingDurationObserver(observer pipeline.EventProcessingDurationObserver) {
 	r.observer = observer
@@ -89,8 +123,11 @@ func (r *EventRelabelManager) observeDuration(start time.Time) {
 // relabelEvent applies the relabel configs on the event metadata.
 // If event is about to be dropped, nil is returned.
 func (r *EventRelabelManager) relabelEvent(e *event.Raw) *event.Raw {
+	r.relabelConfigMtx.RLock()
+	relabelConfig := r.relabelConfig
+	r.relabelConfigMtx.RUnlock()
 	newLabels := e.Metadata.AsPrometheusLabels()
-	for _, relabelConfigRule := range r.relabelConfig {
+	for _, relabelConfigRule := range relabelConfig {
 		newLabels = relabel.Process(newLabels, &relabelConfigRule)
 		if newLabels == nil {
 			return nil
@@ -100,25 +137,26 @@ func (r *EventRelabelManager) relabelEvent(e *event.Raw) *event.Raw {
 	return e
 }
 
-// Run event replacer receiving events and filling their Key if not already filled.
-func (r *EventRelabelManager) Run() {
-	go func() {
-		defer func() {
-			close(r.outputChannel)
-			r.done = true
-		}()
-		for newEvent := range r.inputChannel {
-			start := time.Now()
-			relabeledEvent := r.relabelEvent(newEvent)
-			if relabeledEvent == nil {
-				r.logger.WithField("event", newEvent).Debug("dropping event")
-				droppedEventsTotal.Inc()
-				continue
-			}
-			r.logger.WithField("event", newEvent).Debug("relabeled event")
-			r.outputChannel <- relabeledEvent
-			r.observeDuration(start)
+func (r *EventRelabelManager) processEvents() {
+	for newEvent := range r.inputChannel {
+		start := time.Now()
+		relabeledEvent := r.relabelEvent(newEvent)
+		if relabeledEvent == nil {
+			r.logger.WithField("event", newEvent).Debug("dropping event")
+			r.droppedEventsTotal.Inc()
+			r.reject(newEvent, "dropped by relabel config")
+			continue
 		}
-		r.logger.Info("input channel closed, finishing")
-	}()
+		r.logger.WithField("event", newEvent).Debug("relabeled event")
+		r.outputChannel <- relabeledEvent
+		r.observeDuration(start)
+	}
+}
+
+// Run event replacer receiving events and filling their Key if not already filled.
+func (r *EventRelabelManager) Run(_ context.Context) error {
+	defer close(r.outputChannel)
+	pipeline.RunConcurrently(r.concurrency, r.processEvents)
+	r.logger.Info("input channel closed, finishing")
+	return nil
 }
@@ -2,10 +2,12 @@ package relabel
 
 import (
 	"bytes"
+	"context"
 	"testing"
 
 	"github.com/prometheus/prometheus/pkg/relabel"
 	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/internal/moduletest"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
@@ -106,3 +108,74 @@ eventRelabelConfigs:
 		assert.NotNilf(t, err, "Expected error but no one occurred")
 	})
 }
+
+func TestEventRelabelManager_PrepareReload(t *testing.T) {
+	var config []relabel.Config
+	err := yaml.UnmarshalStrict([]byte(`[{source_labels: ["to_be_dropped"], regex: "true", action: drop}]`), &config)
+	assert.NoError(t, err)
+	mgr, err := NewFromConfig(config, logrus.New())
+	assert.NoError(t, err)
+
+	invalidConfig := viper.New()
+	invalidConfig.Set("eventRelabelConfigs", []map[string]interface{}{{"action": "foo"}})
+	_, err = mgr.PrepareReload(invalidConfig)
+	assert.Error(t, err)
+
+	apply, err := mgr.PrepareReload(viper.New())
+	assert.NoError(t, err)
+	assert.Nil(t, mgr.relabelEvent(&event.Raw{Metadata: map[string]string{"to_be_dropped": "true"}}), "relabel configs must not change before the reload is applied")
+
+	assert.NoError(t, apply())
+	assert.NotNil(t, mgr.relabelEvent(&event.Raw{Metadata: map[string]string{"to_be_dropped": "true"}}))
+}
+
+func TestEventRelabelManager_RunConcurrently(t *testing.T) {
+	mgr, err := NewFromConfig([]relabel.Config{}, logrus.New())
+	assert.NoError(t, err)
+	mgr.SetConcurrency(4)
+	input := make(chan *event.Raw)
+	mgr.SetInputChannel(input)
+	go func() {
+		assert.NoError(t, mgr.Run(context.Background()))
+	}()
+	eventsCount := 100
+	go func() {
+		for i := 0; i < eventsCount; i++ {
+			input <- &event.Raw{Metadata: map[string]string{"foo": "bar"}}
+		}
+		close(input)
+	}()
+	processed := 0
+	for range mgr.OutputChannel() {
+		processed++
+	}
+	assert.Equal(t, eventsCount, processed)
+}
+
+func TestEventRelabelManager_DeadLetterSink(t *testing.T) {
+	var config []relabel.Config
+	err := yaml.UnmarshalStrict([]byte(`[{source_labels: ["to_be_dropped"], regex: "true", action: drop}]`), &config)
+	assert.NoError(t, err)
+	mgr, err := NewFromConfig(config, logrus.New())
+	assert.NoError(t, err)
+	sink := &moduletest.DeadLetterSink{}
+	mgr.SetDeadLetterSink(sink)
+	input := make(chan *event.Raw)
+	mgr.SetInputChannel(input)
+	go func() {
+		assert.NoError(t, mgr.Run(context.Background()))
+	}()
+	droppedEvent := &event.Raw{Metadata: map[string]string{"to_be_dropped": "true"}}
+	go func() {
+		input <- droppedEvent
+		input <- &event.Raw{Metadata: map[string]string{"foo": "bar"}}
+		close(input)
+	}()
+	processed := 0
+	for range mgr.OutputChannel() {
+		processed++
+	}
+	assert.Equal(t, 1, processed)
+	assert.Equal(t, []interface{}{droppedEvent}, sink.Payloads)
+	assert.Equal(t, []string{"dropped by relabel config"}, sink.Reasons)
+}
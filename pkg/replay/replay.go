@@ -0,0 +1,122 @@
+// Package replay records metrics computed from historical events so they can be backfilled to Prometheus.
+package replay
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+)
+
+// Recorder collects samples of the gathered metrics with timestamps of the replayed events.
+// The samples are written in the OpenMetrics text format which can be converted to Prometheus TSDB blocks
+// using `promtool tsdb create-blocks-from openmetrics`.
+// All the samples are kept in memory until they are written, since the samples of every series have to be grouped together,
+// so their number is limited to maxSamples.
+type Recorder struct {
+	resolution time.Duration
+	maxSamples int
+	samples    int
+	families   map[string]*dto.MetricFamily
+	mtx        sync.Mutex
+}
+
+// New returns a Recorder expecting the samples to be recorded every resolution, recording fails if more than maxSamples samples is recorded.
+func New(resolution time.Duration, maxSamples int) (*Recorder, error) {
+	if resolution <= 0 {
+		return nil, fmt.Errorf("resolution must be positive, got %s", resolution)
+	}
+	if maxSamples <= 0 {
+		return nil, fmt.Errorf("maximum number of samples must be positive, got %d", maxSamples)
+	}
+	return &Recorder{
+		resolution: resolution,
+		maxSamples: maxSamples,
+		families:   map[string]*dto.MetricFamily{},
+	}, nil
+}
+
+func (r *Recorder) Resolution() time.Duration {
+	return r.resolution
+}
+
+// Record gathers the metrics and stores their samples with the given timestamp.
+func (r *Recorder) Record(gatherer prometheus.Gatherer, timestamp time.Time) error {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+	samples := 0
+	for _, family := range families {
+		samples += len(family.Metric)
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.samples+samples > r.maxSamples {
+		return fmt.Errorf("recorded samples exceed the limit of %d samples, replay shorter period, use longer resolution or increase the limit", r.maxSamples)
+	}
+	r.samples += samples
+	for _, family := range families {
+		recorded, ok := r.families[family.GetName()]
+		if !ok {
+			recorded = &dto.MetricFamily{Name: family.Name, Help: family.Help, Type: family.Type}
+			r.families[family.GetName()] = recorded
+		}
+		for _, metric := range family.Metric {
+			sample := proto.Clone(metric).(*dto.Metric)
+			// The exemplar would be repeated in every recorded sample.
+			if sample.Counter != nil {
+				sample.Counter.Exemplar = nil
+			}
+			sample.TimestampMs = proto.Int64(timestamp.UnixMilli())
+			recorded.Metric = append(recorded.Metric, sample)
+		}
+	}
+	return nil
+}
+
+// labelsKey returns string uniquely identifying the label set of the metric.
+func labelsKey(metric *dto.Metric) string {
+	key := ""
+	for _, label := range metric.Label {
+		key += fmt.Sprintf("%s=%q,", label.GetName(), label.GetValue())
+	}
+	return key
+}
+
+// WriteTo writes all the recorded samples in the OpenMetrics text format.
+// Samples of every metric family are grouped by the label set and ordered by the timestamp.
+func (r *Recorder) WriteTo(w io.Writer) (int64, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var written int64
+	for _, name := range names {
+		family := r.families[name]
+		sort.SliceStable(family.Metric, func(i, j int) bool {
+			iKey, jKey := labelsKey(family.Metric[i]), labelsKey(family.Metric[j])
+			if iKey != jKey {
+				return iKey < jKey
+			}
+			return family.Metric[i].GetTimestampMs() < family.Metric[j].GetTimestampMs()
+		})
+		n, err := expfmt.MetricFamilyToOpenMetrics(w, family)
+		written += int64(n)
+		if err != nil {
+			return written, fmt.Errorf("failed to write metric %s: %w", name, err)
+		}
+	}
+	n, err := expfmt.FinalizeOpenMetrics(w)
+	written += int64(n)
+	return written, err
+}
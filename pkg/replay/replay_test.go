@@ -0,0 +1,61 @@
+package replay
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New(0, 10)
+	assert.Error(t, err)
+	_, err = New(time.Minute, 0)
+	assert.Error(t, err)
+	recorder, err := New(time.Minute, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, recorder.Resolution())
+}
+
+func TestRecorder_WriteTo(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "events_total", Help: "Total number of events."}, []string{"result"})
+	registry.MustRegister(counter)
+	recorder, err := New(time.Minute, 10)
+	assert.NoError(t, err)
+
+	counter.WithLabelValues("success").Inc()
+	assert.NoError(t, recorder.Record(registry, time.Unix(60, 0)))
+	counter.WithLabelValues("success").Inc()
+	counter.WithLabelValues("fail").Inc()
+	assert.NoError(t, recorder.Record(registry, time.Unix(120, 0)))
+
+	expected := `# HELP events Total number of events.
+# TYPE events counter
+events_total{result="fail"} 1.0 120.0
+events_total{result="success"} 1.0 60.0
+events_total{result="success"} 2.0 120.0
+# EOF
+`
+	out := &bytes.Buffer{}
+	written, err := recorder.WriteTo(out)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(expected)), written)
+	assert.Equal(t, expected, out.String())
+}
+
+func TestRecorder_RecordMaxSamples(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "events_total", Help: "Total number of events."}, []string{"result"})
+	registry.MustRegister(counter)
+	counter.WithLabelValues("success").Inc()
+	counter.WithLabelValues("fail").Inc()
+	recorder, err := New(time.Minute, 3)
+	assert.NoError(t, err)
+
+	assert.NoError(t, recorder.Record(registry, time.Unix(60, 0)))
+	assert.Error(t, recorder.Record(registry, time.Unix(120, 0)))
+	assert.Equal(t, 2, recorder.samples, "samples of the step exceeding the limit are not recorded")
+}
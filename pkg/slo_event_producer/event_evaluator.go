@@ -1,9 +1,12 @@
 package slo_event_producer
 
 import (
+	"sync"
+
 	"github.com/hashicorp/go-multierror"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/pipeline"
 	"github.com/seznam/slo-exporter/pkg/stringmap"
 	"github.com/sirupsen/logrus"
 )
@@ -20,7 +23,7 @@ func configFromFile(path string) (*rulesConfig, error) {
 	return &config, nil
 }
 
-func NewEventEvaluatorFromConfigFiles(paths []string, logger logrus.FieldLogger) (*EventEvaluator, error) {
+func configFromFiles(paths []string) (*rulesConfig, error) {
 	var config rulesConfig
 	for _, path := range paths {
 		tmpConfig, err := configFromFile(path)
@@ -29,40 +32,73 @@ func NewEventEvaluatorFromConfigFiles(paths []string, logger logrus.FieldLogger)
 		}
 		config.Rules = append(config.Rules, tmpConfig.Rules...)
 	}
-	evaluator, err := NewEventEvaluatorFromConfig(&config, logger)
+	return &config, nil
+}
+
+func NewEventEvaluatorFromConfigFiles(paths []string, logger logrus.FieldLogger) (*EventEvaluator, error) {
+	config, err := configFromFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	evaluator, err := NewEventEvaluatorFromConfig(config, logger)
 	if err != nil {
 		return nil, err
 	}
 	return evaluator, nil
 }
 
-func NewEventEvaluatorFromConfig(config *rulesConfig, logger logrus.FieldLogger) (*EventEvaluator, error) {
+// rulesFromConfig creates evaluation rules from the configuration, all invalid rules are reported in the returned error.
+func rulesFromConfig(config *rulesConfig, logger logrus.FieldLogger) ([]*evaluationRule, error) {
 	var configurationErrors error
-	evaluator := EventEvaluator{
-		rules:  []*evaluationRule{},
-		logger: logger,
-	}
+	rules := []*evaluationRule{}
 	for _, ruleOpts := range config.Rules {
 		rule, err := newEvaluationRule(ruleOpts, logger)
 		if err != nil {
 			configurationErrors = multierror.Append(configurationErrors, err)
 			continue
 		}
-		evaluator.AddEvaluationRule(rule)
+		rules = append(rules, rule)
+	}
+	return rules, configurationErrors
+}
+
+func NewEventEvaluatorFromConfig(config *rulesConfig, logger logrus.FieldLogger) (*EventEvaluator, error) {
+	rules, configurationErrors := rulesFromConfig(config, logger)
+	evaluator := EventEvaluator{
+		rules: rules,
+		unclassifiedEventsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "unclassified_events_total",
+			Help: "Total number of dropped events without classification.",
+		}),
+		didNotMatchAnyRule: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "events_not_matching_any_rule_total",
+			Help: "Total number of events not matching any SLO rule.",
+		}),
+		evaluationDurationSeconds: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "evaluation_duration_seconds",
+			Help:    "Histogram of event evaluation duration.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 5, 7),
+		}),
+		logger: logger,
 	}
 	return &evaluator, configurationErrors
 }
 
 type EventEvaluator struct {
-	rules  []*evaluationRule
-	logger logrus.FieldLogger
+	rules                     []*evaluationRule
+	rulesMtx                  sync.RWMutex
+	deadLetterSink            pipeline.DeadLetterSink
+	unclassifiedEventsTotal   prometheus.Counter
+	didNotMatchAnyRule        prometheus.Counter
+	evaluationDurationSeconds prometheus.Histogram
+	logger                    logrus.FieldLogger
 }
 
-func (re *EventEvaluator) ruleOptionsToMetrics() (metrics []metric, possibleLabels []string) {
+func ruleOptionsToMetrics(rules []*evaluationRule) (metrics []metric, possibleLabels []string) {
 	metrics = []metric{}
 	possibleLabelsMap := stringmap.StringMap{}
 
-	for _, rule := range re.rules {
+	for _, rule := range rules {
 		for _, failureCondition := range rule.failureConditions {
 			exposableFailureCondition, ok := failureCondition.(exposableOperator)
 			if !ok {
@@ -88,8 +124,9 @@ func (re *EventEvaluator) ruleOptionsToMetrics() (metrics []metric, possibleLabe
 	return metrics, possibleLabelsMap.Keys()
 }
 
-func (re *EventEvaluator) registerMetrics(wrappedRegistry prometheus.Registerer) error {
-	metrics, possibleLabels := re.ruleOptionsToMetrics()
+// thresholdsGaugeVec creates metric with thresholds of the given rules.
+func thresholdsGaugeVec(rules []*evaluationRule) (*prometheus.GaugeVec, error) {
+	metrics, possibleLabels := ruleOptionsToMetrics(rules)
 	thresholdsGaugeVec := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        metricFromRulesName,
@@ -103,32 +140,69 @@ func (re *EventEvaluator) registerMetrics(wrappedRegistry prometheus.Registerer)
 		labels.AddKeys(possibleLabels...)
 		m, err := thresholdsGaugeVec.GetMetricWith(prometheus.Labels(labels.Merge(metric.Labels)))
 		if err != nil {
-			return err
+			return nil, err
 		}
 		m.Set(metric.Value)
 	}
+	return thresholdsGaugeVec, nil
+}
 
-	err := wrappedRegistry.Register(thresholdsGaugeVec)
+// thresholdsCollector exposes thresholds of the current rules. Since labels of the metric change with the rules,
+// it is an unchecked collector not describing any metrics in advance.
+type thresholdsCollector struct {
+	evaluator *EventEvaluator
+}
+
+func (c thresholdsCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c thresholdsCollector) Collect(ch chan<- prometheus.Metric) {
+	metric, err := thresholdsGaugeVec(c.evaluator.currentRules())
 	if err != nil {
+		c.evaluator.logger.Errorf("failed to expose rules as metrics: %v", err)
+		return
+	}
+	metric.Collect(ch)
+}
+
+func (re *EventEvaluator) registerMetrics(wrappedRegistry prometheus.Registerer) error {
+	if _, err := thresholdsGaugeVec(re.currentRules()); err != nil {
 		return err
 	}
-	return nil
+	return wrappedRegistry.Register(thresholdsCollector{evaluator: re})
 }
 
 func (re *EventEvaluator) AddEvaluationRule(rule *evaluationRule) {
+	re.rulesMtx.Lock()
+	defer re.rulesMtx.Unlock()
 	re.rules = append(re.rules, rule)
 }
 
+// setRules replaces all the evaluation rules at once.
+func (re *EventEvaluator) setRules(rules []*evaluationRule) {
+	re.rulesMtx.Lock()
+	defer re.rulesMtx.Unlock()
+	re.rules = rules
+}
+
+func (re *EventEvaluator) currentRules() []*evaluationRule {
+	re.rulesMtx.RLock()
+	defer re.rulesMtx.RUnlock()
+	return re.rules
+}
+
 func (re *EventEvaluator) Evaluate(newEvent *event.Raw, outChan chan<- *event.Slo) {
 	if !newEvent.IsClassified() {
-		unclassifiedEventsTotal.Inc()
+		re.unclassifiedEventsTotal.Inc()
 		re.logger.Warnf("dropping event %s with no classification", newEvent)
+		if re.deadLetterSink != nil {
+			re.deadLetterSink.Reject(newEvent, "missing classification")
+		}
 		return
 	}
-	timer := prometheus.NewTimer(evaluationDurationSeconds)
+	timer := prometheus.NewTimer(re.evaluationDurationSeconds)
 	defer timer.ObserveDuration()
 	matchedRulesCount := 0
-	for _, rule := range re.rules {
+	for _, rule := range re.currentRules() {
 		newSloEvent, matched := rule.processEvent(newEvent)
 		if !matched {
 			continue
@@ -138,6 +212,6 @@ func (re *EventEvaluator) Evaluate(newEvent *event.Raw, outChan chan<- *event.Sl
 	}
 	if matchedRulesCount == 0 {
 		re.logger.Warnf("event %+v did not match any SLO rule", newEvent)
-		didNotMatchAnyRule.Inc()
+		re.didNotMatchAnyRule.Inc()
 	}
 }
@@ -150,7 +150,7 @@ func TestConfig_getMetricsFromRuleOptions(t *testing.T) {
 				if err != nil {
 					t.Error(err)
 				}
-				metrics, _ = evaluator.ruleOptionsToMetrics()
+				metrics, _ = ruleOptionsToMetrics(evaluator.rules)
 				assert.Equal(t, testCase.ExpectedMetric, metrics)
 			},
 		)
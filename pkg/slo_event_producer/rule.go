@@ -149,6 +149,7 @@ func (er *evaluationRule) processEvent(newEvent *event.Raw) (*event.Slo, bool) {
 		App:           eventSloClassification.App,
 		Metadata:      er.additionalMetadata,
 		Quantity:      newEvent.Quantity,
+		Timestamp:     newEvent.Timestamp,
 		OriginalEvent: *newEvent,
 	}
 	er.markEventResult(failed, newSloEvent)
@@ -1,6 +1,7 @@
 package slo_event_producer
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -12,31 +13,14 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var (
-	unclassifiedEventsTotal = prometheus.NewCounter(prometheus.CounterOpts{
-		Name: "unclassified_events_total",
-		Help: "Total number of dropped events without classification.",
-	})
-
-	didNotMatchAnyRule = prometheus.NewCounter(prometheus.CounterOpts{
-		Name: "events_not_matching_any_rule_total",
-		Help: "Total number of events not matching any SLO rule.",
-	})
-
-	evaluationDurationSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
-		Name:    "evaluation_duration_seconds",
-		Help:    "Histogram of event evaluation duration.",
-		Buckets: prometheus.ExponentialBuckets(0.0001, 5, 7),
-	})
-)
-
-type sloEventProducerConfig struct {
+// SloEventProducerConfig is configuration of the SLO event producer module.
+type SloEventProducerConfig struct {
 	ExposeRulesAsMetrics bool
 	RulesFiles           []string
 }
 
 func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*SloEventProducer, error) {
-	var config sloEventProducerConfig
+	var config SloEventProducerConfig
 	if err := viperConfig.UnmarshalExact(&config); err != nil {
 		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
@@ -44,7 +28,7 @@ func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*SloEven
 	return New(config, logger)
 }
 
-func New(config sloEventProducerConfig, logger logrus.FieldLogger) (*SloEventProducer, error) {
+func New(config SloEventProducerConfig, logger logrus.FieldLogger) (*SloEventProducer, error) {
 	eventEvaluator, err := NewEventEvaluatorFromConfigFiles(config.RulesFiles, logger)
 	if err != nil {
 		return nil, err
@@ -55,7 +39,6 @@ func New(config sloEventProducerConfig, logger logrus.FieldLogger) (*SloEventPro
 		outputChannel:        make(chan *event.Slo),
 		logger:               logger,
 		exposeRulesInMetrics: config.ExposeRulesAsMetrics,
-		done:                 false,
 	}, nil
 }
 
@@ -66,7 +49,7 @@ type SloEventProducer struct {
 	outputChannel        chan *event.Slo
 	logger               logrus.FieldLogger
 	exposeRulesInMetrics bool
-	done                 bool
+	concurrency          int
 }
 
 func (sep *SloEventProducer) String() string {
@@ -82,7 +65,7 @@ func (sep *SloEventProducer) SetInputChannel(channel chan *event.Raw) {
 }
 
 func (sep *SloEventProducer) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
-	toRegister := []prometheus.Collector{didNotMatchAnyRule, evaluationDurationSeconds, unclassifiedEventsTotal}
+	toRegister := []prometheus.Collector{sep.eventEvaluator.didNotMatchAnyRule, sep.eventEvaluator.evaluationDurationSeconds, sep.eventEvaluator.unclassifiedEventsTotal}
 	for _, collector := range toRegister {
 		if err := wrappedRegistry.Register(collector); err != nil {
 			return err
@@ -96,10 +79,37 @@ func (sep *SloEventProducer) RegisterMetrics(_, wrappedRegistry prometheus.Regis
 	return nil
 }
 
-func (sep *SloEventProducer) Stop() {}
+// PrepareReload loads the rules files again, only the rules are reloaded, rest of the configuration requires restart.
+func (sep *SloEventProducer) PrepareReload(viperConfig *viper.Viper) (func() error, error) {
+	var config SloEventProducerConfig
+	if err := viperConfig.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	rulesConfig, err := configFromFiles(config.RulesFiles)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := rulesFromConfig(rulesConfig, sep.logger)
+	if err != nil {
+		return nil, err
+	}
+	if sep.exposeRulesInMetrics {
+		if _, err := thresholdsGaugeVec(rules); err != nil {
+			return nil, fmt.Errorf("failed to expose rules as metrics: %w", err)
+		}
+	}
+	return func() error {
+		sep.eventEvaluator.setRules(rules)
+		return nil
+	}, nil
+}
+
+func (sep *SloEventProducer) SetConcurrency(workers int) {
+	sep.concurrency = workers
+}
 
-func (sep *SloEventProducer) Done() bool {
-	return sep.done
+func (sep *SloEventProducer) SetDeadLetterSink(sink pipeline.DeadLetterSink) {
+	sep.eventEvaluator.deadLetterSink = sink
 }
 
 func (sep *SloEventProducer) RegisterEventProcessingDurationObserver(observer pipeline.EventProcessingDurationObserver) {
@@ -116,17 +126,17 @@ func (sep *SloEventProducer) generateSLOEvents(e *event.Raw, sloEventsChan chan<
 	sep.eventEvaluator.Evaluate(e, sloEventsChan)
 }
 
-func (sep *SloEventProducer) Run() {
-	go func() {
-		defer func() {
-			close(sep.outputChannel)
-			sep.done = true
-		}()
-		for newEvent := range sep.inputChannel {
-			start := time.Now()
-			sep.generateSLOEvents(newEvent, sep.outputChannel)
-			sep.observeDuration(start)
-		}
-		sep.logger.Info("input channel closed, finishing")
-	}()
+func (sep *SloEventProducer) processEvents() {
+	for newEvent := range sep.inputChannel {
+		start := time.Now()
+		sep.generateSLOEvents(newEvent, sep.outputChannel)
+		sep.observeDuration(start)
+	}
+}
+
+func (sep *SloEventProducer) Run(_ context.Context) error {
+	defer close(sep.outputChannel)
+	pipeline.RunConcurrently(sep.concurrency, sep.processEvents)
+	sep.logger.Info("input channel closed, finishing")
+	return nil
 }
@@ -0,0 +1,45 @@
+package slo_event_producer
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func evaluatedEventsCount(sep *SloEventProducer) int {
+	out := make(chan *event.Slo, 100)
+	sep.generateSLOEvents(&event.Raw{Metadata: stringmap.StringMap{"statusCode": "502"}, SloClassification: &event.SloClassification{Class: "class", App: "app", Domain: "domain"}}, out)
+	close(out)
+	return len(out)
+}
+
+func TestSloEventProducer_PrepareReload(t *testing.T) {
+	sep, err := New(SloEventProducerConfig{ExposeRulesAsMetrics: true}, logrus.New())
+	assert.NoError(t, err)
+	registry := prometheus.NewPedanticRegistry()
+	assert.NoError(t, sep.RegisterMetrics(registry, registry))
+	assert.Equal(t, 0, evaluatedEventsCount(sep))
+
+	invalidConfig := viper.New()
+	invalidConfig.Set("rulesFiles", []string{"testdata/slo_rules_invalid.yaml.golden"})
+	_, err = sep.PrepareReload(invalidConfig)
+	assert.Error(t, err)
+
+	validConfig := viper.New()
+	validConfig.Set("rulesFiles", []string{"testdata/slo_rules_valid.yaml.golden"})
+	apply, err := sep.PrepareReload(validConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, evaluatedEventsCount(sep), "rules must not change before the reload is applied")
+
+	assert.NoError(t, apply())
+	assert.Equal(t, 1, evaluatedEventsCount(sep))
+	count, err := testutil.GatherAndCount(registry, metricFromRulesName)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
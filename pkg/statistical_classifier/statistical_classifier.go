@@ -16,38 +16,21 @@ const (
 	guessedLabelPlaceholder = "statistically-guessed"
 )
 
-var (
-	eventsTotal = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "events_processed_total",
-			Help: "Total number of processed events by result.",
-		},
-		[]string{"result"},
-	)
-
-	errorsTotal = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "errors_total",
-			Help: "Total number of errors.",
-		},
-		[]string{"type"},
-	)
-)
-
 type weightClassification struct {
 	SloDomain string
 	SloClass  string
 }
 
-type defaultClassificationWeight struct {
+type DefaultClassificationWeight struct {
 	Weight         float64
 	Classification weightClassification
 }
 
-type classifierConfig struct {
+// ClassifierConfig is configuration of the statistical classifier module.
+type ClassifierConfig struct {
 	HistoryWindowSize           time.Duration
 	HistoryWeightUpdateInterval time.Duration
-	DefaultWeights              []defaultClassificationWeight
+	DefaultWeights              []DefaultClassificationWeight
 }
 
 // StatisticalClassifier is classifier based on cache and regexp matches.
@@ -57,12 +40,13 @@ type StatisticalClassifier struct {
 	logger        logrus.FieldLogger
 	inputChannel  chan *event.Raw
 	outputChannel chan *event.Raw
-	done          bool
+	eventsTotal   *prometheus.CounterVec
+	errorsTotal   *prometheus.CounterVec
 }
 
 // NewFromViper create new instance of StatisticalClassifier based on viper config.
 func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*StatisticalClassifier, error) {
-	var config classifierConfig
+	var config ClassifierConfig
 	defaultWindowSize, err := time.ParseDuration("30m")
 	if err != nil {
 		return nil, fmt.Errorf("invalid default historyWindowSize vaule: %w", err)
@@ -80,7 +64,7 @@ func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*Statist
 	return New(config, logger)
 }
 
-func defaultWeightsSetFromConfig(conf classifierConfig) *weightedClassificationSet {
+func defaultWeightsSetFromConfig(conf ClassifierConfig) *weightedClassificationSet {
 	if len(conf.DefaultWeights) < 1 {
 		return nil
 	}
@@ -102,7 +86,7 @@ func defaultWeightsSetFromConfig(conf classifierConfig) *weightedClassificationS
 }
 
 // New returns new instance of StatisticalClassifier.
-func New(conf classifierConfig, logger logrus.FieldLogger) (*StatisticalClassifier, error) {
+func New(conf ClassifierConfig, logger logrus.FieldLogger) (*StatisticalClassifier, error) {
 	newClassifier, err := newWeightedClassifier(conf.HistoryWindowSize, conf.HistoryWeightUpdateInterval, logger)
 	if err != nil {
 		return nil, err
@@ -113,7 +97,20 @@ func New(conf classifierConfig, logger logrus.FieldLogger) (*StatisticalClassifi
 		logger:        logger,
 		inputChannel:  make(chan *event.Raw),
 		outputChannel: make(chan *event.Raw),
-		done:          false,
+		eventsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "events_processed_total",
+				Help: "Total number of processed events by result.",
+			},
+			[]string{"result"},
+		),
+		errorsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "errors_total",
+				Help: "Total number of errors.",
+			},
+			[]string{"type"},
+		),
 	}, nil
 }
 
@@ -129,14 +126,8 @@ func (sc *StatisticalClassifier) RegisterEventProcessingDurationObserver(observe
 	sc.observer = observer
 }
 
-func (sc *StatisticalClassifier) Stop() {}
-
-func (sc *StatisticalClassifier) Done() bool {
-	return sc.done
-}
-
 func (sc *StatisticalClassifier) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
-	toRegister := []prometheus.Collector{eventsTotal, errorsTotal, classificationWeightsMetric}
+	toRegister := []prometheus.Collector{sc.eventsTotal, sc.errorsTotal, sc.classifier.weightsMetric}
 	for _, metric := range toRegister {
 		if err := wrappedRegistry.Register(metric); err != nil {
 			return err
@@ -163,40 +154,38 @@ func (sc *StatisticalClassifier) Classify(e *event.Raw) error {
 	if !e.IsClassified() {
 		classification, err := sc.classifier.guessClass()
 		if err != nil {
-			eventsTotal.WithLabelValues("unclassified").Inc()
+			sc.eventsTotal.WithLabelValues("unclassified").Inc()
 			return err
 		}
 		e.UpdateSLOClassification(classification)
-		eventsTotal.WithLabelValues("classified").Inc()
+		sc.eventsTotal.WithLabelValues("classified").Inc()
 	} else {
 		sc.classifier.increaseWeight(sc.sanitizeGuessedClassification(e.GetSloClassification()), 1)
-		eventsTotal.WithLabelValues("increased-weight").Inc()
+		sc.eventsTotal.WithLabelValues("increased-weight").Inc()
 	}
 	return nil
 }
 
 // Run statistic classifier receiving events and trying to classify event.
-func (sc *StatisticalClassifier) Run() {
-	go func() {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer func() {
-			cancel()
-			close(sc.outputChannel)
-			sc.done = true
-		}()
-
-		sc.classifier.Run(ctx)
-		for newEvent := range sc.inputChannel {
-			start := time.Now()
-			if err := sc.Classify(newEvent); err != nil {
-				sc.logger.WithField("event", newEvent).Error(err)
-				errorsTotal.WithLabelValues("failedToClassify").Inc()
-			} else {
-				sc.logger.WithField("event", newEvent).Debug("processed event")
-				sc.outputChannel <- newEvent
-			}
-			sc.observeDuration(start)
-		}
-		sc.logger.Info("input channel closed, finishing")
+func (sc *StatisticalClassifier) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		close(sc.outputChannel)
 	}()
+
+	sc.classifier.Run(ctx, sc.errorsTotal)
+	for newEvent := range sc.inputChannel {
+		start := time.Now()
+		if err := sc.Classify(newEvent); err != nil {
+			sc.logger.WithField("event", newEvent).Error(err)
+			sc.errorsTotal.WithLabelValues("failedToClassify").Inc()
+		} else {
+			sc.logger.WithField("event", newEvent).Debug("processed event")
+			sc.outputChannel <- newEvent
+		}
+		sc.observeDuration(start)
+	}
+	sc.logger.Info("input channel closed, finishing")
+	return nil
 }
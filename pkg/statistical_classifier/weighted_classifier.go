@@ -15,14 +15,6 @@ import (
 	"gonum.org/v1/gonum/stat/sampleuv"
 )
 
-var classificationWeightsMetric = prometheus.NewGaugeVec(
-	prometheus.GaugeOpts{
-		Name: "classification_weight",
-		Help: "Current weight for given classification.",
-	},
-	[]string{"slo_domain", "slo_class"},
-)
-
 type classificationWeight struct {
 	classification *event.SloClassification
 	weight         float64
@@ -83,7 +75,15 @@ func (w *weightedClassificationSet) setWeights(weights []classificationWeight) {
 	w.classificationWeights = []float64{}
 	for _, classificationWeight := range w.enumeratedClassifications {
 		w.classificationWeights = append(w.classificationWeights, classificationWeight.weight)
-		classificationWeightsMetric.WithLabelValues(
+	}
+}
+
+// report sets current weights of the classifications to the given metric.
+func (w *weightedClassificationSet) report(weightsMetric *prometheus.GaugeVec) {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+	for _, classificationWeight := range w.enumeratedClassifications {
+		weightsMetric.WithLabelValues(
 			classificationWeight.classification.Domain,
 			classificationWeight.classification.Class,
 		).Set(classificationWeight.weight)
@@ -103,6 +103,7 @@ type weightedClassifier struct {
 	recentWeights           classificationMapping
 	lock                    sync.RWMutex
 	historyUpdateInterval   time.Duration
+	weightsMetric           *prometheus.GaugeVec
 	logger                  logrus.FieldLogger
 }
 
@@ -117,7 +118,14 @@ func newWeightedClassifier(windowSize, historyUpdateInterval time.Duration, logg
 		recentWeights:           classificationMapping{},
 		lock:                    sync.RWMutex{},
 		historyUpdateInterval:   historyUpdateInterval,
-		logger:                  logger,
+		weightsMetric: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "classification_weight",
+				Help: "Current weight for given classification.",
+			},
+			[]string{"slo_domain", "slo_class"},
+		),
+		logger: logger,
 	}, nil
 }
 
@@ -128,6 +136,7 @@ func (s *weightedClassifier) increaseWeight(classification event.SloClassificati
 
 func (s *weightedClassifier) setDefaultWeights(defaultWeights *weightedClassificationSet) {
 	s.defaultWeights = defaultWeights
+	s.defaultWeights.report(s.weightsMetric)
 }
 
 // archive puts most recent weights to history queue, drops old expired data from it and recalculates the weights from the updated history.
@@ -153,6 +162,7 @@ func (s *weightedClassifier) reweight() error {
 		totalClassificationsWeights.merge(itemClassificationsWeights)
 	}
 	s.totalWeightsOverHistory = newWeightedClassificationSetFromClassifications(&totalClassificationsWeights)
+	s.totalWeightsOverHistory.report(s.weightsMetric)
 	return nil
 }
 
@@ -180,7 +190,7 @@ func (s *weightedClassifier) guessClass() (*event.SloClassification, error) {
 }
 
 // Run runs statistic refresher - archive recentWeights classifications and recount weightedClassifier.
-func (s *weightedClassifier) Run(ctx context.Context) {
+func (s *weightedClassifier) Run(ctx context.Context, errorsTotal *prometheus.CounterVec) {
 	go func() {
 		ticker := time.NewTicker(s.historyUpdateInterval)
 		defer ticker.Stop()
@@ -0,0 +1,301 @@
+package synthetic_prober
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// maxBodyBytes limits how much of the HTTP response body is read to match the body regexp.
+const maxBodyBytes = 1024 * 1024
+
+// TLSConfig is configuration of the TLS connection of the probe.
+type TLSConfig struct {
+	// Enabled enables TLS of the TCP and gRPC probes, HTTP probes use TLS for the https targets.
+	Enabled bool
+	// InsecureSkipVerify disables verification of the server certificate.
+	InsecureSkipVerify bool
+	// ServerName used to verify the server certificate, host of the target is used if empty.
+	ServerName string
+	// CAFile is path to the PEM encoded CA certificates used to verify the server certificate instead of the system ones.
+	CAFile string
+}
+
+func (c TLSConfig) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		ServerName:         c.ServerName,
+	}
+	if c.CAFile != "" {
+		caCertificates, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA file: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(caCertificates) {
+			return nil, fmt.Errorf("no PEM encoded certificate found in the CA file %s", c.CAFile)
+		}
+	}
+	return config, nil
+}
+
+// HTTPHeader is header of the HTTP probe request, its value can be read from the environment so secrets are not stored in the configuration.
+type HTTPHeader struct {
+	Name         string
+	Value        *string
+	ValueFromEnv *string
+}
+
+func (h HTTPHeader) value() (string, error) {
+	if h.Name == "" {
+		return "", fmt.Errorf("header name must be set")
+	}
+	if (h.Value == nil) == (h.ValueFromEnv == nil) {
+		return "", fmt.Errorf("exactly one of 'Value' or 'ValueFromEnv' of the header %s must be set", h.Name)
+	}
+	if h.Value != nil {
+		return *h.Value, nil
+	}
+	value, ok := os.LookupEnv(*h.ValueFromEnv)
+	if !ok {
+		return "", fmt.Errorf("environment variable '%s' of the header %s is not set", *h.ValueFromEnv, h.Name)
+	}
+	return value, nil
+}
+
+// HTTPProbeConfig is configuration specific for the HTTP probes.
+type HTTPProbeConfig struct {
+	// Method of the request.
+	Method string
+	// Headers of the request.
+	Headers []HTTPHeader
+	// Body of the request.
+	Body string
+	// ValidStatusCodes of the response, any 2xx status code is valid if empty.
+	ValidStatusCodes []int
+	// BodyRegexp the response body has to match, the body is not checked if empty.
+	BodyRegexp string
+	// NoFollowRedirects disables following of the redirects, the redirect response is checked instead.
+	NoFollowRedirects bool
+}
+
+// GRPCProbeConfig is configuration specific for the gRPC probes.
+type GRPCProbeConfig struct {
+	// Service name sent in the health check request, empty checks health of the whole server.
+	Service string
+}
+
+// checker performs the check of a single probe.
+type checker interface {
+	// check adds metadata of the check, returns error if the check failed.
+	check(ctx context.Context, metadata stringmap.StringMap) error
+}
+
+// addTLSMetadata adds the negotiated TLS version and time left until the earliest expiry of the server certificates.
+func addTLSMetadata(metadata stringmap.StringMap, state *tls.ConnectionState) {
+	if state == nil {
+		return
+	}
+	metadata[MetadataTLSVersion] = tls.VersionName(state.Version)
+	var expiry time.Time
+	for _, certificate := range state.PeerCertificates {
+		if expiry.IsZero() || certificate.NotAfter.Before(expiry) {
+			expiry = certificate.NotAfter
+		}
+	}
+	if !expiry.IsZero() {
+		metadata[MetadataTLSCertificateExpiresInSeconds] = strconv.FormatInt(int64(time.Until(expiry).Seconds()), 10)
+	}
+}
+
+type httpChecker struct {
+	client           *http.Client
+	method           string
+	url              string
+	headers          map[string]string
+	body             string
+	validStatusCodes []int
+	bodyRegexp       *regexp.Regexp
+}
+
+func newHTTPChecker(target string, httpConfig HTTPProbeConfig, tlsConfig TLSConfig) (*httpChecker, error) {
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		return nil, fmt.Errorf("target of the HTTP probe has to be http or https URL, got '%s'", target)
+	}
+	clientTLSConfig, err := tlsConfig.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{}
+	for _, header := range httpConfig.Headers {
+		value, err := header.value()
+		if err != nil {
+			return nil, err
+		}
+		headers[header.Name] = value
+	}
+	checker := &httpChecker{
+		client: &http.Client{
+			// Each probe opens a new connection so its failure is not hidden by an existing one.
+			Transport: &http.Transport{TLSClientConfig: clientTLSConfig, DisableKeepAlives: true, Proxy: http.ProxyFromEnvironment},
+		},
+		method:           httpConfig.Method,
+		url:              target,
+		headers:          headers,
+		body:             httpConfig.Body,
+		validStatusCodes: httpConfig.ValidStatusCodes,
+	}
+	if checker.method == "" {
+		checker.method = http.MethodGet
+	}
+	if httpConfig.NoFollowRedirects {
+		checker.client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	if httpConfig.BodyRegexp != "" {
+		if checker.bodyRegexp, err = regexp.Compile(httpConfig.BodyRegexp); err != nil {
+			return nil, fmt.Errorf("invalid body regexp: %w", err)
+		}
+	}
+	return checker, nil
+}
+
+func (c *httpChecker) validStatusCode(code int) bool {
+	if len(c.validStatusCodes) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, validCode := range c.validStatusCodes {
+		if code == validCode {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *httpChecker) check(ctx context.Context, metadata stringmap.StringMap) error {
+	request, err := http.NewRequestWithContext(ctx, c.method, c.url, strings.NewReader(c.body))
+	if err != nil {
+		return err
+	}
+	for name, value := range c.headers {
+		request.Header.Set(name, value)
+	}
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	metadata[MetadataStatusCode] = strconv.Itoa(response.StatusCode)
+	addTLSMetadata(metadata, response.TLS)
+	if !c.validStatusCode(response.StatusCode) {
+		return fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxBodyBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read the response body: %w", err)
+	}
+	if c.bodyRegexp != nil && !c.bodyRegexp.Match(body) {
+		return fmt.Errorf("response body does not match the regexp '%s'", c.bodyRegexp)
+	}
+	return nil
+}
+
+type tcpChecker struct {
+	address   string
+	tlsConfig *tls.Config
+}
+
+func newTCPChecker(target string, tlsConfig TLSConfig) (*tcpChecker, error) {
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		return nil, fmt.Errorf("target of the TCP probe has to be host:port: %w", err)
+	}
+	checker := &tcpChecker{address: target}
+	if tlsConfig.Enabled {
+		if checker.tlsConfig, err = tlsConfig.tlsConfig(); err != nil {
+			return nil, err
+		}
+		if checker.tlsConfig.ServerName == "" {
+			checker.tlsConfig.ServerName = host
+		}
+	}
+	return checker, nil
+}
+
+func (c *tcpChecker) check(ctx context.Context, metadata stringmap.StringMap) error {
+	var dialer net.Dialer
+	connection, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+	if c.tlsConfig == nil {
+		return nil
+	}
+	tlsConnection := tls.Client(connection, c.tlsConfig)
+	if err := tlsConnection.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	state := tlsConnection.ConnectionState()
+	addTLSMetadata(metadata, &state)
+	return nil
+}
+
+type grpcChecker struct {
+	target      string
+	service     string
+	credentials credentials.TransportCredentials
+}
+
+func newGRPCChecker(target string, grpcConfig GRPCProbeConfig, tlsConfig TLSConfig) (*grpcChecker, error) {
+	checker := &grpcChecker{target: target, service: grpcConfig.Service, credentials: insecure.NewCredentials()}
+	if tlsConfig.Enabled {
+		clientTLSConfig, err := tlsConfig.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		checker.credentials = credentials.NewTLS(clientTLSConfig)
+	}
+	return checker, nil
+}
+
+func (c *grpcChecker) check(ctx context.Context, metadata stringmap.StringMap) error {
+	// Each probe opens a new connection so its failure is not hidden by an existing one.
+	connection, err := grpc.NewClient(c.target, grpc.WithTransportCredentials(c.credentials))
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+	var responsePeer peer.Peer
+	response, err := healthpb.NewHealthClient(connection).Check(ctx, &healthpb.HealthCheckRequest{Service: c.service}, grpc.Peer(&responsePeer))
+	metadata[MetadataGRPCStatusCode] = status.Code(err).String()
+	if tlsInfo, ok := responsePeer.AuthInfo.(credentials.TLSInfo); ok {
+		addTLSMetadata(metadata, &tlsInfo.State)
+	}
+	if err != nil {
+		return err
+	}
+	metadata[MetadataServingStatus] = response.GetStatus().String()
+	if response.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("unexpected serving status %s", response.GetStatus())
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package synthetic_prober
+
+import (
+	"context"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+func TestHTTPHeader_value(t *testing.T) {
+	t.Setenv("TEST_PROBE_TOKEN", "Bearer secret")
+	testCases := []struct {
+		name          string
+		header        HTTPHeader
+		expectedValue string
+		expectedErr   bool
+	}{
+		{name: "value", header: HTTPHeader{Name: "X-Probe", Value: stringPointer("true")}, expectedValue: "true"},
+		{name: "value from environment", header: HTTPHeader{Name: "Authorization", ValueFromEnv: stringPointer("TEST_PROBE_TOKEN")}, expectedValue: "Bearer secret"},
+		{name: "missing environment variable", header: HTTPHeader{Name: "Authorization", ValueFromEnv: stringPointer("TEST_PROBE_MISSING")}, expectedErr: true},
+		{name: "missing value", header: HTTPHeader{Name: "X-Probe"}, expectedErr: true},
+		{name: "both values", header: HTTPHeader{Name: "X-Probe", Value: stringPointer("true"), ValueFromEnv: stringPointer("TEST_PROBE_TOKEN")}, expectedErr: true},
+		{name: "missing name", header: HTTPHeader{Value: stringPointer("true")}, expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := tc.header.value()
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedValue, value)
+		})
+	}
+}
+
+func TestHTTPChecker_check(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			if r.Header.Get("X-Probe") != "true" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"status": "ok"}`))
+		case "/redirect":
+			http.Redirect(w, r, "/health", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	headers := []HTTPHeader{{Name: "X-Probe", Value: stringPointer("true")}}
+	testCases := []struct {
+		name               string
+		path               string
+		config             HTTPProbeConfig
+		expectedStatusCode string
+		expectedErr        bool
+	}{
+		{name: "success", path: "/health", config: HTTPProbeConfig{Headers: headers}, expectedStatusCode: "200"},
+		{name: "invalid status code", path: "/unavailable", expectedStatusCode: "503", expectedErr: true},
+		{name: "configured valid status code", path: "/unavailable", config: HTTPProbeConfig{ValidStatusCodes: []int{503}}, expectedStatusCode: "503"},
+		{name: "body matches", path: "/health", config: HTTPProbeConfig{Headers: headers, BodyRegexp: `"status": "ok"`}, expectedStatusCode: "200"},
+		{name: "body does not match", path: "/health", config: HTTPProbeConfig{Headers: headers, BodyRegexp: `"status": "failing"`}, expectedStatusCode: "200", expectedErr: true},
+		{name: "redirect followed", path: "/redirect", config: HTTPProbeConfig{Headers: headers}, expectedStatusCode: "200"},
+		{name: "redirect not followed", path: "/redirect", config: HTTPProbeConfig{NoFollowRedirects: true}, expectedStatusCode: "302", expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker, err := newHTTPChecker(server.URL+tc.path, tc.config, TLSConfig{})
+			assert.NoError(t, err)
+			metadata := stringmap.StringMap{}
+			err = checker.check(context.Background(), metadata)
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedStatusCode, metadata[MetadataStatusCode])
+		})
+	}
+}
+
+func TestNewHTTPChecker_invalid(t *testing.T) {
+	_, err := newHTTPChecker("localhost:8080", HTTPProbeConfig{}, TLSConfig{})
+	assert.Error(t, err)
+	_, err = newHTTPChecker("http://localhost:8080", HTTPProbeConfig{BodyRegexp: "("}, TLSConfig{})
+	assert.Error(t, err)
+	_, err = newHTTPChecker("https://localhost:8080", HTTPProbeConfig{}, TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.Error(t, err)
+}
+
+func TestHTTPChecker_checkTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+
+	checker, err := newHTTPChecker(server.URL, HTTPProbeConfig{}, TLSConfig{})
+	assert.NoError(t, err)
+	assert.Error(t, checker.check(context.Background(), stringmap.StringMap{}), "certificate of the test server is not trusted")
+
+	checker, err = newHTTPChecker(server.URL, HTTPProbeConfig{}, TLSConfig{CAFile: caFile})
+	assert.NoError(t, err)
+	metadata := stringmap.StringMap{}
+	assert.NoError(t, checker.check(context.Background(), metadata))
+	assert.Equal(t, "TLS 1.3", metadata[MetadataTLSVersion])
+	assert.NotEmpty(t, metadata[MetadataTLSCertificateExpiresInSeconds])
+}
+
+func TestTCPChecker_check(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			connection.Close()
+		}
+	}()
+	checker, err := newTCPChecker(listener.Addr().String(), TLSConfig{})
+	assert.NoError(t, err)
+	assert.NoError(t, checker.check(context.Background(), stringmap.StringMap{}))
+
+	listener.Close()
+	assert.Error(t, checker.check(context.Background(), stringmap.StringMap{}))
+
+	_, err = newTCPChecker("localhost", TLSConfig{})
+	assert.Error(t, err)
+}
+
+func TestTCPChecker_checkTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer server.Close()
+	checker, err := newTCPChecker(server.Listener.Addr().String(), TLSConfig{Enabled: true, InsecureSkipVerify: true})
+	assert.NoError(t, err)
+	metadata := stringmap.StringMap{}
+	assert.NoError(t, checker.check(context.Background(), metadata))
+	assert.Equal(t, "TLS 1.3", metadata[MetadataTLSVersion])
+	assert.NotEmpty(t, metadata[MetadataTLSCertificateExpiresInSeconds])
+}
+
+func TestGRPCChecker_check(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("checkout", healthpb.HealthCheckResponse_NOT_SERVING)
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+
+	testCases := []struct {
+		name                  string
+		service               string
+		expectedCode          string
+		expectedServingStatus string
+		expectedErr           bool
+	}{
+		{name: "serving", expectedCode: "OK", expectedServingStatus: "SERVING"},
+		{name: "not serving", service: "checkout", expectedCode: "OK", expectedServingStatus: "NOT_SERVING", expectedErr: true},
+		{name: "unknown service", service: "unknown", expectedCode: "NotFound", expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker, err := newGRPCChecker(listener.Addr().String(), GRPCProbeConfig{Service: tc.service}, TLSConfig{})
+			assert.NoError(t, err)
+			metadata := stringmap.StringMap{}
+			err = checker.check(context.Background(), metadata)
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedCode, metadata[MetadataGRPCStatusCode])
+			assert.Equal(t, tc.expectedServingStatus, metadata[MetadataServingStatus])
+		})
+	}
+
+	server.Stop()
+	checker, err := newGRPCChecker(listener.Addr().String(), GRPCProbeConfig{}, TLSConfig{})
+	assert.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	metadata := stringmap.StringMap{}
+	assert.Error(t, checker.check(ctx, metadata))
+	assert.Equal(t, "Unavailable", metadata[MetadataGRPCStatusCode])
+}
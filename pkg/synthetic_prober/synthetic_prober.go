@@ -0,0 +1,286 @@
+package synthetic_prober
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/pipeline"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Metadata keys of the produced events.
+const (
+	MetadataProbe                          = "probe"
+	MetadataProbeType                      = "probeType"
+	MetadataTarget                         = "target"
+	MetadataSuccess                        = "success"
+	MetadataDuration                       = "duration"
+	MetadataError                          = "error"
+	MetadataStatusCode                     = "statusCode"
+	MetadataGRPCStatusCode                 = "grpcStatusCode"
+	MetadataServingStatus                  = "servingStatus"
+	MetadataTLSVersion                     = "tlsVersion"
+	MetadataTLSCertificateExpiresInSeconds = "tlsCertificateExpiresInSeconds"
+)
+
+// Results of the probes used as a label of the probes_total metric.
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+type ProbeType string
+
+const (
+	HTTPProbeType ProbeType = "http"
+	TCPProbeType  ProbeType = "tcp"
+	GRPCProbeType ProbeType = "grpc"
+)
+
+// ProbeConfig is configuration of a single probe.
+type ProbeConfig struct {
+	// Name of the probe, has to be unique.
+	Name string
+	// Type of the probe, one of http, tcp or grpc.
+	Type ProbeType
+	// Target of the probe, URL for the HTTP probes and host:port for the TCP and gRPC probes.
+	Target string
+	// Interval of the probe, DefaultInterval is used if not set.
+	Interval time.Duration
+	// Timeout of the probe, DefaultTimeout is used if not set. Must not be longer than the interval.
+	Timeout time.Duration
+	// SloClassification of the produced events.
+	SloClassification *event.SloClassification
+	// Metadata added to the produced events.
+	Metadata stringmap.StringMap
+	// TLS configuration of the probe.
+	TLS TLSConfig
+	// HTTP configuration specific for the HTTP probes.
+	HTTP HTTPProbeConfig
+	// GRPC configuration specific for the gRPC probes.
+	GRPC GRPCProbeConfig
+}
+
+// SyntheticProberConfig is configuration of the synthetic prober module.
+type SyntheticProberConfig struct {
+	// DefaultInterval of the probes which do not set their own.
+	DefaultInterval time.Duration
+	// DefaultTimeout of the probes which do not set their own.
+	DefaultTimeout time.Duration
+	// Probes to be executed.
+	Probes []ProbeConfig
+}
+
+// probe is a single configured probe executed periodically.
+type probe struct {
+	name              string
+	probeType         ProbeType
+	target            string
+	interval          time.Duration
+	timeout           time.Duration
+	sloClassification *event.SloClassification
+	metadata          stringmap.StringMap
+	checker           checker
+}
+
+func newProbe(config ProbeConfig, defaultInterval, defaultTimeout time.Duration) (*probe, error) {
+	if config.Target == "" {
+		return nil, fmt.Errorf("target must be set")
+	}
+	p := &probe{
+		name:              config.Name,
+		probeType:         config.Type,
+		target:            config.Target,
+		interval:          config.Interval,
+		timeout:           config.Timeout,
+		sloClassification: config.SloClassification,
+		metadata:          config.Metadata,
+	}
+	if p.interval == 0 {
+		p.interval = defaultInterval
+	}
+	if p.timeout == 0 {
+		p.timeout = defaultTimeout
+	}
+	if p.interval <= 0 || p.timeout <= 0 {
+		return nil, fmt.Errorf("interval and timeout must be positive, got %s and %s", p.interval, p.timeout)
+	}
+	if p.timeout > p.interval {
+		return nil, fmt.Errorf("timeout %s must not be longer than the interval %s", p.timeout, p.interval)
+	}
+	var err error
+	switch config.Type {
+	case HTTPProbeType:
+		p.checker, err = newHTTPChecker(config.Target, config.HTTP, config.TLS)
+	case TCPProbeType:
+		p.checker, err = newTCPChecker(config.Target, config.TLS)
+	case GRPCProbeType:
+		p.checker, err = newGRPCChecker(config.Target, config.GRPC, config.TLS)
+	default:
+		return nil, fmt.Errorf("unknown probe type '%s', valid types are %s, %s and %s", config.Type, HTTPProbeType, TCPProbeType, GRPCProbeType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SyntheticProber produces an event for each execution of the configured probes.
+type SyntheticProber struct {
+	probes        []*probe
+	outputChannel chan *event.Raw
+	observer      pipeline.EventProcessingDurationObserver
+	logger        logrus.FieldLogger
+
+	probesTotal *prometheus.CounterVec
+}
+
+func (s *SyntheticProber) String() string {
+	return "syntheticProber"
+}
+
+func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*SyntheticProber, error) {
+	viperConfig.SetDefault("DefaultInterval", 30*time.Second)
+	viperConfig.SetDefault("DefaultTimeout", 10*time.Second)
+	var config SyntheticProberConfig
+	if err := viperConfig.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return New(config, logger)
+}
+
+// New returns an instance of SyntheticProber.
+func New(config SyntheticProberConfig, logger logrus.FieldLogger) (*SyntheticProber, error) {
+	if len(config.Probes) == 0 {
+		return nil, fmt.Errorf("at least one probe has to be configured")
+	}
+	prober := &SyntheticProber{
+		outputChannel: make(chan *event.Raw),
+		logger:        logger,
+		probesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "probes_total",
+			Help: "Total number of executed probes by the probe name and result.",
+		}, []string{"probe", "result"}),
+	}
+	names := map[string]bool{}
+	for i, probeConfig := range config.Probes {
+		if probeConfig.Name == "" {
+			return nil, fmt.Errorf("name of the probe %d must be set", i)
+		}
+		if names[probeConfig.Name] {
+			return nil, fmt.Errorf("duplicate probe name '%s'", probeConfig.Name)
+		}
+		names[probeConfig.Name] = true
+		p, err := newProbe(probeConfig, config.DefaultInterval, config.DefaultTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid probe '%s': %w", probeConfig.Name, err)
+		}
+		prober.probes = append(prober.probes, p)
+	}
+	return prober, nil
+}
+
+func (s *SyntheticProber) RegisterEventProcessingDurationObserver(observer pipeline.EventProcessingDurationObserver) {
+	s.observer = observer
+}
+
+func (s *SyntheticProber) observeDuration(start time.Time) {
+	if s.observer != nil {
+		s.observer.Observe(time.Since(start).Seconds())
+	}
+}
+
+func (s *SyntheticProber) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
+	toRegister := []prometheus.Collector{s.probesTotal}
+	for _, collector := range toRegister {
+		if err := wrappedRegistry.Register(collector); err != nil {
+			return fmt.Errorf("error registering metric %s: %w", collector, err)
+		}
+	}
+	return nil
+}
+
+func (s *SyntheticProber) OutputChannel() chan *event.Raw {
+	return s.outputChannel
+}
+
+// Run executes the probes periodically until the context is cancelled.
+func (s *SyntheticProber) Run(ctx context.Context) error {
+	defer close(s.outputChannel)
+	var wg sync.WaitGroup
+	for _, p := range s.probes {
+		wg.Add(1)
+		go s.run(ctx, p, &wg)
+	}
+	<-ctx.Done()
+	s.logger.Info("received shutdown request, waiting for all ongoing probes to finish")
+	wg.Wait()
+	return nil
+}
+
+// run executes the probe right away and then in its interval, the probe does not overlap with its previous execution since its timeout is not longer than the interval.
+func (s *SyntheticProber) run(ctx context.Context, p *probe, wg *sync.WaitGroup) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	defer wg.Done()
+
+	// make sure that this metric is exposed even when no probe has failed yet
+	s.probesTotal.WithLabelValues(p.name, resultSuccess).Add(0)
+	s.probesTotal.WithLabelValues(p.name, resultFailure).Add(0)
+
+	for {
+		newEvent := s.execute(ctx, p)
+		if newEvent == nil {
+			return
+		}
+		select {
+		case s.outputChannel <- newEvent:
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// execute performs the check of the probe and returns its event, nil is returned if the check was interrupted by the ctx cancellation.
+func (s *SyntheticProber) execute(ctx context.Context, p *probe) *event.Raw {
+	start := time.Now()
+	timeoutCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	metadata := p.metadata.Copy()
+	err := p.checker.check(timeoutCtx, metadata)
+	if ctx.Err() != nil {
+		return nil
+	}
+	processingStart := time.Now()
+	defer s.observeDuration(processingStart)
+	metadata[MetadataProbe] = p.name
+	metadata[MetadataProbeType] = string(p.probeType)
+	metadata[MetadataTarget] = p.target
+	metadata[MetadataDuration] = processingStart.Sub(start).String()
+	metadata[MetadataSuccess] = strconv.FormatBool(err == nil)
+	if err != nil {
+		metadata[MetadataError] = err.Error()
+		s.probesTotal.WithLabelValues(p.name, resultFailure).Inc()
+		s.logger.WithField("probe", p.name).Debugf("probe failed: %v", err)
+	} else {
+		s.probesTotal.WithLabelValues(p.name, resultSuccess).Inc()
+	}
+	newEvent := &event.Raw{Metadata: metadata, Quantity: 1, Timestamp: start}
+	if p.sloClassification != nil {
+		classification := *p.sloClassification
+		newEvent.SloClassification = &classification
+	}
+	return newEvent
+}
@@ -0,0 +1,133 @@
+package synthetic_prober
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/internal/moduletest"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name        string
+		probes      []ProbeConfig
+		expectedErr bool
+	}{
+		{name: "valid probes", probes: []ProbeConfig{
+			{Name: "web", Type: HTTPProbeType, Target: "https://example.com"},
+			{Name: "db", Type: TCPProbeType, Target: "db:5432", Interval: time.Minute},
+			{Name: "api", Type: GRPCProbeType, Target: "api:9090", Timeout: time.Second},
+		}},
+		{name: "no probes", expectedErr: true},
+		{name: "missing name", probes: []ProbeConfig{{Type: TCPProbeType, Target: "db:5432"}}, expectedErr: true},
+		{name: "duplicate name", probes: []ProbeConfig{
+			{Name: "db", Type: TCPProbeType, Target: "db:5432"},
+			{Name: "db", Type: TCPProbeType, Target: "db:5433"},
+		}, expectedErr: true},
+		{name: "missing target", probes: []ProbeConfig{{Name: "db", Type: TCPProbeType}}, expectedErr: true},
+		{name: "unknown type", probes: []ProbeConfig{{Name: "db", Type: "icmp", Target: "db"}}, expectedErr: true},
+		{name: "timeout longer than interval", probes: []ProbeConfig{{Name: "db", Type: TCPProbeType, Target: "db:5432", Interval: time.Second}}, expectedErr: true},
+		{name: "invalid HTTP target", probes: []ProbeConfig{{Name: "web", Type: HTTPProbeType, Target: "example.com"}}, expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prober, err := New(SyntheticProberConfig{DefaultInterval: 30 * time.Second, DefaultTimeout: 10 * time.Second, Probes: tc.probes}, logrus.New())
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, prober.probes, len(tc.probes))
+		})
+	}
+}
+
+func TestNewFromViper(t *testing.T) {
+	viperConfig := viper.New()
+	viperConfig.SetConfigType("yaml")
+	assert.NoError(t, viperConfig.ReadConfig(strings.NewReader(`
+defaultInterval: 1m
+probes:
+  - name: "web"
+    type: "http"
+    target: "https://example.com/health"
+    timeout: 5s
+    sloClassification:
+      domain: "example"
+      app: "web"
+      class: "critical"
+    metadata:
+      team: "frontend"
+    http:
+      method: "HEAD"
+      validStatusCodes: [200, 204]
+`)))
+	prober, err := NewFromViper(viperConfig, logrus.New())
+	assert.NoError(t, err)
+	if assert.Len(t, prober.probes, 1) {
+		p := prober.probes[0]
+		assert.Equal(t, time.Minute, p.interval)
+		assert.Equal(t, 5*time.Second, p.timeout)
+		assert.Equal(t, &event.SloClassification{Domain: "example", App: "web", Class: "critical"}, p.sloClassification)
+		assert.Equal(t, stringmap.StringMap{"team": "frontend"}, p.metadata)
+		assert.Equal(t, http.MethodHead, p.checker.(*httpChecker).method)
+		assert.Equal(t, []int{200, 204}, p.checker.(*httpChecker).validStatusCodes)
+	}
+}
+
+func TestSyntheticProber_Run(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	classification := &event.SloClassification{Domain: "example", App: "web", Class: "critical"}
+	prober, err := New(SyntheticProberConfig{Probes: []ProbeConfig{
+		{Name: "healthy", Type: HTTPProbeType, Target: server.URL + "/health", Interval: time.Hour, Timeout: time.Second, SloClassification: classification, Metadata: stringmap.StringMap{"team": "frontend"}},
+		{Name: "unavailable", Type: HTTPProbeType, Target: server.URL + "/unavailable", Interval: time.Hour, Timeout: time.Second},
+	}}, logrus.New())
+	assert.NoError(t, err)
+	stop := moduletest.Run(t, prober)
+
+	events := map[string]*event.Raw{}
+	for i := 0; i < 2; i++ {
+		newEvent := <-prober.OutputChannel()
+		events[newEvent.Metadata[MetadataProbe]] = newEvent
+	}
+	healthy := events["healthy"]
+	if assert.NotNil(t, healthy) {
+		assert.Equal(t, "true", healthy.Metadata[MetadataSuccess])
+		assert.Equal(t, "200", healthy.Metadata[MetadataStatusCode])
+		assert.Equal(t, "http", healthy.Metadata[MetadataProbeType])
+		assert.Equal(t, server.URL+"/health", healthy.Metadata[MetadataTarget])
+		assert.Equal(t, "frontend", healthy.Metadata["team"])
+		assert.NotContains(t, healthy.Metadata, MetadataError)
+		_, err := time.ParseDuration(healthy.Metadata[MetadataDuration])
+		assert.NoError(t, err)
+		assert.Equal(t, classification, healthy.SloClassification)
+		assert.Equal(t, 1.0, healthy.Quantity)
+		assert.False(t, healthy.Timestamp.IsZero())
+	}
+	unavailable := events["unavailable"]
+	if assert.NotNil(t, unavailable) {
+		assert.Equal(t, "false", unavailable.Metadata[MetadataSuccess])
+		assert.Equal(t, "503", unavailable.Metadata[MetadataStatusCode])
+		assert.Equal(t, "unexpected status code 503", unavailable.Metadata[MetadataError])
+		assert.Nil(t, unavailable.SloClassification)
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(prober.probesTotal.WithLabelValues("healthy", resultSuccess)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(prober.probesTotal.WithLabelValues("healthy", resultFailure)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(prober.probesTotal.WithLabelValues("unavailable", resultFailure)))
+
+	stop()
+}
@@ -0,0 +1,240 @@
+package syslog_ingester
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+)
+
+// Metadata keys of the syslog header fields.
+const (
+	facilityKey = "syslogFacility"
+	severityKey = "syslogSeverity"
+	hostnameKey = "syslogHostname"
+	appNameKey  = "syslogAppName"
+	procIDKey   = "syslogProcID"
+	msgIDKey    = "syslogMsgID"
+	// structuredDataKeyPrefix prefixes the metadata keys of the structured data parameters `structuredData.<SD-ID>.<PARAM-NAME>`.
+	structuredDataKeyPrefix = "structuredData."
+)
+
+// byteOrderMark may prefix the UTF-8 encoded body of the RFC 5424 message.
+const byteOrderMark = "\ufeff"
+
+// nilValue is the value of the RFC 5424 header fields which are not set.
+const nilValue = "-"
+
+// message is a parsed syslog message.
+type message struct {
+	// header contains the header fields and structured data of the message.
+	header    stringmap.StringMap
+	timestamp time.Time
+	body      string
+}
+
+// parseMessage parses the syslog message in the RFC 5424 or RFC 3164 format, now is used to complete the RFC 3164 timestamp.
+func parseMessage(data string, now time.Time) (message, error) {
+	priority, rest, err := parsePriority(data)
+	if err != nil {
+		return message{}, err
+	}
+	msg := message{header: stringmap.StringMap{
+		facilityKey: strconv.Itoa(priority / 8),
+		severityKey: strconv.Itoa(priority % 8),
+	}}
+	if strings.HasPrefix(rest, "1 ") {
+		err = msg.parseRFC5424(rest[2:])
+	} else {
+		err = msg.parseRFC3164(rest, now)
+	}
+	if err != nil {
+		return message{}, err
+	}
+	return msg, nil
+}
+
+// parsePriority parses the `<PRI>` prefix of the message.
+func parsePriority(data string) (int, string, error) {
+	if !strings.HasPrefix(data, "<") {
+		return 0, "", errors.New("missing priority of the message")
+	}
+	end := strings.IndexByte(data, '>')
+	if end < 2 || end > 4 {
+		return 0, "", errors.New("invalid priority of the message")
+	}
+	priority, err := strconv.Atoi(data[1:end])
+	if err != nil || priority > 191 {
+		return 0, "", fmt.Errorf("invalid priority of the message '%s'", data[1:end])
+	}
+	return priority, data[end+1:], nil
+}
+
+// nextField returns the field up to the next space and rest of the data.
+func nextField(data string) (string, string) {
+	field, rest, _ := strings.Cut(data, " ")
+	return field, rest
+}
+
+// parseRFC5424 parses the message `TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]` following the version.
+func (m *message) parseRFC5424(data string) error {
+	var timestamp string
+	timestamp, data = nextField(data)
+	if timestamp != nilValue {
+		var err error
+		m.timestamp, err = time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp of the message: %w", err)
+		}
+	}
+	for _, key := range []string{hostnameKey, appNameKey, procIDKey, msgIDKey} {
+		var value string
+		value, data = nextField(data)
+		if value == "" {
+			return errors.New("missing header fields of the message")
+		}
+		if value != nilValue {
+			m.header[key] = value
+		}
+	}
+	if strings.HasPrefix(data, nilValue) {
+		data = data[len(nilValue):]
+	} else {
+		var err error
+		data, err = m.parseStructuredData(data)
+		if err != nil {
+			return err
+		}
+	}
+	if data != "" && data[0] != ' ' {
+		return errors.New("invalid structured data of the message")
+	}
+	m.body = strings.TrimPrefix(strings.TrimPrefix(data, " "), byteOrderMark)
+	return nil
+}
+
+// parseStructuredData parses the structured data elements `[SD-ID PARAM-NAME="PARAM-VALUE" ...]` and returns rest of the data.
+func (m *message) parseStructuredData(data string) (string, error) {
+	if !strings.HasPrefix(data, "[") {
+		return "", errors.New("invalid structured data of the message")
+	}
+	for strings.HasPrefix(data, "[") {
+		end := strings.IndexAny(data, " ]")
+		if end < 0 {
+			return "", errors.New("unterminated structured data element")
+		}
+		id := data[1:end]
+		data = data[end:]
+		for strings.HasPrefix(data, " ") {
+			nameEnd := strings.Index(data, `="`)
+			if nameEnd < 0 {
+				return "", fmt.Errorf("invalid parameter of the structured data element %s", id)
+			}
+			name := data[1:nameEnd]
+			data = data[nameEnd+2:]
+			var value strings.Builder
+			closed := false
+			for i := 0; i < len(data); i++ {
+				if data[i] == '\\' && i+1 < len(data) && strings.IndexByte(`"\]`, data[i+1]) >= 0 {
+					i++
+				} else if data[i] == '"' {
+					data = data[i+1:]
+					closed = true
+					break
+				}
+				value.WriteByte(data[i])
+			}
+			if !closed {
+				return "", fmt.Errorf("unterminated value of the parameter %s of the structured data element %s", name, id)
+			}
+			m.header[structuredDataKeyPrefix+id+"."+name] = value.String()
+		}
+		if !strings.HasPrefix(data, "]") {
+			return "", fmt.Errorf("unterminated structured data element %s", id)
+		}
+		data = data[1:]
+	}
+	return data, nil
+}
+
+// rfc3164TimestampLayout is layout of the RFC 3164 timestamp which has no year and time zone.
+const rfc3164TimestampLayout = time.Stamp
+
+// parseRFC3164 parses the message `TIMESTAMP [HOSTNAME] TAG[PID]: MSG`, the hostname is often omitted by the senders.
+func (m *message) parseRFC3164(data string, now time.Time) error {
+	if len(data) < len(rfc3164TimestampLayout)+1 {
+		return errors.New("message is too short")
+	}
+	timestamp, err := time.ParseInLocation(rfc3164TimestampLayout, data[:len(rfc3164TimestampLayout)], now.Location())
+	if err != nil {
+		return fmt.Errorf("invalid timestamp of the message: %w", err)
+	}
+	m.timestamp = time.Date(now.Year(), timestamp.Month(), timestamp.Day(), timestamp.Hour(), timestamp.Minute(), timestamp.Second(), 0, now.Location())
+	// Message from the end of the previous year.
+	if m.timestamp.After(now.AddDate(0, 0, 1)) {
+		m.timestamp = m.timestamp.AddDate(-1, 0, 0)
+	}
+	field, rest := nextField(strings.TrimPrefix(data[len(rfc3164TimestampLayout):], " "))
+	if !isTag(field) {
+		m.header[hostnameKey] = field
+		tagField, tagRest := nextField(rest)
+		if !isTag(tagField) {
+			// Message without tag.
+			m.body = rest
+			return nil
+		}
+		field, rest = tagField, tagRest
+	}
+	tag := strings.TrimSuffix(field, ":")
+	if start := strings.IndexByte(tag, '['); start > 0 && strings.HasSuffix(tag, "]") {
+		m.header[procIDKey] = tag[start+1 : len(tag)-1]
+		tag = tag[:start]
+	}
+	m.header[appNameKey] = tag
+	m.body = rest
+	return nil
+}
+
+// isTag reports whether the field is the tag of the RFC 3164 message which is terminated by colon.
+func isTag(field string) bool {
+	return len(field) > 1 && strings.HasSuffix(field, ":")
+}
+
+// splitFrames is a bufio.SplitFunc splitting the stream to the syslog messages framed
+// using the octet counting `<length> <message>` or terminated by a new line, see RFC 6587.
+func splitFrames(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, nil
+	}
+	if data[0] >= '0' && data[0] <= '9' {
+		end := bytes.IndexByte(data, ' ')
+		if end < 0 {
+			if atEOF || len(data) > 10 {
+				return 0, nil, errors.New("invalid length of the octet counted frame")
+			}
+			return 0, nil, nil
+		}
+		length, err := strconv.Atoi(string(data[:end]))
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid length of the octet counted frame: %w", err)
+		}
+		if len(data) < end+1+length {
+			if atEOF {
+				return 0, nil, errors.New("incomplete octet counted frame")
+			}
+			return 0, nil, nil
+		}
+		return end + 1 + length, data[end+1 : end+1+length], nil
+	}
+	if end := bytes.IndexByte(data, '\n'); end >= 0 {
+		return end + 1, bytes.TrimSuffix(data[:end], []byte("\r")), nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,117 @@
+package syslog_ingester
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseMessage(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		data   string
+		expMsg message
+		expErr bool
+	}{
+		{
+			name: "RFC 5424 with structured data",
+			data: `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 123 ID47 [exampleSDID@32473 iut="3" eventSource="Application"][meta sequenceId="1"] method=GET status=200`,
+			expMsg: message{
+				header: stringmap.StringMap{
+					facilityKey: "20", severityKey: "5", hostnameKey: "mymachine.example.com", appNameKey: "evntslog", procIDKey: "123", msgIDKey: "ID47",
+					"structuredData.exampleSDID@32473.iut": "3", "structuredData.exampleSDID@32473.eventSource": "Application", "structuredData.meta.sequenceId": "1",
+				},
+				timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				body:      "method=GET status=200",
+			},
+		},
+		{
+			name: "RFC 5424 with nil values and BOM",
+			data: "<34>1 - - su - - - " + byteOrderMark + "method=GET",
+			expMsg: message{
+				header: stringmap.StringMap{facilityKey: "4", severityKey: "2", appNameKey: "su"},
+				body:   "method=GET",
+			},
+		},
+		{
+			name: "RFC 5424 with escaped structured data value and no body",
+			data: `<34>1 - host app - - [a b="x\"y\]z\\"]`,
+			expMsg: message{
+				header: stringmap.StringMap{facilityKey: "4", severityKey: "2", hostnameKey: "host", appNameKey: "app", "structuredData.a.b": `x"y]z\`},
+			},
+		},
+		{
+			name: "RFC 3164",
+			data: "<34>Oct 11 22:14:15 mymachine su[42]: method=GET status=200",
+			expMsg: message{
+				header:    stringmap.StringMap{facilityKey: "4", severityKey: "2", hostnameKey: "mymachine", appNameKey: "su", procIDKey: "42"},
+				timestamp: time.Date(2023, 10, 11, 22, 14, 15, 0, time.UTC),
+				body:      "method=GET status=200",
+			},
+		},
+		{
+			name: "RFC 3164 without hostname",
+			data: "<13>Jan  1 09:59:00 haproxy: method=GET",
+			expMsg: message{
+				header:    stringmap.StringMap{facilityKey: "1", severityKey: "5", appNameKey: "haproxy"},
+				timestamp: time.Date(2024, 1, 1, 9, 59, 0, 0, time.UTC),
+				body:      "method=GET",
+			},
+		},
+		{
+			name: "RFC 3164 without tag",
+			data: "<13>Jan  1 09:59:00 mymachine method=GET",
+			expMsg: message{
+				header:    stringmap.StringMap{facilityKey: "1", severityKey: "5", hostnameKey: "mymachine"},
+				timestamp: time.Date(2024, 1, 1, 9, 59, 0, 0, time.UTC),
+				body:      "method=GET",
+			},
+		},
+		{name: "missing priority", data: "Oct 11 22:14:15 mymachine su: foo", expErr: true},
+		{name: "invalid priority", data: "<192>1 - - - - - -", expErr: true},
+		{name: "invalid RFC 5424 timestamp", data: "<34>1 yesterday - - - - -", expErr: true},
+		{name: "missing RFC 5424 header fields", data: "<34>1 - host", expErr: true},
+		{name: "unterminated structured data", data: `<34>1 - - - - - [a b="c"`, expErr: true},
+		{name: "invalid RFC 3164 timestamp", data: "<34>yesterday at noon mymachine su: foo", expErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := parseMessage(tt.data, now)
+			assert.Equal(t, tt.expErr, err != nil, err)
+			assert.Equal(t, tt.expMsg, msg)
+		})
+	}
+}
+
+func Test_splitFrames(t *testing.T) {
+	tests := []struct {
+		name      string
+		stream    string
+		expFrames []string
+		expErr    bool
+	}{
+		{name: "new line", stream: "<34>first\r\n<34>second\n<34>last", expFrames: []string{"<34>first", "<34>second", "<34>last"}},
+		{name: "octet counting", stream: "9 <34>first11 <34>sec\nond", expFrames: []string{"<34>first", "<34>sec\nond"}},
+		{name: "mixed", stream: "9 <34>first<34>second\n", expFrames: []string{"<34>first", "<34>second"}},
+		{name: "incomplete octet counted frame", stream: "20 <34>first", expErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := bufio.NewScanner(strings.NewReader(tt.stream))
+			scanner.Split(splitFrames)
+			var frames []string
+			for scanner.Scan() {
+				frames = append(frames, scanner.Text())
+			}
+			assert.Equal(t, tt.expErr, scanner.Err() != nil, scanner.Err())
+			if !tt.expErr {
+				assert.Equal(t, tt.expFrames, frames)
+			}
+		})
+	}
+}
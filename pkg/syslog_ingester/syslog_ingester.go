@@ -0,0 +1,327 @@
+package syslog_ingester
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/lineparser"
+	"github.com/seznam/slo-exporter/pkg/pipeline"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const reasonInvalidSyslog = "invalidSyslog"
+
+// ListenerConfig is a socket to receive the syslog messages on.
+type ListenerConfig struct {
+	// Network is one of udp, tcp, unixgram or unix.
+	Network string
+	// Address to listen on, path of the socket for the unix networks.
+	Address string
+}
+
+func (c ListenerConfig) isStream() bool {
+	return c.Network == "tcp" || c.Network == "unix"
+}
+
+// SyslogIngesterConfig is configuration of the syslog ingester module.
+type SyslogIngesterConfig struct {
+	Listeners []ListenerConfig
+	// MaxMessageBytes limits size of the received messages.
+	MaxMessageBytes int
+	// Config of the parser of the message body.
+	lineparser.Config `mapstructure:",squash"`
+}
+
+// SyslogIngester produces events from the syslog messages received on the configured sockets.
+type SyslogIngester struct {
+	listeners       []ListenerConfig
+	maxMessageBytes int
+	parser          *lineparser.Parser
+	outputChannel   chan *event.Raw
+	observer        pipeline.EventProcessingDurationObserver
+	deadLetterSink  pipeline.DeadLetterSink
+	logger          logrus.FieldLogger
+	// connections are the accepted stream connections which are closed once the ingester is stopped.
+	connections   map[net.Conn]struct{}
+	connectionsMu sync.Mutex
+
+	messagesTotal          *prometheus.CounterVec
+	malformedMessagesTotal *prometheus.CounterVec
+}
+
+func (s *SyslogIngester) String() string {
+	return "syslogIngester"
+}
+
+func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*SyslogIngester, error) {
+	viperConfig.SetDefault("MaxMessageBytes", 64*1024)
+	lineparser.SetDefaults(viperConfig)
+	var config SyslogIngesterConfig
+	if err := viperConfig.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return New(config, logger)
+}
+
+// New returns an instance of SyslogIngester.
+func New(config SyslogIngesterConfig, logger logrus.FieldLogger) (*SyslogIngester, error) {
+	if len(config.Listeners) == 0 {
+		return nil, fmt.Errorf("no listener is configured")
+	}
+	for _, listener := range config.Listeners {
+		switch listener.Network {
+		case "udp", "tcp", "unixgram", "unix":
+		default:
+			return nil, fmt.Errorf("unknown network '%s' of the listener, expected one of udp, tcp, unixgram, unix", listener.Network)
+		}
+	}
+	if config.MaxMessageBytes <= 0 {
+		return nil, fmt.Errorf("maximum message size must be positive, got %d", config.MaxMessageBytes)
+	}
+	parser, err := lineparser.New(config.Config)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogIngester{
+		listeners:       config.Listeners,
+		maxMessageBytes: config.MaxMessageBytes,
+		parser:          parser,
+		outputChannel:   make(chan *event.Raw),
+		logger:          logger,
+		connections:     map[net.Conn]struct{}{},
+		messagesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "messages_total",
+			Help: "Total number of received syslog messages by the network.",
+		}, []string{"network"}),
+		malformedMessagesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "malformed_messages_total",
+			Help: "Total number of syslog messages that failed to parse by the reason.",
+		}, []string{"reason"}),
+	}, nil
+}
+
+func (s *SyslogIngester) RegisterEventProcessingDurationObserver(observer pipeline.EventProcessingDurationObserver) {
+	s.observer = observer
+}
+
+func (s *SyslogIngester) observeDuration(start time.Time) {
+	if s.observer != nil {
+		s.observer.Observe(time.Since(start).Seconds())
+	}
+}
+
+func (s *SyslogIngester) SetDeadLetterSink(sink pipeline.DeadLetterSink) {
+	s.deadLetterSink = sink
+}
+
+func (s *SyslogIngester) reject(message string, err error) {
+	if s.deadLetterSink != nil {
+		s.deadLetterSink.Reject(message, err.Error())
+	}
+}
+
+func (s *SyslogIngester) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
+	toRegister := []prometheus.Collector{s.messagesTotal, s.malformedMessagesTotal}
+	for _, collector := range toRegister {
+		if err := wrappedRegistry.Register(collector); err != nil {
+			return fmt.Errorf("error registering metric %s: %w", collector, err)
+		}
+	}
+	return nil
+}
+
+func (s *SyslogIngester) OutputChannel() chan *event.Raw {
+	return s.outputChannel
+}
+
+// removeStaleSocket removes the unix socket file left behind by previous run, so it is possible to listen on it.
+func removeStaleSocket(path string) error {
+	fstat, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fstat.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
+
+// listen opens all the configured sockets, the already opened ones are closed on failure.
+func (s *SyslogIngester) listen() ([]io.Closer, error) {
+	var sockets []io.Closer
+	for _, listener := range s.listeners {
+		var socket io.Closer
+		var err error
+		if strings.HasPrefix(listener.Network, "unix") {
+			err = removeStaleSocket(listener.Address)
+		}
+		if err == nil {
+			if listener.isStream() {
+				socket, err = net.Listen(listener.Network, listener.Address)
+			} else {
+				socket, err = net.ListenPacket(listener.Network, listener.Address)
+			}
+		}
+		if err != nil {
+			for _, opened := range sockets {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("failed to listen on %s %s: %w", listener.Network, listener.Address, err)
+		}
+		s.logger.Infof("listening for syslog messages on %s %s", listener.Network, listener.Address)
+		sockets = append(sockets, socket)
+	}
+	return sockets, nil
+}
+
+// Run receives the syslog messages feeding events to output channel until the context is cancelled.
+func (s *SyslogIngester) Run(ctx context.Context) error {
+	defer close(s.outputChannel)
+	sockets, err := s.listen()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	// serveErr holds the first fatal error of the listeners.
+	serveErr := make(chan error, len(sockets))
+	for i, socket := range sockets {
+		network := s.listeners[i].Network
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			switch typedSocket := socket.(type) {
+			case net.Listener:
+				err = s.serveStream(ctx, network, typedSocket, &wg)
+			case net.PacketConn:
+				err = s.servePackets(ctx, network, typedSocket)
+			}
+			if err != nil && ctx.Err() == nil {
+				serveErr <- err
+				cancel()
+			}
+		}()
+	}
+	<-ctx.Done()
+	for _, socket := range sockets {
+		socket.Close()
+	}
+	s.connectionsMu.Lock()
+	for conn := range s.connections {
+		conn.Close()
+	}
+	s.connectionsMu.Unlock()
+	wg.Wait()
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("%s failed to receive messages: %w", s, err)
+	default:
+		return nil
+	}
+}
+
+// servePackets processes the datagrams, each containing single message.
+func (s *SyslogIngester) servePackets(ctx context.Context, network string, conn net.PacketConn) error {
+	buffer := make([]byte, s.maxMessageBytes)
+	for {
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return err
+		}
+		s.processMessage(ctx, network, strings.TrimRight(string(buffer[:n]), "\r\n"))
+	}
+}
+
+// serveStream accepts the connections and processes the framed messages of each of them.
+func (s *SyslogIngester) serveStream(ctx context.Context, network string, listener net.Listener, wg *sync.WaitGroup) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		s.connectionsMu.Lock()
+		if ctx.Err() != nil {
+			s.connectionsMu.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.connections[conn] = struct{}{}
+		wg.Add(1)
+		s.connectionsMu.Unlock()
+		go func() {
+			defer wg.Done()
+			s.serveConnection(ctx, network, conn)
+		}()
+	}
+}
+
+func (s *SyslogIngester) serveConnection(ctx context.Context, network string, conn net.Conn) {
+	defer func() {
+		s.connectionsMu.Lock()
+		delete(s.connections, conn)
+		s.connectionsMu.Unlock()
+		conn.Close()
+	}()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), s.maxMessageBytes)
+	scanner.Split(splitFrames)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		s.processMessage(ctx, network, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		s.logger.WithField("remoteAddress", conn.RemoteAddr()).Errorf("closing the connection after failing to read message: %v", err)
+	}
+}
+
+func (s *SyslogIngester) processMessage(ctx context.Context, network, data string) {
+	start := time.Now()
+	defer s.observeDuration(start)
+	s.messagesTotal.WithLabelValues(network).Inc()
+	newEvent, err := s.newEvent(data, start)
+	if err != nil {
+		s.malformedMessagesTotal.WithLabelValues(lineparser.MalformedLineReason(err)).Inc()
+		s.logger.WithField("message", data).Errorf("err (%+v) while parsing message", err)
+		s.reject(data, err)
+		return
+	}
+	select {
+	case s.outputChannel <- newEvent:
+	case <-ctx.Done():
+	}
+}
+
+// newEvent returns event of the syslog message, the body of the message is parsed using the line parser.
+func (s *SyslogIngester) newEvent(data string, now time.Time) (*event.Raw, error) {
+	msg, err := parseMessage(data, now)
+	if err != nil {
+		return nil, &lineparser.MalformedLineError{Reason: reasonInvalidSyslog, Err: err}
+	}
+	metadata, timestamp, err := s.parser.Parse(msg.body)
+	if err != nil {
+		return nil, err
+	}
+	// Time of the syslog message is used unless the time is parsed from the body.
+	if timestamp.IsZero() {
+		timestamp = msg.timestamp
+	}
+	return &event.Raw{Metadata: msg.header.Merge(metadata), Quantity: 1, Timestamp: timestamp}, nil
+}
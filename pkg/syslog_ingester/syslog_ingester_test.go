@@ -0,0 +1,81 @@
+package syslog_ingester
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/internal/moduletest"
+	"github.com/seznam/slo-exporter/pkg/lineparser"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	parserConfig := lineparser.Config{Format: "logfmt", EmptyGroupRE: "^$"}
+	_, err := New(SyslogIngesterConfig{Listeners: []ListenerConfig{{Network: "udp", Address: ":0"}}, MaxMessageBytes: 1024, Config: parserConfig}, logrus.New())
+	assert.NoError(t, err)
+	_, err = New(SyslogIngesterConfig{MaxMessageBytes: 1024, Config: parserConfig}, logrus.New())
+	assert.Error(t, err)
+	_, err = New(SyslogIngesterConfig{Listeners: []ListenerConfig{{Network: "sctp", Address: ":0"}}, MaxMessageBytes: 1024, Config: parserConfig}, logrus.New())
+	assert.Error(t, err)
+	_, err = New(SyslogIngesterConfig{Listeners: []ListenerConfig{{Network: "udp", Address: ":0"}}, Config: parserConfig}, logrus.New())
+	assert.Error(t, err)
+}
+
+func TestSyslogIngester(t *testing.T) {
+	dir := t.TempDir()
+	streamSocket := filepath.Join(dir, "stream.sock")
+	datagramSocket := filepath.Join(dir, "datagram.sock")
+	ingester, err := New(SyslogIngesterConfig{
+		Listeners:       []ListenerConfig{{Network: "unix", Address: streamSocket}, {Network: "unixgram", Address: datagramSocket}},
+		MaxMessageBytes: 1024,
+		Config:          lineparser.Config{Format: "logfmt", EmptyGroupRE: "^$"},
+	}, logrus.New())
+	assert.NoError(t, err)
+
+	stop := moduletest.Run(t, ingester)
+
+	// Wait for the sockets to be listened on.
+	var streamConn, datagramConn net.Conn
+	assert.Eventually(t, func() bool {
+		streamConn, err = net.Dial("unix", streamSocket)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		datagramConn, err = net.Dial("unixgram", datagramSocket)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = streamConn.Write([]byte("<165>1 2003-10-11T22:14:15.003Z host app - - [meta sequenceId=\"1\"] method=GET\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, &event.Raw{
+		Metadata:  stringmap.StringMap{facilityKey: "20", severityKey: "5", hostnameKey: "host", appNameKey: "app", "structuredData.meta.sequenceId": "1", "method": "GET"},
+		Quantity:  1,
+		Timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+	}, <-ingester.OutputChannel())
+
+	_, err = streamConn.Write([]byte("invalid\n"))
+	assert.NoError(t, err)
+	_, err = datagramConn.Write([]byte("<165>1 2003-10-11T22:14:15.003Z - - - - - method=POST"))
+	assert.NoError(t, err)
+	assert.Equal(t, &event.Raw{
+		Metadata:  stringmap.StringMap{facilityKey: "20", severityKey: "5", "method": "POST"},
+		Quantity:  1,
+		Timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+	}, <-ingester.OutputChannel())
+	// The malformed message of the stream socket is not ordered with the datagram one.
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(ingester.malformedMessagesTotal.WithLabelValues(reasonInvalidSyslog)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2.0, testutil.ToFloat64(ingester.messagesTotal.WithLabelValues("unix")))
+
+	// Open connection must not block the shutdown.
+	stop()
+	streamConn.Close()
+	datagramConn.Close()
+}
@@ -0,0 +1,137 @@
+package tailer
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/lineparser"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+)
+
+// Formats of the container logs written by the container runtimes.
+const (
+	criContainerLogFormat    = "cri"
+	dockerContainerLogFormat = "docker"
+)
+
+const (
+	reasonInvalidContainerLog = "invalidContainerLog"
+	reasonPartialLineTooLong  = "partialLineTooLong"
+)
+
+// maxPartialLineBytes limits length of the line reassembled from the partial lines, so a container never ending its line cannot exhaust memory.
+const maxPartialLineBytes = 1024 * 1024
+
+// containerLogLine is a line of the container log stripped of the envelope of the container runtime.
+type containerLogLine struct {
+	content   string
+	timestamp time.Time
+	// partial is set if the line continues in the next line of the log.
+	partial bool
+}
+
+type containerLogDecoder func(line string) (containerLogLine, error)
+
+func newContainerLogDecoder(format string) (containerLogDecoder, error) {
+	switch format {
+	case "":
+		return nil, nil
+	case criContainerLogFormat:
+		return decodeCRILine, nil
+	case dockerContainerLogFormat:
+		return decodeDockerLine, nil
+	default:
+		return nil, fmt.Errorf("unknown container log format '%s', expected one of %s, %s", format, criContainerLogFormat, dockerContainerLogFormat)
+	}
+}
+
+// decodeCRILine decodes the line of the CRI log format `<RFC3339 time> <stream> <P|F>[:<tag>...] <content>`.
+func decodeCRILine(line string) (containerLogLine, error) {
+	parts := strings.SplitN(line, " ", 4)
+	if len(parts) < 3 {
+		return containerLogLine{}, fmt.Errorf("missing fields of the CRI log line")
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return containerLogLine{}, fmt.Errorf("invalid time of the CRI log line: %w", err)
+	}
+	decoded := containerLogLine{timestamp: timestamp}
+	switch strings.SplitN(parts[2], ":", 2)[0] {
+	case "P":
+		decoded.partial = true
+	case "F":
+	default:
+		return containerLogLine{}, fmt.Errorf("invalid tag of the CRI log line '%s'", parts[2])
+	}
+	if len(parts) == 4 {
+		decoded.content = parts[3]
+	}
+	return decoded, nil
+}
+
+// dockerLogLine is line of the Docker json-file log driver.
+type dockerLogLine struct {
+	Log    string    `json:"log"`
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+}
+
+// decodeDockerLine decodes the line of the Docker json-file log driver, line of the container is partial unless it ends with new line.
+func decodeDockerLine(line string) (containerLogLine, error) {
+	var dockerLine dockerLogLine
+	if err := json.Unmarshal([]byte(line), &dockerLine); err != nil {
+		return containerLogLine{}, fmt.Errorf("invalid Docker log line: %w", err)
+	}
+	content, complete := strings.CutSuffix(dockerLine.Log, "\n")
+	return containerLogLine{content: strings.TrimSuffix(content, "\r"), timestamp: dockerLine.Time, partial: !complete}, nil
+}
+
+var (
+	// podLogPathRegexp matches path of the container log in the pod logs directory `/var/log/pods/<namespace>_<pod>_<uid>/<container>/<restart>.log`.
+	podLogPathRegexp = regexp.MustCompile(`(?:^|/)(?P<namespace>[^/_]+)_(?P<pod>[^/_]+)_[^/_]+/(?P<container>[^/]+)/[^/]+$`)
+	// containerLogPathRegexp matches path of the container log symlink `/var/log/containers/<pod>_<namespace>_<container>-<container ID>.log`.
+	containerLogPathRegexp = regexp.MustCompile(`(?:^|/)(?P<pod>[^/_]+)_(?P<namespace>[^/_]+)_(?P<container>[^/_]+)-[0-9a-f]{64}\.log$`)
+)
+
+// podMetadata returns namespace, pod and container derived from the path of the container log, if it follows the Kubernetes conventions.
+func podMetadata(path string) stringmap.StringMap {
+	for _, pathRegexp := range []*regexp.Regexp{podLogPathRegexp, containerLogPathRegexp} {
+		match := pathRegexp.FindStringSubmatch(path)
+		if match == nil {
+			continue
+		}
+		metadata := stringmap.StringMap{}
+		for i, name := range pathRegexp.SubexpNames() {
+			if name != "" {
+				metadata[name] = match[i]
+			}
+		}
+		return metadata
+	}
+	return nil
+}
+
+// unwrapContainerLine strips the envelope of the container runtime and reassembles the partial lines of the file.
+// Returns false if the line is partial and continues in the next line of the file.
+func (t *Tailer) unwrapContainerLine(path, line string) (containerLogLine, bool, error) {
+	decoded, err := t.containerLogDecoder(line)
+	if err != nil {
+		delete(t.partialLines, path)
+		return containerLogLine{}, false, &lineparser.MalformedLineError{Reason: reasonInvalidContainerLog, Err: err}
+	}
+	partial := t.partialLines[path] + decoded.content
+	if len(partial) > maxPartialLineBytes {
+		delete(t.partialLines, path)
+		return containerLogLine{}, false, &lineparser.MalformedLineError{Reason: reasonPartialLineTooLong, Err: fmt.Errorf("line reassembled from the partial lines exceeds %d bytes", maxPartialLineBytes)}
+	}
+	if decoded.partial {
+		t.partialLines[path] = partial
+		return containerLogLine{}, false, nil
+	}
+	delete(t.partialLines, path)
+	decoded.content = partial
+	return decoded, true, nil
+}
@@ -0,0 +1,85 @@
+package tailer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/lineparser"
+	"github.com/seznam/slo-exporter/pkg/stringmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerLogDecoders(t *testing.T) {
+	timestamp := time.Date(2016, 10, 6, 0, 17, 9, 669794202, time.UTC)
+	tests := []struct {
+		format  string
+		line    string
+		expLine containerLogLine
+		expErr  bool
+	}{
+		{format: criContainerLogFormat, line: "2016-10-06T00:17:09.669794202Z stdout F method=GET status=200", expLine: containerLogLine{content: "method=GET status=200", timestamp: timestamp}},
+		{format: criContainerLogFormat, line: "2016-10-06T00:17:09.669794202Z stderr P method=GET ", expLine: containerLogLine{content: "method=GET ", timestamp: timestamp, partial: true}},
+		{format: criContainerLogFormat, line: "2016-10-06T00:17:09.669794202Z stdout F:x ", expLine: containerLogLine{content: "", timestamp: timestamp}},
+		{format: criContainerLogFormat, line: "2016-10-06T00:17:09.669794202Z stdout F", expLine: containerLogLine{content: "", timestamp: timestamp}},
+		{format: criContainerLogFormat, line: "2016-10-06T00:17:09.669794202Z stdout X foo", expErr: true},
+		{format: criContainerLogFormat, line: "yesterday stdout F foo", expErr: true},
+		{format: criContainerLogFormat, line: "method=GET", expErr: true},
+		{format: dockerContainerLogFormat, line: `{"log":"method=GET status=200\n","stream":"stdout","time":"2016-10-06T00:17:09.669794202Z"}`, expLine: containerLogLine{content: "method=GET status=200", timestamp: timestamp}},
+		{format: dockerContainerLogFormat, line: `{"log":"method=GET ","stream":"stdout","time":"2016-10-06T00:17:09.669794202Z"}`, expLine: containerLogLine{content: "method=GET ", timestamp: timestamp, partial: true}},
+		{format: dockerContainerLogFormat, line: `method=GET`, expErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.line, func(t *testing.T) {
+			decoder, err := newContainerLogDecoder(tt.format)
+			assert.NoError(t, err)
+			decoded, err := decoder(tt.line)
+			assert.Equal(t, tt.expErr, err != nil, err)
+			assert.Equal(t, tt.expLine, decoded)
+		})
+	}
+	_, err := newContainerLogDecoder("podman")
+	assert.Error(t, err)
+}
+
+func Test_podMetadata(t *testing.T) {
+	assert.Equal(t, stringmap.StringMap{"namespace": "default", "pod": "web-5d8f7c6b9-x2x4q", "container": "nginx"},
+		podMetadata("/var/log/pods/default_web-5d8f7c6b9-x2x4q_2b2c8a9e-6b1f-4f55-9a5c-1d2f3e4a5b6c/nginx/0.log"))
+	assert.Equal(t, stringmap.StringMap{"namespace": "default", "pod": "web-5d8f7c6b9-x2x4q", "container": "nginx"},
+		podMetadata("/var/log/containers/web-5d8f7c6b9-x2x4q_default_nginx-"+strings.Repeat("a", 64)+".log"))
+	assert.Nil(t, podMetadata("/var/log/nginx/access.log"))
+}
+
+func TestTailer_processLineContainerLog(t *testing.T) {
+	parser, err := lineparser.New(lineparser.Config{Format: "logfmt", EmptyGroupRE: "^$"})
+	assert.NoError(t, err)
+	tailer := Tailer{
+		containerLogDecoder: decodeCRILine,
+		partialLines:        map[string]string{},
+		parser:              parser,
+	}
+	path := "/var/log/pods/default_web_2b2c8a9e/nginx/0.log"
+
+	newEvent, err := tailer.processLine(path, "2016-10-06T00:17:09.669794202Z stdout P method=GET ")
+	assert.NoError(t, err)
+	assert.Nil(t, newEvent)
+	newEvent, err = tailer.processLine(path, "2016-10-06T00:17:09.669794202Z stdout P path=/ ")
+	assert.NoError(t, err)
+	assert.Nil(t, newEvent)
+	newEvent, err = tailer.processLine(path, "2016-10-06T00:17:10Z stdout F status=200")
+	assert.NoError(t, err)
+	assert.Equal(t, stringmap.StringMap{"method": "GET", "path": "/", "status": "200", "namespace": "default", "pod": "web", "container": "nginx"}, newEvent.Metadata)
+	assert.Equal(t, time.Date(2016, 10, 6, 0, 17, 10, 0, time.UTC), newEvent.Timestamp)
+	assert.Empty(t, tailer.partialLines)
+
+	_, err = tailer.processLine(path, "method=GET")
+	assert.Equal(t, reasonInvalidContainerLog, lineparser.MalformedLineReason(err))
+
+	for i := 0; i < maxPartialLineBytes/1024; i++ {
+		_, err = tailer.processLine(path, "2016-10-06T00:17:09Z stdout P "+strings.Repeat("a", 1024))
+		assert.NoError(t, err)
+	}
+	_, err = tailer.processLine(path, "2016-10-06T00:17:09Z stdout F a")
+	assert.Equal(t, reasonPartialLineTooLong, lineparser.MalformedLineReason(err))
+	assert.Empty(t, tailer.partialLines)
+}
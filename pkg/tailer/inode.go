@@ -0,0 +1,16 @@
+//go:build !windows
+
+package tailer
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileInode returns inode of the file, zero if unknown.
+func fileInode(fstat os.FileInfo) uint64 {
+	if stat, ok := fstat.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
@@ -0,0 +1,197 @@
+package tailer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hpcloud/tail"
+	"github.com/sirupsen/logrus"
+)
+
+// position is the persisted position of the tailed file.
+// It is stored as `<offset>:<inode>` in the position file, older position files containing only the offset are supported.
+type position struct {
+	offset int64
+	// inode of the file the offset belongs to, zero if unknown.
+	inode uint64
+}
+
+func parsePosition(value string) (position, error) {
+	if value == "" {
+		return position{}, nil
+	}
+	offsetValue, inodeValue, hasInode := strings.Cut(value, ":")
+	offset, err := strconv.ParseInt(offsetValue, 10, 64)
+	if err != nil {
+		return position{}, fmt.Errorf("invalid offset of the position '%s': %w", value, err)
+	}
+	pos := position{offset: offset}
+	if hasInode {
+		pos.inode, err = strconv.ParseUint(inodeValue, 10, 64)
+		if err != nil {
+			return position{}, fmt.Errorf("invalid inode of the position '%s': %w", value, err)
+		}
+	}
+	return pos, nil
+}
+
+func (p position) String() string {
+	if p.inode == 0 {
+		return strconv.FormatInt(p.offset, 10)
+	}
+	return strconv.FormatInt(p.offset, 10) + ":" + strconv.FormatUint(p.inode, 10)
+}
+
+func isCompressed(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+// findRotatedFile returns path of the file the tailed file was rotated to, empty if not found.
+// Rotated files are expected in the same directory with name of the tailed file followed by `.` or `-`,
+// e.g. `access.log.1`, `access.log.2.gz` or `access.log-20240101`.
+// If the inode of the file is known, the rotated file with the same inode is preferred,
+// otherwise the most recently modified rotated file is used, only compressed ones if the inode is known since those have different inode.
+func (t *Tailer) findRotatedFile(path string, inode uint64) string {
+	dir, name := filepath.Split(path)
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return ""
+	}
+	var newest string
+	var newestModTime time.Time
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), name+".") && !strings.HasPrefix(entry.Name(), name+"-") {
+			continue
+		}
+		candidate := filepath.Join(dir, entry.Name())
+		if candidate == t.positionFile || t.matchesPatterns(candidate) {
+			continue
+		}
+		fstat, err := os.Stat(candidate)
+		if err != nil || !fstat.Mode().IsRegular() {
+			continue
+		}
+		if inode != 0 && !isCompressed(candidate) {
+			if fileInode(fstat) == inode {
+				return candidate
+			}
+			continue
+		}
+		if newest == "" || fstat.ModTime().After(newestModTime) {
+			newest, newestModTime = candidate, fstat.ModTime()
+		}
+	}
+	return newest
+}
+
+// matchesPatterns reports whether the path matches any of the patterns of the tailed files.
+func (t *Tailer) matchesPatterns(path string) bool {
+	for _, pattern := range t.patterns {
+		if matched, _ := filepath.Match(pattern, path); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// readRotatedFile forwards lines of the rotated file starting at the offset as lines of the tailed file,
+// until the end of the rotated file is reached or the tailing is stopped.
+func (t *Tailer) readRotatedFile(path, rotated string, offset int64, dying <-chan struct{}) error {
+	file, err := os.Open(rotated)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	if isCompressed(rotated) {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("failed to decompress the file: %w", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+	if _, err := io.CopyN(io.Discard, reader, offset); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("offset %d is beyond the end of the file", offset)
+		}
+		return err
+	}
+	bufferedReader := bufio.NewReader(reader)
+	for {
+		text, err := bufferedReader.ReadString('\n')
+		if text != "" {
+			select {
+			case t.lines <- fileLine{path: path, line: &tail.Line{Text: strings.TrimRight(text, "\n"), Time: time.Now()}}:
+			case <-dying:
+				return nil
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// tailLogger is the logger of the tail library tracking reopening of the file by the tail, since the library does not expose the opened file.
+// The library logs right before and after it reopens the moved or truncated file.
+type tailLogger struct {
+	logrus.FieldLogger
+	path string
+	file *tailedFile
+}
+
+func (l *tailLogger) Printf(format string, args ...interface{}) {
+	switch {
+	case strings.HasPrefix(format, "Re-opening "):
+		l.file.reopenStarted()
+	case strings.HasPrefix(format, "Successfully reopened "):
+		// The reopened file is the one at the path, if it is missing already the tail reopens it once more.
+		if fstat, err := os.Stat(l.path); err == nil {
+			l.file.reopenFinished(fileInode(fstat))
+		}
+	}
+	l.FieldLogger.Printf(format, args...)
+}
+
+// reopenStarted stops persisting the offset, the tail must not be asked for the offset while it reopens the file.
+func (f *tailedFile) reopenStarted() {
+	f.positionMtx.Lock()
+	defer f.positionMtx.Unlock()
+	f.reopening = true
+	f.reopensStarted++
+}
+
+func (f *tailedFile) reopenFinished(inode uint64) {
+	f.positionMtx.Lock()
+	defer f.positionMtx.Unlock()
+	f.inode = inode
+	f.reopensFinished = f.reopensStarted
+}
+
+// lineRead resumes persisting the offset once a line of the reopened file is read, so the tail finished reopening the file.
+// The tail reads and sends the lines one by one, so the line read after the finished reopening was noticed
+// was read from the reopened file unless another reopening started meanwhile.
+func (f *tailedFile) lineRead() {
+	f.positionMtx.Lock()
+	defer f.positionMtx.Unlock()
+	if !f.reopening {
+		return
+	}
+	if f.reopenRead == f.reopensStarted {
+		f.reopening = false
+	} else if f.reopensFinished == f.reopensStarted {
+		f.reopenRead = f.reopensFinished
+	}
+}
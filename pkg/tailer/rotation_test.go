@@ -0,0 +1,149 @@
+package tailer
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/seznam/slo-exporter/pkg/lineparser"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parsePosition(t *testing.T) {
+	for _, value := range []string{"", "42", "42:1234"} {
+		pos, err := parsePosition(value)
+		assert.NoError(t, err)
+		if value != "" {
+			assert.Equal(t, value, pos.String())
+		}
+	}
+	_, err := parsePosition("42:abc")
+	assert.Error(t, err)
+	_, err = parsePosition("abc")
+	assert.Error(t, err)
+}
+
+// runTailer tails the file until cancelled and returns number of the read events.
+func runTailer(t *testing.T, config TailerConfig) int {
+	tailer, err := New(config, logrus.New())
+	assert.NoError(t, err)
+	eventCount := make(chan int)
+	go countEvents(tailer.OutputChannel(), eventCount)
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error)
+	go func() { runErr <- tailer.Run(ctx) }()
+	time.Sleep(time.Second)
+	cancel()
+	assert.NoError(t, <-runErr)
+	return <-eventCount
+}
+
+func gzipFile(t *testing.T, path string) {
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	f, err := os.Create(path + ".gz")
+	assert.NoError(t, err)
+	defer f.Close()
+	writer := gzip.NewWriter(f)
+	_, err = writer.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, os.Remove(path))
+}
+
+func TestTailer_RotatedFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		rotate func(t *testing.T, path string)
+	}{
+		{name: "renamed", rotate: func(t *testing.T, path string) {
+			assert.NoError(t, os.Rename(path, path+".1"))
+		}},
+		{name: "compressed", rotate: func(t *testing.T, path string) {
+			assert.NoError(t, os.Rename(path, path+".1"))
+			gzipFile(t, path+".1")
+		}},
+		{name: "copied and truncated", rotate: func(t *testing.T, path string) {
+			content, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.NoError(t, os.WriteFile(path+"-20240101", content, 0o644))
+			assert.NoError(t, os.Truncate(path, 0))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "access.log")
+			writeLines(t, path, 3)
+			config := TailerConfig{
+				TailedFile:                  path,
+				Follow:                      true,
+				PositionFile:                path + ".pos",
+				PositionPersistenceInterval: time.Second,
+				Config:                      lineparser.Config{LoglineParseRegexp: lineParseRegexp, EmptyGroupRE: emptyGroupRegexp},
+			}
+			assert.Equal(t, 3, runTailer(t, config))
+
+			// Lines written before the rotation while the exporter is down are read from the rotated file.
+			writeLines(t, path, 2)
+			tt.rotate(t, path)
+			writeLines(t, path, 1)
+			assert.Equal(t, 3, runTailer(t, config))
+			// Once read, the rotated file is not read again.
+			writeLines(t, path, 1)
+			assert.Equal(t, 1, runTailer(t, config))
+		})
+	}
+}
+
+func TestTailer_markOffsetPositionLiveRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	writeLines(t, path, 3)
+	tailer, err := New(TailerConfig{
+		TailedFile:                  path,
+		Follow:                      true,
+		Reopen:                      true,
+		PositionFile:                path + ".pos",
+		PositionPersistenceInterval: time.Minute,
+		Config:                      lineparser.Config{LoglineParseRegexp: lineParseRegexp, EmptyGroupRE: emptyGroupRegexp},
+	}, logrus.New())
+	assert.NoError(t, err)
+	defer tailer.positions.Stop()
+	assert.NoError(t, tailer.startTailing(path))
+	file := tailer.files[path]
+	for i := 0; i < 3; i++ {
+		<-tailer.lines
+	}
+	rotatedStat, err := os.Stat(path)
+	assert.NoError(t, err)
+
+	// The file is rotated but the tail still reads the rotated file, so its offset is persisted with the inode of the rotated file.
+	assert.NoError(t, os.Rename(path, path+".1"))
+	writeLines(t, path, 1)
+	assert.NoError(t, tailer.markOffsetPosition(path, file))
+	assert.Equal(t, position{offset: rotatedStat.Size(), inode: fileInode(rotatedStat)}.String(), tailer.positions.GetString(path))
+
+	// Once the second line of the reopened file is read, its offset is persisted with its inode.
+	<-tailer.lines
+	writeLines(t, path, 1)
+	<-tailer.lines
+	newStat, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.NoError(t, tailer.markOffsetPosition(path, file))
+	assert.Equal(t, position{offset: newStat.Size(), inode: fileInode(newStat)}.String(), tailer.positions.GetString(path))
+
+	// Nothing is persisted while the file is being reopened.
+	file.reopenStarted()
+	writeLines(t, path, 1)
+	<-tailer.lines
+	assert.NoError(t, tailer.markOffsetPosition(path, file))
+	assert.Equal(t, position{offset: newStat.Size(), inode: fileInode(newStat)}.String(), tailer.positions.GetString(path))
+
+	tailer.stopTailing(file)
+	<-tailer.finishedFiles
+}
@@ -1,14 +1,20 @@
 package tailer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	"regexp"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/lineparser"
 	"github.com/seznam/slo-exporter/pkg/pipeline"
 	"github.com/spf13/viper"
 
@@ -19,55 +25,93 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var (
-	linesReadTotal = prometheus.NewCounter(prometheus.CounterOpts{
-		Name: "lines_read_total",
-		Help: "Total number of lines tailed from the file.",
-	})
-	malformedLinesTotal = prometheus.NewCounter(
-		prometheus.CounterOpts{
-			Name: "malformed_lines_total",
-			Help: "Total number of invalid lines that failed to parse.",
-		},
-	)
-	fileSizeBytes = prometheus.NewGauge(prometheus.GaugeOpts{
-		Name: "file_size_bytes",
-		Help: "Size of the tailed file in bytes.",
-	})
-	fileOffsetBytes = prometheus.NewGauge(prometheus.GaugeOpts{
-		Name: "file_offset_bytes",
-		Help: "Current tailing offset within the file in bytes (from the beginning of the file).",
-	})
-)
-
-type tailerConfig struct {
-	TailedFile                  string
+// TailerConfig is configuration of the tailer module.
+type TailerConfig struct {
+	// TailedFile is path or glob pattern of the files to be tailed.
+	TailedFile string
+	// TailedFiles are additional paths or glob patterns of the files to be tailed.
+	TailedFiles                 []string
 	Follow                      bool
 	Reopen                      bool
 	PositionFile                string
 	PositionPersistenceInterval time.Duration
-	LoglineParseRegexp          string
-	EmptyGroupRE                string
+	// FileDiscoveryInterval is how often to look for new files matching the glob patterns while following the files, zero disables the discovery after start.
+	FileDiscoveryInterval time.Duration
+	// FileMetadataKey is the metadata key of the path of the file the event was read from, if empty the path is not added.
+	FileMetadataKey string
+	// ContainerLogFormat is format of the container runtime log envelope (cri or docker) to be stripped before parsing the lines, empty if the lines are not wrapped.
+	ContainerLogFormat string
+	lineparser.Config  `mapstructure:",squash"`
 }
 
 // getDefaultPositionsFilePath derives positions file path for given tailed filename.
-func (c *tailerConfig) getDefaultPositionsFilePath() string {
+func (c *TailerConfig) getDefaultPositionsFilePath() string {
 	return c.TailedFile + ".pos"
 }
 
-// Tailer is an instance of github.com/hpcloud/tail dedicated to a single file.
+// patterns returns all the configured paths and glob patterns of the tailed files.
+func (c *TailerConfig) patterns() []string {
+	var patterns []string
+	if c.TailedFile != "" {
+		patterns = append(patterns, c.TailedFile)
+	}
+	return append(patterns, c.TailedFiles...)
+}
+
+func isGlobPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// tailedFile is an instance of github.com/hpcloud/tail dedicated to a single file.
+type tailedFile struct {
+	tail *tail.Tail
+	// removed is set once the file no longer matches any of the patterns.
+	removed bool
+	// catchingUp is set while the file the tailed file was rotated to is being read, the persisted position is kept until it is finished.
+	catchingUp atomic.Bool
+	// positionMtx guards the fields below and orders getting the offset of the file with its reopening by the tail.
+	positionMtx sync.Mutex
+	// inode of the file opened by the tail, the offset is persisted along with it.
+	inode uint64
+	// reopening is set once the tail starts reopening the moved or truncated file until a line of the reopened file is read.
+	reopening bool
+	// reopensStarted and reopensFinished count the reopenings of the file by the tail.
+	reopensStarted, reopensFinished uint64
+	// reopenRead is the finished reopening whose file is being read, see lineRead.
+	reopenRead uint64
+}
+
+// fileLine is a line read from the file of given path.
+type fileLine struct {
+	path string
+	line *tail.Line
+}
+
+// Tailer tails all the files matching the configured patterns.
 type Tailer struct {
-	filename                string
-	tail                    *tail.Tail
+	patterns                []string
+	positionFile            string
+	follow                  bool
+	reopen                  bool
+	files                   map[string]*tailedFile
+	lines                   chan fileLine
+	finishedFiles           chan string
 	positions               positions.Positions
 	persistPositionInterval time.Duration
+	fileDiscoveryInterval   time.Duration
+	fileMetadataKey         string
 	observer                pipeline.EventProcessingDurationObserver
-	lineParseRegexp         *regexp.Regexp
-	emptyGroupRegexp        *regexp.Regexp
+	deadLetterSink          pipeline.DeadLetterSink
+	containerLogDecoder     containerLogDecoder
+	partialLines            map[string]string
+	parser                  *lineparser.Parser
 	outputChannel           chan *event.Raw
-	shutdownChannel         chan struct{}
 	logger                  logrus.FieldLogger
-	done                    bool
+
+	linesReadTotal      prometheus.Counter
+	malformedLinesTotal *prometheus.CounterVec
+	fileSizeBytes       *prometheus.GaugeVec
+	fileOffsetBytes     *prometheus.GaugeVec
 }
 
 func (t *Tailer) String() string {
@@ -78,8 +122,10 @@ func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*Tailer,
 	viperConfig.SetDefault("Follow", true)
 	viperConfig.SetDefault("Reopen", true)
 	viperConfig.SetDefault("PositionPersistenceInterval", 2*time.Second)
-	viperConfig.SetDefault("EmptyGroupRE", "^$")
-	var config tailerConfig
+	viperConfig.SetDefault("FileDiscoveryInterval", 10*time.Second)
+	viperConfig.SetDefault("FileMetadataKey", "file")
+	lineparser.SetDefaults(viperConfig)
+	var config TailerConfig
 	if err := viperConfig.UnmarshalExact(&config); err != nil {
 		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
@@ -87,72 +133,78 @@ func NewFromViper(viperConfig *viper.Viper, logger logrus.FieldLogger) (*Tailer,
 }
 
 // New returns an instance of Tailer.
-func New(config tailerConfig, logger logrus.FieldLogger) (*Tailer, error) {
-	var (
-		offset int64
-		err    error
-		pos    positions.Positions
-	)
-
-	if config.PositionFile == "" {
-		config.PositionFile = config.getDefaultPositionsFilePath()
-	}
-	pos, err = positions.New(logrusAdapter.NewLogger(logger), positions.Config{SyncPeriod: config.PositionPersistenceInterval, PositionsFile: config.PositionFile})
-	if err != nil {
-		return nil, fmt.Errorf("could not initialize file position persister: %w", err)
+func New(config TailerConfig, logger logrus.FieldLogger) (*Tailer, error) {
+	patterns := config.patterns()
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no file to be tailed is configured")
 	}
-	// check that loaded position for a file is valid
-	fstat, err := os.Stat(config.TailedFile)
-	if err != nil {
-		return nil, fmt.Errorf("could not check that loaded offset is valid: %w", err)
-	}
-	offset, err = pos.Get(config.TailedFile)
-	if err != nil {
-		return nil, err
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern of the tailed files '%s': %w", pattern, err)
+		}
+		if isGlobPattern(pattern) {
+			continue
+		}
+		if _, err := os.Stat(pattern); err != nil {
+			return nil, fmt.Errorf("could not check that the tailed file exists: %w", err)
+		}
 	}
-	fileSize := fstat.Size()
-	if fileSize < offset {
-		logger.WithField("file", config.TailedFile).Warnf("loaded position '%d' for the file is larger that the file size '%d'. Tailer will start from the beginning of the file.", offset, fileSize)
-		pos.Remove(config.TailedFile)
-		offset = 0
+	if config.PositionFile == "" {
+		if len(patterns) > 1 || isGlobPattern(patterns[0]) {
+			return nil, fmt.Errorf("position file must be set when tailing multiple files")
+		}
+		config.PositionFile = config.getDefaultPositionsFilePath()
 	}
-
 	if !config.Follow && config.Reopen {
 		return nil, fmt.Errorf("cannot use reopen without follow")
 	}
-	tailFile, err := tail.TailFile(config.TailedFile, tail.Config{
-		Follow:    config.Follow,
-		ReOpen:    config.Reopen,
-		MustExist: true,
-		Location:  &tail.SeekInfo{Offset: offset, Whence: io.SeekStart},
-		// tail library has claimed problems with inotify: https://github.com/grafana/loki/commit/c994823369d65785e72c4247fd50c656801e429a
-		Poll:   true,
-		Logger: logger,
-	})
+	pos, err := positions.New(logrusAdapter.NewLogger(logger), positions.Config{SyncPeriod: config.PositionPersistenceInterval, PositionsFile: config.PositionFile})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("could not initialize file position persister: %w", err)
 	}
 
-	lineParseRegexp, err := regexp.Compile(config.LoglineParseRegexp)
+	decoder, err := newContainerLogDecoder(config.ContainerLogFormat)
 	if err != nil {
-		return nil, fmt.Errorf("error while compiling the line parse RE ('%s'): %w", config.LoglineParseRegexp, err)
+		return nil, err
 	}
-	emptyGroupRegexp, err := regexp.Compile(config.EmptyGroupRE)
+	parser, err := lineparser.New(config.Config)
 	if err != nil {
-		return nil, fmt.Errorf("error while compiling the empty group matching RE ('%s'): %w", config.EmptyGroupRE, err)
+		return nil, err
 	}
 
 	return &Tailer{
-		filename:                config.TailedFile,
-		tail:                    tailFile,
+		patterns:                patterns,
+		positionFile:            config.PositionFile,
+		follow:                  config.Follow,
+		reopen:                  config.Reopen,
+		files:                   map[string]*tailedFile{},
+		lines:                   make(chan fileLine),
+		finishedFiles:           make(chan string),
 		positions:               pos,
 		persistPositionInterval: config.PositionPersistenceInterval,
-		lineParseRegexp:         lineParseRegexp,
-		emptyGroupRegexp:        emptyGroupRegexp,
+		fileDiscoveryInterval:   config.FileDiscoveryInterval,
+		fileMetadataKey:         config.FileMetadataKey,
+		containerLogDecoder:     decoder,
+		partialLines:            map[string]string{},
+		parser:                  parser,
 		outputChannel:           make(chan *event.Raw),
-		shutdownChannel:         make(chan struct{}),
-		done:                    false,
 		logger:                  logger,
+		linesReadTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "lines_read_total",
+			Help: "Total number of lines tailed from the files.",
+		}),
+		malformedLinesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "malformed_lines_total",
+			Help: "Total number of invalid lines that failed to parse by the reason.",
+		}, []string{"reason"}),
+		fileSizeBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "file_size_bytes",
+			Help: "Size of the tailed file in bytes.",
+		}, []string{"file"}),
+		fileOffsetBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "file_offset_bytes",
+			Help: "Current tailing offset within the file in bytes (from the beginning of the file).",
+		}, []string{"file"}),
 	}, nil
 }
 
@@ -166,8 +218,18 @@ func (t *Tailer) observeDuration(start time.Time) {
 	}
 }
 
+func (t *Tailer) SetDeadLetterSink(sink pipeline.DeadLetterSink) {
+	t.deadLetterSink = sink
+}
+
+func (t *Tailer) reject(line string, err error) {
+	if t.deadLetterSink != nil {
+		t.deadLetterSink.Reject(line, err.Error())
+	}
+}
+
 func (t *Tailer) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error {
-	toRegister := []prometheus.Collector{linesReadTotal, malformedLinesTotal, fileSizeBytes, fileOffsetBytes}
+	toRegister := []prometheus.Collector{t.linesReadTotal, t.malformedLinesTotal, t.fileSizeBytes, t.fileOffsetBytes}
 	for _, collector := range toRegister {
 		if err := wrappedRegistry.Register(collector); err != nil {
 			return fmt.Errorf("error registering metric %s: %w", collector, err)
@@ -176,84 +238,234 @@ func (t *Tailer) RegisterMetrics(_, wrappedRegistry prometheus.Registerer) error
 	return nil
 }
 
-func (t *Tailer) Done() bool {
-	return t.done
-}
-
 func (t *Tailer) OutputChannel() chan *event.Raw {
 	return t.outputChannel
 }
 
-// Run starts to tail the associated file, feeding events to output channel.
-func (t *Tailer) Run() {
+// matchingFiles returns sorted paths of all the regular files matching any of the patterns.
+func (t *Tailer) matchingFiles() []string {
+	matching := map[string]struct{}{}
+	for _, pattern := range t.patterns {
+		// Patterns are validated when the tailer is created.
+		paths, _ := filepath.Glob(pattern)
+		for _, path := range paths {
+			if path == t.positionFile {
+				continue
+			}
+			if fstat, err := os.Stat(path); err != nil || !fstat.Mode().IsRegular() {
+				continue
+			}
+			matching[path] = struct{}{}
+		}
+	}
+	paths := make([]string, 0, len(matching))
+	for path := range matching {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// discoverFiles starts tailing of the newly matching files and stops tailing of the files which no longer match.
+func (t *Tailer) discoverFiles() {
+	matching := map[string]struct{}{}
+	for _, path := range t.matchingFiles() {
+		matching[path] = struct{}{}
+		if _, ok := t.files[path]; ok {
+			continue
+		}
+		if err := t.startTailing(path); err != nil {
+			t.logger.WithField("file", path).Errorf("failed to start tailing the file: %v", err)
+		}
+	}
+	for path, file := range t.files {
+		if _, ok := matching[path]; ok || file.removed {
+			continue
+		}
+		t.logger.WithField("file", path).Info("file no longer exists, stopping tailing of the file")
+		file.removed = true
+		t.stopTailing(file)
+	}
+}
+
+// startTailing tails the file from the persisted position and forwards its lines until the tailing stops.
+func (t *Tailer) startTailing(path string) error {
+	// check that loaded position for a file is valid
+	fstat, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("could not check that loaded offset is valid: %w", err)
+	}
+	saved, err := parsePosition(t.positions.GetString(path))
+	if err != nil {
+		return err
+	}
+	offset := saved.offset
+	rotated := ""
+	switch {
+	case saved.inode != 0 && saved.inode != fileInode(fstat):
+		rotated = t.findRotatedFile(path, saved.inode)
+		if rotated == "" {
+			t.logger.WithField("file", path).Warnf("the file was rotated but the rotated file with the loaded position '%d' was not found. Tailer will start from the beginning of the file.", offset)
+		}
+		offset = 0
+	case fstat.Size() < offset:
+		rotated = t.findRotatedFile(path, 0)
+		if rotated == "" {
+			t.logger.WithField("file", path).Warnf("loaded position '%d' for the file is larger that the file size '%d'. Tailer will start from the beginning of the file.", offset, fstat.Size())
+		}
+		offset = 0
+	}
+	if offset == 0 && rotated == "" {
+		t.positions.Remove(path)
+	}
+	// The file is opened by TailFile, so it is the one checked above.
+	file := &tailedFile{inode: fileInode(fstat)}
+	tailFile, err := tail.TailFile(path, tail.Config{
+		Follow:    t.follow,
+		ReOpen:    t.reopen,
+		MustExist: true,
+		Location:  &tail.SeekInfo{Offset: offset, Whence: io.SeekStart},
+		// tail library has claimed problems with inotify: https://github.com/grafana/loki/commit/c994823369d65785e72c4247fd50c656801e429a
+		Poll:   true,
+		Logger: &tailLogger{FieldLogger: t.logger.WithField("file", path), path: path, file: file},
+	})
+	if err != nil {
+		return err
+	}
+	t.logger.WithField("file", path).Infof("tailing the file from offset %d", offset)
+	file.tail = tailFile
+	file.catchingUp.Store(rotated != "")
+	t.files[path] = file
 	go func() {
-		ticker := time.NewTicker(t.persistPositionInterval)
-		defer func() {
-			t.positions.Stop()
-			ticker.Stop()
-			t.tail.Cleanup()
-			close(t.outputChannel)
-			t.done = true
-		}()
-		quitting := false
-		for {
-			select {
-			case line, ok := <-t.tail.Lines:
-				if !ok {
-					t.logger.Info("input channel closed, finishing")
-					return
-				}
-				start := time.Now()
-				if line.Err != nil {
-					t.logger.Error(line.Err)
-				}
-				linesReadTotal.Inc()
-				newEvent, err := t.processLine(line.Text)
-				if err != nil {
-					malformedLinesTotal.Inc()
-					t.logger.WithField("line", line).Errorf("err (%+v) while parsing line", err)
-				} else {
-					t.outputChannel <- newEvent
-				}
-				t.observeDuration(start)
-			case <-ticker.C:
-				if !quitting {
-					// get current offset from tail.TailFile instance
-					if err := t.markOffsetPosition(); err != nil {
-						t.logger.Error(err)
-					}
-				}
-			case <-t.shutdownChannel:
-				if !quitting {
-					quitting = true
-					// we need to perform this strictly once, as tail return 0 offset when already stopped
-					if err := t.markOffsetPosition(); err != nil {
-						t.logger.Error(err)
-					}
-					// keep this in goroutine as this may block on tail's goroutine trying to write into t.tail.Lines
-					go func() {
-						if err := t.tail.Stop(); err != nil {
-							t.logger.Errorf("failed to stop Tailer: %v", err)
-						}
-					}()
-				}
+		if rotated != "" {
+			logger := t.logger.WithField("file", path).WithField("rotatedFile", rotated)
+			logger.Infof("reading the rotated file from offset %d before the tailed file", saved.offset)
+			if err := t.readRotatedFile(path, rotated, saved.offset, tailFile.Dying()); err != nil {
+				logger.Errorf("failed to read the rotated file: %v", err)
 			}
+			file.catchingUp.Store(false)
 		}
+		for line := range tailFile.Lines {
+			file.lineRead()
+			t.lines <- fileLine{path: path, line: line}
+		}
+		t.finishedFiles <- path
 	}()
+	return nil
 }
 
-func (t *Tailer) Stop() {
-	if !t.done {
-		t.shutdownChannel <- struct{}{}
+func (t *Tailer) stopTailing(file *tailedFile) {
+	// keep this in goroutine as this may block on tail's goroutine trying to write into its lines channel
+	go func() {
+		if err := file.tail.Stop(); err != nil {
+			t.logger.WithField("file", file.tail.Filename).Errorf("failed to stop tailing the file: %v", err)
+		}
+	}()
+}
+
+// finishFile cleans up after the tailing of the file stopped, position and metrics of the removed files are dropped.
+func (t *Tailer) finishFile(path string) {
+	file := t.files[path]
+	file.tail.Cleanup()
+	delete(t.files, path)
+	delete(t.partialLines, path)
+	// tail stops on its own if the file is removed and it is not reopened
+	if _, err := os.Stat(path); file.removed || errors.Is(err, os.ErrNotExist) {
+		t.positions.Remove(path)
+		t.fileSizeBytes.DeleteLabelValues(path)
+		t.fileOffsetBytes.DeleteLabelValues(path)
+	}
+}
+
+// Run tails the matching files feeding events to output channel until the context is cancelled.
+// If the files are not followed, it finishes once all the files are read.
+func (t *Tailer) Run(ctx context.Context) error {
+	persistTicker := time.NewTicker(t.persistPositionInterval)
+	defer func() {
+		t.positions.Stop()
+		persistTicker.Stop()
+		close(t.outputChannel)
+	}()
+	var discovery <-chan time.Time
+	if t.follow && t.fileDiscoveryInterval > 0 {
+		discoveryTicker := time.NewTicker(t.fileDiscoveryInterval)
+		defer discoveryTicker.Stop()
+		discovery = discoveryTicker.C
+	}
+	t.discoverFiles()
+	shutdown := ctx.Done()
+	quitting := false
+	for {
+		if len(t.files) == 0 && (quitting || !t.follow) {
+			t.logger.Info("all the files finished, finishing")
+			return nil
+		}
+		select {
+		case fileLine := <-t.lines:
+			start := time.Now()
+			line := fileLine.line
+			if line.Err != nil {
+				t.logger.Error(line.Err)
+			}
+			t.linesReadTotal.Inc()
+			newEvent, err := t.processLine(fileLine.path, line.Text)
+			if err != nil {
+				t.malformedLinesTotal.WithLabelValues(lineparser.MalformedLineReason(err)).Inc()
+				t.logger.WithField("file", fileLine.path).WithField("line", line).Errorf("err (%+v) while parsing line", err)
+				t.reject(line.Text, err)
+			} else if newEvent != nil {
+				t.outputChannel <- newEvent
+			}
+			t.observeDuration(start)
+		case path := <-t.finishedFiles:
+			t.finishFile(path)
+		case <-persistTicker.C:
+			if !quitting {
+				t.markOffsetPositions()
+			}
+		case <-discovery:
+			if !quitting {
+				t.discoverFiles()
+			}
+		case <-shutdown:
+			// we need to perform this strictly once, as tail return 0 offset when already stopped
+			shutdown = nil
+			quitting = true
+			t.markOffsetPositions()
+			for _, file := range t.files {
+				t.stopTailing(file)
+			}
+		}
+	}
+}
+
+// markOffsetPositions marks current offset and size of all the tailed files.
+func (t *Tailer) markOffsetPositions() {
+	for path, file := range t.files {
+		if file.removed || file.catchingUp.Load() {
+			continue
+		}
+		if err := t.markOffsetPosition(path, file); err != nil {
+			t.logger.WithField("file", path).Error(err)
+		}
 	}
 }
 
 // marks current file offset and size for the use of:
 // - offset persistence
 // - prometheus metrics.
-func (t *Tailer) markOffsetPosition() error {
+// The offset is persisted with inode of the file opened by the tail, which differs from the file at the path
+// once the file is rotated until the tail reopens it. Nothing is persisted while the file is being reopened.
+func (t *Tailer) markOffsetPosition(path string, file *tailedFile) error {
+	file.positionMtx.Lock()
+	if file.reopening {
+		file.positionMtx.Unlock()
+		return nil
+	}
+	inode := file.inode
 	// we may lose a log line due to claimed inaccuracy of Tail.tell (https://godoc.org/github.com/hpcloud/tail#Tail.Tell)
-	offset, err := t.tail.Tell()
+	offset, err := file.tail.Tell()
+	file.positionMtx.Unlock()
 	if err != nil {
 		if errors.Unwrap(err) != nil {
 			// include more details about the file inaccessibility, if possible
@@ -261,42 +473,44 @@ func (t *Tailer) markOffsetPosition() error {
 		}
 		return fmt.Errorf("could not get the file offset: %w", err)
 	}
-	fileOffsetBytes.Set(float64(offset))
-	t.positions.Put(t.filename, offset)
+	t.fileOffsetBytes.WithLabelValues(path).Set(float64(offset))
+	t.positions.PutString(path, position{offset: offset, inode: inode}.String())
 
-	fstat, err := os.Stat(t.filename)
+	fstat, err := os.Stat(path)
 	if err != nil {
 		return fmt.Errorf("unable to get file size: %w", err)
 	}
-	fileSizeBytes.Set(float64(fstat.Size()))
+	t.fileSizeBytes.WithLabelValues(path).Set(float64(fstat.Size()))
 
 	return nil
 }
 
-// parseLine parses the given line, producing a RequestEvent instance
-// - lineParseRegexp is used to parse the line
-// - if content of any of the matched named groups matches emptyGroupRegexp, it is replaced by an empty string "".
-func parseLine(lineParseRegexp, emptyGroupRegexp *regexp.Regexp, line string) (map[string]string, error) {
-	lineData := make(map[string]string)
-
-	match := lineParseRegexp.FindStringSubmatch(line)
-	if len(match) != len(lineParseRegexp.SubexpNames()) {
-		return nil, fmt.Errorf("unable to parse line")
-	}
-	for i, name := range lineParseRegexp.SubexpNames() {
-		if i == 0 || name == "" || emptyGroupRegexp.MatchString(match[i]) {
-			continue
+// processLine returns event of the line, nil if the line is partial line of the container log.
+func (t *Tailer) processLine(path, line string) (*event.Raw, error) {
+	var containerLine containerLogLine
+	if t.containerLogDecoder != nil {
+		var complete bool
+		var err error
+		containerLine, complete, err = t.unwrapContainerLine(path, line)
+		if err != nil || !complete {
+			return nil, err
 		}
-		lineData[name] = match[i]
+		line = containerLine.content
 	}
-
-	return lineData, nil
-}
-
-func (t *Tailer) processLine(line string) (*event.Raw, error) {
-	lineData, err := parseLine(t.lineParseRegexp, t.emptyGroupRegexp, line)
+	metadata, timestamp, err := t.parser.Parse(line)
 	if err != nil {
 		return nil, err
 	}
-	return &event.Raw{Quantity: 1, Metadata: lineData}, nil
+	// Time of the container log line is used unless the time is parsed from the line.
+	if timestamp.IsZero() {
+		timestamp = containerLine.timestamp
+	}
+	newEvent := &event.Raw{Metadata: metadata, Quantity: 1, Timestamp: timestamp}
+	if t.fileMetadataKey != "" {
+		newEvent.Metadata[t.fileMetadataKey] = path
+	}
+	if t.containerLogDecoder != nil {
+		newEvent.Metadata = newEvent.Metadata.Merge(podMetadata(path))
+	}
+	return newEvent, nil
 }
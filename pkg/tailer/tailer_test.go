@@ -1,16 +1,18 @@
 package tailer
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"reflect"
-	"regexp"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/seznam/slo-exporter/pkg/event"
+	"github.com/seznam/slo-exporter/pkg/lineparser"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -44,236 +46,6 @@ func getRequestLine(formatMap map[string]string) (requestLine string) {
 	return requestLine
 }
 
-type parseLineTest struct {
-	// lineContentMapping is to be used to generate the request log line via getRequestLine func
-	// (see it for what the defaults are, so that you dont have to fill them for every test case)
-	lineContentMapping map[string]string
-	isLineValid        bool
-}
-
-func Test_ParseLineAndBuildEvent(t *testing.T) {
-	testTable := []parseLineTest{
-		// ipv4
-		{map[string]string{
-			"time":            "12/Nov/2019:10:20:07 +0100",
-			"ip":              "34.65.133.58",
-			"request":         "GET /robots.txt HTTP/1.1",
-			"statusCode":      "200",
-			"requestDuration": "0.123", // in s, as logged by nginx
-			"sloClass":        "-",
-			"sloDomain":       "-",
-			"sloApp":          "-",
-			"sloResult":       "-",
-			"sloEndpoint":     "-",
-			"frpcStatus":      "-",
-			"userAgent":       "-",
-			"referer":         "-",
-		}, true},
-		// ipv6
-		{map[string]string{
-			"time":            "12/Nov/2019:10:20:07 +0100",
-			"ip":              "2001:718:801:230::1",
-			"request":         "GET /robots.txt HTTP/1.1",
-			"statusCode":      "200",
-			"requestDuration": "0.123", // in s, as logged by nginx
-			"sloClass":        "-",
-			"sloDomain":       "-",
-			"sloApp":          "-",
-			"sloResult":       "-",
-			"sloEndpoint":     "-",
-			"frpcStatus":      "-",
-			"userAgent":       "-",
-			"referer":         "-",
-		}, true},
-		// invalid time
-		{map[string]string{
-			"time":            "32/Nov/2019:25:20:07 +0100",
-			"ip":              "2001:718:801:230::1",
-			"request":         "GET /robots.txt HTTP/1.1",
-			"statusCode":      "200x",
-			"requestDuration": "0.123", // in s, as logged by nginx
-			"sloClass":        "-",
-			"sloDomain":       "-",
-			"sloApp":          "-",
-			"sloResult":       "-",
-			"sloEndpoint":     "-",
-			"frpcStatus":      "-",
-			"userAgent":       "-",
-			"referer":         "-",
-		}, false},
-		// invalid request
-		{map[string]string{
-			"time":            "12/Nov/2019:10:20:07 +0100",
-			"ip":              "2001:718:801:230::1",
-			"request":         "invalid-request[eof]",
-			"statusCode":      "200x",
-			"requestDuration": "0.123", // in s, as logged by nginx
-			"sloClass":        "-",
-			"sloDomain":       "-",
-			"sloApp":          "-",
-			"sloResult":       "-",
-			"sloEndpoint":     "-",
-			"frpcStatus":      "-",
-			"userAgent":       "-",
-			"referer":         "-",
-		}, false},
-		// request without protocol
-		{map[string]string{
-			"time":            "12/Nov/2019:10:20:07 +0100",
-			"ip":              "2001:718:801:230::1",
-			"request":         "GET /robots.txt",
-			"statusCode":      "301",
-			"requestDuration": "0.123", // in s, as logged by nginx
-			"sloClass":        "-",
-			"sloDomain":       "-",
-			"sloApp":          "-",
-			"sloResult":       "-",
-			"sloEndpoint":     "-",
-			"frpcStatus":      "-",
-			"userAgent":       "-",
-			"referer":         "-",
-		}, true},
-		// http2.0 proto request
-		{map[string]string{
-			"time":            "12/Nov/2019:10:20:07 +0100",
-			"ip":              "2001:718:801:230::1",
-			"request":         "GET /robots.txt HTTP/2.0",
-			"statusCode":      "200",
-			"requestDuration": "0.123", // in s, as logged by nginx
-			"sloClass":        "-",
-			"sloDomain":       "-",
-			"sloApp":          "-",
-			"sloResult":       "-",
-			"sloEndpoint":     "-",
-			"frpcStatus":      "-",
-			"userAgent":       "-",
-			"referer":         "-",
-		}, true},
-		// zero status code
-		{map[string]string{
-			"time":            "12/Nov/2019:10:20:07 +0100",
-			"ip":              "2001:718:801:230::1",
-			"request":         "GET /robots.txt HTTP/1.1",
-			"statusCode":      "0",
-			"requestDuration": "0.123", // in s, as logged by nginx
-			"sloClass":        "-",
-			"sloDomain":       "-",
-			"sloApp":          "-",
-			"sloResult":       "-",
-			"sloEndpoint":     "-",
-			"frpcStatus":      "-",
-			"userAgent":       "-",
-			"referer":         "-",
-		}, true},
-		// invalid status code
-		{map[string]string{
-			"time":            "12/Nov/2019:10:20:07 +0100",
-			"ip":              "2001:718:801:230::1",
-			"request":         "GET /robots.txt HTTP/1.1",
-			"statusCode":      "xxx",
-			"requestDuration": "0.123", // in s, as logged by nginx
-			"sloClass":        "-",
-			"sloDomain":       "-",
-			"sloApp":          "-",
-			"sloResult":       "-",
-			"sloEndpoint":     "-",
-			"frpcStatus":      "-",
-			"userAgent":       "-",
-			"referer":         "-",
-		}, false},
-		// classified event
-		{map[string]string{
-			"time":            "12/Nov/2019:10:20:07 +0100",
-			"ip":              "2001:718:801:230::1",
-			"request":         "GET /robots.txt HTTP/1.1",
-			"statusCode":      "200",
-			"requestDuration": "0.123", // in s, as logged by nginx
-			"sloClass":        "critical",
-			"sloDomain":       "userportal",
-			"sloApp":          "frontend-api",
-			"sloResult":       "success",
-			"sloEndpoint":     "AdInventoryManagerInterestsQuery",
-			"frpcStatus":      "-",
-			"userAgent":       "-",
-			"referer":         "-",
-		}, true},
-	}
-
-	lineParseRegexpCompiled := regexp.MustCompile(lineParseRegexp)
-	emptyGroupRegexpCompiled := regexp.MustCompile(emptyGroupRegexp)
-	for _, test := range testTable {
-		requestLine := getRequestLine(test.lineContentMapping)
-
-		data, err := parseLine(lineParseRegexpCompiled, emptyGroupRegexpCompiled, requestLine)
-		if err != nil {
-			if test.isLineValid {
-				t.Fatalf("unable to parse request line '%s': %v", requestLine, err)
-			} else {
-				// the tested line is marked as not valid, Err is expected
-				continue
-			}
-		}
-		parsedEvent := &event.Raw{Metadata: data}
-
-		var expectedEvent *event.Raw
-
-		if test.isLineValid {
-			// line is considered valid, build the expectedEvent struct in order to compare it to the parsed one
-
-			// first, drop all data which matches emptyGroupRegexpCompiled, as they should not be included in the data provided to buildEvent
-			for k, v := range test.lineContentMapping {
-				if emptyGroupRegexpCompiled.MatchString(v) {
-					delete(test.lineContentMapping, k)
-				}
-			}
-			expectedEvent = &event.Raw{Metadata: test.lineContentMapping}
-			if !reflect.DeepEqual(expectedEvent, parsedEvent) {
-				t.Errorf("Unexpected result of parse line: %s\nGot: %+v\nExpected: %+v", requestLine, parsedEvent, expectedEvent)
-			}
-		}
-	}
-}
-
-func Test_ParseLine(t *testing.T) {
-	testTable := []parseLineTest{
-		{
-			lineContentMapping: map[string]string{
-				"time":            "12/Nov/2019:10:20:07 +0100",
-				"ip":              "34.65.133.58",
-				"request":         "GET /robots.txt HTTP/1.1",
-				"statusCode":      "200",
-				"requestDuration": "0.123", // in s, as logged by nginx
-				"sloClass":        "-",
-				"sloDomain":       "-",
-				"sloApp":          "-",
-				"sloResult":       "-",
-				"sloEndpoint":     "-",
-				"frpcStatus":      "-",
-			},
-			isLineValid: true,
-		},
-	}
-	lineParseRegexpCompiled := regexp.MustCompile(lineParseRegexp)
-	emptyGroupRegexpCompiled := regexp.MustCompile(emptyGroupRegexp)
-
-	for _, test := range testTable {
-		requestLine := getRequestLine(test.lineContentMapping)
-		data, err := parseLine(lineParseRegexpCompiled, emptyGroupRegexpCompiled, requestLine)
-		if err != nil {
-			t.Fatalf("unable to parse request line '%s': %v", requestLine, err)
-		}
-		for k, v := range test.lineContentMapping {
-			if !emptyGroupRegexpCompiled.MatchString(v) {
-				continue
-			}
-			// test that empty group was correctly replaced by an empty string
-			if _, ok := data[k]; ok {
-				t.Errorf("Content named group '%s':'%s' should not have been included in the resulting stringmap (as value matches emptyGroupRegexp: '%s'): %+v", k, v, emptyGroupRegexp, data)
-			}
-		}
-	}
-}
-
 type offsetPersistenceTest struct {
 	// all values refers to number of events which should be written to a log file at a given phase of test
 	pre    int // *before* the tailing starts
@@ -315,21 +87,23 @@ func offsetPersistenceTestRun(t offsetPersistenceTest) error {
 		}
 	}
 
-	config := tailerConfig{
+	config := TailerConfig{
 		TailedFile:                  fname,
 		Follow:                      true,
 		Reopen:                      true,
 		PositionFile:                positionsFname,
 		PositionPersistenceInterval: persistPositionInterval,
-		LoglineParseRegexp:          lineParseRegexp,
-		EmptyGroupRE:                emptyGroupRegexp,
+		Config:                      lineparser.Config{LoglineParseRegexp: lineParseRegexp, EmptyGroupRE: emptyGroupRegexp},
 	}
 	tailer, err := New(config, logrus.New())
 	if err != nil {
 		return err
 	}
 
-	tailer.Run()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() { runErr <- tailer.Run(ctx) }()
 	go countEvents(tailer.OutputChannel(), eventCount)
 
 	for i := 0; i < t.during; i++ {
@@ -339,8 +113,11 @@ func offsetPersistenceTestRun(t offsetPersistenceTest) error {
 	}
 	time.Sleep(time.Second)
 
-	tailer.Stop()
+	cancel()
 	eventsCount := <-eventCount
+	if err := <-runErr; err != nil {
+		return err
+	}
 
 	if eventsCount != t.pre+t.during {
 		return fmt.Errorf("Number of processed events during first open of a log file does not match: got '%d', expected '%d'", eventsCount, t.pre+t.during)
@@ -356,7 +133,9 @@ func offsetPersistenceTestRun(t offsetPersistenceTest) error {
 	if err != nil {
 		return err
 	}
-	tailer.Run()
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go func() { runErr <- tailer.Run(ctx) }()
 	go countEvents(tailer.OutputChannel(), eventCount)
 
 	for i := 0; i < t.reopen; i++ {
@@ -367,8 +146,11 @@ func offsetPersistenceTestRun(t offsetPersistenceTest) error {
 
 	time.Sleep(time.Second)
 
-	tailer.Stop()
+	cancel()
 	eventsCount = <-eventCount
+	if err := <-runErr; err != nil {
+		return err
+	}
 
 	if eventsCount != t.post+t.reopen {
 		return fmt.Errorf("Number of processed events after reopening a log file does not match: got '%d', expected '%d'", eventsCount, t.post+t.reopen)
@@ -400,7 +182,106 @@ func TestGetDefaultPositionsFilePath(t *testing.T) {
 	}
 
 	for logFile, posFile := range testData {
-		config := tailerConfig{TailedFile: logFile}
+		config := TailerConfig{TailedFile: logFile}
 		assert.Equal(t, posFile, config.getDefaultPositionsFilePath())
 	}
 }
+
+func writeLines(t *testing.T, path string, count int) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	assert.NoError(t, err)
+	defer f.Close()
+	for i := 0; i < count; i++ {
+		_, err := f.WriteString(getRequestLine(requestLineFormatMapValid) + "\n")
+		assert.NoError(t, err)
+	}
+}
+
+// collectFiles returns number of events read from each file.
+func collectFiles(in chan *event.Raw, out chan map[string]int) {
+	files := map[string]int{}
+	for e := range in {
+		files[e.Metadata["file"]]++
+	}
+	out <- files
+}
+
+func TestTailer_MultipleFiles(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.log"), filepath.Join(dir, "second.log")
+	writeLines(t, first, 2)
+	writeLines(t, second, 3)
+	writeLines(t, filepath.Join(dir, "ignored.txt"), 1)
+
+	tailer, err := New(TailerConfig{
+		TailedFile:                  filepath.Join(dir, "*.log"),
+		PositionFile:                filepath.Join(dir, "positions.log"),
+		PositionPersistenceInterval: time.Second,
+		FileMetadataKey:             "file",
+		Config:                      lineparser.Config{LoglineParseRegexp: lineParseRegexp, EmptyGroupRE: emptyGroupRegexp},
+	}, logrus.New())
+	assert.NoError(t, err)
+	files := make(chan map[string]int)
+	go collectFiles(tailer.OutputChannel(), files)
+	assert.NoError(t, tailer.Run(context.Background()))
+	assert.Equal(t, map[string]int{first: 2, second: 3}, <-files)
+}
+
+func TestTailer_FileDiscovery(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.log"), filepath.Join(dir, "second.log")
+	writeLines(t, first, 1)
+
+	tailer, err := New(TailerConfig{
+		TailedFiles:                 []string{filepath.Join(dir, "*.log")},
+		Follow:                      true,
+		PositionFile:                filepath.Join(dir, "positions.yaml"),
+		PositionPersistenceInterval: time.Second,
+		FileDiscoveryInterval:       10 * time.Millisecond,
+		FileMetadataKey:             "file",
+		Config:                      lineparser.Config{LoglineParseRegexp: lineParseRegexp, EmptyGroupRE: emptyGroupRegexp},
+	}, logrus.New())
+	assert.NoError(t, err)
+	files := make(chan map[string]int)
+	go collectFiles(tailer.OutputChannel(), files)
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error)
+	go func() { runErr <- tailer.Run(ctx) }()
+
+	writeLines(t, second, 2)
+	time.Sleep(time.Second)
+	assert.NoError(t, os.Remove(first))
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	assert.NoError(t, <-runErr)
+	assert.Equal(t, map[string]int{first: 1, second: 2}, <-files)
+	// Position of the removed file is dropped.
+	positions, err := os.ReadFile(filepath.Join(dir, "positions.yaml"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(positions), first)
+	assert.Contains(t, string(positions), second)
+}
+
+func TestNew_positionFile(t *testing.T) {
+	dir := t.TempDir()
+	_, err := New(TailerConfig{TailedFile: filepath.Join(dir, "*.log")}, logrus.New())
+	assert.Error(t, err, "position file is required for glob pattern")
+	_, err = New(TailerConfig{TailedFile: filepath.Join(dir, "missing.log"), PositionFile: filepath.Join(dir, "positions.yaml")}, logrus.New())
+	assert.Error(t, err, "explicitly configured file must exist")
+}
+
+func TestNewFromViper_fileMetadataKey(t *testing.T) {
+	dir := t.TempDir()
+	viperConfig := viper.New()
+	viperConfig.Set("TailedFile", filepath.Join(dir, "*.log"))
+	viperConfig.Set("PositionFile", filepath.Join(dir, "positions.yaml"))
+	viperConfig.Set("LoglineParseRegexp", lineParseRegexp)
+	tailer, err := NewFromViper(viperConfig, logrus.New())
+	assert.NoError(t, err)
+	assert.Equal(t, "file", tailer.fileMetadataKey)
+
+	viperConfig.Set("FileMetadataKey", "")
+	tailer, err = NewFromViper(viperConfig, logrus.New())
+	assert.NoError(t, err)
+	assert.Equal(t, "", tailer.fileMetadataKey, "path of the file is not added if the key is empty")
+}